@@ -9,17 +9,34 @@ import (
 	"math/rand"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/micromdm/nanocmd/engine"
 	enginehttp "github.com/micromdm/nanocmd/engine/http"
 	httpcmd "github.com/micromdm/nanocmd/http"
+	"github.com/micromdm/nanocmd/http/api"
+	"github.com/micromdm/nanocmd/http/apikey"
 	"github.com/micromdm/nanocmd/logkeys"
 	"github.com/micromdm/nanocmd/mdm/foss"
+	"github.com/micromdm/nanocmd/subsystem/audit"
+	audithttp "github.com/micromdm/nanocmd/subsystem/audit/http"
+	baselinehttp "github.com/micromdm/nanocmd/subsystem/baseline/http"
 	cmdplanhttp "github.com/micromdm/nanocmd/subsystem/cmdplan/http"
 	fvenablehttp "github.com/micromdm/nanocmd/subsystem/filevault/http"
+	grouphttp "github.com/micromdm/nanocmd/subsystem/group/http"
 	invhttp "github.com/micromdm/nanocmd/subsystem/inventory/http"
+	"github.com/micromdm/nanocmd/subsystem/maintenance/finder"
+	mainthttp "github.com/micromdm/nanocmd/subsystem/maintenance/http"
 	profhttp "github.com/micromdm/nanocmd/subsystem/profile/http"
+	rollouthttp "github.com/micromdm/nanocmd/subsystem/rollout/http"
+	rolloutmanager "github.com/micromdm/nanocmd/subsystem/rollout/manager"
+	schedhttp "github.com/micromdm/nanocmd/subsystem/schedule/http"
+	"github.com/micromdm/nanocmd/subsystem/schedule/scheduler"
+	"github.com/micromdm/nanocmd/subsystem/secret"
+	secrethttp "github.com/micromdm/nanocmd/subsystem/secret/http"
+	"github.com/micromdm/nanocmd/utils/mobileconfig"
+	"github.com/micromdm/nanocmd/workflow"
 
 	"github.com/alexedwards/flow"
 	"github.com/micromdm/nanolib/envflag"
@@ -43,6 +60,7 @@ func main() {
 		flVersion = flag.Bool("version", false, "print version and exit")
 		flDumpWH  = flag.Bool("dump-webhook", false, "dump webhook input")
 		flAPIKey  = flag.String("api", "", "API key for API endpoints")
+		flAPIKeys = flag.String("api-keys", "", "path to JSON file of named API key credentials")
 		flEnqURL  = flag.String("enqueue-url", "", "URL of MDM server enqueue endpoint")
 		flPushURL = flag.String("push-url", "", "URL of MDM server push endpoint")
 		flEnqAPI  = flag.String("enqueue-api", "", "MDM server API key")
@@ -53,6 +71,16 @@ func main() {
 		flWorkSec = flag.Uint("worker-interval", uint(engine.DefaultDuration/time.Second), "interval for worker in seconds")
 		flPushSec = flag.Uint("repush-interval", uint(engine.DefaultRePushDuration/time.Second), "interval for repushes in seconds")
 		flStTOSec = flag.Uint("step-timeout", uint(engine.DefaultTimeout/time.Second), "default step timeout in seconds")
+		flMaxQued = flag.Uint("max-queued", workflow.DefaultMaxQueued, "default maximum queued starts per enrollment of queued workflows")
+		flSgnCert = flag.String("sign-cert", "", "path to PEM profile signing certificate")
+		flSgnKey  = flag.String("sign-key", "", "path to PEM profile signing private key")
+		flSgnP12  = flag.String("sign-p12", "", "path to PKCS#12 profile signing identity")
+		flSgnPass = flag.String("sign-p12-pass", "", "password of PKCS#12 profile signing identity")
+		flSgnInst = flag.Bool("sign-at-install", false, "sign profiles when installing instead of on upload")
+		flApprove = flag.String("approval-workflows", "", "comma-separated workflow names that require approval to start")
+		flApprSec = flag.Uint("approval-expiry", uint(engine.DefaultApprovalExpiry/time.Second), "expiry of pending approval requests in seconds")
+		flSecKeys = flag.String("secret-keys", "", "path to file of hex-encoded secret key encryption keys")
+		flRotPRK  = flag.Bool("rotate-prk-on-view", false, "start the FileVault rotate workflow after a PRK is retrieved")
 	)
 	envflag.Parse("NANOCMD_", []string{"version"})
 
@@ -68,13 +96,56 @@ func main() {
 		os.Exit(1)
 	}
 
+	// load the key encryption keys of secrets (e.g. FileVault PRKs).
+	// the FileVault and lock workflows are always configured so their
+	// secret storage always needs keys.
+	if *flSecKeys == "" {
+		logger.Info(logkeys.Error, "secret keys required (see -secret-keys)")
+		os.Exit(1)
+	}
+	keks, err := secret.ReadKeyFile(*flSecKeys)
+	if err == nil && len(keks) < 1 {
+		err = secret.ErrNoKeys
+	}
+	if err != nil {
+		logger.Info(logkeys.Message, "reading secret keys", logkeys.Error, err)
+		os.Exit(1)
+	}
+
 	// configure storage
-	storage, err := parseStorage(*flStorage, *flDSN, *flOptions)
+	storage, err := parseStorage(*flStorage, *flDSN, *flOptions, keks)
 	if err != nil {
 		logger.Info(logkeys.Message, "parse storage", logkeys.Error, err)
 		os.Exit(1)
 	}
 
+	// re-wrap secrets sealed with previous key encryption keys
+	rewrapped, err := storage.secrets.Rewrap(context.Background())
+	if err != nil {
+		logger.Info(logkeys.Message, "re-wrapping secrets", logkeys.GenericCount, rewrapped, logkeys.Error, err)
+	} else if rewrapped > 0 {
+		logger.Info(logkeys.Message, "re-wrapped secrets", logkeys.GenericCount, rewrapped)
+	}
+
+	// encrypt plaintext secrets stored in inventory by earlier versions
+	migrated, err := storage.secrets.MigrateInventory(context.Background(), storage.inventory)
+	if err != nil {
+		logger.Info(logkeys.Message, "migrating inventory secrets", logkeys.GenericCount, migrated, logkeys.Error, err)
+	} else if migrated > 0 {
+		logger.Info(logkeys.Message, "migrated inventory secrets", logkeys.GenericCount, migrated)
+	}
+
+	// configure profile signing
+	signer, err := loadSigner(*flSgnCert, *flSgnKey, *flSgnP12, *flSgnPass)
+	if err != nil {
+		logger.Info(logkeys.Message, "loading profile signer", logkeys.Error, err)
+		os.Exit(1)
+	}
+	var installSigner *mobileconfig.Signer
+	if *flSgnInst {
+		installSigner = signer
+	}
+
 	// configure our "MDM" i.e. how we send commands and receive responses
 	opts := []foss.Option{
 		foss.WithLogger(logger.With("service", "mdm")),
@@ -89,15 +160,46 @@ func main() {
 		os.Exit(1)
 	}
 
+	// configure the audit log of API requests and engine-initiated starts
+	auditor := audit.New(storage.audit, audit.WithLogger(logger.With("service", "audit")))
+
+	// configure the rollout manager. it starts workflows using the
+	// engine and observes the outcomes of the engine's steps.
+	var e *engine.Engine
+	rollouts := rolloutmanager.New(
+		storage.rollout,
+		engineStarter{&e},
+		rolloutmanager.WithLogger(logger.With("service", "rollout")),
+		rolloutmanager.WithGroupStorage(storage.group),
+		rolloutmanager.WithAuditor(auditor),
+	)
+
 	// configure the workflow engine
-	eOpts := []engine.Option{engine.WithLogger(logger.With("service", "engine"))}
+	eOpts := []engine.Option{
+		engine.WithLogger(logger.With("service", "engine")),
+		engine.WithStepObserver(rollouts),
+		engine.WithAuditor(auditor),
+		engine.WithMaintenanceWindows(finder.New(
+			storage.maint,
+			finder.WithGroupStorage(storage.group),
+		)),
+	}
 	if *flStTOSec > 0 {
 		eOpts = append(eOpts, engine.WithDefaultTimeout(time.Second*time.Duration(*flStTOSec)))
 	}
+	if *flApprove != "" {
+		eOpts = append(eOpts, engine.WithApprovalWorkflows(strings.Split(*flApprove, ",")...))
+	}
+	eOpts = append(eOpts, engine.WithApprovalExpiry(time.Second*time.Duration(*flApprSec)))
+	eOpts = append(eOpts, engine.WithMaxQueued(int(*flMaxQued)))
 	if storage.event != nil {
-		eOpts = append(eOpts, engine.WithEventStorage(storage.event))
+		eOpts = append(eOpts,
+			engine.WithEventStorage(storage.event),
+			engine.WithEventStatsStorage(storage.event),
+			engine.WithInventory(storage.inventory),
+		)
 	}
-	e := engine.New(storage.engine, fossMDM, eOpts...)
+	e = engine.New(storage.engine, fossMDM, eOpts...)
 
 	// configure the workflow engine worker (async runner/job)
 	var eWorker *engine.Worker
@@ -105,6 +207,18 @@ func main() {
 		wOpts := []engine.WorkerOption{
 			engine.WithWorkerLogger(logger.With("service", "engine worker")),
 			engine.WithWorkerDuration(time.Second * time.Duration(*flWorkSec)),
+			engine.WithWorkerPendingStarter(e),
+			engine.WithWorkerStepFinisher(e),
+			engine.WithWorkerStepObserver(rollouts),
+			engine.WithWorkerRolloutRunner(rollouts),
+			engine.WithWorkerApprovalExpirer(e),
+			engine.WithWorkerScheduler(scheduler.New(
+				storage.schedule,
+				e,
+				scheduler.WithLogger(logger.With("service", "scheduler")),
+				scheduler.WithGroupStorage(storage.group),
+				scheduler.WithAuditor(auditor),
+			)),
 		}
 		if *flPushSec > 0 {
 			wOpts = append(wOpts, engine.WithWorkerRePushDuration(time.Second*time.Duration(*flPushSec)))
@@ -118,12 +232,34 @@ func main() {
 	}
 
 	// register workflows with the engine
-	err = registerWorkflows(logger, e, storage, e)
+	err = registerWorkflows(logger, e, storage, e, installSigner)
 	if err != nil {
 		logger.Info(logkeys.Message, "registering workflows", logkeys.Error, err)
 		os.Exit(1)
 	}
 
+	// configure API authentication
+	var apiAuth *apikey.Authenticator
+	if *flAPIKey != "" || *flAPIKeys != "" {
+		apiAuth, err = loadAPIKeys(*flAPIKey, *flAPIKeys)
+		if err != nil {
+			logger.Info(logkeys.Message, "loading API keys", logkeys.Error, err)
+			os.Exit(1)
+		}
+	}
+
+	// refuse to start if approval requests could never be decided
+	if approvalWorkflows := e.ApprovalWorkflows(); len(approvalWorkflows) > 0 {
+		if err = checkApprovers(apiAuth); err != nil {
+			logger.Info(
+				logkeys.Message, "checking approvers",
+				"approval_workflows", strings.Join(approvalWorkflows, ","),
+				logkeys.Error, err,
+			)
+			os.Exit(1)
+		}
+	}
+
 	mux := flow.New()
 
 	mux.Handle("/version", nanohttp.NewJSONVersionHandler(version))
@@ -139,17 +275,32 @@ func main() {
 
 	mux.Handle("/webhook", h)
 
-	if *flAPIKey != "" {
+	if apiAuth != nil {
 		mux.Group(func(mux *flow.Mux) {
 			mux.Use(func(h http.Handler) http.Handler {
-				return nanohttp.NewSimpleBasicAuthHandler(h, apiUsername, *flAPIKey, apiRealm)
+				return apikey.NewBasicAuthHandler(h, apiAuth, apiRealm)
+			})
+			mux.Use(func(h http.Handler) http.Handler {
+				return audithttp.NewAuditHandler(h, auditor)
 			})
 
-			enginehttp.HandleAPIv1("/v1", mux, logger, e, storage.event)
-			invhttp.HandleAPIv1("/v1", mux, logger, storage.inventory)
-			profhttp.HandleAPIv1("/v1", mux, logger, storage.profile)
-			fvenablehttp.HandleAPIv1("/v1", mux)
+			enginehttp.HandleAPIv1("/v1", mux, logger, e, storage.engine)
+			invhttp.HandleAPIv1("/v1", mux, logger, storage.inventory, storage.apps, storage.certs)
+			profhttp.HandleAPIv1("/v1", mux, logger, storage.profile, storage.profiles, signer, *flSgnInst)
+			fvenablehttp.HandleAPIv1("/v1", mux, logger)
 			cmdplanhttp.HandleAPIv1("/v1", mux, logger, storage.cmdplan)
+			grouphttp.HandleAPIv1("/v1", mux, logger, storage.group)
+			baselinehttp.HandleAPIv1("/v1", mux, logger, storage.baseline)
+			schedhttp.HandleAPIv1("/v1", mux, logger, storage.schedule, e)
+			rollouthttp.HandleAPIv1("/v1", mux, logger, storage.rollout, rollouts, e)
+			mainthttp.HandleAPIv1("/v1", mux, logger, storage.maint)
+			audithttp.HandleAPIv1("/v1", mux, logger, storage.audit)
+
+			var rotator secrethttp.WorkflowStarter
+			if *flRotPRK {
+				rotator = e
+			}
+			secrethttp.HandleAPIv1("/v1", mux, logger, storage.filevault, storage.secrets, storage.inventory, auditor, rotator)
 		})
 	}
 
@@ -177,6 +328,55 @@ func main() {
 	logger.Info(logs...)
 }
 
+// loadAPIKeys creates an API key authenticator for the credentials in
+// the file at path. A non-empty key is added as a credential for the
+// "nanocmd" user with all permissions.
+func loadAPIKeys(key, path string) (*apikey.Authenticator, error) {
+	var creds []apikey.Credential
+	if path != "" {
+		var err error
+		if creds, err = apikey.ReadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if key != "" {
+		creds = append(creds, apikey.Credential{
+			Name:        apiUsername,
+			KeySHA256:   apikey.HashKey(key),
+			Permissions: []string{api.PermAll},
+		})
+	}
+	return apikey.New(creds...)
+}
+
+// checkApprovers returns an error if a has no approver for approval
+// requests. Approval requests are decided by a principal with the
+// approvals:decide permission other than the requester. The "nanocmd"
+// principal of -api alone can therefore never decide them.
+func checkApprovers(a *apikey.Authenticator) error {
+	if a == nil {
+		return errors.New("no API keys configured: see -api-keys")
+	}
+	approvers := a.Permitted(api.PermApprovalsDecide)
+	if len(approvers) < 1 {
+		return fmt.Errorf("no API key has the %s permission: see -api-keys", api.PermApprovalsDecide)
+	}
+	if a.Len() < 2 {
+		return fmt.Errorf("API key %s can not decide its own approval requests: see -api-keys", approvers[0])
+	}
+	return nil
+}
+
+// engineStarter starts workflows with an engine that is created after
+// the components (i.e. step observers) that the engine depends on.
+type engineStarter struct {
+	e **engine.Engine
+}
+
+func (s engineStarter) StartWorkflow(ctx context.Context, name string, context []byte, ids []string, ev *workflow.Event, mdmCtx *workflow.MDMContext) (string, error) {
+	return (*s.e).StartWorkflow(ctx, name, context, ids, ev, mdmCtx)
+}
+
 type NullHandler struct{}
 
 func (h *NullHandler) WebhookConnectEvent(ctx context.Context, id string, uuid string, raw []byte) error {
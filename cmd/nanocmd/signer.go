@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/micromdm/nanocmd/utils/cryptoutil"
+	"github.com/micromdm/nanocmd/utils/mobileconfig"
+)
+
+// loadSigner loads a profile signing identity from either PEM cert and
+// key files or a PKCS#12 file. A nil signer is returned if no files
+// are configured.
+func loadSigner(certPath, keyPath, p12Path, p12Pass string) (*mobileconfig.Signer, error) {
+	if p12Path != "" {
+		if certPath != "" || keyPath != "" {
+			return nil, errors.New("both PKCS#12 and PEM signing identity specified")
+		}
+		data, err := os.ReadFile(p12Path)
+		if err != nil {
+			return nil, fmt.Errorf("reading PKCS#12: %w", err)
+		}
+		cert, key, intermediates, err := cryptoutil.ParsePKCS12Identity(data, p12Pass)
+		if err != nil {
+			return nil, err
+		}
+		return mobileconfig.NewSigner(cert, key, intermediates...)
+	}
+	if certPath == "" && keyPath == "" {
+		return nil, nil
+	}
+	if certPath == "" || keyPath == "" {
+		return nil, errors.New("both signing certificate and key required")
+	}
+	certPEM, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading signing certificate: %w", err)
+	}
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading signing key: %w", err)
+	}
+	cert, key, intermediates, err := cryptoutil.ParsePEMIdentity(certPEM, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	return mobileconfig.NewSigner(cert, key, intermediates...)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"database/sql"
 	"fmt"
 	"path/filepath"
 
@@ -8,38 +9,92 @@ import (
 	storageengdiskv "github.com/micromdm/nanocmd/engine/storage/diskv"
 	storageenginmem "github.com/micromdm/nanocmd/engine/storage/inmem"
 	storageengmysql "github.com/micromdm/nanocmd/engine/storage/mysql"
+	storageaudit "github.com/micromdm/nanocmd/subsystem/audit/storage"
+	storageauditdiskv "github.com/micromdm/nanocmd/subsystem/audit/storage/diskv"
+	storageauditinmem "github.com/micromdm/nanocmd/subsystem/audit/storage/inmem"
+	storageauditmysql "github.com/micromdm/nanocmd/subsystem/audit/storage/mysql"
+	storagebaseline "github.com/micromdm/nanocmd/subsystem/baseline/storage"
+	storagebaselinediskv "github.com/micromdm/nanocmd/subsystem/baseline/storage/diskv"
+	storagebaselineinmem "github.com/micromdm/nanocmd/subsystem/baseline/storage/inmem"
+	storagebaselinemysql "github.com/micromdm/nanocmd/subsystem/baseline/storage/mysql"
 	storagecmdplan "github.com/micromdm/nanocmd/subsystem/cmdplan/storage"
 	storagecmdplandiskv "github.com/micromdm/nanocmd/subsystem/cmdplan/storage/diskv"
 	storagecmdplaninmem "github.com/micromdm/nanocmd/subsystem/cmdplan/storage/inmem"
+	storagecmdplanmysql "github.com/micromdm/nanocmd/subsystem/cmdplan/storage/mysql"
 	storagefv "github.com/micromdm/nanocmd/subsystem/filevault/storage"
 	storagefvdiskv "github.com/micromdm/nanocmd/subsystem/filevault/storage/diskv"
 	storagefvinmem "github.com/micromdm/nanocmd/subsystem/filevault/storage/inmem"
-	storagefvinvprk "github.com/micromdm/nanocmd/subsystem/filevault/storage/invprk"
+	storagefvsecretprk "github.com/micromdm/nanocmd/subsystem/filevault/storage/secretprk"
+	storagegroup "github.com/micromdm/nanocmd/subsystem/group/storage"
+	storagegroupdiskv "github.com/micromdm/nanocmd/subsystem/group/storage/diskv"
+	storagegroupinmem "github.com/micromdm/nanocmd/subsystem/group/storage/inmem"
+	storagegroupmysql "github.com/micromdm/nanocmd/subsystem/group/storage/mysql"
 	storageinv "github.com/micromdm/nanocmd/subsystem/inventory/storage"
 	storageinvdiskv "github.com/micromdm/nanocmd/subsystem/inventory/storage/diskv"
 	storageinvinmem "github.com/micromdm/nanocmd/subsystem/inventory/storage/inmem"
+	storagemaint "github.com/micromdm/nanocmd/subsystem/maintenance/storage"
+	storagemaintdiskv "github.com/micromdm/nanocmd/subsystem/maintenance/storage/diskv"
+	storagemaintinmem "github.com/micromdm/nanocmd/subsystem/maintenance/storage/inmem"
+	storagemaintmysql "github.com/micromdm/nanocmd/subsystem/maintenance/storage/mysql"
 	storageprof "github.com/micromdm/nanocmd/subsystem/profile/storage"
 	storageprofdiskv "github.com/micromdm/nanocmd/subsystem/profile/storage/diskv"
 	storageprofinmem "github.com/micromdm/nanocmd/subsystem/profile/storage/inmem"
 	storageprofmysql "github.com/micromdm/nanocmd/subsystem/profile/storage/mysql"
+	storagerollout "github.com/micromdm/nanocmd/subsystem/rollout/storage"
+	storagerolloutdiskv "github.com/micromdm/nanocmd/subsystem/rollout/storage/diskv"
+	storagerolloutinmem "github.com/micromdm/nanocmd/subsystem/rollout/storage/inmem"
+	storagerolloutmysql "github.com/micromdm/nanocmd/subsystem/rollout/storage/mysql"
+	storagesched "github.com/micromdm/nanocmd/subsystem/schedule/storage"
+	storagescheddiskv "github.com/micromdm/nanocmd/subsystem/schedule/storage/diskv"
+	storageschedinmem "github.com/micromdm/nanocmd/subsystem/schedule/storage/inmem"
+	storageschedmysql "github.com/micromdm/nanocmd/subsystem/schedule/storage/mysql"
+	"github.com/micromdm/nanocmd/subsystem/secret"
+	storagesecret "github.com/micromdm/nanocmd/subsystem/secret/storage"
+	storagesecretdiskv "github.com/micromdm/nanocmd/subsystem/secret/storage/diskv"
+	storagesecretinmem "github.com/micromdm/nanocmd/subsystem/secret/storage/inmem"
+	storagesecretmysql "github.com/micromdm/nanocmd/subsystem/secret/storage/mysql"
 
 	_ "github.com/go-sql-driver/mysql"
 )
 
 type storageConfig struct {
 	inventory storageinv.Storage
+	apps      storageinv.AppStorage
+	certs     storageinv.CertStorage
+	profiles  storageinv.InstalledProfileStorage
 	engine    storageeng.AllStorage
 	profile   storageprof.Storage
 	cmdplan   storagecmdplan.Storage
 	event     storageeng.EventSubscriptionStorage
 	filevault storagefv.FVRotate
+	group     storagegroup.Storage
+	baseline  storagebaseline.Storage
+	schedule  storagesched.Storage
+	rollout   storagerollout.Storage
+	maint     storagemaint.Storage
+	audit     storageaudit.Storage
+	secrets   *secret.Secrets
 }
 
-func parseStorage(name, dsn, _ string) (*storageConfig, error) {
+// newSecrets creates secrets stored in store sealed with keks.
+// KEKs are never stored alongside the secrets.
+func newSecrets(store storagesecret.Storage, keks [][]byte) (*secret.Secrets, error) {
+	keyring, err := secret.NewKeyring(keks...)
+	if err != nil {
+		return nil, fmt.Errorf("creating keyring: %w", err)
+	}
+	return secret.New(store, keyring), nil
+}
+
+func parseStorage(name, dsn, _ string, keks [][]byte) (*storageConfig, error) {
 	switch name {
 	case "inmem":
 		inv := storageinvinmem.New()
-		fv, err := storagefvinmem.New(storagefvinvprk.NewInvPRK(inv))
+		secrets, err := newSecrets(storagesecretinmem.New(), keks)
+		if err != nil {
+			return nil, fmt.Errorf("creating secret inmem storage: %w", err)
+		}
+		fv, err := storagefvinmem.New(storagefvsecretprk.New(secrets, inv))
 		if err != nil {
 			return nil, fmt.Errorf("creating filevault inmem storage: %w", err)
 		}
@@ -47,17 +102,31 @@ func parseStorage(name, dsn, _ string) (*storageConfig, error) {
 		return &storageConfig{
 			engine:    eng,
 			inventory: inv,
+			apps:      inv,
+			certs:     inv,
+			profiles:  inv,
 			profile:   storageprofinmem.New(),
 			cmdplan:   storagecmdplaninmem.New(),
 			event:     eng,
 			filevault: fv,
+			group:     storagegroupinmem.New(),
+			baseline:  storagebaselineinmem.New(),
+			schedule:  storageschedinmem.New(),
+			rollout:   storagerolloutinmem.New(),
+			maint:     storagemaintinmem.New(),
+			audit:     storageauditinmem.New(),
+			secrets:   secrets,
 		}, nil
 	case "file", "diskv":
 		if dsn == "" {
 			dsn = "db"
 		}
 		inv := storageinvdiskv.New(filepath.Join(dsn, "inventory"))
-		fv, err := storagefvdiskv.New(filepath.Join(dsn, "fvkey"), storagefvinvprk.NewInvPRK(inv))
+		secrets, err := newSecrets(storagesecretdiskv.New(filepath.Join(dsn, "secret")), keks)
+		if err != nil {
+			return nil, fmt.Errorf("creating secret diskv storage: %w", err)
+		}
+		fv, err := storagefvdiskv.New(filepath.Join(dsn, "fvkey"), storagefvsecretprk.New(secrets, inv))
 		if err != nil {
 			return nil, fmt.Errorf("creating filevault diskv storage: %w", err)
 		}
@@ -65,14 +134,32 @@ func parseStorage(name, dsn, _ string) (*storageConfig, error) {
 		return &storageConfig{
 			engine:    eng,
 			inventory: inv,
+			apps:      inv,
+			certs:     inv,
+			profiles:  inv,
 			profile:   storageprofdiskv.New(filepath.Join(dsn, "profile")),
 			cmdplan:   storagecmdplandiskv.New(filepath.Join(dsn, "cmdplan")),
 			event:     eng,
 			filevault: fv,
+			group:     storagegroupdiskv.New(filepath.Join(dsn, "group")),
+			baseline:  storagebaselinediskv.New(filepath.Join(dsn, "baseline")),
+			schedule:  storagescheddiskv.New(filepath.Join(dsn, "schedule")),
+			rollout:   storagerolloutdiskv.New(filepath.Join(dsn, "rollout")),
+			maint:     storagemaintdiskv.New(filepath.Join(dsn, "maintenance")),
+			audit:     storageauditdiskv.New(filepath.Join(dsn, "audit")),
+			secrets:   secrets,
 		}, nil
 	case "mysql":
 		inv := storageinvinmem.New()
-		fv, err := storagefvinmem.New(storagefvinvprk.NewInvPRK(inv))
+		secretStore, err := storagesecretmysql.New(storagesecretmysql.WithDSN(dsn))
+		if err != nil {
+			return nil, err
+		}
+		secrets, err := newSecrets(secretStore, keks)
+		if err != nil {
+			return nil, fmt.Errorf("creating secret mysql storage: %w", err)
+		}
+		fv, err := storagefvinmem.New(storagefvsecretprk.New(secrets, inv))
 		if err != nil {
 			return nil, fmt.Errorf("creating filevault inmem storage: %w", err)
 		}
@@ -84,13 +171,35 @@ func parseStorage(name, dsn, _ string) (*storageConfig, error) {
 		if err != nil {
 			return nil, err
 		}
+		audit, err := storageauditmysql.New(storageauditmysql.WithDSN(dsn))
+		if err != nil {
+			return nil, err
+		}
+		// subsystems implemented with key-value storage share a table
+		kvDB, err := sql.Open("mysql", dsn)
+		if err != nil {
+			return nil, err
+		}
+		if err = kvDB.Ping(); err != nil {
+			return nil, err
+		}
 		return &storageConfig{
 			engine:    eng,
 			inventory: inv,
+			apps:      inv,
+			certs:     inv,
+			profiles:  inv,
 			profile:   prof,
-			cmdplan:   storagecmdplaninmem.New(),
+			cmdplan:   storagecmdplanmysql.New(kvDB),
 			event:     eng,
 			filevault: fv,
+			group:     storagegroupmysql.New(kvDB),
+			baseline:  storagebaselinemysql.New(kvDB),
+			schedule:  storageschedmysql.New(kvDB),
+			rollout:   storagerolloutmysql.New(kvDB),
+			maint:     storagemaintmysql.New(kvDB),
+			audit:     audit,
+			secrets:   secrets,
 		}, nil
 	}
 	return nil, fmt.Errorf("unknown storage: %s", name)
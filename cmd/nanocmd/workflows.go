@@ -3,7 +3,12 @@ package main
 import (
 	"fmt"
 
+	"github.com/micromdm/nanocmd/subsystem/profile/render"
+	"github.com/micromdm/nanocmd/utils/mobileconfig"
 	"github.com/micromdm/nanocmd/workflow"
+	"github.com/micromdm/nanocmd/workflow/appinv"
+	"github.com/micromdm/nanocmd/workflow/baseline"
+	"github.com/micromdm/nanocmd/workflow/certinv"
 	"github.com/micromdm/nanocmd/workflow/certprof"
 	"github.com/micromdm/nanocmd/workflow/cmdplan"
 	"github.com/micromdm/nanocmd/workflow/devinfolog"
@@ -12,6 +17,7 @@ import (
 	"github.com/micromdm/nanocmd/workflow/inventory"
 	"github.com/micromdm/nanocmd/workflow/lock"
 	"github.com/micromdm/nanocmd/workflow/profile"
+	"github.com/micromdm/nanocmd/workflow/restart"
 
 	"github.com/micromdm/nanolib/log"
 )
@@ -20,22 +26,61 @@ type registerer interface {
 	RegisterWorkflow(w workflow.Workflow) error
 }
 
-func registerWorkflows(logger log.Logger, r registerer, s *storageConfig, e workflow.StepEnqueuer) error {
+func registerWorkflows(logger log.Logger, r registerer, s *storageConfig, e workflow.StepEnqueuer, signer *mobileconfig.Signer) error {
 	var w workflow.Workflow
 	var err error
 
+	// profile templates are rendered using inventory data
+	var rOpts []render.Option
+	if signer != nil {
+		rOpts = append(rOpts, render.WithSigner(signer))
+	}
+	renderer := render.New(s.inventory, rOpts...)
+
 	if w, err = inventory.New(e, s.inventory); err != nil {
 		return fmt.Errorf("creating inventory workflow: %w", err)
 	} else if err = r.RegisterWorkflow(w); err != nil {
 		return fmt.Errorf("registering inventory workflow: %w", err)
 	}
 
-	if w, err = profile.New(e, s.profile, profile.WithLogger(logger)); err != nil {
+	if w, err = appinv.New(e, s.apps, appinv.WithLogger(logger)); err != nil {
+		return fmt.Errorf("creating appinv workflow: %w", err)
+	} else if err = r.RegisterWorkflow(w); err != nil {
+		return fmt.Errorf("registering appinv workflow: %w", err)
+	}
+
+	if w, err = certinv.New(e, s.certs, certinv.WithLogger(logger)); err != nil {
+		return fmt.Errorf("creating certinv workflow: %w", err)
+	} else if err = r.RegisterWorkflow(w); err != nil {
+		return fmt.Errorf("registering certinv workflow: %w", err)
+	}
+
+	if w, err = profile.New(
+		e,
+		s.profile,
+		profile.WithLogger(logger),
+		profile.WithInstalledProfileStorage(s.profiles),
+		profile.WithRenderer(renderer),
+	); err != nil {
 		return fmt.Errorf("creating profile workflow: %w", err)
 	} else if err = r.RegisterWorkflow(w); err != nil {
 		return fmt.Errorf("registering profile workflow: %w", err)
 	}
 
+	if w, err = baseline.New(
+		e,
+		s.baseline,
+		s.profile,
+		s.group,
+		baseline.WithLogger(logger),
+		baseline.WithInstalledProfileStorage(s.profiles),
+		baseline.WithRenderer(renderer),
+	); err != nil {
+		return fmt.Errorf("creating baseline workflow: %w", err)
+	} else if err = r.RegisterWorkflow(w); err != nil {
+		return fmt.Errorf("registering baseline workflow: %w", err)
+	}
+
 	if w, err = fvenable.New(e, s.filevault, s.profile, fvenable.WithLogger(logger)); err != nil {
 		return fmt.Errorf("creating fvenable workflow: %w", err)
 	} else if err = r.RegisterWorkflow(w); err != nil {
@@ -48,13 +93,19 @@ func registerWorkflows(logger log.Logger, r registerer, s *storageConfig, e work
 		return fmt.Errorf("registering fvrotate workflow: %w", err)
 	}
 
-	if w, err = cmdplan.New(e, s.cmdplan, s.profile, cmdplan.WithLogger(logger)); err != nil {
+	if w, err = cmdplan.New(
+		e,
+		s.cmdplan,
+		s.profile,
+		cmdplan.WithLogger(logger),
+		cmdplan.WithRenderer(renderer),
+	); err != nil {
 		return fmt.Errorf("creating cmdplan workflow: %w", err)
 	} else if err = r.RegisterWorkflow(w); err != nil {
 		return fmt.Errorf("registering cmdplan workflow: %w", err)
 	}
 
-	if w, err = lock.New(e, s.inventory, lock.WithLogger(logger)); err != nil {
+	if w, err = lock.New(e, s.inventory, lock.WithLogger(logger), lock.WithSecretStorage(s.secrets)); err != nil {
 		return fmt.Errorf("creating lock workflow: %w", err)
 	} else if err = r.RegisterWorkflow(w); err != nil {
 		return fmt.Errorf("registering lock workflow: %w", err)
@@ -72,5 +123,11 @@ func registerWorkflows(logger log.Logger, r registerer, s *storageConfig, e work
 		return fmt.Errorf("registering devinfolog workflow: %w", err)
 	}
 
+	if w, err = restart.New(e, restart.WithLogger(logger)); err != nil {
+		return fmt.Errorf("creating restart workflow: %w", err)
+	} else if err = r.RegisterWorkflow(w); err != nil {
+		return fmt.Errorf("registering restart workflow: %w", err)
+	}
+
 	return nil
 }
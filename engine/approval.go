@@ -0,0 +1,309 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/micromdm/nanocmd/engine/storage"
+	"github.com/micromdm/nanocmd/logkeys"
+
+	"github.com/micromdm/nanolib/log/ctxlog"
+)
+
+// DefaultApprovalExpiry is the default duration after which pending
+// approval requests expire.
+const DefaultApprovalExpiry = time.Hour * 24
+
+var (
+	// ErrWorkflowRequiresApproval is returned when a workflow requiring
+	// approval is started without approval or is used where it can not
+	// be approved (e.g. as a follow-up or for a schedule).
+	ErrWorkflowRequiresApproval = errors.New("workflow requires approval")
+
+	// ErrApprovalExpired is returned when deciding an expired approval request.
+	ErrApprovalExpired = errors.New("approval request expired")
+
+	// ErrSelfApproval is returned when the requester of an approval
+	// request tries to decide it.
+	ErrSelfApproval = errors.New("approval request can not be decided by its requester")
+
+	// ErrMissingPrincipal is returned when an approval request is
+	// requested or decided without an API principal.
+	ErrMissingPrincipal = errors.New("missing principal")
+)
+
+// WithApprovalWorkflows requires approval for starting the named workflows.
+// This is in addition to workflows that are configured to require approval.
+func WithApprovalWorkflows(names ...string) Option {
+	return func(e *Engine) {
+		if e.approvalWorkflows == nil {
+			e.approvalWorkflows = make(map[string]struct{})
+		}
+		for _, name := range names {
+			e.approvalWorkflows[name] = struct{}{}
+		}
+	}
+}
+
+// WithApprovalExpiry sets the duration after which pending approval requests expire.
+func WithApprovalExpiry(d time.Duration) Option {
+	return func(e *Engine) {
+		e.approvalExpiry = d
+	}
+}
+
+// ApprovalRequired returns true if starting workflow name requires approval.
+func (e *Engine) ApprovalRequired(name string) bool {
+	if _, ok := e.approvalWorkflows[name]; ok {
+		return true
+	}
+	w := e.Workflow(name)
+	if w == nil {
+		return false
+	}
+	cfg := w.Config()
+	return cfg != nil && cfg.RequireApproval
+}
+
+// ApprovalWorkflows returns the sorted names of workflows that require approval.
+// These are the configured approval workflows and the registered
+// workflows that are configured to require approval.
+func (e *Engine) ApprovalWorkflows() []string {
+	names := make(map[string]struct{})
+	for name := range e.approvalWorkflows {
+		names[name] = struct{}{}
+	}
+	e.workflowsMu.RLock()
+	for name, w := range e.workflows {
+		if cfg := w.Config(); cfg != nil && cfg.RequireApproval {
+			names[name] = struct{}{}
+		}
+	}
+	e.workflowsMu.RUnlock()
+	ret := make([]string, 0, len(names))
+	for name := range names {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+type approvedKey struct{}
+
+// withApproved returns a copy of ctx that marks workflow starts as approved.
+func withApproved(ctx context.Context) context.Context {
+	return context.WithValue(ctx, approvedKey{}, true)
+}
+
+// approved returns true if workflow starts with ctx are approved.
+func approved(ctx context.Context) bool {
+	v, _ := ctx.Value(approvedKey{}).(bool)
+	return v
+}
+
+// approvalExpired returns true if ar is pending and expired at now.
+func approvalExpired(ar *storage.ApprovalRequest, now time.Time) bool {
+	return ar.Status == storage.ApprovalPending && !ar.ExpiresAt.IsZero() && now.After(ar.ExpiresAt)
+}
+
+// RequestApproval stores a pending approval request by requester for
+// starting workflow name. The workflow is started once a different
+// principal approves the request with DecideApproval.
+func (e *Engine) RequestApproval(ctx context.Context, requester, name string, context []byte, ids []string, followUps []storage.FollowUp) (*storage.ApprovalRequest, error) {
+	if requester == "" {
+		return nil, ErrMissingPrincipal
+	}
+	if e.Workflow(name) == nil {
+		return nil, NewErrNoSuchWorkflow(name)
+	}
+	if len(ids) < 1 {
+		return nil, ErrNoIDs
+	}
+	if err := e.validateFollowUps(followUps); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	ar := &storage.ApprovalRequest{
+		ID:           e.ider.ID(),
+		WorkflowName: name,
+		IDs:          ids,
+		Context:      string(context),
+		FollowUps:    followUps,
+		Requester:    requester,
+		RequestedAt:  now,
+		Status:       storage.ApprovalPending,
+	}
+	if e.approvalExpiry > 0 {
+		ar.ExpiresAt = now.Add(e.approvalExpiry)
+	}
+	if err := e.storage.StoreApprovalRequest(ctx, ar); err != nil {
+		return nil, fmt.Errorf("storing approval request: %w", err)
+	}
+
+	ctxlog.Logger(ctx, e.logger).Info(
+		logkeys.Message, "approval requested",
+		"approval_id", ar.ID,
+		logkeys.WorkflowName, name,
+		"requester", requester,
+		logkeys.FirstEnrollmentID, ids[0],
+		logkeys.GenericCount, len(ids),
+	)
+	return ar, nil
+}
+
+// ApprovalRequest retrieves approval request id.
+// Pending requests that have expired are expired (see ExpireApprovals).
+func (e *Engine) ApprovalRequest(ctx context.Context, id string) (*storage.ApprovalRequest, error) {
+	ar, err := e.storage.RetrieveApprovalRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if now := time.Now(); approvalExpired(ar, now) {
+		e.expireApproval(ctx, ar, now)
+	}
+	return ar, nil
+}
+
+// ApprovalRequests retrieves the approval requests with status.
+// All approval requests are returned if status is empty. Pending
+// requests that have expired are expired (see ExpireApprovals).
+func (e *Engine) ApprovalRequests(ctx context.Context, status storage.ApprovalStatus) ([]*storage.ApprovalRequest, error) {
+	retrieveStatus := status
+	if status == storage.ApprovalPending || status == storage.ApprovalExpired {
+		// expired requests may still be stored as pending
+		retrieveStatus = ""
+	}
+	ars, err := e.storage.RetrieveApprovalRequests(ctx, retrieveStatus)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	var ret []*storage.ApprovalRequest
+	for _, ar := range ars {
+		if approvalExpired(ar, now) {
+			e.expireApproval(ctx, ar, now)
+		}
+		if status == "" || ar.Status == status {
+			ret = append(ret, ar)
+		}
+	}
+	return ret, nil
+}
+
+// DecideApproval approves or rejects pending approval request id by decider.
+// The decider must differ from the requester of the approval request.
+// Approving starts the workflow of the approval request. The instance
+// ID (or start error) is recorded with the approval request: a failed
+// start of an approved workflow is not returned as an error.
+func (e *Engine) DecideApproval(ctx context.Context, id, decider string, approve bool, reason string) (*storage.ApprovalRequest, error) {
+	if decider == "" {
+		return nil, ErrMissingPrincipal
+	}
+	ar, err := e.storage.RetrieveApprovalRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if ar.Status == storage.ApprovalExpired {
+		return ar, ErrApprovalExpired
+	} else if ar.Status != storage.ApprovalPending {
+		return ar, fmt.Errorf("%w: %s", storage.ErrApprovalNotPending, ar.Status)
+	}
+	if ar.Requester == decider {
+		return ar, ErrSelfApproval
+	}
+
+	logger := ctxlog.Logger(ctx, e.logger).With(
+		"approval_id", ar.ID,
+		logkeys.WorkflowName, ar.WorkflowName,
+		"requester", ar.Requester,
+		"decided_by", decider,
+	)
+
+	now := time.Now()
+	if approvalExpired(ar, now) {
+		if err = e.expireApproval(ctx, ar, now); err != nil {
+			return ar, err
+		}
+		return ar, ErrApprovalExpired
+	}
+
+	ar.Status = storage.ApprovalRejected
+	if approve {
+		ar.Status = storage.ApprovalApproved
+	}
+	ar.DecidedBy = decider
+	ar.DecidedAt = &now
+	ar.Reason = reason
+	if err = e.storage.DecideApprovalRequest(ctx, ar); err != nil {
+		return ar, fmt.Errorf("deciding approval request: %w", err)
+	}
+	logger.Info(
+		logkeys.Message, "approval decided",
+		"status", ar.Status,
+		"reason", reason,
+	)
+	if !approve {
+		return ar, nil
+	}
+
+	ar.InstanceID, err = e.StartWorkflowWithFollowUps(withApproved(ctx), ar.WorkflowName, []byte(ar.Context), ar.IDs, nil, nil, ar.FollowUps)
+	if err != nil {
+		ar.StartError = err.Error()
+		logger.Info(logkeys.Message, "starting approved workflow", logkeys.Error, err)
+	} else {
+		logger.Info(
+			logkeys.Message, "started approved workflow",
+			logkeys.InstanceID, ar.InstanceID,
+		)
+	}
+	if err = e.storage.StoreApprovalRequest(ctx, ar); err != nil {
+		logger.Info(logkeys.Message, "storing approval request", logkeys.Error, err)
+	}
+	return ar, nil
+}
+
+// expireApproval records the expiry of pending approval request ar at
+// now. The expiry is logged and audited. Errors are logged and returned.
+func (e *Engine) expireApproval(ctx context.Context, ar *storage.ApprovalRequest, now time.Time) error {
+	ar.Status = storage.ApprovalExpired
+	ar.DecidedAt = &now
+	logger := ctxlog.Logger(ctx, e.logger).With(
+		"approval_id", ar.ID,
+		logkeys.WorkflowName, ar.WorkflowName,
+		"requester", ar.Requester,
+	)
+	err := e.storage.DecideApprovalRequest(ctx, ar)
+	if errors.Is(err, storage.ErrApprovalNotPending) {
+		// decided (or expired) concurrently
+		return nil
+	} else if err != nil {
+		err = fmt.Errorf("expiring approval request: %w", err)
+		logger.Info(logkeys.Message, "expiring approval", logkeys.Error, err)
+		return err
+	}
+	logger.Info(logkeys.Message, "approval expired")
+	e.auditApprovalExpiry(ctx, ar)
+	return nil
+}
+
+// ExpireApprovals expires the pending approval requests that have
+// expired at now. Expiring an approval request is logged and audited.
+func (e *Engine) ExpireApprovals(ctx context.Context, now time.Time) error {
+	ars, err := e.storage.RetrieveApprovalRequests(ctx, storage.ApprovalPending)
+	if err != nil {
+		return fmt.Errorf("retrieving approval requests: %w", err)
+	}
+	for _, ar := range ars {
+		if !approvalExpired(ar, now) {
+			continue
+		}
+		if err = e.expireApproval(ctx, ar, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"context"
+
+	"github.com/micromdm/nanocmd/engine/storage"
+	"github.com/micromdm/nanocmd/subsystem/audit"
+	auditstorage "github.com/micromdm/nanocmd/subsystem/audit/storage"
+	"github.com/micromdm/nanocmd/workflow"
+)
+
+// Auditor records audit events.
+type Auditor interface {
+	Audit(ctx context.Context, e *auditstorage.Event)
+}
+
+// WithAuditor configures an auditor for recording the workflow starts
+// of event subscriptions and follow-ups and the expiry of approval requests.
+func WithAuditor(auditor Auditor) Option {
+	return func(e *Engine) {
+		e.auditor = auditor
+	}
+}
+
+// auditEventStart records an audit event of the start of workflow name
+// for enrollment id by the event subscription sub for event ev.
+func (e *Engine) auditEventStart(ctx context.Context, sub, name, id string, ev workflow.EventFlag, instanceID string, startErr error) {
+	if e.auditor == nil {
+		return
+	}
+	e.auditor.Audit(ctx, &auditstorage.Event{
+		Actor:        audit.ActorEngine,
+		Action:       "event_subscription.start",
+		TargetIDs:    []string{id},
+		WorkflowName: name,
+		InstanceID:   instanceID,
+		Error:        audit.ErrorString(startErr),
+		Params: map[string][]string{
+			"event_subscription": {sub},
+			"event":              {ev.String()},
+		},
+	})
+}
+
+// auditFollowUpStart records an audit event of the start of the
+// follow-up workflow name for enrollment id after parentInstanceID
+// finished with outcome.
+func (e *Engine) auditFollowUpStart(ctx context.Context, name, id, parentInstanceID string, outcome workflow.Outcome, instanceID string, startErr error) {
+	if e.auditor == nil {
+		return
+	}
+	e.auditor.Audit(ctx, &auditstorage.Event{
+		Actor:        audit.ActorEngine,
+		Action:       "follow_up.start",
+		TargetIDs:    []string{id},
+		WorkflowName: name,
+		InstanceID:   instanceID,
+		Error:        audit.ErrorString(startErr),
+		Params: map[string][]string{
+			"parent_instance_id": {parentInstanceID},
+			"outcome":            {string(outcome)},
+		},
+	})
+}
+
+// auditApprovalExpiry records an audit event of the expiry of approval request ar.
+func (e *Engine) auditApprovalExpiry(ctx context.Context, ar *storage.ApprovalRequest) {
+	if e.auditor == nil {
+		return
+	}
+	e.auditor.Audit(ctx, &auditstorage.Event{
+		Actor:        audit.ActorEngine,
+		Action:       "approval.expire",
+		TargetIDs:    ar.IDs,
+		WorkflowName: ar.WorkflowName,
+		Params: map[string][]string{
+			"approval_id": {ar.ID},
+			"requester":   {ar.Requester},
+		},
+	})
+}
@@ -0,0 +1,203 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/micromdm/nanocmd/engine/storage"
+	"github.com/micromdm/nanocmd/logkeys"
+	"github.com/micromdm/nanocmd/mdm"
+	invstorage "github.com/micromdm/nanocmd/subsystem/inventory/storage"
+	"github.com/micromdm/nanocmd/utils/version"
+	"github.com/micromdm/nanocmd/workflow"
+
+	"github.com/micromdm/nanolib/log"
+)
+
+// enrollmentOf returns the MDM enrollment identifiers of an event, if any.
+func enrollmentOf(ev *workflow.Event) *mdm.Enrollment {
+	if ev == nil {
+		return nil
+	}
+	switch v := ev.EventData.(type) {
+	case *mdm.Authenticate:
+		return &v.Enrollment
+	case *mdm.TokenUpdate:
+		return &v.Enrollment
+	case *mdm.CheckOut:
+		return &v.Enrollment
+	}
+	return nil
+}
+
+// enrollmentType returns the MDM channel type of the event's enrollment.
+// An empty string is returned if it can't be determined.
+func enrollmentType(ev *workflow.Event) string {
+	enrollment := enrollmentOf(ev)
+	if enrollment == nil {
+		return ""
+	}
+	if enrollment.UserID != "" || enrollment.EnrollmentUserID != "" {
+		return storage.EnrollmentTypeUser
+	}
+	return storage.EnrollmentTypeDevice
+}
+
+// matchAny returns true if value matches any of patterns.
+// A pattern with a trailing "*" matches a prefix.
+func matchAny(patterns []string, value string) bool {
+	for _, p := range patterns {
+		if strings.HasSuffix(p, "*") {
+			if strings.HasPrefix(value, p[:len(p)-1]) {
+				return true
+			}
+		} else if p == value {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticateAttributes are the Authenticate message attributes
+// that event subscription conditions can match.
+type authenticateAttributes struct {
+	model, productName, osVersion string
+}
+
+// authenticateOf returns the Authenticate message of ev, if any.
+func authenticateOf(ev *workflow.Event) *mdm.Authenticate {
+	if ev == nil {
+		return nil
+	}
+	auth, _ := ev.EventData.(*mdm.Authenticate)
+	return auth
+}
+
+// inventoryAuthenticate returns the Authenticate attributes recorded
+// in the inventory values (see recordAuthenticate).
+func inventoryAuthenticate(values invstorage.Values) authenticateAttributes {
+	str := func(k string) string {
+		s, _ := values[k].(string)
+		return s
+	}
+	return authenticateAttributes{
+		model:       str(invstorage.KeyAuthenticateModel),
+		productName: str(invstorage.KeyAuthenticateProductName),
+		osVersion:   str(invstorage.KeyAuthenticateOSVersion),
+	}
+}
+
+// matchAuthenticate tests the Authenticate message conditions of c against a.
+func matchAuthenticate(c *storage.EventConditions, a authenticateAttributes) bool {
+	if len(c.Model) > 0 && !matchAny(c.Model, a.model) {
+		return false
+	}
+	if len(c.ProductName) > 0 && !matchAny(c.ProductName, a.productName) {
+		return false
+	}
+	if c.MinOSVersion != "" && (a.osVersion == "" || version.Compare(a.osVersion, c.MinOSVersion) < 0) {
+		return false
+	}
+	if c.MaxOSVersion != "" && (a.osVersion == "" || version.Compare(a.osVersion, c.MaxOSVersion) > 0) {
+		return false
+	}
+	return true
+}
+
+// matchPredicate tests p against the inventory values.
+// Missing inventory values only match the "ne" operator.
+func matchPredicate(p storage.InventoryPredicate, values invstorage.Values) bool {
+	v, ok := values[p.Key]
+	if p.Op == storage.OpExists {
+		return ok
+	}
+	if !ok {
+		return p.Op == storage.OpNotEqual
+	}
+	s := fmt.Sprint(v)
+	switch p.Op {
+	case storage.OpEqual:
+		return s == p.Value
+	case storage.OpNotEqual:
+		return s != p.Value
+	case storage.OpLessThan:
+		return version.Compare(s, p.Value) < 0
+	case storage.OpLessOrEqual:
+		return version.Compare(s, p.Value) <= 0
+	case storage.OpGreaterThan:
+		return version.Compare(s, p.Value) > 0
+	case storage.OpGreaterOrEqual:
+		return version.Compare(s, p.Value) >= 0
+	}
+	return false
+}
+
+// conditionsMatch tests the event subscription conditions c for enrollment id.
+// A nil c always matches. The Authenticate conditions of events other
+// than Authenticate are tested against the Authenticate attributes last
+// recorded in inventory. Inventory conditions never match if the engine
+// has no inventory configured.
+func (e *Engine) conditionsMatch(ctx context.Context, c *storage.EventConditions, id string, ev *workflow.Event, mdmCtx *workflow.MDMContext) (bool, error) {
+	if c == nil {
+		return true, nil
+	}
+	for k, v := range c.Params {
+		if mdmCtx == nil || mdmCtx.Params == nil {
+			return false, nil
+		}
+		if param, ok := mdmCtx.Params[k]; !ok || param != v {
+			return false, nil
+		}
+	}
+	if c.EnrollmentType != "" && enrollmentType(ev) != c.EnrollmentType {
+		return false, nil
+	}
+	auth := authenticateOf(ev)
+	if auth != nil && !matchAuthenticate(c, authenticateAttributes{auth.Model, auth.ProductName, auth.OSVersion}) {
+		return false, nil
+	}
+	authInventory := auth == nil && c.AuthenticateConditions()
+	if len(c.Inventory) < 1 && !authInventory {
+		return true, nil
+	}
+	if e.inventory == nil {
+		return false, nil
+	}
+	idValues, err := e.inventory.RetrieveInventory(ctx, &invstorage.SearchOptions{IDs: []string{id}})
+	if err != nil {
+		return false, fmt.Errorf("retrieving inventory: %w", err)
+	}
+	if authInventory && !matchAuthenticate(c, inventoryAuthenticate(idValues[id])) {
+		return false, nil
+	}
+	for _, p := range c.Inventory {
+		if !matchPredicate(p, idValues[id]) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// inventoryStorer stores inventory values.
+type inventoryStorer interface {
+	StoreInventoryValues(ctx context.Context, id string, values invstorage.Values) error
+}
+
+// recordAuthenticate records the Authenticate attributes of auth for
+// enrollment id in inventory, if the engine inventory can store values.
+// Errors are logged.
+func (e *Engine) recordAuthenticate(ctx context.Context, logger log.Logger, id string, auth *mdm.Authenticate) {
+	inv, ok := e.inventory.(inventoryStorer)
+	if !ok || auth == nil {
+		return
+	}
+	err := inv.StoreInventoryValues(ctx, id, invstorage.Values{
+		invstorage.KeyAuthenticateModel:       auth.Model,
+		invstorage.KeyAuthenticateProductName: auth.ProductName,
+		invstorage.KeyAuthenticateOSVersion:   auth.OSVersion,
+	})
+	if err != nil {
+		logger.Info(logkeys.Message, "recording authenticate inventory", logkeys.Error, err)
+	}
+}
@@ -0,0 +1,106 @@
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/micromdm/nanocmd/engine/storage"
+	"github.com/micromdm/nanocmd/engine/storage/inmem"
+	"github.com/micromdm/nanocmd/mdm"
+	invstorage "github.com/micromdm/nanocmd/subsystem/inventory/storage"
+	invinmem "github.com/micromdm/nanocmd/subsystem/inventory/storage/inmem"
+	"github.com/micromdm/nanocmd/workflow"
+)
+
+func TestConditionsMatch(t *testing.T) {
+	ctx := context.Background()
+	id := "AAABBBCCC111222333"
+
+	inv := invinmem.New()
+	err := inv.StoreInventoryValues(ctx, id, invstorage.Values{
+		invstorage.KeyOSVersion:  "14.4.1",
+		invstorage.KeySupervised: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := New(inmem.New(), new(singleTargetEnqueuer), WithInventory(inv))
+
+	auth := &workflow.Event{
+		EventFlag: workflow.EventAuthenticate,
+		EventData: &mdm.Authenticate{Model: "MacBookPro18,3", ProductName: "MacBookPro18,3", OSVersion: "14.4.1"},
+	}
+	userTU := &workflow.Event{
+		EventFlag: workflow.EventTokenUpdate,
+		EventData: &mdm.TokenUpdate{Enrollment: mdm.Enrollment{UDID: id, UserID: "USER1"}},
+	}
+	mdmCtx := &workflow.MDMContext{Params: map[string]string{"site": "hq"}}
+
+	for _, tc := range []struct {
+		name   string
+		c      *storage.EventConditions
+		ev     *workflow.Event
+		mdmCtx *workflow.MDMContext
+		want   bool
+	}{
+		{"nil", nil, auth, nil, true},
+		{"params", &storage.EventConditions{Params: map[string]string{"site": "hq"}}, auth, mdmCtx, true},
+		{"params-mismatch", &storage.EventConditions{Params: map[string]string{"site": "branch"}}, auth, mdmCtx, false},
+		{"params-no-context", &storage.EventConditions{Params: map[string]string{"site": "hq"}}, auth, nil, false},
+		{"device", &storage.EventConditions{EnrollmentType: storage.EnrollmentTypeDevice}, auth, nil, true},
+		{"user", &storage.EventConditions{EnrollmentType: storage.EnrollmentTypeUser}, userTU, nil, true},
+		{"user-mismatch", &storage.EventConditions{EnrollmentType: storage.EnrollmentTypeUser}, auth, nil, false},
+		{"model-prefix", &storage.EventConditions{Model: []string{"iPhone*", "MacBookPro*"}}, auth, nil, true},
+		{"model-mismatch", &storage.EventConditions{Model: []string{"iPhone*"}}, auth, nil, false},
+		{"model-not-authenticate", &storage.EventConditions{Model: []string{"MacBookPro*"}}, userTU, nil, false},
+		{"os-range", &storage.EventConditions{MinOSVersion: "14", MaxOSVersion: "14.5"}, auth, nil, true},
+		{"os-min", &storage.EventConditions{MinOSVersion: "15"}, auth, nil, false},
+		{"inventory", &storage.EventConditions{Inventory: []storage.InventoryPredicate{
+			{Key: invstorage.KeyOSVersion, Op: storage.OpGreaterOrEqual, Value: "14.4"},
+			{Key: invstorage.KeySupervised, Op: storage.OpEqual, Value: "true"},
+		}}, userTU, nil, true},
+		{"inventory-mismatch", &storage.EventConditions{Inventory: []storage.InventoryPredicate{
+			{Key: invstorage.KeyOSVersion, Op: storage.OpLessThan, Value: "14"},
+		}}, userTU, nil, false},
+		{"inventory-missing", &storage.EventConditions{Inventory: []storage.InventoryPredicate{
+			{Key: invstorage.KeyFDEEnabled, Op: storage.OpExists},
+		}}, userTU, nil, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			have, err := e.conditionsMatch(ctx, tc.c, id, tc.ev, tc.mdmCtx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if have != tc.want {
+				t.Errorf("have: %v, want: %v", have, tc.want)
+			}
+		})
+	}
+
+	// Authenticate attributes are recorded for later events
+	if err = e.MDMCheckinEvent(ctx, id, auth.EventData, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		c    *storage.EventConditions
+		want bool
+	}{
+		{"model", &storage.EventConditions{Model: []string{"MacBookPro*"}}, true},
+		{"model-mismatch", &storage.EventConditions{Model: []string{"iPhone*"}}, false},
+		{"os-range", &storage.EventConditions{MinOSVersion: "14", MaxOSVersion: "14.5"}, true},
+		{"os-max", &storage.EventConditions{MaxOSVersion: "13"}, false},
+	} {
+		t.Run("recorded-"+tc.name, func(t *testing.T) {
+			have, err := e.conditionsMatch(ctx, tc.c, id, userTU, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if have != tc.want {
+				t.Errorf("have: %v, want: %v", have, tc.want)
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/jessepeterson/mdmcommands"
 	"github.com/micromdm/nanocmd/engine/storage"
+	"github.com/micromdm/nanocmd/mdm"
 	"github.com/micromdm/nanocmd/workflow"
 	"github.com/micromdm/plist"
 )
@@ -185,3 +186,50 @@ func workflowStepResultFromStorageStepResult(ss *storage.StepResult, newCtx newC
 	}
 	return sr, nil
 }
+
+// workflowStepResultFromStorageTimerStep converts a storage timer step into a workflow step result.
+// The step result has no command results.
+func workflowStepResultFromStorageTimerStep(ts *storage.TimerStep, newCtx newContextValuer) (*workflow.StepResult, error) {
+	sr := &workflow.StepResult{
+		StepContext: workflow.StepContext{
+			InstanceID: ts.InstanceID,
+			Name:       ts.Name,
+			Context:    newCtx.NewContextValue(ts.Name),
+		},
+		ID: ts.EnrollmentID,
+	}
+	if sr.Context != nil && len(ts.Context) > 0 {
+		if err := sr.Context.UnmarshalBinary(ts.Context); err != nil {
+			return sr, fmt.Errorf("unmarshal context: %w", err)
+		}
+	}
+	return sr, nil
+}
+
+// storagePendingEventFromEvent converts the event data of ev to raw plist bytes.
+func storagePendingEventFromEvent(ev *workflow.Event) ([]byte, error) {
+	if ev == nil || ev.EventData == nil {
+		return nil, nil
+	}
+	return plist.Marshal(ev.EventData)
+}
+
+// workflowEventFromStoragePendingEvent converts raw plist event data back into an event.
+// Event data is only converted for check-in message event types.
+func workflowEventFromStoragePendingEvent(f workflow.EventFlag, data []byte) (*workflow.Event, error) {
+	ev := &workflow.Event{EventFlag: f}
+	if len(data) < 1 {
+		return ev, nil
+	}
+	switch f {
+	case workflow.EventAuthenticate:
+		ev.EventData = new(mdm.Authenticate)
+	case workflow.EventTokenUpdate, workflow.EventEnrollment:
+		ev.EventData = new(mdm.TokenUpdate)
+	case workflow.EventCheckOut:
+		ev.EventData = new(mdm.CheckOut)
+	default:
+		return ev, nil
+	}
+	return ev, plist.Unmarshal(data, ev.EventData)
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/micromdm/nanocmd/engine/storage"
 	"github.com/micromdm/nanocmd/logkeys"
 	"github.com/micromdm/nanocmd/mdm"
+	invstorage "github.com/micromdm/nanocmd/subsystem/inventory/storage"
 	"github.com/micromdm/nanocmd/utils/uuid"
 	"github.com/micromdm/nanocmd/workflow"
 
@@ -26,6 +27,10 @@ var (
 	// ErrWorkflowAlreadyStarted is when a workflow is already started
 	// that workflow is configured for exclusive running.
 	ErrWorkflowAlreadyStarted = errors.New("workflow already started")
+
+	// ErrInvalidExclusivity is when a workflow is registered with an
+	// exclusivity domain but allows multiple simultaneous instances.
+	ErrInvalidExclusivity = errors.New("invalid exclusivity")
 )
 
 func NewErrNoSuchWorkflow(name string) error {
@@ -63,11 +68,20 @@ type Engine struct {
 	storage      storage.Storage
 	enqueuer     Enqueuer
 	eventStorage storage.ReadEventSubscriptionStorage
+	eventStats   storage.EventSubscriptionStatsStorage
+	inventory    invstorage.ReadStorage
+	observer     StepObserver
+	windows      MaintenanceWindows
+	auditor      Auditor
+
+	approvalWorkflows map[string]struct{}
+	approvalExpiry    time.Duration
 
 	logger log.Logger
 	ider   uuid.IDer
 
 	defaultTimeout time.Duration
+	maxQueued      int
 }
 
 // Options configure the engine.
@@ -87,6 +101,17 @@ func WithDefaultTimeout(timeout time.Duration) Option {
 	}
 }
 
+// WithMaxQueued sets the maximum number of queued starts per enrollment
+// ID of queued workflows that do not configure their own maximum.
+// Values less than one are ignored.
+func WithMaxQueued(max int) Option {
+	return func(e *Engine) {
+		if max > 0 {
+			e.maxQueued = max
+		}
+	}
+}
+
 // WithEventStorage turns on the event dispatch and configures the storage.
 func WithEventStorage(evStorage storage.ReadEventSubscriptionStorage) Option {
 	return func(e *Engine) {
@@ -94,6 +119,24 @@ func WithEventStorage(evStorage storage.ReadEventSubscriptionStorage) Option {
 	}
 }
 
+// WithEventStatsStorage configures the storage for event subscription run statistics.
+func WithEventStatsStorage(statsStorage storage.EventSubscriptionStatsStorage) Option {
+	return func(e *Engine) {
+		e.eventStats = statsStorage
+	}
+}
+
+// WithInventory configures inventory storage for evaluating the
+// inventory conditions of event subscriptions. If inv can also store
+// inventory values then the model, product name, and OS version of
+// Authenticate messages are recorded in it for evaluating the
+// Authenticate conditions of later events.
+func WithInventory(inv invstorage.ReadStorage) Option {
+	return func(e *Engine) {
+		e.inventory = inv
+	}
+}
+
 // New creates a new NanoCMD engine with default configurations.
 func New(storage storage.Storage, enqueuer Enqueuer, opts ...Option) *Engine {
 	engine := &Engine{
@@ -104,6 +147,8 @@ func New(storage storage.Storage, enqueuer Enqueuer, opts ...Option) *Engine {
 		logger:         log.NopLogger,
 		ider:           uuid.NewUUID(),
 		defaultTimeout: DefaultTimeout,
+		approvalExpiry: DefaultApprovalExpiry,
+		maxQueued:      workflow.DefaultMaxQueued,
 	}
 	for _, opt := range opts {
 		opt(engine)
@@ -129,26 +174,73 @@ func diff(a, b []string) (r []string) {
 	return
 }
 
+// retrieveOutstanding finds the ids that have outstanding steps of the
+// named workflow or of any workflow sharing its exclusivity domain.
+func (e *Engine) retrieveOutstanding(ctx context.Context, name string, ids []string) ([]string, error) {
+	var outstanding []string
+	for _, wName := range e.exclusivityDomain(name) {
+		if len(ids) < 1 {
+			break
+		}
+		wOutstanding, err := e.storage.RetrieveOutstandingWorkflowStatus(ctx, wName, ids)
+		if err != nil {
+			return nil, fmt.Errorf("workflow %s: %w", wName, err)
+		}
+		if len(wOutstanding) > 0 {
+			outstanding = append(outstanding, wOutstanding...)
+			// only check the remaining ids against the next workflow
+			ids = diff(ids, wOutstanding)
+		}
+	}
+	return outstanding, nil
+}
+
 // StartWorkflow starts a new workflow instance for workflow name.
 func (e *Engine) StartWorkflow(ctx context.Context, name string, context []byte, ids []string, ev *workflow.Event, mdmCtx *workflow.MDMContext) (string, error) {
+	return e.StartWorkflowWithFollowUps(ctx, name, context, ids, ev, mdmCtx, nil)
+}
+
+// StartWorkflowWithFollowUps starts a new workflow instance for workflow name.
+// The followUps workflows are started for each enrollment ID when the
+// instance finishes for it. Workflows requiring approval are only
+// started by approving their approval request (see DecideApproval).
+func (e *Engine) StartWorkflowWithFollowUps(ctx context.Context, name string, context []byte, ids []string, ev *workflow.Event, mdmCtx *workflow.MDMContext, followUps []storage.FollowUp) (string, error) {
 	// retrieve our workflow and check validity
 	w := e.Workflow(name)
 	if w == nil {
 		return "", NewErrNoSuchWorkflow(name)
 	}
+	if e.ApprovalRequired(name) && !approved(ctx) {
+		return "", fmt.Errorf("%w: %s", ErrWorkflowRequiresApproval, name)
+	}
+	if err := e.validateFollowUps(followUps); err != nil {
+		return "", err
+	}
 
 	logger := ctxlog.Logger(ctx, e.logger).With(logkeys.WorkflowName, name)
 
-	if cfg := w.Config(); cfg == nil || cfg.Exclusivity == workflow.Exclusive {
+	if cfg := w.Config(); cfg == nil || cfg.Exclusivity == workflow.Exclusive || queued(cfg) {
 		// check if our ids have any outstanding workflows running
-		wRunningIDs, err := e.storage.RetrieveOutstandingWorkflowStatus(ctx, name, ids)
+		wRunningIDs, err := e.retrieveOutstanding(ctx, name, ids)
 		if err != nil {
 			return "", fmt.Errorf("retrieving outstanding status: %w", err)
 		}
 		if len(wRunningIDs) > 0 {
 			ct := len(ids)
 			ids = diff(ids, wRunningIDs) // replace our ids with the set of NON-outstanding ids
-			if len(ids) < 1 {
+			if queued(cfg) {
+				// queue the starts of the running ids
+				if _, err = e.queueStarts(ctx, logger, w, context, wRunningIDs, ev, mdmCtx, followUps); err != nil {
+					if len(ids) < 1 {
+						return "", err
+					}
+					logger.Info(logkeys.Message, "queueing workflow starts", logkeys.Error, err)
+				}
+				if len(ids) < 1 {
+					// all ids were queued
+					return "", nil
+				}
+			} else if len(ids) < 1 {
 				// if all IDs are already running, then return an error
 				return "", fmt.Errorf("%w on %d (of %d) ids", ErrWorkflowAlreadyStarted, len(wRunningIDs), ct)
 			} else {
@@ -178,6 +270,13 @@ func (e *Engine) StartWorkflow(ctx context.Context, name string, context []byte,
 	// create a new instance ID
 	instanceID := e.ider.ID()
 
+	if len(followUps) > 0 {
+		// store before starting in case the instance finishes quickly
+		if err := e.storage.StoreFollowUps(ctx, instanceID, ids, followUps); err != nil {
+			return "", fmt.Errorf("storing follow-ups: %w", err)
+		}
+	}
+
 	var retErr error // accumulate and return the last start error
 	for _, startID := range startIDs {
 		// check that we have enrollment IDs to start in our IDs
@@ -197,6 +296,10 @@ func (e *Engine) StartWorkflow(ctx context.Context, name string, context []byte,
 		if err = e.storage.RecordWorkflowStarted(ctx, startID, name, time.Now()); err != nil {
 			return instanceID, fmt.Errorf("recording workflow status: %w", err)
 		}
+		// the workflow may have finished some enrollments without enqueuing any steps
+		for _, stepResult := range ss.Finished() {
+			e.StepFinished(ctx, w, stepResult, nil, false)
+		}
 		logger.Debug(
 			logkeys.InstanceID, instanceID,
 			logkeys.Message, "starting workflow",
@@ -228,13 +331,41 @@ func (e *Engine) stepDefaultTimeout(workflowName string) (defaultTimeout time.Ti
 }
 
 // EnqueueStep stores the step and enqueues the commands to the MDM server.
+// Timer and event steps (without commands) are stored for later callback.
 func (e *Engine) EnqueueStep(ctx context.Context, n workflow.Namer, se *workflow.StepEnqueueing) error {
+	if timerStep(se) || eventStep(se) {
+		// timer and event steps have no MDM commands and are never
+		// enqueued to the MDM server so they are not subject to
+		// maintenance windows.
+		return e.storeTimerSteps(ctx, n, se)
+	}
+
 	ss, err := storageStepEnqueuingWithConfigFromWorkflowStepEnqueueing(n, e.stepDefaultTimeout(n.Name()), se)
 	if err != nil {
 		return fmt.Errorf("converting workflow step: %w", err)
 	}
 
-	if err = e.storage.StoreStep(ctx, ss, time.Now()); err != nil {
+	if !e.usesMaintenanceWindow(n.Name()) {
+		return e.storeAndEnqueueStep(ctx, ss)
+	}
+
+	steps, err := e.windowSteps(ctx, ss, time.Now())
+	if err != nil {
+		return err
+	}
+	for _, ss := range steps {
+		if err = e.storeAndEnqueueStep(ctx, ss); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// storeAndEnqueueStep stores the step and enqueues the commands to the
+// MDM server if the step is not delayed.
+func (e *Engine) storeAndEnqueueStep(ctx context.Context, ss *storage.StepEnqueuingWithConfig) error {
+	err := e.storage.StoreStep(ctx, ss, time.Now())
+	if err != nil {
 		return fmt.Errorf("storing step: %w", err)
 	}
 
@@ -350,9 +481,9 @@ func (e *Engine) MDMIdleEvent(ctx context.Context, id string, raw []byte, mdmCon
 
 	var wg sync.WaitGroup
 	event = &workflow.Event{EventFlag: workflow.EventIdleNotStartedSince}
-	for _, sub := range subs {
+	for name, sub := range subs {
 		wg.Add(1)
-		go func(es *storage.EventSubscription) {
+		go func(name string, es *storage.EventSubscription) {
 			defer wg.Done()
 
 			if es == nil {
@@ -403,7 +534,17 @@ func (e *Engine) MDMIdleEvent(ctx context.Context, id string, raw []byte, mdmCon
 				return
 			}
 
-			if instanceID, err := e.StartWorkflow(ctx, es.Workflow, []byte(es.Context), []string{id}, event, mdmContext); err != nil {
+			if !e.subscriptionMatches(ctx, subLogger, name, es, id, event, mdmContext) {
+				return
+			}
+
+			if es.Deferred() {
+				e.deferStart(ctx, subLogger, name, es, id, event, mdmContext)
+				return
+			}
+
+			instanceID, err := e.StartWorkflowWithFollowUps(ctx, es.Workflow, []byte(es.Context), []string{id}, event, mdmContext, es.FollowUps)
+			if err != nil {
 				subLogger.Info(
 					logkeys.Message, "start workflow",
 					logkeys.InstanceID, instanceID,
@@ -415,7 +556,9 @@ func (e *Engine) MDMIdleEvent(ctx context.Context, id string, raw []byte, mdmCon
 					logkeys.InstanceID, instanceID,
 				)
 			}
-		}(sub)
+			e.recordEventFired(ctx, subLogger, name, err)
+			e.auditEventStart(ctx, name, es.Workflow, id, event.EventFlag, instanceID, err)
+		}(name, sub)
 	}
 	wg.Wait()
 	return nil
@@ -497,8 +640,14 @@ func (e *Engine) MDMCommandResponseEvent(ctx context.Context, id string, uuid st
 		stepResult.MDMContext = *mdmContext
 	}
 
+	if e.observer != nil {
+		e.observer.StepObserved(ctx, ssr.InstanceID, ssr.WorkflowName, id, stepErrored(stepResult), false)
+	}
+
 	// let our workflow know that we have completed the step
-	if err = w.StepCompleted(ctx, stepResult); err != nil {
+	err = w.StepCompleted(ctx, stepResult)
+	e.StepFinished(ctx, w, stepResult, err, false)
+	if err != nil {
 		return logAndError(err, logger, "completing workflow step")
 	}
 	logger.Debug(logkeys.Message, "completed workflow step")
@@ -523,11 +672,19 @@ func (e *Engine) dispatchEvents(ctx context.Context, id string, ev *workflow.Eve
 				logkeys.Error, err,
 			)
 		} else {
-			for _, sub := range subs {
+			for name, sub := range subs {
 				wg.Add(1)
-				go func(es *storage.EventSubscription) {
+				go func(name string, es *storage.EventSubscription) {
 					defer wg.Done()
-					if instanceID, err := e.StartWorkflow(ctx, es.Workflow, []byte(es.Context), []string{id}, ev, mdmCtx); err != nil {
+					if !e.subscriptionMatches(ctx, logger, name, es, id, ev, mdmCtx) {
+						return
+					}
+					if es.Deferred() {
+						e.deferStart(ctx, logger, name, es, id, ev, mdmCtx)
+						return
+					}
+					instanceID, err := e.StartWorkflowWithFollowUps(ctx, es.Workflow, []byte(es.Context), []string{id}, ev, mdmCtx, es.FollowUps)
+					if err != nil {
 						logger.Info(
 							logkeys.Message, "start workflow",
 							logkeys.WorkflowName, es.Workflow,
@@ -541,7 +698,9 @@ func (e *Engine) dispatchEvents(ctx context.Context, id string, ev *workflow.Eve
 							logkeys.InstanceID, instanceID,
 						)
 					}
-				}(sub)
+					e.recordEventFired(ctx, logger, name, err)
+					e.auditEventStart(ctx, name, es.Workflow, id, ev.EventFlag, instanceID, err)
+				}(name, sub)
 			}
 		}
 	}
@@ -569,6 +728,40 @@ func (e *Engine) dispatchEvents(ctx context.Context, id string, ev *workflow.Eve
 	return nil
 }
 
+// subscriptionMatches returns true if the conditions of the named event
+// subscription es match enrollment id. Errors are logged and do not match.
+func (e *Engine) subscriptionMatches(ctx context.Context, logger log.Logger, name string, es *storage.EventSubscription, id string, ev *workflow.Event, mdmCtx *workflow.MDMContext) bool {
+	ok, err := e.conditionsMatch(ctx, es.Conditions, id, ev, mdmCtx)
+	if err != nil {
+		logger.Info(
+			logkeys.Message, "evaluating event subscription conditions",
+			"name", name,
+			logkeys.Error, err,
+		)
+	} else if !ok {
+		logger.Debug(
+			logkeys.Message, "event subscription conditions not matched",
+			"name", name,
+			logkeys.WorkflowName, es.Workflow,
+		)
+	}
+	return ok && err == nil
+}
+
+// recordEventFired records the run statistics of the named event subscription.
+func (e *Engine) recordEventFired(ctx context.Context, logger log.Logger, name string, startErr error) {
+	if e.eventStats == nil {
+		return
+	}
+	if err := e.eventStats.RecordEventSubscriptionFired(ctx, name, time.Now(), startErr); err != nil {
+		logger.Info(
+			logkeys.Message, "recording event subscription stats",
+			"name", name,
+			logkeys.Error, err,
+		)
+	}
+}
+
 // MDMCheckinEvent receives MDM checkin messages.
 func (e *Engine) MDMCheckinEvent(ctx context.Context, id string, checkin interface{}, mdmContext *workflow.MDMContext) error {
 	logger := ctxlog.Logger(ctx, e.logger).With(logkeys.EnrollmentID, id)
@@ -577,6 +770,7 @@ func (e *Engine) MDMCheckinEvent(ctx context.Context, id string, checkin interfa
 	switch v := checkin.(type) {
 	case *mdm.Authenticate:
 		cancelSteps = true
+		e.recordAuthenticate(ctx, logger, id, v)
 		events = []*workflow.Event{{
 			EventFlag: workflow.EventAuthenticate,
 			EventData: v,
@@ -613,6 +807,10 @@ func (e *Engine) MDMCheckinEvent(ctx context.Context, id string, checkin interfa
 			EventData: v,
 		}}
 	}
+	// retrieve any event steps waiting for these events before the
+	// steps of the enrollment may be canceled below. they are completed
+	// after canceling so that workflows can enqueue further steps.
+	waiting := e.retrieveEventSteps(ctx, logger, id, events)
 	if cancelSteps {
 		// we cancel all steps for an enrollment upon re-enrollment
 		// or checkout. this will allow us to enqueue workflows again.
@@ -625,7 +823,20 @@ func (e *Engine) MDMCheckinEvent(ctx context.Context, id string, checkin interfa
 		if err := e.storage.ClearWorkflowStatus(ctx, id); err != nil {
 			return logAndError(err, logger, "checkin event: clearing workflow status")
 		}
+		// and any delayed event subscription workflow starts
+		if err := e.storage.CancelPendingStarts(ctx, id); err != nil {
+			return logAndError(err, logger, "checkin event: cancel pending starts")
+		}
+		// and any queued workflow starts
+		if err := e.storage.CancelQueuedStarts(ctx, id); err != nil {
+			return logAndError(err, logger, "checkin event: cancel queued starts")
+		}
+		// and any follow-up workflows
+		if err := e.storage.CancelFollowUps(ctx, id); err != nil {
+			return logAndError(err, logger, "checkin event: cancel follow-ups")
+		}
 	}
+	e.completeEventSteps(ctx, logger, waiting)
 	for _, event := range events {
 		if err := e.dispatchEvents(ctx, id, event, mdmContext, true, true); err != nil {
 			logger.Info(
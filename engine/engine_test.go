@@ -2,10 +2,16 @@ package engine
 
 import (
 	"context"
+	"errors"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/jessepeterson/mdmcommands"
+	"github.com/micromdm/nanocmd/engine/storage"
 	"github.com/micromdm/nanocmd/engine/storage/inmem"
+	"github.com/micromdm/nanocmd/mdm"
+	auditstorage "github.com/micromdm/nanocmd/subsystem/audit/storage"
 	"github.com/micromdm/nanocmd/utils/uuid"
 	"github.com/micromdm/nanocmd/workflow"
 )
@@ -24,6 +30,16 @@ func (e *singleTargetEnqueuer) Enqueue(_ context.Context, ids []string, _ []byte
 
 func (e *singleTargetEnqueuer) SupportsMultiCommands() bool { return false }
 
+func (e *singleTargetEnqueuer) Push(_ context.Context, _ []string) error { return nil }
+
+// multiTargetEnqueuer is a singleTargetEnqueuer that supports
+// multi-targeted commands.
+type multiTargetEnqueuer struct {
+	singleTargetEnqueuer
+}
+
+func (e *multiTargetEnqueuer) SupportsMultiCommands() bool { return true }
+
 // oneCommandWorkflow enqueues a single MDM command when started.
 type oneCommandWorkflow struct {
 	enq  workflow.StepEnqueuer
@@ -86,3 +102,1098 @@ func TestStartWorkflowSingleTarget(t *testing.T) {
 		}
 	}
 }
+
+// TestEventSubscriptionStats checks that event subscriptions record
+// run statistics when they start workflows.
+func TestEventSubscriptionStats(t *testing.T) {
+	s := inmem.New()
+	e := New(s, new(singleTargetEnqueuer), WithEventStorage(s), WithEventStatsStorage(s))
+
+	w := &oneCommandWorkflow{enq: e, ider: uuid.NewUUID()}
+	if err := e.RegisterWorkflow(w); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	err := s.StoreEventSubscription(ctx, "enroll", &storage.EventSubscription{
+		Event:    "Enrollment",
+		Workflow: w.Name(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a subscription for an unregistered workflow will fail to start
+	err = s.StoreEventSubscription(ctx, "enroll-missing", &storage.EventSubscription{
+		Event:    "Enrollment",
+		Workflow: "test.wf.missing.v1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tu := &mdm.TokenUpdateEnrolling{TokenUpdate: new(mdm.TokenUpdate), Enrolling: true}
+	if err = e.MDMCheckinEvent(ctx, "AAABBBCCC111222333", tu, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := s.RetrieveEventSubscriptionStats(ctx, []string{"enroll", "enroll-missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, have := 2, len(stats); want != have {
+		t.Fatalf("want: %d; have: %d", want, have)
+	}
+
+	if want, have := int64(1), stats["enroll"].Count; want != have {
+		t.Errorf("count: want: %d; have: %d", want, have)
+	}
+
+	if want, have := int64(0), stats["enroll"].ErrorCount; want != have {
+		t.Errorf("error count: want: %d; have: %d", want, have)
+	}
+
+	if want, have := int64(1), stats["enroll-missing"].ErrorCount; want != have {
+		t.Errorf("error count: want: %d; have: %d", want, have)
+	}
+}
+
+// TestDeferredEventSubscription checks that delayed event subscriptions
+// start their workflows from the worker and are canceled by check-outs.
+func TestDeferredEventSubscription(t *testing.T) {
+	s := inmem.New()
+	enq := new(singleTargetEnqueuer)
+	e := New(s, enq, WithEventStorage(s), WithEventStatsStorage(s))
+
+	w := &oneCommandWorkflow{enq: e, ider: uuid.NewUUID()}
+	if err := e.RegisterWorkflow(w); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	err := s.StoreEventSubscription(ctx, "enroll", &storage.EventSubscription{
+		Event:    "Enrollment",
+		Workflow: w.Name(),
+		Jitter:   1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enroll := func(id string) {
+		tu := &mdm.TokenUpdateEnrolling{
+			TokenUpdate: &mdm.TokenUpdate{AwaitingConfiguration: true},
+			Enrolling:   true,
+		}
+		if err := e.MDMCheckinEvent(ctx, id, tu, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	enroll("AAABBBCCC111222333")
+	enroll("DDDEEEFFF444555666")
+
+	if want, have := 0, len(enq.enqueuedIDs); want != have {
+		t.Fatalf("enqueue count: want: %d; have: %d", want, have)
+	}
+
+	// the second enrollment checks out before its workflow starts
+	if err = e.MDMCheckinEvent(ctx, "DDDEEEFFF444555666", new(mdm.CheckOut), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// process the pending starts after the maximum jitter
+	starts, err := s.RetrieveDuePendingStarts(ctx, time.Now().Add(2*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, have := 1, len(starts); want != have {
+		t.Fatalf("pending starts: want: %d; have: %d", want, have)
+	}
+
+	if _, err = e.StartPending(ctx, starts[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	if want, have := 1, len(enq.enqueuedIDs); want != have {
+		t.Fatalf("enqueue count: want: %d; have: %d", want, have)
+	}
+
+	if want, have := "AAABBBCCC111222333", enq.enqueuedIDs[0][0]; want != have {
+		t.Errorf("want: %s; have: %s", want, have)
+	}
+
+	ev, err := workflowEventFromStoragePendingEvent(starts[0].EventFlag, starts[0].EventData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tu, ok := ev.EventData.(*mdm.TokenUpdate); !ok || !tu.AwaitingConfiguration {
+		t.Error("expected awaiting configuration token update event data")
+	}
+}
+
+type recordingObserver struct {
+	instanceIDs []string
+	errored     []bool
+	finished    []string
+}
+
+func (o *recordingObserver) StepObserved(_ context.Context, instanceID, _, _ string, errored, _ bool) {
+	o.instanceIDs = append(o.instanceIDs, instanceID)
+	o.errored = append(o.errored, errored)
+}
+
+func (o *recordingObserver) InstanceFinished(_ context.Context, instanceID, _, _ string, _ workflow.Outcome) {
+	o.finished = append(o.finished, instanceID)
+}
+
+// TestStepObserver checks that the step observer is notified of
+// completed steps and whether their commands errored and of finished
+// instances.
+func TestStepObserver(t *testing.T) {
+	o := new(recordingObserver)
+	e := New(inmem.New(), new(singleTargetEnqueuer), WithStepObserver(o))
+
+	w := &oneCommandWorkflow{enq: e, ider: uuid.NewStaticIDs("CMD1")}
+	if err := e.RegisterWorkflow(w); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	id := "AAABBBCCC111222333"
+
+	instanceID, err := e.StartWorkflow(ctx, w.Name(), nil, []string{id}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>CommandUUID</key>
+	<string>CMD1</string>
+	<key>Status</key>
+	<string>Error</string>
+	<key>UDID</key>
+	<string>AAABBBCCC111222333</string>
+</dict>
+</plist>`)
+	if err = e.MDMCommandResponseEvent(ctx, id, "CMD1", raw, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(o.instanceIDs), 1; have != want {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+	if have, want := o.instanceIDs[0], instanceID; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+	if !o.errored[0] {
+		t.Error("expected errored step")
+	}
+	if have, want := o.finished, []string{instanceID}; !reflect.DeepEqual(have, want) {
+		t.Errorf("finished: have: %v, want: %v", have, want)
+	}
+}
+
+// windowWorkflow is a oneCommandWorkflow configured for maintenance windows.
+type windowWorkflow struct {
+	oneCommandWorkflow
+}
+
+func (w *windowWorkflow) Name() string { return "test.wf.window.v1" }
+
+func (w *windowWorkflow) Config() *workflow.Config {
+	return &workflow.Config{MaintenanceWindow: true}
+}
+
+func (w *windowWorkflow) Start(ctx context.Context, step *workflow.StepStart) error {
+	se := step.NewStepEnqueueing()
+	se.Commands = []interface{}{mdmcommands.NewRestartDeviceCommand(w.ider.ID())}
+	return w.enq.EnqueueStep(ctx, w, se)
+}
+
+// staticWindows has maintenance windows starting at a static time.
+type staticWindows map[string]time.Time
+
+func (sw staticWindows) NextWindows(_ context.Context, ids []string, t time.Time) (map[string]time.Time, error) {
+	r := make(map[string]time.Time)
+	for _, id := range ids {
+		r[id] = t
+		if next, ok := sw[id]; ok && next.After(t) {
+			r[id] = next
+		}
+	}
+	return r, nil
+}
+
+// TestMaintenanceWindow checks that steps of workflows configured for
+// maintenance windows are delayed until the enrollments' windows.
+func TestMaintenanceWindow(t *testing.T) {
+	enq := new(multiTargetEnqueuer)
+	s := inmem.New()
+	windowStart := time.Now().Add(100 * time.Millisecond)
+	e := New(s, enq, WithMaintenanceWindows(staticWindows{"BBB": windowStart}))
+
+	w := &windowWorkflow{oneCommandWorkflow{enq: e, ider: uuid.NewUUID()}}
+	if err := e.RegisterWorkflow(w); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if _, err := e.StartWorkflow(ctx, w.Name(), nil, []string{"AAA", "BBB", "CCC"}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// only the enrollments inside their windows are sent the command
+	if have, want := len(enq.enqueuedIDs), 1; have != want {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+	if have, want := enq.enqueuedIDs[0], []string{"AAA", "CCC"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	steps, err := s.RetrieveStepsToEnqueue(ctx, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := len(steps), 0; have != want {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+
+	// wait for the window to start
+	time.Sleep(time.Until(windowStart))
+
+	steps, err = s.RetrieveStepsToEnqueue(ctx, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := len(steps), 1; have != want {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+	if have, want := steps[0].IDs, []string{"BBB"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+}
+
+// countingWindows is a staticWindows that counts its lookups.
+type countingWindows struct {
+	staticWindows
+	calls int
+}
+
+func (cw *countingWindows) NextWindows(ctx context.Context, ids []string, t time.Time) (map[string]time.Time, error) {
+	cw.calls++
+	return cw.staticWindows.NextWindows(ctx, ids, t)
+}
+
+// TestWindowSteps checks that steps are split by the maintenance window
+// starts of their enrollments and that the windows are found once.
+func TestWindowSteps(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Hour)
+	latest := now.Add(2 * time.Hour)
+	windows := &countingWindows{staticWindows: staticWindows{"BBB": later, "CCC": latest, "DDD": later}}
+	e := New(inmem.New(), new(multiTargetEnqueuer), WithMaintenanceWindows(windows))
+
+	ss := &storage.StepEnqueuingWithConfig{
+		StepEnqueueing: storage.StepEnqueueing{IDs: []string{"AAA", "BBB", "CCC", "DDD", "EEE"}},
+		Timeout:        now.Add(time.Minute),
+	}
+	steps, err := e.windowSteps(context.Background(), ss, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := windows.calls, 1; have != want {
+		t.Errorf("window lookups: have: %v, want: %v", have, want)
+	}
+	if have, want := len(steps), 3; have != want {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+	for i, test := range []struct {
+		ids      []string
+		notUntil time.Time
+		timeout  time.Time
+	}{
+		{[]string{"AAA", "EEE"}, time.Time{}, now.Add(time.Minute)},
+		{[]string{"BBB", "DDD"}, later, later.Add(time.Minute)},
+		{[]string{"CCC"}, latest, latest.Add(time.Minute)},
+	} {
+		if have, want := steps[i].IDs, test.ids; !reflect.DeepEqual(have, want) {
+			t.Errorf("%d: ids: have: %v, want: %v", i, have, want)
+		}
+		if have, want := steps[i].NotUntil, test.notUntil; !have.Equal(want) {
+			t.Errorf("%d: not until: have: %v, want: %v", i, have, want)
+		}
+		if have, want := steps[i].Timeout, test.timeout; !have.Equal(want) {
+			t.Errorf("%d: timeout: have: %v, want: %v", i, have, want)
+		}
+	}
+}
+
+// queuedWorkflow is a oneCommandWorkflow configured for queued
+// exclusivity. It records the context of each start.
+type queuedWorkflow struct {
+	oneCommandWorkflow
+	started []string
+}
+
+func (w *queuedWorkflow) Name() string { return "test.wf.queued.v1" }
+
+func (w *queuedWorkflow) Config() *workflow.Config {
+	return &workflow.Config{Exclusivity: workflow.ExclusiveQueued, MaxQueued: 1}
+}
+
+func (w *queuedWorkflow) NewContextValue(_ string) workflow.ContextMarshaler {
+	return new(workflow.StringContext)
+}
+
+func (w *queuedWorkflow) Start(ctx context.Context, step *workflow.StepStart) error {
+	w.started = append(w.started, string(*step.Context.(*workflow.StringContext)))
+	se := step.NewStepEnqueueing()
+	se.Commands = []interface{}{mdmcommands.NewDeviceInformationCommand(w.ider.ID())}
+	return w.enq.EnqueueStep(ctx, w, se)
+}
+
+// TestQueuedExclusivity checks that starts of a running workflow are
+// queued, coalesced, and started once the running instance completes.
+func TestQueuedExclusivity(t *testing.T) {
+	e := New(inmem.New(), new(singleTargetEnqueuer))
+
+	w := &queuedWorkflow{oneCommandWorkflow: oneCommandWorkflow{ider: uuid.NewStaticIDs("CMD1", "CMD2")}}
+	w.enq = e
+	if err := e.RegisterWorkflow(w); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	id := "AAABBBCCC111222333"
+
+	for _, c := range []string{"a", "b", "b"} {
+		if _, err := e.StartWorkflow(ctx, w.Name(), []byte(c), []string{id}, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the queue only holds one start
+	_, err := e.StartWorkflow(ctx, w.Name(), []byte("c"), []string{id}, nil, nil)
+	if !errors.Is(err, storage.ErrQueueFull) {
+		t.Errorf("have: %v, want: %v", err, storage.ErrQueueFull)
+	}
+
+	if have, want := w.started, []string{"a"}; !reflect.DeepEqual(have, want) {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+
+	raw := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>CommandUUID</key>
+	<string>CMD1</string>
+	<key>Status</key>
+	<string>Acknowledged</string>
+	<key>UDID</key>
+	<string>AAABBBCCC111222333</string>
+</dict>
+</plist>`)
+	if err = e.MDMCommandResponseEvent(ctx, id, "CMD1", raw, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// completing the running instance starts the queued start
+	if have, want := w.started, []string{"a", "b"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+}
+
+// defaultQueuedWorkflow is a queuedWorkflow without its own maximum
+// number of queued starts.
+type defaultQueuedWorkflow struct {
+	queuedWorkflow
+}
+
+func (w *defaultQueuedWorkflow) Config() *workflow.Config {
+	return &workflow.Config{Exclusivity: workflow.ExclusiveQueued}
+}
+
+// TestQueuedStartReport checks that queued starts are reported and
+// that the engine maximum of queued starts is used.
+func TestQueuedStartReport(t *testing.T) {
+	e := New(inmem.New(), new(multiTargetEnqueuer), WithMaxQueued(1))
+
+	w := &defaultQueuedWorkflow{queuedWorkflow{oneCommandWorkflow: oneCommandWorkflow{ider: uuid.NewUUID()}}}
+	w.enq = e
+	if err := e.RegisterWorkflow(w); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if _, err := e.StartWorkflow(ctx, w.Name(), []byte("a"), []string{"AAA"}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	report := new(StartReport)
+	instanceID, err := e.StartWorkflow(NewStartReportContext(ctx, report), w.Name(), []byte("b"), []string{"AAA", "BBB"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if instanceID == "" {
+		t.Error("expected instance ID")
+	}
+	if have, want := report.QueuedIDs, []string{"AAA"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	// the engine maximum applies
+	_, err = e.StartWorkflow(ctx, w.Name(), []byte("c"), []string{"AAA"}, nil, nil)
+	if !errors.Is(err, storage.ErrQueueFull) {
+		t.Errorf("have: %v, want: %v", err, storage.ErrQueueFull)
+	}
+}
+
+// domainWorkflow is a named oneCommandWorkflow in an exclusivity domain.
+type domainWorkflow struct {
+	oneCommandWorkflow
+	name string
+}
+
+func (w *domainWorkflow) Name() string { return w.name }
+
+func (w *domainWorkflow) Config() *workflow.Config {
+	return &workflow.Config{ExclusivityDomain: "test"}
+}
+
+func (w *domainWorkflow) Start(ctx context.Context, step *workflow.StepStart) error {
+	se := step.NewStepEnqueueing()
+	se.Commands = []interface{}{mdmcommands.NewDeviceInformationCommand(w.ider.ID())}
+	return w.enq.EnqueueStep(ctx, w, se)
+}
+
+// TestExclusivityDomain checks that workflows sharing an exclusivity
+// domain are not started while another workflow in the domain runs.
+func TestExclusivityDomain(t *testing.T) {
+	e := New(inmem.New(), new(singleTargetEnqueuer))
+
+	wA := &domainWorkflow{oneCommandWorkflow{enq: e, ider: uuid.NewUUID()}, "test.wf.domain.a"}
+	wB := &domainWorkflow{oneCommandWorkflow{enq: e, ider: uuid.NewUUID()}, "test.wf.domain.b"}
+	for _, w := range []workflow.Workflow{wA, wB, &oneCommandWorkflow{enq: e, ider: uuid.NewUUID()}} {
+		if err := e.RegisterWorkflow(w); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := context.Background()
+
+	if _, err := e.StartWorkflow(ctx, wA.Name(), nil, []string{"AAA"}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := e.StartWorkflow(ctx, wB.Name(), nil, []string{"AAA"}, nil, nil)
+	if !errors.Is(err, ErrWorkflowAlreadyStarted) {
+		t.Errorf("have: %v, want: %v", err, ErrWorkflowAlreadyStarted)
+	}
+
+	// other enrollments and workflows outside of the domain are not affected
+	if _, err = e.StartWorkflow(ctx, wB.Name(), nil, []string{"BBB"}, nil, nil); err != nil {
+		t.Error(err)
+	}
+	if _, err = e.StartWorkflow(ctx, "test.wf.onecommand.v1", nil, []string{"AAA"}, nil, nil); err != nil {
+		t.Error(err)
+	}
+
+	// simultaneous workflows can not join a domain
+	wC := &simultaneousDomainWorkflow{domainWorkflow{oneCommandWorkflow{enq: e, ider: uuid.NewUUID()}, "test.wf.domain.c"}}
+	if err = e.RegisterWorkflow(wC); !errors.Is(err, ErrInvalidExclusivity) {
+		t.Errorf("have: %v, want: %v", err, ErrInvalidExclusivity)
+	}
+	if e.WorkflowRegistered(wC.Name()) {
+		t.Errorf("unexpected registration of %s", wC.Name())
+	}
+}
+
+// simultaneousDomainWorkflow is a domainWorkflow with multiple simultaneous exclusivity.
+type simultaneousDomainWorkflow struct {
+	domainWorkflow
+}
+
+func (w *simultaneousDomainWorkflow) Config() *workflow.Config {
+	return &workflow.Config{Exclusivity: workflow.MultipleSimultaneous, ExclusivityDomain: "test"}
+}
+
+// followUpWorkflow is a named oneCommandWorkflow that records its starts.
+type followUpWorkflow struct {
+	oneCommandWorkflow
+	name    string
+	started []string
+}
+
+func (w *followUpWorkflow) Name() string { return w.name }
+
+func (w *followUpWorkflow) NewContextValue(_ string) workflow.ContextMarshaler {
+	return new(workflow.StringContext)
+}
+
+func (w *followUpWorkflow) Start(ctx context.Context, step *workflow.StepStart) error {
+	w.started = append(w.started, string(*step.Context.(*workflow.StringContext)))
+	se := step.NewStepEnqueueing()
+	se.Commands = []interface{}{mdmcommands.NewDeviceInformationCommand(w.ider.ID())}
+	return w.enq.EnqueueStep(ctx, w, se)
+}
+
+// TestFollowUps checks that follow-up workflows matching the instance
+// outcome are started when the instance finishes.
+func TestFollowUps(t *testing.T) {
+	auditor := new(testAuditor)
+	e := New(inmem.New(), new(singleTargetEnqueuer), WithAuditor(auditor))
+
+	w := &followUpWorkflow{oneCommandWorkflow: oneCommandWorkflow{enq: e, ider: uuid.NewStaticIDs("CMD1")}, name: "test.wf.first"}
+	wSucceeded := &followUpWorkflow{oneCommandWorkflow: oneCommandWorkflow{enq: e, ider: uuid.NewUUID()}, name: "test.wf.succeeded"}
+	wFailed := &followUpWorkflow{oneCommandWorkflow: oneCommandWorkflow{enq: e, ider: uuid.NewUUID()}, name: "test.wf.failed"}
+	for _, w := range []workflow.Workflow{w, wSucceeded, wFailed} {
+		if err := e.RegisterWorkflow(w); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := context.Background()
+	id := "AAABBBCCC111222333"
+
+	_, err := e.StartWorkflowWithFollowUps(ctx, w.Name(), nil, []string{id}, nil, nil, []storage.FollowUp{{Workflow: "test.wf.missing"}})
+	if !errors.Is(err, ErrNoSuchWorkflow) {
+		t.Errorf("have: %v, want: %v", err, ErrNoSuchWorkflow)
+	}
+
+	followUps := []storage.FollowUp{
+		{On: []workflow.Outcome{workflow.OutcomeSucceeded}, Workflow: wSucceeded.Name(), Context: "next"},
+		{On: []workflow.Outcome{workflow.OutcomeFailed, workflow.OutcomeTimedOut}, Workflow: wFailed.Name()},
+	}
+	if _, err = e.StartWorkflowWithFollowUps(ctx, w.Name(), nil, []string{id}, nil, nil, followUps); err != nil {
+		t.Fatal(err)
+	}
+
+	raw := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>CommandUUID</key>
+	<string>CMD1</string>
+	<key>Status</key>
+	<string>Acknowledged</string>
+	<key>UDID</key>
+	<string>AAABBBCCC111222333</string>
+</dict>
+</plist>`)
+	if err = e.MDMCommandResponseEvent(ctx, id, "CMD1", raw, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := wSucceeded.started, []string{"next"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	if len(wFailed.started) > 0 {
+		t.Errorf("unexpected start of %s", wFailed.Name())
+	}
+
+	if len(auditor.events) != 1 {
+		t.Fatalf("audit events: have: %d, want: %d", len(auditor.events), 1)
+	}
+	if have, want := auditor.events[0].Action, "follow_up.start"; have != want {
+		t.Errorf("action: have: %q, want: %q", have, want)
+	}
+	if have, want := auditor.events[0].WorkflowName, wSucceeded.Name(); have != want {
+		t.Errorf("workflow name: have: %q, want: %q", have, want)
+	}
+}
+
+// simultaneousWorkflow is a followUpWorkflow with multiple simultaneous exclusivity.
+type simultaneousWorkflow struct {
+	followUpWorkflow
+}
+
+func (w *simultaneousWorkflow) Config() *workflow.Config {
+	return &workflow.Config{Exclusivity: workflow.MultipleSimultaneous}
+}
+
+// TestFollowUpsSimultaneousInstances checks that the follow-ups of an
+// instance are started when it finishes even if another instance of
+// the same workflow is still running for the enrollment.
+func TestFollowUpsSimultaneousInstances(t *testing.T) {
+	e := New(inmem.New(), new(singleTargetEnqueuer))
+
+	w := &simultaneousWorkflow{followUpWorkflow{oneCommandWorkflow: oneCommandWorkflow{enq: e, ider: uuid.NewStaticIDs("CMD1", "CMD2")}, name: "test.wf.simultaneous"}}
+	wNext := &simultaneousWorkflow{followUpWorkflow{oneCommandWorkflow: oneCommandWorkflow{enq: e, ider: uuid.NewUUID()}, name: "test.wf.next"}}
+	for _, w := range []workflow.Workflow{w, wNext} {
+		if err := e.RegisterWorkflow(w); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := context.Background()
+	id := "AAABBBCCC111222333"
+
+	// the first instance sends CMD1 and the second CMD2
+	for _, c := range []string{"first", "second"} {
+		followUps := []storage.FollowUp{{Workflow: wNext.Name(), Context: c}}
+		if _, err := e.StartWorkflowWithFollowUps(ctx, w.Name(), []byte(c), []string{id}, nil, nil, followUps); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	respond := func(cmdUUID string) {
+		raw := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>CommandUUID</key>
+	<string>` + cmdUUID + `</string>
+	<key>Status</key>
+	<string>Acknowledged</string>
+	<key>UDID</key>
+	<string>AAABBBCCC111222333</string>
+</dict>
+</plist>`)
+		if err := e.MDMCommandResponseEvent(ctx, id, cmdUUID, raw, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the second instance is still running
+	respond("CMD1")
+
+	if have, want := wNext.started, []string{"first"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	respond("CMD2")
+
+	if have, want := wNext.started, []string{"first", "second"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+}
+
+// startFinishedWorkflow finishes its instances when starting without
+// enqueuing any steps.
+type startFinishedWorkflow struct {
+	oneCommandWorkflow
+}
+
+func (w *startFinishedWorkflow) Name() string { return "test.wf.startfinished.v1" }
+
+func (w *startFinishedWorkflow) Start(_ context.Context, step *workflow.StepStart) error {
+	for _, id := range step.IDs {
+		step.Finish(id, workflow.OutcomeSucceeded, "nothing to do")
+	}
+	return nil
+}
+
+// TestFollowUpsFinishedAtStart checks that the outcomes of instances
+// finished when starting are stored and their follow-ups are started.
+func TestFollowUpsFinishedAtStart(t *testing.T) {
+	s := inmem.New()
+	e := New(s, new(singleTargetEnqueuer))
+
+	w := &startFinishedWorkflow{oneCommandWorkflow{enq: e, ider: uuid.NewUUID()}}
+	wNext := &followUpWorkflow{oneCommandWorkflow: oneCommandWorkflow{enq: e, ider: uuid.NewUUID()}, name: "test.wf.next"}
+	for _, w := range []workflow.Workflow{w, wNext} {
+		if err := e.RegisterWorkflow(w); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := context.Background()
+	id := "AAABBBCCC111222333"
+
+	followUps := []storage.FollowUp{{On: []workflow.Outcome{workflow.OutcomeSucceeded}, Workflow: wNext.Name(), Context: "next"}}
+	instanceID, err := e.StartWorkflowWithFollowUps(ctx, w.Name(), nil, []string{id}, nil, nil, followUps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outcomes, err := s.RetrieveOutcomes(ctx, instanceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outcomes) != 1 {
+		t.Fatalf("outcomes: have: %d, want: %d", len(outcomes), 1)
+	}
+	if have, want := outcomes[0].Outcome, workflow.OutcomeSucceeded; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+	if have, want := outcomes[0].Message, "nothing to do"; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	if have, want := wNext.started, []string{"next"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+}
+
+// timerWorkflow waits with a timer step before sending a command.
+type timerWorkflow struct {
+	oneCommandWorkflow
+	fired []string
+}
+
+func (w *timerWorkflow) Name() string { return "test.wf.timer.v1" }
+
+func (w *timerWorkflow) NewContextValue(_ string) workflow.ContextMarshaler {
+	return new(workflow.StringContext)
+}
+
+func (w *timerWorkflow) Start(ctx context.Context, step *workflow.StepStart) error {
+	se := step.NewStepEnqueueing()
+	se.Name = "wait"
+	se.Context = step.Context
+	se.NotUntil = time.Now()
+	return w.enq.EnqueueStep(ctx, w, se)
+}
+
+func (w *timerWorkflow) StepCompleted(_ context.Context, stepResult *workflow.StepResult) error {
+	if len(stepResult.CommandResults) > 0 {
+		return errors.New("unexpected command results")
+	}
+	w.fired = append(w.fired, stepResult.Name+":"+string(*stepResult.Context.(*workflow.StringContext)))
+	return nil
+}
+
+// TestTimerStep checks that timer steps call back their workflow from
+// the worker without sending MDM commands.
+func TestTimerStep(t *testing.T) {
+	s := inmem.New()
+	enq := new(singleTargetEnqueuer)
+	e := New(s, enq)
+
+	w := &timerWorkflow{oneCommandWorkflow: oneCommandWorkflow{enq: e, ider: uuid.NewUUID()}}
+	if err := e.RegisterWorkflow(w); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	id := "AAABBBCCC111222333"
+
+	if _, err := e.StartWorkflow(ctx, w.Name(), []byte("hello"), []string{id}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// the timer step is outstanding
+	_, err := e.StartWorkflow(ctx, w.Name(), nil, []string{id}, nil, nil)
+	if !errors.Is(err, ErrWorkflowAlreadyStarted) {
+		t.Errorf("have: %v, want: %v", err, ErrWorkflowAlreadyStarted)
+	}
+
+	worker := NewWorker(e, s, enq, WithWorkerStepFinisher(e))
+	if err = worker.RunOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := w.fired, []string{"wait:hello"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	if len(enq.enqueuedIDs) > 0 {
+		t.Errorf("unexpected enqueued commands: %v", enq.enqueuedIDs)
+	}
+
+	// the finished instance is no longer outstanding
+	if _, err = e.StartWorkflow(ctx, w.Name(), nil, []string{id}, nil, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// eventWorkflow waits with an event step for a TokenUpdate check-in.
+type eventWorkflow struct {
+	oneCommandWorkflow
+	timeout  time.Time
+	events   []workflow.EventFlag
+	timedOut int
+}
+
+func (w *eventWorkflow) Name() string { return "test.wf.event.v1" }
+
+func (w *eventWorkflow) Start(ctx context.Context, step *workflow.StepStart) error {
+	se := step.NewStepEnqueueing()
+	se.Name = "wait"
+	se.WaitEvent = workflow.EventTokenUpdate
+	se.Timeout = w.timeout
+	return w.enq.EnqueueStep(ctx, w, se)
+}
+
+func (w *eventWorkflow) StepCompleted(_ context.Context, stepResult *workflow.StepResult) error {
+	if stepResult.Event == nil {
+		return errors.New("missing event")
+	}
+	w.events = append(w.events, stepResult.Event.EventFlag)
+	return nil
+}
+
+func (w *eventWorkflow) StepTimeout(_ context.Context, stepResult *workflow.StepResult) error {
+	if stepResult.Event != nil {
+		return errors.New("unexpected event")
+	}
+	w.timedOut++
+	return nil
+}
+
+// TestEventStep checks that event steps call back their workflow upon
+// the check-in event they wait for or time out from the worker.
+func TestEventStep(t *testing.T) {
+	s := inmem.New()
+	enq := new(singleTargetEnqueuer)
+	e := New(s, enq)
+
+	w := &eventWorkflow{oneCommandWorkflow: oneCommandWorkflow{enq: e, ider: uuid.NewUUID()}}
+	if err := e.RegisterWorkflow(w); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	id := "AAABBBCCC111222333"
+
+	if _, err := e.StartWorkflow(ctx, w.Name(), nil, []string{id}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// the event step is outstanding
+	_, err := e.StartWorkflow(ctx, w.Name(), nil, []string{id}, nil, nil)
+	if !errors.Is(err, ErrWorkflowAlreadyStarted) {
+		t.Errorf("have: %v, want: %v", err, ErrWorkflowAlreadyStarted)
+	}
+
+	// without a timeout the worker never fires the event step
+	worker := NewWorker(e, s, enq, WithWorkerStepFinisher(e))
+	if err = worker.RunOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(w.events) > 0 || w.timedOut > 0 {
+		t.Fatalf("unexpected callback: events=%v timed_out=%d", w.events, w.timedOut)
+	}
+
+	if err = e.MDMCheckinEvent(ctx, id, new(mdm.TokenUpdate), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := w.events, []workflow.EventFlag{workflow.EventTokenUpdate}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	if len(enq.enqueuedIDs) > 0 {
+		t.Errorf("unexpected enqueued commands: %v", enq.enqueuedIDs)
+	}
+
+	// the event step only fires once
+	if err = e.MDMCheckinEvent(ctx, id, new(mdm.TokenUpdate), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(w.events), 1; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	// an event step with a past timeout times out from the worker
+	w.timeout = time.Now().Add(-time.Second)
+	if _, err = e.StartWorkflow(ctx, w.Name(), nil, []string{id}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = worker.RunOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := w.timedOut, 1; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	// the timed out instance is no longer outstanding
+	if _, err = e.StartWorkflow(ctx, w.Name(), nil, []string{id}, nil, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestApproval checks that workflows requiring approval are only
+// started once a different principal approves the start.
+func TestApproval(t *testing.T) {
+	s := inmem.New()
+	enq := new(singleTargetEnqueuer)
+	w := &oneCommandWorkflow{ider: uuid.NewUUID()}
+	e := New(s, enq, WithApprovalWorkflows(w.Name()))
+	w.enq = e
+	if err := e.RegisterWorkflow(w); err != nil {
+		t.Fatal(err)
+	}
+
+	if !e.ApprovalRequired(w.Name()) {
+		t.Fatal("expected workflow to require approval")
+	}
+	if have, want := e.ApprovalWorkflows(), []string{w.Name()}; !reflect.DeepEqual(have, want) {
+		t.Errorf("approval workflows: have: %v, want: %v", have, want)
+	}
+
+	ctx := context.Background()
+	id := "AAABBBCCC111222333"
+
+	// workflows requiring approval can not be started directly
+	_, err := e.StartWorkflow(ctx, w.Name(), nil, []string{id}, nil, nil)
+	if !errors.Is(err, ErrWorkflowRequiresApproval) {
+		t.Errorf("have: %v, want: %v", err, ErrWorkflowRequiresApproval)
+	}
+
+	// workflows requiring approval can not be follow-ups
+	_, err = e.RequestApproval(ctx, "alice", w.Name(), nil, []string{id}, []storage.FollowUp{{Workflow: w.Name()}})
+	if !errors.Is(err, ErrWorkflowRequiresApproval) {
+		t.Errorf("have: %v, want: %v", err, ErrWorkflowRequiresApproval)
+	}
+
+	ar, err := e.RequestApproval(ctx, "alice", w.Name(), nil, []string{id}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := ar.Status, storage.ApprovalPending; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	if len(enq.enqueuedIDs) > 0 {
+		t.Fatalf("unexpected enqueued commands before approval: %v", enq.enqueuedIDs)
+	}
+
+	// the requester can not approve their own request
+	_, err = e.DecideApproval(ctx, ar.ID, "alice", true, "")
+	if !errors.Is(err, ErrSelfApproval) {
+		t.Errorf("have: %v, want: %v", err, ErrSelfApproval)
+	}
+
+	ar, err = e.DecideApproval(ctx, ar.ID, "bob", true, "looks good")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := ar.Status, storage.ApprovalApproved; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	if ar.InstanceID == "" || ar.StartError != "" {
+		t.Errorf("expected started instance: instance_id=%q start_error=%q", ar.InstanceID, ar.StartError)
+	}
+
+	if have, want := len(enq.enqueuedIDs), 1; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	// an approval request is only decided once
+	_, err = e.DecideApproval(ctx, ar.ID, "carol", false, "")
+	if !errors.Is(err, storage.ErrApprovalNotPending) {
+		t.Errorf("have: %v, want: %v", err, storage.ErrApprovalNotPending)
+	}
+
+	// expired approval requests can not be approved
+	e.approvalExpiry = time.Nanosecond
+	ar, err = e.RequestApproval(ctx, "alice", w.Name(), nil, []string{id}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+
+	ars, err := e.ApprovalRequests(ctx, storage.ApprovalExpired)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(ars), 1; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	_, err = e.DecideApproval(ctx, ar.ID, "bob", true, "")
+	if !errors.Is(err, ErrApprovalExpired) {
+		t.Errorf("have: %v, want: %v", err, ErrApprovalExpired)
+	}
+
+	if have, want := len(enq.enqueuedIDs), 1; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+}
+
+type testAuditor struct {
+	events []*auditstorage.Event
+}
+
+func (a *testAuditor) Audit(_ context.Context, e *auditstorage.Event) {
+	a.events = append(a.events, e)
+}
+
+// TestApprovalExpiry checks that expired approval requests are stored
+// as expired and that the expiry is audited.
+func TestApprovalExpiry(t *testing.T) {
+	s := inmem.New()
+	enq := new(singleTargetEnqueuer)
+	w := &oneCommandWorkflow{ider: uuid.NewUUID()}
+	auditor := new(testAuditor)
+	e := New(s, enq, WithApprovalWorkflows(w.Name()), WithApprovalExpiry(time.Minute), WithAuditor(auditor))
+	w.enq = e
+	if err := e.RegisterWorkflow(w); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	ar, err := e.RequestApproval(ctx, "alice", w.Name(), nil, []string{"AAA"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// not yet expired
+	worker := NewWorker(e, s, enq, WithWorkerApprovalExpirer(e))
+	if err = worker.RunOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(auditor.events), 0; have != want {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+
+	if err = e.ExpireApprovals(ctx, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := s.RetrieveApprovalRequest(ctx, ar.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := stored.Status, storage.ApprovalExpired; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	if stored.DecidedAt == nil {
+		t.Error("expected decided at time")
+	}
+
+	if have, want := len(auditor.events), 1; have != want {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+
+	if have, want := auditor.events[0].Action, "approval.expire"; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	// expiring again is a no-op
+	if err = e.ExpireApprovals(ctx, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(auditor.events), 1; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	_, err = e.DecideApproval(ctx, ar.ID, "bob", true, "")
+	if !errors.Is(err, ErrApprovalExpired) {
+		t.Errorf("have: %v, want: %v", err, ErrApprovalExpired)
+	}
+}
@@ -0,0 +1,137 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/micromdm/nanocmd/engine/storage"
+	"github.com/micromdm/nanocmd/logkeys"
+	"github.com/micromdm/nanocmd/workflow"
+
+	"github.com/micromdm/nanolib/log/ctxlog"
+)
+
+// stepOutcome determines the outcome of the instance of stepResult.
+// An outcome reported by the workflow takes precedence. Otherwise the
+// outcome is inferred from the step error and timeout.
+func stepOutcome(stepResult *workflow.StepResult, stepErr error, timedOut bool) (workflow.Outcome, string) {
+	if o, msg := stepResult.Outcome(); o != "" {
+		return o, msg
+	}
+	if timedOut {
+		return workflow.OutcomeTimedOut, "step timed out"
+	} else if stepErr != nil {
+		return workflow.OutcomeFailed, stepErr.Error()
+	}
+	return workflow.OutcomeSucceeded, ""
+}
+
+// StepFinished is called after workflow w has been called back for a
+// completed or timed out step. If the step finished the instance for
+// the enrollment then its follow-up workflows matching the outcome and
+// any queued workflow starts are started. Errors are logged.
+func (e *Engine) StepFinished(ctx context.Context, w workflow.Workflow, stepResult *workflow.StepResult, stepErr error, timedOut bool) {
+	if stepResult == nil || stepResult.ID == "" {
+		return
+	}
+	logger := ctxlog.Logger(ctx, e.logger).With(
+		logkeys.InstanceID, stepResult.InstanceID,
+		logkeys.WorkflowName, w.Name(),
+		logkeys.EnrollmentID, stepResult.ID,
+	)
+
+	if o, _ := stepResult.Outcome(); o == "" {
+		// without a reported outcome the instance is only finished if
+		// the workflow did not enqueue further steps.
+		// other instances of the workflow may still be running for
+		// the enrollment (e.g. with multiple simultaneous exclusivity).
+		outstanding, err := e.storage.RetrieveOutstandingInstanceStatus(ctx, w.Name(), stepResult.InstanceID, stepResult.ID)
+		if err != nil {
+			logger.Info(logkeys.Message, "retrieving outstanding status", logkeys.Error, err)
+			return
+		}
+		if outstanding {
+			return
+		}
+	}
+	outcome, msg := stepOutcome(stepResult, stepErr, timedOut)
+
+	if err := e.storage.StoreOutcome(ctx, &storage.InstanceOutcome{
+		InstanceID:   stepResult.InstanceID,
+		EnrollmentID: stepResult.ID,
+		WorkflowName: w.Name(),
+		Outcome:      outcome,
+		Message:      msg,
+		FinishedAt:   time.Now(),
+	}); err != nil {
+		logger.Info(logkeys.Message, "storing outcome", logkeys.Error, err)
+	}
+
+	if o, ok := e.observer.(InstanceObserver); ok {
+		o.InstanceFinished(ctx, stepResult.InstanceID, w.Name(), stepResult.ID, outcome)
+	}
+
+	if err := e.startFollowUps(ctx, stepResult.InstanceID, stepResult.ID, outcome); err != nil {
+		logger.Info(
+			logkeys.Message, "starting follow-up workflows",
+			"outcome", outcome,
+			logkeys.Error, err,
+		)
+	} else {
+		logger.Debug(
+			logkeys.Message, "instance finished",
+			"outcome", outcome,
+			"outcome_message", msg,
+		)
+	}
+
+	e.startQueued(ctx, w, stepResult.ID)
+}
+
+// startFollowUps starts the follow-up workflows of instanceID for id
+// that match outcome.
+func (e *Engine) startFollowUps(ctx context.Context, instanceID, id string, outcome workflow.Outcome) error {
+	followUps, err := e.storage.RetrieveFollowUps(ctx, instanceID, id)
+	if err != nil {
+		return fmt.Errorf("retrieving follow-ups: %w", err)
+	}
+	var fuErr error
+	for _, fu := range followUps {
+		if !fu.Matches(outcome) {
+			continue
+		}
+		fuInstanceID, err := e.StartWorkflow(ctx, fu.Workflow, []byte(fu.Context), []string{id}, nil, nil)
+		e.auditFollowUpStart(ctx, fu.Workflow, id, instanceID, outcome, fuInstanceID, err)
+		if err != nil {
+			// try to start the remaining follow-ups
+			fuErr = fmt.Errorf("starting follow-up workflow %s: %w", fu.Workflow, err)
+			continue
+		}
+		ctxlog.Logger(ctx, e.logger).Debug(
+			logkeys.Message, "started follow-up workflow",
+			logkeys.InstanceID, fuInstanceID,
+			logkeys.WorkflowName, fu.Workflow,
+			logkeys.EnrollmentID, id,
+			"parent_instance_id", instanceID,
+		)
+	}
+	return fuErr
+}
+
+// validateFollowUps validates followUps and checks that their
+// workflows are registered and do not require approval.
+func (e *Engine) validateFollowUps(followUps []storage.FollowUp) error {
+	for i, fu := range followUps {
+		if err := fu.Validate(); err != nil {
+			return fmt.Errorf("follow-up %d: %w", i, err)
+		}
+		if e.Workflow(fu.Workflow) == nil {
+			return fmt.Errorf("follow-up %d: %w", i, NewErrNoSuchWorkflow(fu.Workflow))
+		}
+		if e.ApprovalRequired(fu.Workflow) {
+			return fmt.Errorf("follow-up %d: %w: %s", i, ErrWorkflowRequiresApproval, fu.Workflow)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/micromdm/nanocmd/engine"
+	"github.com/micromdm/nanocmd/engine/storage"
+	"github.com/micromdm/nanocmd/http/api"
+	"github.com/micromdm/nanocmd/logkeys"
+	"github.com/micromdm/nanocmd/subsystem/audit"
+	auditstorage "github.com/micromdm/nanocmd/subsystem/audit/storage"
+
+	"github.com/alexedwards/flow"
+	"github.com/micromdm/nanolib/log"
+	"github.com/micromdm/nanolib/log/ctxlog"
+)
+
+var (
+	ErrNoApprover      = errors.New("missing approver")
+	ErrNoApprovalID    = errors.New("missing approval ID parameter")
+	ErrInvalidApproval = errors.New("invalid approval status")
+)
+
+// Approver retrieves and decides approval requests.
+type Approver interface {
+	ApprovalRequest(ctx context.Context, id string) (*storage.ApprovalRequest, error)
+	ApprovalRequests(ctx context.Context, status storage.ApprovalStatus) ([]*storage.ApprovalRequest, error)
+	DecideApproval(ctx context.Context, id, decider string, approve bool, reason string) (*storage.ApprovalRequest, error)
+}
+
+// approvalErrorStatus returns the HTTP status code for approval errors.
+func approvalErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, storage.ErrApprovalNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, storage.ErrApprovalNotPending), errors.Is(err, engine.ErrApprovalExpired):
+		return http.StatusConflict
+	case errors.Is(err, engine.ErrSelfApproval), errors.Is(err, engine.ErrMissingPrincipal):
+		return http.StatusForbidden
+	}
+	return 0
+}
+
+// ListApprovalsHandler returns JSON of the approval requests.
+// The optional "status" query parameter filters the approval requests.
+func ListApprovalsHandler(approver Approver, logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := ctxlog.Logger(r.Context(), logger)
+		if approver == nil {
+			logger.Info(logkeys.Error, ErrNoApprover)
+			api.JSONError(w, ErrNoApprover, 0)
+			return
+		}
+
+		status := storage.ApprovalStatus(r.URL.Query().Get("status"))
+		if status != "" && !status.Valid() {
+			logger.Info(logkeys.Message, "parameters", logkeys.Error, ErrInvalidApproval)
+			api.JSONError(w, ErrInvalidApproval, http.StatusBadRequest)
+			return
+		}
+
+		ars, err := approver.ApprovalRequests(r.Context(), status)
+		if err != nil {
+			logger.Info(logkeys.Message, "retrieve approval requests", logkeys.Error, err)
+			api.JSONError(w, err, 0)
+			return
+		}
+		if ars == nil {
+			// encode an empty JSON list rather than null
+			ars = []*storage.ApprovalRequest{}
+		}
+
+		logger.Debug(
+			logkeys.Message, "retrieved approval requests",
+			logkeys.GenericCount, len(ars),
+		)
+		w.Header().Set("Content-Type", "application/json")
+		if err = json.NewEncoder(w).Encode(ars); err != nil {
+			logger.Info(logkeys.Message, "encoding json to body", logkeys.Error, err)
+			return
+		}
+	}
+}
+
+// GetApprovalHandler returns JSON of an approval request.
+func GetApprovalHandler(approver Approver, logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := ctxlog.Logger(r.Context(), logger)
+		if approver == nil {
+			logger.Info(logkeys.Error, ErrNoApprover)
+			api.JSONError(w, ErrNoApprover, 0)
+			return
+		}
+
+		id := flow.Param(r.Context(), "id")
+		if id == "" {
+			logger.Info(logkeys.Message, "parameters", logkeys.Error, ErrNoApprovalID)
+			api.JSONError(w, ErrNoApprovalID, http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("approval_id", id)
+		ar, err := approver.ApprovalRequest(r.Context(), id)
+		if err != nil {
+			logger.Info(logkeys.Message, "retrieve approval request", logkeys.Error, err)
+			api.JSONError(w, err, approvalErrorStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err = json.NewEncoder(w).Encode(ar); err != nil {
+			logger.Info(logkeys.Message, "encoding json to body", logkeys.Error, err)
+			return
+		}
+	}
+}
+
+// DecideApprovalHandler approves (or rejects) an approval request as
+// the API principal and returns JSON of the decided approval request.
+// The optional "reason" query parameter is recorded with the decision.
+// A failed start of an approved workflow is reported in the approval request.
+func DecideApprovalHandler(approver Approver, approve bool, logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := ctxlog.Logger(r.Context(), logger)
+		if approver == nil {
+			logger.Info(logkeys.Error, ErrNoApprover)
+			api.JSONError(w, ErrNoApprover, 0)
+			return
+		}
+
+		id := flow.Param(r.Context(), "id")
+		if id == "" {
+			logger.Info(logkeys.Message, "parameters", logkeys.Error, ErrNoApprovalID)
+			api.JSONError(w, ErrNoApprovalID, http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("approval_id", id, "approve", approve)
+		ar, err := approver.DecideApproval(
+			r.Context(),
+			id,
+			api.Principal(r.Context()),
+			approve,
+			r.URL.Query().Get("reason"),
+		)
+		if err != nil {
+			logger.Info(logkeys.Message, "deciding approval request", logkeys.Error, err)
+			api.JSONError(w, err, approvalErrorStatus(err))
+			return
+		}
+		logger.Debug(logkeys.Message, "decided approval request", "status", ar.Status)
+		audit.Annotate(r.Context(), func(e *auditstorage.Event) {
+			e.TargetIDs = ar.IDs
+			e.WorkflowName = ar.WorkflowName
+			e.InstanceID = ar.InstanceID
+			if ar.StartError != "" {
+				e.Outcome = auditstorage.OutcomeFailure
+				e.Error = ar.StartError
+			}
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		if err = json.NewEncoder(w).Encode(ar); err != nil {
+			logger.Info(logkeys.Message, "encoding json to body", logkeys.Error, err)
+			return
+		}
+	}
+}
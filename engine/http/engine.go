@@ -5,10 +5,17 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
 
+	"github.com/micromdm/nanocmd/engine"
+	"github.com/micromdm/nanocmd/engine/storage"
 	"github.com/micromdm/nanocmd/http/api"
 	"github.com/micromdm/nanocmd/logkeys"
+	"github.com/micromdm/nanocmd/subsystem/audit"
+	auditstorage "github.com/micromdm/nanocmd/subsystem/audit/storage"
 	"github.com/micromdm/nanocmd/workflow"
 
 	"github.com/alexedwards/flow"
@@ -22,11 +29,54 @@ var (
 )
 
 type WorkflowStarter interface {
-	StartWorkflow(ctx context.Context, name string, context []byte, ids []string, e *workflow.Event, mdmCtx *workflow.MDMContext) (string, error)
+	StartWorkflowWithFollowUps(ctx context.Context, name string, context []byte, ids []string, e *workflow.Event, mdmCtx *workflow.MDMContext, followUps []storage.FollowUp) (string, error)
+}
+
+// ApprovalRequester requests approval for starting workflows that require it.
+type ApprovalRequester interface {
+	ApprovalRequired(name string) bool
+	RequestApproval(ctx context.Context, requester, name string, context []byte, ids []string, followUps []storage.FollowUp) (*storage.ApprovalRequest, error)
+}
+
+// workflowNames returns name and the workflow names of followUps.
+func workflowNames(name string, followUps []storage.FollowUp) []string {
+	names := []string{name}
+	for _, fu := range followUps {
+		names = append(names, fu.Workflow)
+	}
+	return names
+}
+
+// startRequest is the optional JSON body of a workflow start.
+type startRequest struct {
+	FollowUps []storage.FollowUp `json:"follow_ups,omitempty"`
+}
+
+// decodeStartRequest decodes the optional JSON body of r into req.
+// Empty bodies and bodies that are not JSON (per their Content-Type)
+// are ignored so that clients without follow-ups can send any body.
+func decodeStartRequest(r *http.Request, req *startRequest) error {
+	if r.Body == nil || r.ContentLength == 0 {
+		return nil
+	}
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		return nil
+	}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
 }
 
 // StartWorkflowHandler creates a HandlerFunc that starts a workflow.
-func StartWorkflowHandler(starter WorkflowStarter, logger log.Logger) http.HandlerFunc {
+// An optional JSON body may specify follow-up workflows. The API
+// principal must have the permission to start each follow-up workflow.
+// If the workflow requires approval then an approval request by the API
+// principal is created instead and returned with an Accepted status.
+// The enrollment IDs whose starts were queued (see workflow.ExclusiveQueued)
+// are returned along with the instance ID, which is empty if every
+// start was queued.
+func StartWorkflowHandler(starter WorkflowStarter, approvals ApprovalRequester, logger log.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		logger := ctxlog.Logger(r.Context(), logger)
 		ids := r.URL.Query()["id"]
@@ -41,30 +91,78 @@ func StartWorkflowHandler(starter WorkflowStarter, logger log.Logger) http.Handl
 			logkeys.FirstEnrollmentID, ids[0],
 			logkeys.WorkflowName, name,
 		)
+		audit.Annotate(r.Context(), func(e *auditstorage.Event) { e.WorkflowName = name })
 		if starter == nil {
 			logger.Info(logkeys.Message, "starting workflow", logkeys.Error, ErrNoStarter)
 			api.JSONError(w, ErrNoStarter, 0)
 			return
 		}
 
+		req := new(startRequest)
+		if err := decodeStartRequest(r, req); err != nil {
+			logger.Info(logkeys.Message, "decoding body", logkeys.Error, err)
+			api.JSONError(w, err, http.StatusBadRequest)
+			return
+		}
+		for i := range req.FollowUps {
+			if err := req.FollowUps[i].Validate(); err != nil {
+				err = fmt.Errorf("follow-up %d: %w", i, err)
+				logger.Info(logkeys.Message, "validating follow-ups", logkeys.Error, err)
+				api.JSONError(w, err, http.StatusBadRequest)
+				return
+			}
+		}
+		if err := api.CheckWorkflowStart(r.Context(), workflowNames(name, req.FollowUps)...); err != nil {
+			logger.Info(logkeys.Message, "checking follow-up permissions", logkeys.Error, err)
+			api.JSONError(w, err, http.StatusForbidden)
+			return
+		}
+
+		if approvals != nil && approvals.ApprovalRequired(name) {
+			ar, err := approvals.RequestApproval(
+				r.Context(),
+				api.Principal(r.Context()),
+				name,
+				[]byte(r.URL.Query().Get("context")),
+				ids,
+				req.FollowUps,
+			)
+			if err != nil {
+				logger.Info(logkeys.Message, "requesting approval", logkeys.Error, err)
+				api.JSONError(w, err, 0)
+				return
+			}
+			logger.Debug(logkeys.Message, "requested approval", "approval_id", ar.ID)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusAccepted)
+			if err = json.NewEncoder(w).Encode(ar); err != nil {
+				logger.Info(logkeys.Message, "encoding json response", logkeys.Error, err)
+			}
+			return
+		}
+
 		logger.Debug(logkeys.Message, "starting workflow")
-		instanceID, err := starter.StartWorkflow(
-			r.Context(),
+		report := new(engine.StartReport)
+		instanceID, err := starter.StartWorkflowWithFollowUps(
+			engine.NewStartReportContext(r.Context(), report),
 			name,
 			[]byte(r.URL.Query().Get("context")),
 			ids,
 			nil,
 			nil,
+			req.FollowUps,
 		)
 		if err != nil {
 			logger.Info(logkeys.Message, "starting workflow", logkeys.Error, err)
 			api.JSONError(w, err, 0)
 			return
 		}
+		audit.Annotate(r.Context(), func(e *auditstorage.Event) { e.InstanceID = instanceID })
 
 		jsonResp := &struct {
-			InstanceID string `json:"instance_id"`
-		}{InstanceID: instanceID}
+			InstanceID string   `json:"instance_id"`
+			QueuedIDs  []string `json:"queued_ids,omitempty"`
+		}{InstanceID: instanceID, QueuedIDs: report.QueuedIDs}
 		if err = json.NewEncoder(w).Encode(jsonResp); err != nil {
 			logger.Info(logkeys.Message, "encoding json response", logkeys.Error, err)
 		}
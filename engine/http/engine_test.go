@@ -0,0 +1,195 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/micromdm/nanocmd/engine"
+	"github.com/micromdm/nanocmd/engine/storage"
+	"github.com/micromdm/nanocmd/engine/storage/inmem"
+	"github.com/micromdm/nanocmd/http/api"
+	"github.com/micromdm/nanocmd/workflow"
+	"github.com/micromdm/nanocmd/workflow/test"
+
+	"github.com/alexedwards/flow"
+	"github.com/jessepeterson/mdmcommands"
+	"github.com/micromdm/nanolib/log"
+)
+
+type testStarter struct {
+	started []string
+}
+
+func (s *testStarter) StartWorkflowWithFollowUps(_ context.Context, name string, _ []byte, _ []string, _ *workflow.Event, _ *workflow.MDMContext, _ []storage.FollowUp) (string, error) {
+	s.started = append(s.started, name)
+	return "inst1", nil
+}
+
+type testChecker struct{}
+
+func (testChecker) WorkflowRegistered(string) bool { return true }
+
+func (testChecker) ApprovalRequired(string) bool { return false }
+
+func TestStartWorkflowFollowUpPermission(t *testing.T) {
+	starter := new(testStarter)
+	mux := flow.New()
+	mux.Handle("/v1/workflow/:name/start", StartWorkflowHandler(starter, nil, log.NopLogger), "POST")
+
+	body := `{"follow_ups": [{"workflow": "wf.b"}]}`
+	for _, test := range []struct {
+		perms  []string
+		status int
+	}{
+		{[]string{api.PermWorkflowStart("wf.a")}, http.StatusForbidden},
+		{[]string{api.PermWorkflowStart("wf.a"), api.PermWorkflowStart("wf.b")}, http.StatusOK},
+	} {
+		h := api.NewPrincipalHandler(mux, "alice", test.perms...)
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/v1/workflow/wf.a/start?id=AAA", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		h.ServeHTTP(w, r)
+		if have, want := w.Code, test.status; have != want {
+			t.Errorf("%v: status: have: %d, want: %d", test.perms, have, want)
+		}
+	}
+	if have, want := len(starter.started), 1; have != want {
+		t.Errorf("started: have: %d, want: %d", have, want)
+	}
+}
+
+func TestStartWorkflowBody(t *testing.T) {
+	starter := new(testStarter)
+	mux := flow.New()
+	mux.Handle("/v1/workflow/:name/start", StartWorkflowHandler(starter, nil, log.NopLogger), "POST")
+	h := api.NewPrincipalHandler(mux, "alice", api.PermWorkflowStart("wf.a"))
+
+	for _, test := range []struct {
+		contentType string
+		body        string
+		status      int
+	}{
+		{"", "", http.StatusOK},
+		{"application/json", "", http.StatusOK},
+		{"application/x-www-form-urlencoded", "context=foo", http.StatusOK},
+		{"text/plain", "{", http.StatusOK},
+		{"application/json; charset=utf-8", "{", http.StatusBadRequest},
+		{"application/json", `{"follow_ups": [{"workflow": "wf.b"}]}`, http.StatusForbidden},
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/v1/workflow/wf.a/start?id=AAA", strings.NewReader(test.body))
+		if test.contentType != "" {
+			r.Header.Set("Content-Type", test.contentType)
+		}
+		h.ServeHTTP(w, r)
+		if have, want := w.Code, test.status; have != want {
+			t.Errorf("%q %q: status: have: %d, want: %d", test.contentType, test.body, have, want)
+		}
+	}
+	if have, want := len(starter.started), 4; have != want {
+		t.Errorf("started: have: %d, want: %d", have, want)
+	}
+}
+
+func TestPutEventSubscriptionPermission(t *testing.T) {
+	store := inmem.New()
+	mux := flow.New()
+	mux.Handle("/v1/event/:name", PutHandler(store, testChecker{}, log.NopLogger), "PUT")
+
+	body := `{"event": "Enrollment", "workflow": "wf.a", "follow_ups": [{"workflow": "wf.b"}]}`
+	for _, test := range []struct {
+		perms  []string
+		status int
+	}{
+		{[]string{api.PermSubscriptionsManage}, http.StatusForbidden},
+		{[]string{api.PermSubscriptionsManage, api.PermWorkflowStart("wf.a")}, http.StatusForbidden},
+		{[]string{api.PermSubscriptionsManage, api.PermWorkflowStartPrefix + "*"}, http.StatusNoContent},
+	} {
+		h := api.NewPrincipalHandler(mux, "alice", test.perms...)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("PUT", "/v1/event/es1", strings.NewReader(body)))
+		if have, want := w.Code, test.status; have != want {
+			t.Errorf("%v: status: have: %d, want: %d", test.perms, have, want)
+		}
+		if w.Code == http.StatusForbidden {
+			// missing event subscriptions are an error
+			es, err := store.RetrieveEventSubscriptions(context.Background(), []string{"es1"})
+			if err == nil && es["es1"] != nil {
+				t.Error("expected event subscription not to be stored")
+			}
+		}
+	}
+}
+
+// queuedWorkflow is a workflow with queued exclusivity that enqueues
+// one command.
+type queuedWorkflow struct {
+	enq workflow.StepEnqueuer
+	ct  int
+}
+
+func (w *queuedWorkflow) Name() string { return "wf.queued" }
+
+func (w *queuedWorkflow) Config() *workflow.Config {
+	return &workflow.Config{Exclusivity: workflow.ExclusiveQueued}
+}
+
+func (w *queuedWorkflow) NewContextValue(_ string) workflow.ContextMarshaler { return nil }
+
+func (w *queuedWorkflow) Start(ctx context.Context, step *workflow.StepStart) error {
+	w.ct++
+	se := step.NewStepEnqueueing()
+	se.Commands = []interface{}{mdmcommands.NewDeviceInformationCommand(fmt.Sprintf("CMD%d", w.ct))}
+	return w.enq.EnqueueStep(ctx, w, se)
+}
+
+func (w *queuedWorkflow) StepCompleted(_ context.Context, _ *workflow.StepResult) error { return nil }
+
+func (w *queuedWorkflow) StepTimeout(_ context.Context, _ *workflow.StepResult) error { return nil }
+
+func (w *queuedWorkflow) Event(_ context.Context, _ *workflow.Event, _ string, _ *workflow.MDMContext) error {
+	return nil
+}
+
+func TestStartWorkflowQueuedIDs(t *testing.T) {
+	e := engine.New(inmem.New(), &test.NullEnqueuer{})
+	if err := e.RegisterWorkflow(&queuedWorkflow{enq: e}); err != nil {
+		t.Fatal(err)
+	}
+	mux := flow.New()
+	mux.Handle("/v1/workflow/:name/start", StartWorkflowHandler(e, nil, log.NopLogger), "POST")
+	h := api.NewPrincipalHandler(mux, "alice", api.PermAll)
+
+	for _, want := range []struct {
+		instanceID bool
+		queuedIDs  []string
+	}{
+		{true, nil},
+		{false, []string{"AAA"}},
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("POST", "/v1/workflow/wf.queued/start?id=AAA", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status: have: %d, want: %d", w.Code, http.StatusOK)
+		}
+		resp := &struct {
+			InstanceID string   `json:"instance_id"`
+			QueuedIDs  []string `json:"queued_ids"`
+		}{}
+		if err := json.NewDecoder(w.Body).Decode(resp); err != nil {
+			t.Fatal(err)
+		}
+		if have := resp.InstanceID != ""; have != want.instanceID {
+			t.Errorf("instance id: have: %q", resp.InstanceID)
+		}
+		if have := resp.QueuedIDs; !reflect.DeepEqual(have, want.queuedIDs) {
+			t.Errorf("queued ids: have: %v, want: %v", have, want.queuedIDs)
+		}
+	}
+}
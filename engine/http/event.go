@@ -3,11 +3,14 @@ package http
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 
+	"github.com/micromdm/nanocmd/engine"
 	"github.com/micromdm/nanocmd/engine/storage"
 	"github.com/micromdm/nanocmd/http/api"
 	"github.com/micromdm/nanocmd/logkeys"
+	"github.com/micromdm/nanocmd/workflow"
 
 	"github.com/alexedwards/flow"
 	"github.com/micromdm/nanolib/log"
@@ -18,6 +21,7 @@ var (
 	ErrMissingStore          = errors.New("missing store")
 	ErrNoName                = errors.New("missing name parameter")
 	ErrWorkflowNotRegistered = errors.New("workflow not registered")
+	ErrInvalidEvent          = errors.New("invalid event type")
 )
 
 // GetHandler retrieves and returns JSON of the named event subscription.
@@ -57,11 +61,16 @@ func GetHandler(store storage.ReadEventSubscriptionStorage, logger log.Logger) h
 	}
 }
 
+// WorkflowNameChecker checks that a workflow name is registered and
+// whether it requires approval to start.
 type WorkflowNameChecker interface {
 	WorkflowRegistered(name string) bool
+	ApprovalRequired(name string) bool
 }
 
 // PutHandler stores JSON of the named event subscription.
+// The API principal must have the permission to start the workflow and
+// each follow-up workflow of the event subscription.
 func PutHandler(store storage.EventSubscriptionStorage, chk WorkflowNameChecker, logger log.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		logger := ctxlog.Logger(r.Context(), logger)
@@ -99,6 +108,30 @@ func PutHandler(store storage.EventSubscriptionStorage, chk WorkflowNameChecker,
 			logger.Info(logkeys.Message, "checking workflow name", logkeys.Error, ErrWorkflowNotRegistered)
 			api.JSONError(w, ErrWorkflowNotRegistered, http.StatusBadRequest)
 			return
+		} else if chk.ApprovalRequired(es.Workflow) {
+			err := fmt.Errorf("%w: %s", engine.ErrWorkflowRequiresApproval, es.Workflow)
+			logger.Info(logkeys.Message, "checking workflow name", logkeys.Error, err)
+			api.JSONError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		for i, fu := range es.FollowUps {
+			if !chk.WorkflowRegistered(fu.Workflow) {
+				err = fmt.Errorf("follow-up %d: %w: %s", i, ErrWorkflowNotRegistered, fu.Workflow)
+			} else if chk.ApprovalRequired(fu.Workflow) {
+				err = fmt.Errorf("follow-up %d: %w: %s", i, engine.ErrWorkflowRequiresApproval, fu.Workflow)
+			}
+			if err != nil {
+				logger.Info(logkeys.Message, "checking follow-up workflow name", logkeys.Error, err)
+				api.JSONError(w, err, http.StatusBadRequest)
+				return
+			}
+		}
+
+		if err = api.CheckWorkflowStart(r.Context(), workflowNames(es.Workflow, es.FollowUps)...); err != nil {
+			logger.Info(logkeys.Message, "checking workflow permissions", logkeys.Error, err)
+			api.JSONError(w, err, http.StatusForbidden)
+			return
 		}
 
 		if err = store.StoreEventSubscription(r.Context(), name, es); err != nil {
@@ -111,3 +144,105 @@ func PutHandler(store storage.EventSubscriptionStorage, chk WorkflowNameChecker,
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
+
+// DeleteHandler deletes the named event subscription.
+func DeleteHandler(store storage.EventSubscriptionStorage, logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := ctxlog.Logger(r.Context(), logger)
+		if store == nil {
+			logger.Info(logkeys.Error, ErrMissingStore)
+			api.JSONError(w, ErrMissingStore, 0)
+			return
+		}
+
+		name := flow.Param(r.Context(), "name")
+		if name == "" {
+			logger.Info(logkeys.Message, "parameters", logkeys.Error, ErrNoName)
+			api.JSONError(w, ErrNoName, http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("name", name)
+		if err := store.DeleteEventSubscription(r.Context(), name); err != nil {
+			logger.Info(logkeys.Message, "deleting event subscription", logkeys.Error, err)
+			api.JSONError(w, err, 0)
+			return
+		}
+
+		logger.Debug(logkeys.Message, "deleted event subscription")
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// eventSubscriptionWithStats is an event subscription and its run statistics.
+type eventSubscriptionWithStats struct {
+	*storage.EventSubscription
+	Stats *storage.EventSubscriptionStats `json:"stats,omitempty"`
+}
+
+// ListHandler returns JSON of all event subscriptions and their run statistics.
+// Event subscriptions are optionally filtered by the "event" and
+// "workflow" query parameters.
+func ListHandler(store storage.EventSubscriptionStorage, logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := ctxlog.Logger(r.Context(), logger)
+		if store == nil {
+			logger.Info(logkeys.Error, ErrMissingStore)
+			api.JSONError(w, ErrMissingStore, 0)
+			return
+		}
+
+		event := r.URL.Query().Get("event")
+		if event != "" && !workflow.EventFlagForString(event).Valid() {
+			logger.Info(logkeys.Message, "parameters", logkeys.Error, ErrInvalidEvent)
+			api.JSONError(w, ErrInvalidEvent, http.StatusBadRequest)
+			return
+		}
+		wfName := r.URL.Query().Get("workflow")
+
+		var es map[string]*storage.EventSubscription
+		var err error
+		if event != "" {
+			es, err = store.RetrieveEventSubscriptionsByEvent(r.Context(), workflow.EventFlagForString(event))
+		} else {
+			es, err = store.RetrieveAllEventSubscriptions(r.Context())
+		}
+		if err != nil {
+			logger.Info(logkeys.Message, "retrieve event subscriptions", logkeys.Error, err)
+			api.JSONError(w, err, 0)
+			return
+		}
+
+		names := make([]string, 0, len(es))
+		for name, sub := range es {
+			if wfName != "" && sub.Workflow != wfName {
+				delete(es, name)
+				continue
+			}
+			names = append(names, name)
+		}
+
+		ret := make(map[string]eventSubscriptionWithStats)
+		if len(names) > 0 {
+			stats, err := store.RetrieveEventSubscriptionStats(r.Context(), names)
+			if err != nil {
+				logger.Info(logkeys.Message, "retrieve event subscription stats", logkeys.Error, err)
+				api.JSONError(w, err, 0)
+				return
+			}
+			for _, name := range names {
+				ret[name] = eventSubscriptionWithStats{EventSubscription: es[name], Stats: stats[name]}
+			}
+		}
+
+		logger.Debug(
+			logkeys.Message, "retrieved event subscriptions",
+			logkeys.GenericCount, len(ret),
+		)
+		w.Header().Set("Content-Type", "application/json")
+		if err = json.NewEncoder(w).Encode(ret); err != nil {
+			logger.Info(logkeys.Message, "encoding json to body", logkeys.Error, err)
+			return
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/micromdm/nanocmd/engine/storage"
+	"github.com/micromdm/nanocmd/http/api"
+	"github.com/micromdm/nanocmd/logkeys"
+
+	"github.com/alexedwards/flow"
+	"github.com/micromdm/nanolib/log"
+	"github.com/micromdm/nanolib/log/ctxlog"
+)
+
+var ErrNoInstanceID = errors.New("missing instance ID parameter")
+
+// OutcomesHandler retrieves and returns JSON of the outcomes of a workflow instance.
+func OutcomesHandler(store storage.OutcomeStorage, logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := ctxlog.Logger(r.Context(), logger)
+		if store == nil {
+			logger.Info(logkeys.Error, ErrMissingStore)
+			api.JSONError(w, ErrMissingStore, 0)
+			return
+		}
+
+		instanceID := flow.Param(r.Context(), "id")
+		if instanceID == "" {
+			logger.Info(logkeys.Message, "parameters", logkeys.Error, ErrNoInstanceID)
+			api.JSONError(w, ErrNoInstanceID, http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With(logkeys.InstanceID, instanceID)
+		outcomes, err := store.RetrieveOutcomes(r.Context(), instanceID)
+		if err != nil {
+			logger.Info(logkeys.Message, "retrieve outcomes", logkeys.Error, err)
+			api.JSONError(w, err, 0)
+			return
+		}
+		if outcomes == nil {
+			// encode an empty JSON list rather than null
+			outcomes = []*storage.InstanceOutcome{}
+		}
+
+		logger.Debug(
+			logkeys.Message, "retrieved outcomes",
+			logkeys.GenericCount, len(outcomes),
+		)
+		w.Header().Set("Content-Type", "application/json")
+		if err = json.NewEncoder(w).Encode(outcomes); err != nil {
+			logger.Info(logkeys.Message, "encoding json to body", logkeys.Error, err)
+			return
+		}
+	}
+}
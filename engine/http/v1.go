@@ -4,16 +4,22 @@ import (
 	"net/http"
 
 	"github.com/micromdm/nanocmd/engine/storage"
+	"github.com/micromdm/nanocmd/http/api"
+
+	"github.com/alexedwards/flow"
 	"github.com/micromdm/nanolib/log"
 )
 
 type APIStorage interface {
 	storage.EventSubscriptionStorage
+	storage.OutcomeStorage
 }
 
 type APIEngine interface {
 	WorkflowNameChecker
 	WorkflowStarter
+	ApprovalRequester
+	Approver
 }
 
 // Mux can register HTTP handlers.
@@ -23,10 +29,17 @@ type Mux interface {
 	Handle(pattern string, handler http.Handler, methods ...string)
 }
 
+// workflowStartPermission returns the permission to start the workflow
+// named in the path of r.
+func workflowStartPermission(r *http.Request) string {
+	return api.PermWorkflowStart(flow.Param(r.Context(), "name"))
+}
+
 // HandleAPIv1 registers the various API handlers into mux.
 // API endpoint paths are prepended with prefix.
 // Authentication or any other layered handlers are not present.
 // They are assumed to be layered with mux, possibly at the Handle call.
+// Each handler requires a permission of the API principal.
 // If prefix is empty and these handlers are used in sub-paths then
 // handlers should have that sub-path stripped from the request.
 // The logger is adorned with a "handler" key of the endpoint name.
@@ -35,21 +48,103 @@ func HandleAPIv1(prefix string, mux Mux, logger log.Logger, e APIEngine, s APISt
 
 	mux.Handle(
 		prefix+"/workflow/:name/start",
-		StartWorkflowHandler(e, logger.With("handler", "start workflow")),
+		api.RequirePermissionFunc(
+			StartWorkflowHandler(e, e, logger.With("handler", "start workflow")),
+			workflowStartPermission,
+			logger,
+		),
+		"POST",
+	)
+
+	mux.Handle(
+		prefix+"/approvals",
+		api.RequirePermission(
+			ListApprovalsHandler(e, logger.With("handler", "list approvals")),
+			api.PermApprovalsRead,
+			logger,
+		),
+		"GET",
+	)
+
+	mux.Handle(
+		prefix+"/approvals/:id",
+		api.RequirePermission(
+			GetApprovalHandler(e, logger.With("handler", "get approval")),
+			api.PermApprovalsRead,
+			logger,
+		),
+		"GET",
+	)
+
+	mux.Handle(
+		prefix+"/approvals/:id/approve",
+		api.RequirePermission(
+			DecideApprovalHandler(e, true, logger.With("handler", "approve approval")),
+			api.PermApprovalsDecide,
+			logger,
+		),
 		"POST",
 	)
 
+	mux.Handle(
+		prefix+"/approvals/:id/reject",
+		api.RequirePermission(
+			DecideApprovalHandler(e, false, logger.With("handler", "reject approval")),
+			api.PermApprovalsDecide,
+			logger,
+		),
+		"POST",
+	)
+
+	mux.Handle(
+		prefix+"/instance/:id/outcomes",
+		api.RequirePermission(
+			OutcomesHandler(s, logger.With("handler", "instance outcomes")),
+			api.PermWorkflowsRead,
+			logger,
+		),
+		"GET",
+	)
+
 	// engine (event subscriptions)
 
 	mux.Handle(
 		prefix+"/event/:name",
-		GetHandler(s, logger.With("handler", "get event")),
+		api.RequirePermission(
+			GetHandler(s, logger.With("handler", "get event")),
+			api.PermSubscriptionsManage,
+			logger,
+		),
 		"GET",
 	)
 
 	mux.Handle(
 		prefix+"/event/:name",
-		PutHandler(s, e, logger.With("handler", "put event")),
+		api.RequirePermission(
+			PutHandler(s, e, logger.With("handler", "put event")),
+			api.PermSubscriptionsManage,
+			logger,
+		),
 		"PUT",
 	)
+
+	mux.Handle(
+		prefix+"/event/:name",
+		api.RequirePermission(
+			DeleteHandler(s, logger.With("handler", "delete event")),
+			api.PermSubscriptionsManage,
+			logger,
+		),
+		"DELETE",
+	)
+
+	mux.Handle(
+		prefix+"/events",
+		api.RequirePermission(
+			ListHandler(s, logger.With("handler", "list events")),
+			api.PermSubscriptionsManage,
+			logger,
+		),
+		"GET",
+	)
 }
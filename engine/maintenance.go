@@ -0,0 +1,82 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/micromdm/nanocmd/engine/storage"
+)
+
+// MaintenanceWindows finds the maintenance windows of enrollments.
+type MaintenanceWindows interface {
+	// NextWindows returns, for each of ids, t if t is inside a
+	// maintenance window of the enrollment or if it has no maintenance
+	// windows. Otherwise the start of the next maintenance window of
+	// the enrollment is returned.
+	NextWindows(ctx context.Context, ids []string, t time.Time) (map[string]time.Time, error)
+}
+
+// WithMaintenanceWindows configures the engine to delay the steps of
+// workflows configured for maintenance windows until the windows of
+// the step enrollments.
+func WithMaintenanceWindows(windows MaintenanceWindows) Option {
+	return func(e *Engine) {
+		e.windows = windows
+	}
+}
+
+// usesMaintenanceWindow returns true if the engine has maintenance
+// windows and the named workflow is configured for them.
+func (e *Engine) usesMaintenanceWindow(workflowName string) bool {
+	if e.windows == nil {
+		return false
+	}
+	w := e.Workflow(workflowName)
+	if w == nil {
+		return false
+	}
+	cfg := w.Config()
+	return cfg != nil && cfg.MaintenanceWindow
+}
+
+// windowSteps splits ss into steps for each distinct maintenance window
+// start of the step enrollments. The NotUntil of steps outside of a
+// window is set to the window start and the step timeout is delayed by
+// the same amount.
+func (e *Engine) windowSteps(ctx context.Context, ss *storage.StepEnqueuingWithConfig, now time.Time) ([]*storage.StepEnqueuingWithConfig, error) {
+	at := ss.NotUntil
+	if at.IsZero() {
+		at = now
+	}
+	nextWindows, err := e.windows.NextWindows(ctx, ss.IDs, at)
+	if err != nil {
+		return nil, fmt.Errorf("finding maintenance windows: %w", err)
+	}
+	var steps []*storage.StepEnqueuingWithConfig
+	byNotUntil := make(map[time.Time]*storage.StepEnqueuingWithConfig)
+	for _, id := range ss.IDs {
+		next, ok := nextWindows[id]
+		if !ok {
+			next = at
+		}
+		notUntil := ss.NotUntil
+		if next.After(at) {
+			notUntil = next
+		}
+		step, ok := byNotUntil[notUntil]
+		if !ok {
+			copied := *ss
+			step = &copied
+			step.IDs = nil
+			step.NotUntil = notUntil
+			if !ss.Timeout.IsZero() && next.After(at) {
+				step.Timeout = ss.Timeout.Add(next.Sub(at))
+			}
+			byNotUntil[notUntil] = step
+			steps = append(steps, step)
+		}
+		step.IDs = append(step.IDs, id)
+	}
+	return steps, nil
+}
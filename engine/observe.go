@@ -0,0 +1,52 @@
+package engine
+
+import (
+	"context"
+
+	"github.com/jessepeterson/mdmcommands"
+	"github.com/micromdm/nanocmd/workflow"
+)
+
+// StepObserver is notified of the outcome of completed and timed-out steps.
+type StepObserver interface {
+	// StepObserved is called once per enrollment id for each completed
+	// or timed-out step. errored is true if any of the step commands
+	// had an Error status.
+	StepObserved(ctx context.Context, instanceID, workflowName, id string, errored, timedOut bool)
+}
+
+// InstanceObserver is notified when workflow instances finish.
+type InstanceObserver interface {
+	// InstanceFinished is called once per enrollment id when the
+	// instance instanceID finished for it with outcome.
+	InstanceFinished(ctx context.Context, instanceID, workflowName, id string, outcome workflow.Outcome)
+}
+
+// WithStepObserver configures the engine to notify o of completed steps.
+// If o is also an InstanceObserver then it is notified of finished instances.
+func WithStepObserver(o StepObserver) Option {
+	return func(e *Engine) {
+		e.observer = o
+	}
+}
+
+// WithWorkerStepObserver configures the worker to notify o of timed-out steps.
+func WithWorkerStepObserver(o StepObserver) WorkerOption {
+	return func(w *Worker) {
+		w.observer = o
+	}
+}
+
+// stepErrored returns true if any command result of sr has an Error status.
+func stepErrored(sr *workflow.StepResult) bool {
+	for _, resp := range sr.CommandResults {
+		genResper, ok := resp.(mdmcommands.GenericResponser)
+		if !ok {
+			continue
+		}
+		if genResp := genResper.GetGenericResponse(); genResp != nil && genResp.Status == "Error" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/micromdm/nanocmd/engine/storage"
+	"github.com/micromdm/nanocmd/logkeys"
+	"github.com/micromdm/nanocmd/workflow"
+
+	"github.com/micromdm/nanolib/log"
+	"github.com/micromdm/nanolib/log/ctxlog"
+)
+
+// deferStart stores a pending workflow start of the named event
+// subscription es for the engine worker to start later.
+func (e *Engine) deferStart(ctx context.Context, logger log.Logger, name string, es *storage.EventSubscription, id string, ev *workflow.Event, mdmCtx *workflow.MDMContext) {
+	delay := time.Second * time.Duration(es.Delay)
+	if es.Jitter > 0 {
+		delay += time.Second * time.Duration(rand.Intn(es.Jitter+1))
+	}
+	ps := &storage.PendingStart{
+		EnrollmentID:      id,
+		EventSubscription: name,
+		WorkflowName:      es.Workflow,
+		Context:           []byte(es.Context),
+		FollowUps:         es.FollowUps,
+		StartAt:           time.Now().Add(delay),
+	}
+	if ev != nil {
+		ps.EventFlag = ev.EventFlag
+	}
+	if mdmCtx != nil {
+		ps.Params = mdmCtx.Params
+	}
+	logger = logger.With(
+		"name", name,
+		logkeys.WorkflowName, es.Workflow,
+	)
+	var err error
+	if ps.EventData, err = storagePendingEventFromEvent(ev); err != nil {
+		logger.Info(logkeys.Message, "converting event data", logkeys.Error, err)
+		return
+	}
+	if err = e.storage.StorePendingStart(ctx, ps); err != nil {
+		logger.Info(logkeys.Message, "storing pending start", logkeys.Error, err)
+		return
+	}
+	logger.Debug(
+		logkeys.Message, "deferred workflow start",
+		"start_at", ps.StartAt,
+	)
+}
+
+// StartPending starts the workflow of a pending event subscription start.
+func (e *Engine) StartPending(ctx context.Context, ps *storage.PendingStart) (string, error) {
+	if ps == nil {
+		return "", fmt.Errorf("nil pending start")
+	}
+	ev, err := workflowEventFromStoragePendingEvent(ps.EventFlag, ps.EventData)
+	if err != nil {
+		return "", fmt.Errorf("converting event data: %w", err)
+	}
+	var mdmCtx *workflow.MDMContext
+	if ps.Params != nil {
+		mdmCtx = &workflow.MDMContext{Params: ps.Params}
+	}
+	instanceID, err := e.StartWorkflowWithFollowUps(ctx, ps.WorkflowName, ps.Context, []string{ps.EnrollmentID}, ev, mdmCtx, ps.FollowUps)
+	e.recordEventFired(ctx, ctxlog.Logger(ctx, e.logger), ps.EventSubscription, err)
+	e.auditEventStart(ctx, ps.EventSubscription, ps.WorkflowName, ps.EnrollmentID, ps.EventFlag, instanceID, err)
+	return instanceID, err
+}
@@ -0,0 +1,154 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/micromdm/nanocmd/engine/storage"
+	"github.com/micromdm/nanocmd/logkeys"
+	"github.com/micromdm/nanocmd/workflow"
+
+	"github.com/micromdm/nanolib/log"
+	"github.com/micromdm/nanolib/log/ctxlog"
+)
+
+// queued returns true if cfg is configured for queued exclusivity.
+func queued(cfg *workflow.Config) bool {
+	return cfg != nil && cfg.Exclusivity == workflow.ExclusiveQueued
+}
+
+// StartReport reports details of a workflow start.
+type StartReport struct {
+	// QueuedIDs are the enrollment IDs whose starts were queued.
+	QueuedIDs []string
+}
+
+type startReportKey struct{}
+
+// NewStartReportContext returns a copy of ctx that reports the details
+// of workflow starts with ctx into r.
+func NewStartReportContext(ctx context.Context, r *StartReport) context.Context {
+	return context.WithValue(ctx, startReportKey{}, r)
+}
+
+// startReport returns the start report of ctx or nil if none.
+func startReport(ctx context.Context) *StartReport {
+	r, _ := ctx.Value(startReportKey{}).(*StartReport)
+	return r
+}
+
+// queueStarts queues workflow starts of w for each of ids.
+// Returns the number of ids that were queued.
+func (e *Engine) queueStarts(ctx context.Context, logger log.Logger, w workflow.Workflow, context []byte, ids []string, ev *workflow.Event, mdmCtx *workflow.MDMContext, followUps []storage.FollowUp) (int, error) {
+	max := e.maxQueued
+	if cfg := w.Config(); cfg != nil && cfg.MaxQueued > 0 {
+		max = cfg.MaxQueued
+	}
+	eventData, err := storagePendingEventFromEvent(ev)
+	if err != nil {
+		return 0, fmt.Errorf("converting event data: %w", err)
+	}
+	var full int
+	for _, id := range ids {
+		qs := &storage.QueuedStart{
+			EnrollmentID: id,
+			WorkflowName: w.Name(),
+			Context:      context,
+			EventData:    eventData,
+			FollowUps:    followUps,
+			QueuedAt:     time.Now(),
+		}
+		if ev != nil {
+			qs.EventFlag = ev.EventFlag
+		}
+		if mdmCtx != nil {
+			qs.Params = mdmCtx.Params
+		}
+		err = e.storage.StoreQueuedStart(ctx, qs, max)
+		if errors.Is(err, storage.ErrQueueFull) {
+			full++
+			continue
+		} else if err != nil {
+			return len(ids) - full, fmt.Errorf("storing queued start: %w", err)
+		}
+		if r := startReport(ctx); r != nil {
+			r.QueuedIDs = append(r.QueuedIDs, id)
+		}
+	}
+	if full > 0 {
+		return len(ids) - full, fmt.Errorf("%w on %d (of %d) ids", storage.ErrQueueFull, full, len(ids))
+	}
+	logger.Debug(
+		logkeys.Message, "queued workflow start",
+		logkeys.FirstEnrollmentID, ids[0],
+		logkeys.GenericCount, len(ids),
+	)
+	return len(ids), nil
+}
+
+// mayStartQueued returns true if a workflow configured with cfg ending
+// its instance may start queued starts. This is the case for queued
+// exclusivity and for workflows sharing an exclusivity domain.
+func mayStartQueued(cfg *workflow.Config) bool {
+	return queued(cfg) || (cfg != nil && cfg.ExclusivityDomain != "")
+}
+
+// StartQueued starts the next queued start for id of the named
+// workflow or of the workflows sharing its exclusivity domain.
+// The queued start is only started if id has no outstanding steps for
+// the workflows. An empty instance ID is returned if nothing is started.
+func (e *Engine) StartQueued(ctx context.Context, name, id string) (string, error) {
+	outstanding, err := e.retrieveOutstanding(ctx, name, []string{id})
+	if err != nil {
+		return "", fmt.Errorf("retrieving outstanding status: %w", err)
+	}
+	if len(outstanding) > 0 {
+		return "", nil
+	}
+	for _, wName := range e.exclusivityDomain(name) {
+		if w := e.Workflow(wName); w == nil || !queued(w.Config()) {
+			continue
+		}
+		qs, err := e.storage.RetrieveNextQueuedStart(ctx, id, wName)
+		if err != nil {
+			return "", fmt.Errorf("retrieving queued start: %w", err)
+		} else if qs == nil {
+			continue
+		}
+		ev, err := workflowEventFromStoragePendingEvent(qs.EventFlag, qs.EventData)
+		if err != nil {
+			return "", fmt.Errorf("converting event data: %w", err)
+		}
+		var mdmCtx *workflow.MDMContext
+		if qs.Params != nil {
+			mdmCtx = &workflow.MDMContext{Params: qs.Params}
+		}
+		// queued starts continue starts that were already permitted
+		// (and approved, if required) when they were queued
+		return e.StartWorkflowWithFollowUps(withApproved(ctx), qs.WorkflowName, qs.Context, []string{qs.EnrollmentID}, ev, mdmCtx, qs.FollowUps)
+	}
+	return "", nil
+}
+
+// startQueued starts the next queued start for id after an instance
+// of w has ended, if any could be queued. Errors are logged.
+func (e *Engine) startQueued(ctx context.Context, w workflow.Workflow, id string) {
+	if !mayStartQueued(w.Config()) {
+		return
+	}
+	logger := ctxlog.Logger(ctx, e.logger).With(
+		logkeys.WorkflowName, w.Name(),
+		logkeys.EnrollmentID, id,
+	)
+	instanceID, err := e.StartQueued(ctx, w.Name(), id)
+	if err != nil {
+		logger.Info(logkeys.Message, "starting queued workflow", logkeys.Error, err)
+	} else if instanceID != "" {
+		logger.Debug(
+			logkeys.Message, "started queued workflow",
+			logkeys.InstanceID, instanceID,
+		)
+	}
+}
@@ -1,6 +1,11 @@
 package engine
 
-import "github.com/micromdm/nanocmd/workflow"
+import (
+	"fmt"
+	"sort"
+
+	"github.com/micromdm/nanocmd/workflow"
+)
 
 func in(s []string, i string) int {
 	for j, v := range s {
@@ -22,11 +27,17 @@ func (e *Engine) registerAllResp(name string, allRespReqTypes []string) {
 }
 
 // RegisterWorkflow associates w with the engine by name.
+// Workflows with an exclusivity domain must not allow multiple
+// simultaneous instances as the domain would not be enforced.
 func (e *Engine) RegisterWorkflow(w workflow.Workflow) error {
+	cfg := w.Config()
+	if cfg != nil && cfg.ExclusivityDomain != "" && cfg.Exclusivity == workflow.MultipleSimultaneous {
+		return fmt.Errorf("%w: %s: exclusivity domain %s with multiple simultaneous instances", ErrInvalidExclusivity, w.Name(), cfg.ExclusivityDomain)
+	}
 	e.workflowsMu.Lock()
 	defer e.workflowsMu.Unlock()
 	e.workflows[w.Name()] = w
-	if cfg := w.Config(); cfg != nil {
+	if cfg != nil {
 		e.registerAllResp(w.Name(), cfg.AllCommandResponseRequestTypes)
 	}
 	e.logger.Debug("msg", "registered workflow", "name", w.Name())
@@ -99,3 +110,27 @@ func (e *Engine) eventWorkflows(ev workflow.EventFlag) (workflows []workflow.Wor
 	}
 	return
 }
+
+// exclusivityDomain returns the names of the registered workflows that
+// share the exclusivity domain of the named workflow sorted by name.
+// Only name is returned if the workflow has no exclusivity domain.
+func (e *Engine) exclusivityDomain(name string) []string {
+	e.workflowsMu.RLock()
+	defer e.workflowsMu.RUnlock()
+	w, ok := e.workflows[name]
+	if !ok {
+		return []string{name}
+	}
+	cfg := w.Config()
+	if cfg == nil || cfg.ExclusivityDomain == "" {
+		return []string{name}
+	}
+	var names []string
+	for wName, w := range e.workflows {
+		if wCfg := w.Config(); wCfg != nil && wCfg.ExclusivityDomain == cfg.ExclusivityDomain {
+			names = append(names, wName)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
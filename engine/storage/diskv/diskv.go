@@ -40,5 +40,35 @@ func New(path string) *Diskv {
 			Transform:    flatTransform,
 			CacheSizeMax: 1024 * 1024,
 		})),
+		kvdiskv.New(diskv.New(diskv.Options{
+			BasePath:     filepath.Join(path, "engine", "pending"),
+			Transform:    flatTransform,
+			CacheSizeMax: 1024 * 1024,
+		})),
+		kvdiskv.New(diskv.New(diskv.Options{
+			BasePath:     filepath.Join(path, "engine", "queue"),
+			Transform:    flatTransform,
+			CacheSizeMax: 1024 * 1024,
+		})),
+		kvdiskv.New(diskv.New(diskv.Options{
+			BasePath:     filepath.Join(path, "engine", "followup"),
+			Transform:    flatTransform,
+			CacheSizeMax: 1024 * 1024,
+		})),
+		kvdiskv.New(diskv.New(diskv.Options{
+			BasePath:     filepath.Join(path, "engine", "outcome"),
+			Transform:    flatTransform,
+			CacheSizeMax: 1024 * 1024,
+		})),
+		kvdiskv.New(diskv.New(diskv.Options{
+			BasePath:     filepath.Join(path, "engine", "timer"),
+			Transform:    flatTransform,
+			CacheSizeMax: 1024 * 1024,
+		})),
+		kvdiskv.New(diskv.New(diskv.Options{
+			BasePath:     filepath.Join(path, "engine", "approval"),
+			Transform:    flatTransform,
+			CacheSizeMax: 1024 * 1024,
+		})),
 	)}
 }
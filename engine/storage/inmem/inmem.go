@@ -20,5 +20,11 @@ func New() *InMem {
 		kvmap.New(),
 		uuid.NewUUID(),
 		kvmap.New(),
+		kvmap.New(),
+		kvmap.New(),
+		kvmap.New(),
+		kvmap.New(),
+		kvmap.New(),
+		kvmap.New(),
 	)}
 }
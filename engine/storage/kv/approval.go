@@ -0,0 +1,101 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/micromdm/nanocmd/engine/storage"
+)
+
+// getApprovalRequest retrieves the approval request id.
+// Must be called with the lock held.
+func (s *KV) getApprovalRequest(ctx context.Context, id string) (*storage.ApprovalRequest, error) {
+	if ok, err := s.approvalStore.Has(ctx, id); err != nil {
+		return nil, fmt.Errorf("checking approval request %s: %w", id, err)
+	} else if !ok {
+		return nil, fmt.Errorf("%w: %s", storage.ErrApprovalNotFound, id)
+	}
+	arBytes, err := s.approvalStore.Get(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("getting approval request %s: %w", id, err)
+	}
+	ar := new(storage.ApprovalRequest)
+	if err = json.Unmarshal(arBytes, ar); err != nil {
+		return nil, fmt.Errorf("unmarshal approval request %s: %w", id, err)
+	}
+	return ar, nil
+}
+
+// setApprovalRequest stores ar.
+// Must be called with the lock held.
+func (s *KV) setApprovalRequest(ctx context.Context, ar *storage.ApprovalRequest) error {
+	arBytes, err := json.Marshal(ar)
+	if err != nil {
+		return fmt.Errorf("marshal approval request: %w", err)
+	}
+	return s.approvalStore.Set(ctx, ar.ID, arBytes)
+}
+
+// StoreApprovalRequest implements the storage interface method.
+func (s *KV) StoreApprovalRequest(ctx context.Context, ar *storage.ApprovalRequest) error {
+	if err := ar.Validate(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.setApprovalRequest(ctx, ar)
+}
+
+// RetrieveApprovalRequest implements the storage interface method.
+func (s *KV) RetrieveApprovalRequest(ctx context.Context, id string) (*storage.ApprovalRequest, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.getApprovalRequest(ctx, id)
+}
+
+// RetrieveApprovalRequests implements the storage interface method.
+func (s *KV) RetrieveApprovalRequests(ctx context.Context, status storage.ApprovalStatus) ([]*storage.ApprovalRequest, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var ret []*storage.ApprovalRequest
+	for k := range s.approvalStore.Keys(ctx, nil) {
+		ar, err := s.getApprovalRequest(ctx, k)
+		if err != nil {
+			return nil, err
+		}
+		if status != "" && ar.Status != status {
+			continue
+		}
+		ret = append(ret, ar)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].RequestedAt.Before(ret[j].RequestedAt)
+	})
+	return ret, nil
+}
+
+// DecideApprovalRequest implements the storage interface method.
+func (s *KV) DecideApprovalRequest(ctx context.Context, ar *storage.ApprovalRequest) error {
+	if ar == nil {
+		return fmt.Errorf("%w: empty request", storage.ErrApprovalNotFound)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, err := s.getApprovalRequest(ctx, ar.ID)
+	if err != nil {
+		return err
+	}
+	if stored.Status != storage.ApprovalPending {
+		return fmt.Errorf("%w: %s", storage.ErrApprovalNotPending, stored.Status)
+	}
+	stored.Status = ar.Status
+	stored.DecidedBy = ar.DecidedBy
+	stored.DecidedAt = ar.DecidedAt
+	stored.Reason = ar.Reason
+	if err = stored.Validate(); err != nil {
+		return err
+	}
+	return s.setApprovalRequest(ctx, stored)
+}
@@ -2,10 +2,12 @@ package kv
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/micromdm/nanocmd/engine/storage"
 	"github.com/micromdm/nanocmd/workflow"
@@ -18,6 +20,15 @@ const (
 	keySfxEventWorkflow     = ".name"
 	keySfxEventContext      = ".ctx"
 	keySfxEventEventContext = ".evctx"
+	keySfxEventConditions   = ".cond"   // contains JSON
+	keySfxEventDelay        = ".delay"  // contains a strconv integer
+	keySfxEventJitter       = ".jitter" // contains a strconv integer
+
+	// event subscription statistics
+	keySfxEventCount     = ".count"    // contains a strconv integer
+	keySfxEventLastFired = ".fired"    // contains an RFC 3339 timestamp
+	keySfxEventErrCount  = ".errcount" // contains a strconv integer
+	keySfxEventLastError = ".lasterr"
 )
 
 type kvEventSubscription struct {
@@ -42,6 +53,26 @@ func (es *kvEventSubscription) set(ctx context.Context, b kv.Bucket, name string
 	if len(es.EventContext) > 0 {
 		esMap[name+keySfxEventEventContext] = []byte(es.EventContext)
 	}
+	var toDelete []string
+	for k, v := range map[string]int{keySfxEventDelay: es.Delay, keySfxEventJitter: es.Jitter} {
+		if v > 0 {
+			esMap[name+k] = []byte(strconv.Itoa(v))
+		} else {
+			toDelete = append(toDelete, name+k)
+		}
+	}
+	if err = kvDeleteKeysIfExists(ctx, b, toDelete); err != nil {
+		return fmt.Errorf("deleting delay: %w", err)
+	}
+	if es.Conditions != nil {
+		condBytes, err := json.Marshal(es.Conditions)
+		if err != nil {
+			return fmt.Errorf("marshal conditions: %w", err)
+		}
+		esMap[name+keySfxEventConditions] = condBytes
+	} else if err = kvDeleteKeysIfExists(ctx, b, []string{name + keySfxEventConditions}); err != nil {
+		return fmt.Errorf("deleting conditions: %w", err)
+	}
 	return kv.SetMap(ctx, b, esMap)
 }
 
@@ -83,6 +114,25 @@ func (es *kvEventSubscription) get(ctx context.Context, b kv.Bucket, name string
 			es.EventContext = string(evCtxBytes)
 		}
 	}
+	if ok, err := b.Has(ctx, name+keySfxEventConditions); err != nil {
+		return fmt.Errorf("checking event conditions: %w", err)
+	} else if ok {
+		condBytes, err := b.Get(ctx, name+keySfxEventConditions)
+		if err != nil {
+			return fmt.Errorf("getting event conditions: %w", err)
+		}
+		es.Conditions = new(storage.EventConditions)
+		if err = json.Unmarshal(condBytes, es.Conditions); err != nil {
+			return fmt.Errorf("unmarshal event conditions: %w", err)
+		}
+	}
+	for k, v := range map[string]*int{keySfxEventDelay: &es.Delay, keySfxEventJitter: &es.Jitter} {
+		i, err := kvGetInt(ctx, b, name+k)
+		if err != nil {
+			return fmt.Errorf("getting event %s: %w", k[1:], err)
+		}
+		*v = int(i)
+	}
 	return nil
 }
 
@@ -101,6 +151,8 @@ func (s *KV) RetrieveEventSubscriptions(ctx context.Context, names []string) (ma
 	return ret, nil
 }
 
+// kvFindEventSubNamesByEvent finds event subscription names for event f.
+// All event subscription names are returned if f is zero.
 func kvFindEventSubNamesByEvent(ctx context.Context, b kv.KeysPrefixTraversingBucket, f workflow.EventFlag) ([]string, error) {
 	var names []string
 
@@ -110,41 +162,51 @@ func kvFindEventSubNamesByEvent(ctx context.Context, b kv.KeysPrefixTraversingBu
 		if !strings.HasSuffix(k, keySfxEventFlag) {
 			continue
 		}
-		flagBytes, err := b.Get(ctx, k)
-		if err != nil {
-			return nil, err
-		}
-		eventFlag, err := strconv.Atoi(string(flagBytes))
-		if err != nil {
-			continue
-		}
-		if eventFlag != int(f) {
-			continue
+		if f != 0 {
+			flagBytes, err := b.Get(ctx, k)
+			if err != nil {
+				return nil, err
+			}
+			eventFlag, err := strconv.Atoi(string(flagBytes))
+			if err != nil {
+				continue
+			}
+			if eventFlag != int(f) {
+				continue
+			}
 		}
 		names = append(names, k[:len(k)-len(keySfxEventFlag)])
 	}
 	return names, nil
 }
 
-func (s *KV) RetrieveEventSubscriptionsByEvent(ctx context.Context, f workflow.EventFlag) ([]*storage.EventSubscription, error) {
-	if f < 1 {
-		return nil, errors.New("invalid event flag")
-	}
+func (s *KV) retrieveEventSubscriptionsByEvent(ctx context.Context, f workflow.EventFlag) (map[string]*storage.EventSubscription, error) {
 	names, err := kvFindEventSubNamesByEvent(ctx, s.eventStore, f)
 	if err != nil {
 		return nil, fmt.Errorf("finding event subscriptions: %w", err)
 	}
-	var ret []*storage.EventSubscription
+	ret := make(map[string]*storage.EventSubscription)
 	for _, name := range names {
 		es := new(kvEventSubscription)
 		if err = es.get(ctx, s.eventStore, name); err != nil {
 			return ret, fmt.Errorf("getting event subscription for %s: %w", name, err)
 		}
-		ret = append(ret, es.EventSubscription)
+		ret[name] = es.EventSubscription
 	}
 	return ret, nil
 }
 
+func (s *KV) RetrieveEventSubscriptionsByEvent(ctx context.Context, f workflow.EventFlag) (map[string]*storage.EventSubscription, error) {
+	if f < 1 {
+		return nil, errors.New("invalid event flag")
+	}
+	return s.retrieveEventSubscriptionsByEvent(ctx, f)
+}
+
+func (s *KV) RetrieveAllEventSubscriptions(ctx context.Context) (map[string]*storage.EventSubscription, error) {
+	return s.retrieveEventSubscriptionsByEvent(ctx, 0)
+}
+
 func (s *KV) StoreEventSubscription(ctx context.Context, name string, es *storage.EventSubscription) error {
 	wrapped := &kvEventSubscription{EventSubscription: es}
 	if err := wrapped.set(ctx, s.eventStore, name); err != nil {
@@ -154,10 +216,102 @@ func (s *KV) StoreEventSubscription(ctx context.Context, name string, es *storag
 }
 
 func (s *KV) DeleteEventSubscription(ctx context.Context, name string) error {
+	// lock so that the statistics of a concurrently firing event
+	// subscription are not recorded after they are deleted.
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return kvDeleteKeysIfExists(ctx, s.eventStore, []string{
 		name + keySfxEventFlag,
 		name + keySfxEventWorkflow,
 		name + keySfxEventContext,
 		name + keySfxEventEventContext,
+		name + keySfxEventConditions,
+		name + keySfxEventDelay,
+		name + keySfxEventJitter,
+		name + keySfxEventCount,
+		name + keySfxEventLastFired,
+		name + keySfxEventErrCount,
+		name + keySfxEventLastError,
 	})
 }
+
+// kvGetInt retrieves the integer at key k. Missing keys are zero.
+func kvGetInt(ctx context.Context, b kv.Bucket, k string) (int64, error) {
+	if ok, err := b.Has(ctx, k); err != nil || !ok {
+		return 0, err
+	}
+	intBytes, err := b.Get(ctx, k)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(string(intBytes), 10, 64)
+}
+
+func (s *KV) RecordEventSubscriptionFired(ctx context.Context, name string, firedAt time.Time, startErr error) error {
+	if name == "" {
+		return errors.New("empty name")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// don't recreate the statistics of a deleted event subscription
+	if ok, err := s.eventStore.Has(ctx, name+keySfxEventFlag); err != nil {
+		return fmt.Errorf("checking event subscription: %w", err)
+	} else if !ok {
+		return nil
+	}
+	count, err := kvGetInt(ctx, s.eventStore, name+keySfxEventCount)
+	if err != nil {
+		return fmt.Errorf("getting count: %w", err)
+	}
+	statsMap := map[string][]byte{
+		name + keySfxEventCount:     []byte(strconv.FormatInt(count+1, 10)),
+		name + keySfxEventLastFired: []byte(firedAt.Format(time.RFC3339Nano)),
+	}
+	if startErr != nil {
+		errCount, err := kvGetInt(ctx, s.eventStore, name+keySfxEventErrCount)
+		if err != nil {
+			return fmt.Errorf("getting error count: %w", err)
+		}
+		statsMap[name+keySfxEventErrCount] = []byte(strconv.FormatInt(errCount+1, 10))
+		statsMap[name+keySfxEventLastError] = []byte(startErr.Error())
+	}
+	return kv.SetMap(ctx, s.eventStore, statsMap)
+}
+
+func (s *KV) RetrieveEventSubscriptionStats(ctx context.Context, names []string) (map[string]*storage.EventSubscriptionStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ret := make(map[string]*storage.EventSubscriptionStats)
+	for _, name := range names {
+		if ok, err := s.eventStore.Has(ctx, name+keySfxEventLastFired); err != nil {
+			return ret, fmt.Errorf("checking last fired for %s: %w", name, err)
+		} else if !ok {
+			continue
+		}
+		stats := new(storage.EventSubscriptionStats)
+		firedBytes, err := s.eventStore.Get(ctx, name+keySfxEventLastFired)
+		if err != nil {
+			return ret, fmt.Errorf("getting last fired for %s: %w", name, err)
+		}
+		if stats.LastFired, err = time.Parse(time.RFC3339Nano, string(firedBytes)); err != nil {
+			return ret, fmt.Errorf("parsing last fired for %s: %w", name, err)
+		}
+		if stats.Count, err = kvGetInt(ctx, s.eventStore, name+keySfxEventCount); err != nil {
+			return ret, fmt.Errorf("getting count for %s: %w", name, err)
+		}
+		if stats.ErrorCount, err = kvGetInt(ctx, s.eventStore, name+keySfxEventErrCount); err != nil {
+			return ret, fmt.Errorf("getting error count for %s: %w", name, err)
+		}
+		if ok, err := s.eventStore.Has(ctx, name+keySfxEventLastError); err != nil {
+			return ret, fmt.Errorf("checking last error for %s: %w", name, err)
+		} else if ok {
+			errBytes, err := s.eventStore.Get(ctx, name+keySfxEventLastError)
+			if err != nil {
+				return ret, fmt.Errorf("getting last error for %s: %w", name, err)
+			}
+			stats.LastError = string(errBytes)
+		}
+		ret[name] = stats
+	}
+	return ret, nil
+}
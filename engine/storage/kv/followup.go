@@ -0,0 +1,64 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/micromdm/nanocmd/engine/storage"
+
+	"github.com/micromdm/nanolib/storage/kv"
+)
+
+func followUpKey(id, instanceID string) string {
+	return id + "." + instanceID
+}
+
+// StoreFollowUps implements the storage interface method.
+func (s *KV) StoreFollowUps(ctx context.Context, instanceID string, ids []string, followUps []storage.FollowUp) error {
+	if instanceID == "" {
+		return storage.ErrMissingInstanceID
+	}
+	fuBytes, err := json.Marshal(followUps)
+	if err != nil {
+		return fmt.Errorf("marshal follow-ups: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		if err = s.followUpStore.Set(ctx, followUpKey(id, instanceID), fuBytes); err != nil {
+			return fmt.Errorf("setting follow-ups for %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// RetrieveFollowUps implements the storage interface method.
+func (s *KV) RetrieveFollowUps(ctx context.Context, instanceID, id string) ([]storage.FollowUp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := followUpKey(id, instanceID)
+	fuBytes, err := s.followUpStore.Get(ctx, key)
+	if errors.Is(err, kv.ErrKeyNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("getting follow-ups: %w", err)
+	}
+	var followUps []storage.FollowUp
+	if err = json.Unmarshal(fuBytes, &followUps); err != nil {
+		return nil, fmt.Errorf("unmarshal follow-ups: %w", err)
+	}
+	return followUps, s.followUpStore.Delete(ctx, key)
+}
+
+// CancelFollowUps implements the storage interface method.
+func (s *KV) CancelFollowUps(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var toDelete []string
+	for k := range s.followUpStore.KeysPrefix(ctx, id+".", nil) {
+		toDelete = append(toDelete, k)
+	}
+	return kv.DeleteSlice(ctx, s.followUpStore, toDelete)
+}
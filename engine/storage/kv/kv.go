@@ -23,16 +23,31 @@ type KV struct {
 	eventStore  kv.KeysPrefixTraversingBucket
 	ider        uuid.IDer
 	statusStore kv.KeysPrefixTraversingBucket
+
+	pendingStore kv.KeysPrefixTraversingBucket
+	queueStore   kv.KeysPrefixTraversingBucket
+
+	followUpStore kv.KeysPrefixTraversingBucket
+	outcomeStore  kv.KeysPrefixTraversingBucket
+	timerStore    kv.KeysPrefixTraversingBucket
+	approvalStore kv.KeysPrefixTraversingBucket
 }
 
 // New creates a new key-value workflow engine storage backend.
-func New(stepStore kv.KeysPrefixTraversingBucket, idCmdStore kv.KeysPrefixTraversingBucket, eventStore kv.KeysPrefixTraversingBucket, ider uuid.IDer, statusStore kv.KeysPrefixTraversingBucket) *KV {
+func New(stepStore kv.KeysPrefixTraversingBucket, idCmdStore kv.KeysPrefixTraversingBucket, eventStore kv.KeysPrefixTraversingBucket, ider uuid.IDer, statusStore kv.KeysPrefixTraversingBucket, pendingStore kv.KeysPrefixTraversingBucket, queueStore kv.KeysPrefixTraversingBucket, followUpStore kv.KeysPrefixTraversingBucket, outcomeStore kv.KeysPrefixTraversingBucket, timerStore kv.KeysPrefixTraversingBucket, approvalStore kv.KeysPrefixTraversingBucket) *KV {
 	return &KV{
-		stepStore:   stepStore,
-		idCmdStore:  idCmdStore,
-		eventStore:  eventStore,
-		ider:        ider,
-		statusStore: statusStore,
+		stepStore:    stepStore,
+		idCmdStore:   idCmdStore,
+		eventStore:   eventStore,
+		ider:         ider,
+		statusStore:  statusStore,
+		pendingStore: pendingStore,
+		queueStore:   queueStore,
+
+		followUpStore: followUpStore,
+		outcomeStore:  outcomeStore,
+		timerStore:    timerStore,
+		approvalStore: approvalStore,
 	}
 }
 
@@ -231,28 +246,32 @@ func (s *KV) RetrieveOutstandingWorkflowStatus(ctx context.Context, workflowName
 		if err != nil {
 			return nil, fmt.Errorf("getting step commands for %s: %w", stepID, err)
 		}
-	foundInStep:
 		for _, id := range ids {
 			if _, ok := idAcc[id]; ok {
 				continue
 			}
-			for _, cmdUUID := range cmdUUIDs {
-				if ok, err := kvIDCmdExists(ctx, s.idCmdStore, id, cmdUUID); err != nil {
-					return nil, fmt.Errorf("checking command exists for %s: %w", cmdUUID, err)
-				} else if !ok {
-					// command does not exist for this id, perhaps already completed (and deleted?)
-					continue
-				}
-				if ok, err := kvIDCmdIsComplete(ctx, s.idCmdStore, id, cmdUUID); err != nil {
-					return nil, fmt.Errorf("getting command complete status for %s: %w", cmdUUID, err)
-				} else if !ok {
-					idAcc[id] = struct{}{}
-					continue foundInStep
-				}
+			if ok, err := s.incompleteCmds(ctx, id, cmdUUIDs); err != nil {
+				return nil, err
+			} else if ok {
+				idAcc[id] = struct{}{}
 			}
 		}
 	}
 
+	// timer steps that have not yet fired are outstanding, too
+	for _, id := range ids {
+		if _, ok := idAcc[id]; ok {
+			continue
+		}
+		keys, err := s.timerStepKeys(ctx, id, workflowName)
+		if err != nil {
+			return nil, fmt.Errorf("finding timer steps: %w", err)
+		}
+		if len(keys) > 0 {
+			idAcc[id] = struct{}{}
+		}
+	}
+
 	outstandingIDs := make([]string, 0, len(idAcc))
 	for id := range idAcc {
 		outstandingIDs = append(outstandingIDs, id)
@@ -261,6 +280,67 @@ func (s *KV) RetrieveOutstandingWorkflowStatus(ctx context.Context, workflowName
 	return outstandingIDs, nil
 }
 
+// incompleteCmds returns true if any of cmdUUIDs are not yet completed for id.
+// Must be called with the lock held.
+func (s *KV) incompleteCmds(ctx context.Context, id string, cmdUUIDs []string) (bool, error) {
+	for _, cmdUUID := range cmdUUIDs {
+		if ok, err := kvIDCmdExists(ctx, s.idCmdStore, id, cmdUUID); err != nil {
+			return false, fmt.Errorf("checking command exists for %s: %w", cmdUUID, err)
+		} else if !ok {
+			// command does not exist for this id, perhaps already completed (and deleted?)
+			continue
+		}
+		if ok, err := kvIDCmdIsComplete(ctx, s.idCmdStore, id, cmdUUID); err != nil {
+			return false, fmt.Errorf("getting command complete status for %s: %w", cmdUUID, err)
+		} else if !ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// RetrieveOutstandingInstanceStatus implements the storage interface method.
+func (s *KV) RetrieveOutstandingInstanceStatus(ctx context.Context, workflowName, instanceID, id string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	stepIDs, err := kvFindWorkflowStepsWithIDs(ctx, s.stepStore, workflowName, []string{id})
+	if err != nil {
+		return false, fmt.Errorf("finding workflow steps: %w", err)
+	}
+	for _, stepID := range stepIDs {
+		stepInstanceID, err := kvGetStepInstanceID(ctx, s.stepStore, stepID)
+		if err != nil {
+			return false, fmt.Errorf("getting step instance ID for %s: %w", stepID, err)
+		}
+		if stepInstanceID != instanceID {
+			continue
+		}
+		cmdUUIDs, err := kvGetStepCmds(ctx, s.stepStore, stepID)
+		if err != nil {
+			return false, fmt.Errorf("getting step commands for %s: %w", stepID, err)
+		}
+		if ok, err := s.incompleteCmds(ctx, id, cmdUUIDs); err != nil || ok {
+			return ok, err
+		}
+	}
+
+	// timer steps that have not yet fired are outstanding, too
+	keys, err := s.timerStepKeys(ctx, id, workflowName)
+	if err != nil {
+		return false, fmt.Errorf("finding timer steps: %w", err)
+	}
+	for _, k := range keys {
+		ts, err := s.getTimerStep(ctx, k)
+		if err != nil {
+			return false, err
+		}
+		if ts.InstanceID == instanceID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // CancelSteps implements the storage interface method.
 func (s *KV) CancelSteps(ctx context.Context, id, workflowName string) error {
 	s.mu.Lock()
@@ -283,7 +363,11 @@ func (s *KV) CancelSteps(ctx context.Context, id, workflowName string) error {
 			return fmt.Errorf("deleting step for %s: %w", stepID, err)
 		}
 	}
-	return nil
+	timerKeys, err := s.timerStepKeys(ctx, id, workflowName)
+	if err != nil {
+		return fmt.Errorf("finding timer steps: %w", err)
+	}
+	return kv.DeleteSlice(ctx, s.timerStore, timerKeys)
 }
 
 func workflowStatusKey(id, workflowName string) string {
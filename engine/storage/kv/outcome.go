@@ -0,0 +1,58 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/micromdm/nanocmd/engine/storage"
+)
+
+func outcomeKey(instanceID, id string) string {
+	return instanceID + "." + id
+}
+
+// StoreOutcome implements the storage interface method.
+func (s *KV) StoreOutcome(ctx context.Context, o *storage.InstanceOutcome) error {
+	if o == nil || o.EnrollmentID == "" {
+		return errors.New("invalid outcome")
+	}
+	if o.InstanceID == "" {
+		return storage.ErrMissingInstanceID
+	}
+	oBytes, err := json.Marshal(o)
+	if err != nil {
+		return fmt.Errorf("marshal outcome: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.outcomeStore.Set(ctx, outcomeKey(o.InstanceID, o.EnrollmentID), oBytes)
+}
+
+// RetrieveOutcomes implements the storage interface method.
+func (s *KV) RetrieveOutcomes(ctx context.Context, instanceID string) ([]*storage.InstanceOutcome, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var outcomes []*storage.InstanceOutcome
+	for k := range s.outcomeStore.KeysPrefix(ctx, instanceID+".", nil) {
+		oBytes, err := s.outcomeStore.Get(ctx, k)
+		if err != nil {
+			return nil, fmt.Errorf("getting outcome %s: %w", k, err)
+		}
+		o := new(storage.InstanceOutcome)
+		if err = json.Unmarshal(oBytes, o); err != nil {
+			return nil, fmt.Errorf("unmarshal outcome %s: %w", k, err)
+		}
+		if o.InstanceID != instanceID {
+			// key prefix collision with another instance
+			continue
+		}
+		outcomes = append(outcomes, o)
+	}
+	sort.Slice(outcomes, func(i, j int) bool {
+		return outcomes[i].EnrollmentID < outcomes[j].EnrollmentID
+	})
+	return outcomes, nil
+}
@@ -0,0 +1,75 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/micromdm/nanocmd/engine/storage"
+
+	"github.com/micromdm/nanolib/storage/kv"
+)
+
+func pendingStartKey(id, name string) string {
+	return id + "." + name
+}
+
+// StorePendingStart implements the storage interface method.
+func (s *KV) StorePendingStart(ctx context.Context, ps *storage.PendingStart) error {
+	if ps == nil || ps.EnrollmentID == "" || ps.EventSubscription == "" {
+		return errors.New("invalid pending start")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := pendingStartKey(ps.EnrollmentID, ps.EventSubscription)
+	if ok, err := s.pendingStore.Has(ctx, key); err != nil {
+		return fmt.Errorf("checking pending start: %w", err)
+	} else if ok {
+		return nil
+	}
+	psBytes, err := json.Marshal(ps)
+	if err != nil {
+		return fmt.Errorf("marshal pending start: %w", err)
+	}
+	return s.pendingStore.Set(ctx, key, psBytes)
+}
+
+// CancelPendingStarts implements the storage interface method.
+func (s *KV) CancelPendingStarts(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var toDelete []string
+	for k := range s.pendingStore.Keys(ctx, nil) {
+		if strings.HasPrefix(k, id+".") {
+			toDelete = append(toDelete, k)
+		}
+	}
+	return kv.DeleteSlice(ctx, s.pendingStore, toDelete)
+}
+
+// RetrieveDuePendingStarts implements the storage interface method.
+func (s *KV) RetrieveDuePendingStarts(ctx context.Context, now time.Time) ([]*storage.PendingStart, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ret []*storage.PendingStart
+	var toDelete []string
+	for k := range s.pendingStore.Keys(ctx, nil) {
+		psBytes, err := s.pendingStore.Get(ctx, k)
+		if err != nil {
+			return nil, fmt.Errorf("getting pending start %s: %w", k, err)
+		}
+		ps := new(storage.PendingStart)
+		if err = json.Unmarshal(psBytes, ps); err != nil {
+			return nil, fmt.Errorf("unmarshal pending start %s: %w", k, err)
+		}
+		if ps.StartAt.After(now) {
+			continue
+		}
+		ret = append(ret, ps)
+		toDelete = append(toDelete, k)
+	}
+	return ret, kv.DeleteSlice(ctx, s.pendingStore, toDelete)
+}
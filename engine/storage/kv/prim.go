@@ -124,6 +124,15 @@ func kvGetStepIDs(ctx context.Context, b kv.Bucket, stepID string) ([]string, er
 	return unmarshalStrings(stepEnrIDs), err
 }
 
+// kvGetStepInstanceID returns the workflow instance ID of a step.
+func kvGetStepInstanceID(ctx context.Context, b kv.Bucket, stepID string) (string, error) {
+	metaBytes, err := b.Get(ctx, stepID+keySfxStepMeta)
+	if err != nil {
+		return "", err
+	}
+	return unmarshalStrings(metaBytes)[0], nil
+}
+
 // kvGetStepResult creates and populates a step result from a stored step.
 func kvGetStepResult(ctx context.Context, b kv.Bucket, stepID string) (*storage.StepResult, error) {
 	step := new(storage.StepResult)
@@ -0,0 +1,99 @@
+package kv
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/micromdm/nanocmd/engine/storage"
+
+	"github.com/micromdm/nanolib/storage/kv"
+)
+
+// queuedStartPrefix is the key prefix of the queued starts of workflowName for id.
+func queuedStartPrefix(id, workflowName string) string {
+	return id + "." + workflowName + "."
+}
+
+// keyedQueuedStart is a queued start and its storage key.
+type keyedQueuedStart struct {
+	key string
+	*storage.QueuedStart
+}
+
+// queuedStarts retrieves the queued starts of workflowName for id sorted oldest first.
+// Must be called with the lock held.
+func (s *KV) queuedStarts(ctx context.Context, id, workflowName string) ([]keyedQueuedStart, error) {
+	var starts []keyedQueuedStart
+	for k := range s.queueStore.KeysPrefix(ctx, queuedStartPrefix(id, workflowName), nil) {
+		qsBytes, err := s.queueStore.Get(ctx, k)
+		if err != nil {
+			return nil, fmt.Errorf("getting queued start %s: %w", k, err)
+		}
+		qs := new(storage.QueuedStart)
+		if err = json.Unmarshal(qsBytes, qs); err != nil {
+			return nil, fmt.Errorf("unmarshal queued start %s: %w", k, err)
+		}
+		if qs.EnrollmentID != id || qs.WorkflowName != workflowName {
+			// key prefix collision with another id or workflow
+			continue
+		}
+		starts = append(starts, keyedQueuedStart{key: k, QueuedStart: qs})
+	}
+	sort.SliceStable(starts, func(i, j int) bool {
+		return starts[i].QueuedAt.Before(starts[j].QueuedAt)
+	})
+	return starts, nil
+}
+
+// StoreQueuedStart implements the storage interface method.
+func (s *KV) StoreQueuedStart(ctx context.Context, qs *storage.QueuedStart, max int) error {
+	if qs == nil || qs.EnrollmentID == "" || qs.WorkflowName == "" {
+		return errors.New("invalid queued start")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	starts, err := s.queuedStarts(ctx, qs.EnrollmentID, qs.WorkflowName)
+	if err != nil {
+		return err
+	}
+	for _, queued := range starts {
+		if bytes.Equal(queued.Context, qs.Context) {
+			// coalesce into the already queued start
+			return nil
+		}
+	}
+	if len(starts) >= max {
+		return storage.ErrQueueFull
+	}
+	qsBytes, err := json.Marshal(qs)
+	if err != nil {
+		return fmt.Errorf("marshal queued start: %w", err)
+	}
+	return s.queueStore.Set(ctx, queuedStartPrefix(qs.EnrollmentID, qs.WorkflowName)+s.ider.ID(), qsBytes)
+}
+
+// RetrieveNextQueuedStart implements the storage interface method.
+func (s *KV) RetrieveNextQueuedStart(ctx context.Context, id, workflowName string) (*storage.QueuedStart, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	starts, err := s.queuedStarts(ctx, id, workflowName)
+	if err != nil || len(starts) < 1 {
+		return nil, err
+	}
+	return starts[0].QueuedStart, s.queueStore.Delete(ctx, starts[0].key)
+}
+
+// CancelQueuedStarts implements the storage interface method.
+func (s *KV) CancelQueuedStarts(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var toDelete []string
+	for k := range s.queueStore.KeysPrefix(ctx, id+".", nil) {
+		toDelete = append(toDelete, k)
+	}
+	return kv.DeleteSlice(ctx, s.queueStore, toDelete)
+}
@@ -0,0 +1,108 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/micromdm/nanocmd/engine/storage"
+	"github.com/micromdm/nanocmd/workflow"
+
+	"github.com/micromdm/nanolib/storage/kv"
+)
+
+// timerStepPrefix is the key prefix of the timer steps of workflowName for id.
+// All timer steps for id are returned if workflowName is empty.
+func timerStepPrefix(id, workflowName string) string {
+	if workflowName == "" {
+		return id + "."
+	}
+	return id + "." + workflowName + "."
+}
+
+// getTimerStep retrieves the timer step at key k.
+func (s *KV) getTimerStep(ctx context.Context, k string) (*storage.TimerStep, error) {
+	tsBytes, err := s.timerStore.Get(ctx, k)
+	if err != nil {
+		return nil, fmt.Errorf("getting timer step %s: %w", k, err)
+	}
+	ts := new(storage.TimerStep)
+	if err = json.Unmarshal(tsBytes, ts); err != nil {
+		return nil, fmt.Errorf("unmarshal timer step %s: %w", k, err)
+	}
+	return ts, nil
+}
+
+// timerStepKeys returns the keys of the timer steps of workflowName for id.
+// Must be called with the lock held.
+func (s *KV) timerStepKeys(ctx context.Context, id, workflowName string) ([]string, error) {
+	var keys []string
+	for k := range s.timerStore.KeysPrefix(ctx, timerStepPrefix(id, workflowName), nil) {
+		ts, err := s.getTimerStep(ctx, k)
+		if err != nil {
+			return nil, err
+		}
+		if ts.EnrollmentID != id || (workflowName != "" && ts.WorkflowName != workflowName) {
+			// key prefix collision with another id or workflow
+			continue
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// StoreTimerStep implements the storage interface method.
+func (s *KV) StoreTimerStep(ctx context.Context, ts *storage.TimerStep) error {
+	if err := ts.Validate(); err != nil {
+		return err
+	}
+	tsBytes, err := json.Marshal(ts)
+	if err != nil {
+		return fmt.Errorf("marshal timer step: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.timerStore.Set(ctx, timerStepPrefix(ts.EnrollmentID, ts.WorkflowName)+s.ider.ID(), tsBytes)
+}
+
+// RetrieveDueTimerSteps implements the storage interface method.
+func (s *KV) RetrieveDueTimerSteps(ctx context.Context, now time.Time) ([]*storage.TimerStep, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ret []*storage.TimerStep
+	var toDelete []string
+	for k := range s.timerStore.Keys(ctx, nil) {
+		ts, err := s.getTimerStep(ctx, k)
+		if err != nil {
+			return nil, err
+		}
+		if ts.FireAt.IsZero() || ts.FireAt.After(now) {
+			// event steps without a timeout never fire
+			continue
+		}
+		ret = append(ret, ts)
+		toDelete = append(toDelete, k)
+	}
+	return ret, kv.DeleteSlice(ctx, s.timerStore, toDelete)
+}
+
+// RetrieveEventSteps implements the storage interface method.
+func (s *KV) RetrieveEventSteps(ctx context.Context, id string, f workflow.EventFlag) ([]*storage.TimerStep, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ret []*storage.TimerStep
+	var toDelete []string
+	for k := range s.timerStore.KeysPrefix(ctx, timerStepPrefix(id, ""), nil) {
+		ts, err := s.getTimerStep(ctx, k)
+		if err != nil {
+			return nil, err
+		}
+		if ts.EnrollmentID != id || ts.EventFlag != f {
+			continue
+		}
+		ret = append(ret, ts)
+		toDelete = append(toDelete, k)
+	}
+	return ret, kv.DeleteSlice(ctx, s.timerStore, toDelete)
+}
@@ -0,0 +1,159 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/micromdm/nanocmd/engine/storage"
+	"github.com/micromdm/nanocmd/engine/storage/mysql/sqlc"
+)
+
+// approvalRow is a row of approval request columns.
+// The rows of the other approval request queries convert to it.
+type approvalRow = sqlc.GetApprovalRow
+
+// newApprovalRequest creates an approval request from a.
+func newApprovalRequest(a approvalRow) (*storage.ApprovalRequest, error) {
+	ar := &storage.ApprovalRequest{
+		ID:           a.ID,
+		WorkflowName: a.WorkflowName,
+		Context:      a.Context.String,
+		Requester:    a.Requester,
+		RequestedAt:  time.Unix(a.RequestedAtUnix, 0),
+		ExpiresAt:    time.Unix(a.ExpiresAtUnix, 0),
+		Status:       storage.ApprovalStatus(a.Status),
+		DecidedBy:    a.DecidedBy.String,
+		Reason:       a.Reason.String,
+		InstanceID:   a.InstanceID.String,
+		StartError:   a.StartError.String,
+	}
+	if err := json.Unmarshal([]byte(a.Ids), &ar.IDs); err != nil {
+		return nil, fmt.Errorf("unmarshal ids: %w", err)
+	}
+	var err error
+	if ar.FollowUps, err = followUpsFromSQLNull(a.FollowUps); err != nil {
+		return nil, err
+	}
+	if a.DecidedAtUnix.Valid {
+		decidedAt := time.Unix(a.DecidedAtUnix.Int64, 0)
+		ar.DecidedAt = &decidedAt
+	}
+	return ar, nil
+}
+
+// sqlNullUnix converts t to a nullable unix time.
+func sqlNullUnix(t *time.Time) sql.NullInt64 {
+	if t == nil || t.IsZero() {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: t.Unix(), Valid: true}
+}
+
+// StoreApprovalRequest stores an approval request.
+// See the storage interface type for further docs.
+func (s *MySQLStorage) StoreApprovalRequest(ctx context.Context, ar *storage.ApprovalRequest) error {
+	if err := ar.Validate(); err != nil {
+		return err
+	}
+	idsBytes, err := json.Marshal(ar.IDs)
+	if err != nil {
+		return fmt.Errorf("marshal ids: %w", err)
+	}
+	followUps, err := sqlNullFollowUps(ar.FollowUps)
+	if err != nil {
+		return err
+	}
+	return s.q.UpsertApproval(ctx, sqlc.UpsertApprovalParams{
+		ID:              ar.ID,
+		WorkflowName:    ar.WorkflowName,
+		Ids:             string(idsBytes),
+		Context:         sqlNullString(ar.Context),
+		FollowUps:       followUps,
+		Requester:       ar.Requester,
+		RequestedAtUnix: ar.RequestedAt.Unix(),
+		ExpiresAtUnix:   ar.ExpiresAt.Unix(),
+		Status:          string(ar.Status),
+		DecidedBy:       sqlNullString(ar.DecidedBy),
+		DecidedAtUnix:   sqlNullUnix(ar.DecidedAt),
+		Reason:          sqlNullString(ar.Reason),
+		InstanceID:      sqlNullString(ar.InstanceID),
+		StartError:      sqlNullString(ar.StartError),
+	})
+}
+
+// RetrieveApprovalRequest retrieves an approval request.
+// See the storage interface type for further docs.
+func (s *MySQLStorage) RetrieveApprovalRequest(ctx context.Context, id string) (*storage.ApprovalRequest, error) {
+	row, err := s.q.GetApproval(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", storage.ErrApprovalNotFound, id)
+	} else if err != nil {
+		return nil, fmt.Errorf("get approval request: %w", err)
+	}
+	return newApprovalRequest(row)
+}
+
+// RetrieveApprovalRequests retrieves approval requests by status.
+// See the storage interface type for further docs.
+func (s *MySQLStorage) RetrieveApprovalRequests(ctx context.Context, status storage.ApprovalStatus) ([]*storage.ApprovalRequest, error) {
+	var rows []approvalRow
+	if status == "" {
+		approvals, err := s.q.GetApprovals(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("get approval requests: %w", err)
+		}
+		for _, a := range approvals {
+			rows = append(rows, approvalRow(a))
+		}
+	} else {
+		approvals, err := s.q.GetApprovalsByStatus(ctx, string(status))
+		if err != nil {
+			return nil, fmt.Errorf("get approval requests by status: %w", err)
+		}
+		for _, a := range approvals {
+			rows = append(rows, approvalRow(a))
+		}
+	}
+	var ret []*storage.ApprovalRequest
+	for _, row := range rows {
+		ar, err := newApprovalRequest(row)
+		if err != nil {
+			return ret, fmt.Errorf("approval request %s: %w", row.ID, err)
+		}
+		ret = append(ret, ar)
+	}
+	return ret, nil
+}
+
+// DecideApprovalRequest records the decision of a pending approval request.
+// See the storage interface type for further docs.
+func (s *MySQLStorage) DecideApprovalRequest(ctx context.Context, ar *storage.ApprovalRequest) error {
+	if ar == nil {
+		return fmt.Errorf("%w: empty request", storage.ErrApprovalNotFound)
+	}
+	if !ar.Status.Valid() {
+		return fmt.Errorf("invalid approval status: %s", ar.Status)
+	}
+	return tx(ctx, s.db, s.q, func(ctx context.Context, _ *sql.Tx, qtx *sqlc.Queries) error {
+		status, err := qtx.GetApprovalStatusAndLock(ctx, ar.ID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: %s", storage.ErrApprovalNotFound, ar.ID)
+		} else if err != nil {
+			return fmt.Errorf("get approval status: %w", err)
+		}
+		if storage.ApprovalStatus(status) != storage.ApprovalPending {
+			return fmt.Errorf("%w: %s", storage.ErrApprovalNotPending, status)
+		}
+		return qtx.UpdateApprovalDecision(ctx, sqlc.UpdateApprovalDecisionParams{
+			Status:        string(ar.Status),
+			DecidedBy:     sqlNullString(ar.DecidedBy),
+			DecidedAtUnix: sqlNullUnix(ar.DecidedAt),
+			Reason:        sqlNullString(ar.Reason),
+			ID:            ar.ID,
+		})
+	})
+}
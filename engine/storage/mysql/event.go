@@ -2,12 +2,39 @@ package mysql
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/micromdm/nanocmd/engine/storage"
+	"github.com/micromdm/nanocmd/engine/storage/mysql/sqlc"
 	"github.com/micromdm/nanocmd/workflow"
 )
 
+// newEventSubscription creates an event subscription from database columns.
+func newEventSubscription(eventType, workflowName string, wfContext, eventContext, conditions sql.NullString, delay, jitter int32, followUps sql.NullString) (*storage.EventSubscription, error) {
+	es := &storage.EventSubscription{
+		Event:        eventType,
+		Workflow:     workflowName,
+		Context:      wfContext.String,
+		EventContext: eventContext.String,
+		Delay:        int(delay),
+		Jitter:       int(jitter),
+	}
+	if conditions.Valid {
+		es.Conditions = new(storage.EventConditions)
+		if err := json.Unmarshal([]byte(conditions.String), es.Conditions); err != nil {
+			return nil, fmt.Errorf("unmarshal conditions: %w", err)
+		}
+	}
+	var err error
+	if es.FollowUps, err = followUpsFromSQLNull(followUps); err != nil {
+		return nil, err
+	}
+	return es, nil
+}
+
 // RetrieveEventSubscriptions retrieves event subscriptions by names.
 // See the storage interface type for further docs.
 func (s *MySQLStorage) RetrieveEventSubscriptions(ctx context.Context, names []string) (map[string]*storage.EventSubscription, error) {
@@ -17,11 +44,17 @@ func (s *MySQLStorage) RetrieveEventSubscriptions(ctx context.Context, names []s
 	}
 	retEvents := make(map[string]*storage.EventSubscription)
 	for _, event := range events {
-		retEvents[event.EventName] = &storage.EventSubscription{
-			Event:        event.EventType,
-			Workflow:     event.WorkflowName,
-			Context:      event.Context.String,
-			EventContext: event.EventContext.String,
+		if retEvents[event.EventName], err = newEventSubscription(
+			event.EventType,
+			event.WorkflowName,
+			event.Context,
+			event.EventContext,
+			event.Conditions,
+			event.DelaySeconds,
+			event.JitterSeconds,
+			event.FollowUps,
+		); err != nil {
+			return retEvents, fmt.Errorf("event subscription %s: %w", event.EventName, err)
 		}
 	}
 	return retEvents, nil
@@ -29,19 +62,50 @@ func (s *MySQLStorage) RetrieveEventSubscriptions(ctx context.Context, names []s
 
 // RetrieveEventSubscriptionsByEvent retrieves event subscriptions by event flag.
 // See the storage interface type for further docs.
-func (s *MySQLStorage) RetrieveEventSubscriptionsByEvent(ctx context.Context, f workflow.EventFlag) ([]*storage.EventSubscription, error) {
+func (s *MySQLStorage) RetrieveEventSubscriptionsByEvent(ctx context.Context, f workflow.EventFlag) (map[string]*storage.EventSubscription, error) {
 	events, err := s.q.GetEventsByType(ctx, f.String())
 	if err != nil {
 		return nil, fmt.Errorf("get events by type: %w", err)
 	}
-	var retEvents []*storage.EventSubscription
+	retEvents := make(map[string]*storage.EventSubscription)
 	for _, event := range events {
-		retEvents = append(retEvents, &storage.EventSubscription{
-			Event:        event.EventType,
-			Workflow:     event.WorkflowName,
-			Context:      event.Context.String,
-			EventContext: event.EventContext.String,
-		})
+		if retEvents[event.EventName], err = newEventSubscription(
+			event.EventType,
+			event.WorkflowName,
+			event.Context,
+			event.EventContext,
+			event.Conditions,
+			event.DelaySeconds,
+			event.JitterSeconds,
+			event.FollowUps,
+		); err != nil {
+			return retEvents, fmt.Errorf("event subscription %s: %w", event.EventName, err)
+		}
+	}
+	return retEvents, nil
+}
+
+// RetrieveAllEventSubscriptions retrieves all event subscriptions.
+// See the storage interface type for further docs.
+func (s *MySQLStorage) RetrieveAllEventSubscriptions(ctx context.Context) (map[string]*storage.EventSubscription, error) {
+	events, err := s.q.GetAllEvents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get all events: %w", err)
+	}
+	retEvents := make(map[string]*storage.EventSubscription)
+	for _, event := range events {
+		if retEvents[event.EventName], err = newEventSubscription(
+			event.EventType,
+			event.WorkflowName,
+			event.Context,
+			event.EventContext,
+			event.Conditions,
+			event.DelaySeconds,
+			event.JitterSeconds,
+			event.FollowUps,
+		); err != nil {
+			return retEvents, fmt.Errorf("event subscription %s: %w", event.EventName, err)
+		}
 	}
 	return retEvents, nil
 }
@@ -49,26 +113,29 @@ func (s *MySQLStorage) RetrieveEventSubscriptionsByEvent(ctx context.Context, f
 // StoreEventSubscription stores an event subscription.
 // See the storage interface type for further docs.
 func (s *MySQLStorage) StoreEventSubscription(ctx context.Context, name string, es *storage.EventSubscription) error {
-	_, err := s.db.ExecContext(
-		ctx,
-		`
-INSERT INTO wf_events
-  (event_name, event_type, workflow_name, event_context, context)
-VALUES
-  (?, ?, ?, ?, ?) AS new
-ON DUPLICATE KEY
-UPDATE
-  workflow_name = new.workflow_name,
-  event_type = new.event_type,
-  event_context = new.event_context,
-  context = new.context;`,
-		name,
-		es.Event,
-		es.Workflow,
-		sqlNullString(es.EventContext),
-		sqlNullString(es.Context),
-	)
-	return err
+	var conditions sql.NullString
+	if es.Conditions != nil {
+		condBytes, err := json.Marshal(es.Conditions)
+		if err != nil {
+			return fmt.Errorf("marshal conditions: %w", err)
+		}
+		conditions = sqlNullString(string(condBytes))
+	}
+	followUps, err := sqlNullFollowUps(es.FollowUps)
+	if err != nil {
+		return err
+	}
+	return s.q.UpsertEvent(ctx, sqlc.UpsertEventParams{
+		EventName:     name,
+		EventType:     es.Event,
+		WorkflowName:  es.Workflow,
+		EventContext:  sqlNullString(es.EventContext),
+		Context:       sqlNullString(es.Context),
+		Conditions:    conditions,
+		DelaySeconds:  int32(es.Delay),
+		JitterSeconds: int32(es.Jitter),
+		FollowUps:     followUps,
+	})
 }
 
 // DeleteEventSubscription removes an event subscription.
@@ -76,3 +143,38 @@ UPDATE
 func (s *MySQLStorage) DeleteEventSubscription(ctx context.Context, name string) error {
 	return s.q.RemoveEvent(ctx, name)
 }
+
+// RecordEventSubscriptionFired updates event subscription statistics.
+// See the storage interface type for further docs.
+func (s *MySQLStorage) RecordEventSubscriptionFired(ctx context.Context, name string, firedAt time.Time, startErr error) error {
+	if startErr != nil {
+		return s.q.UpdateEventFiredError(ctx, sqlc.UpdateEventFiredErrorParams{
+			LastFiredUnix: firedAt.Unix(),
+			LastError:     sqlNullString(startErr.Error()),
+			EventName:     name,
+		})
+	}
+	return s.q.UpdateEventFired(ctx, sqlc.UpdateEventFiredParams{
+		LastFiredUnix: firedAt.Unix(),
+		EventName:     name,
+	})
+}
+
+// RetrieveEventSubscriptionStats retrieves event subscription statistics.
+// See the storage interface type for further docs.
+func (s *MySQLStorage) RetrieveEventSubscriptionStats(ctx context.Context, names []string) (map[string]*storage.EventSubscriptionStats, error) {
+	stats, err := s.q.GetEventStatsByNames(ctx, names)
+	if err != nil {
+		return nil, fmt.Errorf("get event stats by names: %w", err)
+	}
+	retStats := make(map[string]*storage.EventSubscriptionStats)
+	for _, stat := range stats {
+		retStats[stat.EventName] = &storage.EventSubscriptionStats{
+			Count:      stat.FiredCount,
+			LastFired:  time.Unix(stat.LastFiredUnix, 0),
+			ErrorCount: stat.ErrorCount,
+			LastError:  stat.LastError.String,
+		}
+	}
+	return retStats, nil
+}
@@ -0,0 +1,94 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/micromdm/nanocmd/engine/storage"
+	"github.com/micromdm/nanocmd/engine/storage/mysql/sqlc"
+)
+
+// sqlNullFollowUps marshals followUps for a nullable JSON column.
+func sqlNullFollowUps(followUps []storage.FollowUp) (sql.NullString, error) {
+	if len(followUps) < 1 {
+		return sql.NullString{}, nil
+	}
+	fuBytes, err := json.Marshal(followUps)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("marshal follow-ups: %w", err)
+	}
+	return sqlNullString(string(fuBytes)), nil
+}
+
+// followUpsFromSQLNull unmarshals follow-ups from a nullable JSON column.
+func followUpsFromSQLNull(s sql.NullString) ([]storage.FollowUp, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	var followUps []storage.FollowUp
+	if err := json.Unmarshal([]byte(s.String), &followUps); err != nil {
+		return nil, fmt.Errorf("unmarshal follow-ups: %w", err)
+	}
+	return followUps, nil
+}
+
+// StoreFollowUps stores the follow-up workflows of a workflow instance.
+// See the storage interface type for further docs.
+func (s *MySQLStorage) StoreFollowUps(ctx context.Context, instanceID string, ids []string, followUps []storage.FollowUp) error {
+	if instanceID == "" {
+		return storage.ErrMissingInstanceID
+	}
+	fuBytes, err := json.Marshal(followUps)
+	if err != nil {
+		return fmt.Errorf("marshal follow-ups: %w", err)
+	}
+	return tx(ctx, s.db, s.q, func(ctx context.Context, _ *sql.Tx, qtx *sqlc.Queries) error {
+		for _, id := range ids {
+			if err := qtx.UpsertFollowUps(ctx, sqlc.UpsertFollowUpsParams{
+				EnrollmentID: id,
+				InstanceID:   instanceID,
+				FollowUps:    string(fuBytes),
+			}); err != nil {
+				return fmt.Errorf("upsert follow-ups for %s: %w", id, err)
+			}
+		}
+		return nil
+	})
+}
+
+// RetrieveFollowUps retrieves and deletes the follow-up workflows of a workflow instance.
+// See the storage interface type for further docs.
+func (s *MySQLStorage) RetrieveFollowUps(ctx context.Context, instanceID, id string) ([]storage.FollowUp, error) {
+	var followUps []storage.FollowUp
+	err := tx(ctx, s.db, s.q, func(ctx context.Context, _ *sql.Tx, qtx *sqlc.Queries) error {
+		fuJSON, err := qtx.GetFollowUpsAndLock(ctx, sqlc.GetFollowUpsAndLockParams{
+			EnrollmentID: id,
+			InstanceID:   instanceID,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("get follow-ups: %w", err)
+		}
+		if followUps, err = followUpsFromSQLNull(sqlNullString(fuJSON)); err != nil {
+			return err
+		}
+		if err = qtx.RemoveFollowUps(ctx, sqlc.RemoveFollowUpsParams{
+			EnrollmentID: id,
+			InstanceID:   instanceID,
+		}); err != nil {
+			return fmt.Errorf("remove follow-ups: %w", err)
+		}
+		return nil
+	})
+	return followUps, err
+}
+
+// CancelFollowUps deletes follow-up workflows.
+// See the storage interface type for further docs.
+func (s *MySQLStorage) CancelFollowUps(ctx context.Context, id string) error {
+	return s.q.DeleteFollowUps(ctx, id)
+}
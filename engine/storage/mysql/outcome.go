@@ -0,0 +1,52 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/micromdm/nanocmd/engine/storage"
+	"github.com/micromdm/nanocmd/engine/storage/mysql/sqlc"
+	"github.com/micromdm/nanocmd/workflow"
+)
+
+// StoreOutcome stores the outcome of a workflow instance for an enrollment.
+// See the storage interface type for further docs.
+func (s *MySQLStorage) StoreOutcome(ctx context.Context, o *storage.InstanceOutcome) error {
+	if o == nil || o.EnrollmentID == "" {
+		return errors.New("invalid outcome")
+	}
+	if o.InstanceID == "" {
+		return storage.ErrMissingInstanceID
+	}
+	return s.q.UpsertOutcome(ctx, sqlc.UpsertOutcomeParams{
+		InstanceID:     o.InstanceID,
+		EnrollmentID:   o.EnrollmentID,
+		WorkflowName:   o.WorkflowName,
+		Outcome:        string(o.Outcome),
+		Message:        sqlNullString(o.Message),
+		FinishedAtUnix: o.FinishedAt.Unix(),
+	})
+}
+
+// RetrieveOutcomes retrieves the outcomes of a workflow instance.
+// See the storage interface type for further docs.
+func (s *MySQLStorage) RetrieveOutcomes(ctx context.Context, instanceID string) ([]*storage.InstanceOutcome, error) {
+	rows, err := s.q.GetOutcomes(ctx, instanceID)
+	if err != nil {
+		return nil, fmt.Errorf("get outcomes: %w", err)
+	}
+	var outcomes []*storage.InstanceOutcome
+	for _, row := range rows {
+		outcomes = append(outcomes, &storage.InstanceOutcome{
+			InstanceID:   instanceID,
+			EnrollmentID: row.EnrollmentID,
+			WorkflowName: row.WorkflowName,
+			Outcome:      workflow.Outcome(row.Outcome),
+			Message:      row.Message.String,
+			FinishedAt:   time.Unix(row.FinishedAtUnix, 0),
+		})
+	}
+	return outcomes, nil
+}
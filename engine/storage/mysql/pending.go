@@ -0,0 +1,95 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/micromdm/nanocmd/engine/storage"
+	"github.com/micromdm/nanocmd/engine/storage/mysql/sqlc"
+	"github.com/micromdm/nanocmd/workflow"
+)
+
+// StorePendingStart stores a delayed workflow start.
+// See the storage interface type for further docs.
+func (s *MySQLStorage) StorePendingStart(ctx context.Context, ps *storage.PendingStart) error {
+	if ps == nil || ps.EnrollmentID == "" || ps.EventSubscription == "" {
+		return errors.New("invalid pending start")
+	}
+	var params sql.NullString
+	if len(ps.Params) > 0 {
+		paramsBytes, err := json.Marshal(ps.Params)
+		if err != nil {
+			return fmt.Errorf("marshal params: %w", err)
+		}
+		params = sqlNullString(string(paramsBytes))
+	}
+	followUps, err := sqlNullFollowUps(ps.FollowUps)
+	if err != nil {
+		return err
+	}
+	return s.q.CreatePendingStart(ctx, sqlc.CreatePendingStartParams{
+		EnrollmentID: ps.EnrollmentID,
+		EventName:    ps.EventSubscription,
+		WorkflowName: ps.WorkflowName,
+		Context:      sqlNullString(string(ps.Context)),
+		EventType:    ps.EventFlag.String(),
+		EventData:    ps.EventData,
+		Params:       params,
+		FollowUps:    followUps,
+		StartAtUnix:  ps.StartAt.Unix(),
+	})
+}
+
+// CancelPendingStarts deletes pending workflow starts.
+// See the storage interface type for further docs.
+func (s *MySQLStorage) CancelPendingStarts(ctx context.Context, id string) error {
+	return s.q.DeletePendingStarts(ctx, id)
+}
+
+// RetrieveDuePendingStarts retrieves and deletes due pending workflow starts.
+// See the storage interface type for further docs.
+func (s *MySQLStorage) RetrieveDuePendingStarts(ctx context.Context, now time.Time) ([]*storage.PendingStart, error) {
+	var ret []*storage.PendingStart
+	err := tx(ctx, s.db, s.q, func(ctx context.Context, _ *sql.Tx, qtx *sqlc.Queries) error {
+		rows, err := qtx.GetDuePendingStartsAndLock(ctx, now.Unix())
+		if err != nil {
+			return fmt.Errorf("get due pending starts: %w", err)
+		}
+		for _, i := range rows {
+			ps := &storage.PendingStart{
+				EnrollmentID:      i.EnrollmentID,
+				EventSubscription: i.EventName,
+				WorkflowName:      i.WorkflowName,
+				EventFlag:         workflow.EventFlagForString(i.EventType),
+				EventData:         i.EventData,
+				StartAt:           time.Unix(i.StartAtUnix, 0),
+			}
+			if i.Context.Valid {
+				ps.Context = []byte(i.Context.String)
+			}
+			if i.Params.Valid {
+				if err = json.Unmarshal([]byte(i.Params.String), &ps.Params); err != nil {
+					return fmt.Errorf("unmarshal params: %w", err)
+				}
+			}
+			if ps.FollowUps, err = followUpsFromSQLNull(i.FollowUps); err != nil {
+				return err
+			}
+			ret = append(ret, ps)
+		}
+		for _, ps := range ret {
+			if err = qtx.RemovePendingStart(ctx, sqlc.RemovePendingStartParams{
+				EnrollmentID: ps.EnrollmentID,
+				EventName:    ps.EventSubscription,
+			}); err != nil {
+				return fmt.Errorf("remove pending start: %w", err)
+			}
+		}
+		return nil
+	})
+	return ret, err
+}
@@ -183,3 +183,54 @@ DELETE FROM
   wf_status
 WHERE
   enrollment_id = ?;
+
+-- name: GetOutstandingInstance :one
+SELECT
+  EXISTS (
+    SELECT
+      1
+    FROM
+      id_commands c
+      JOIN steps s
+        ON s.id = c.step_id
+    WHERE
+      c.enrollment_id = sqlc.arg(enrollment_id) AND
+      c.completed = 0 AND
+      s.workflow_name = sqlc.arg(workflow_name) AND
+      s.instance_id = sqlc.arg(instance_id)
+  ) OR EXISTS (
+    SELECT
+      1
+    FROM
+      wf_timer_steps t
+    WHERE
+      t.enrollment_id = sqlc.arg(enrollment_id) AND
+      t.workflow_name = sqlc.arg(workflow_name) AND
+      t.instance_id = sqlc.arg(instance_id)
+  ) AS outstanding;
+
+-- name: UpsertOutcome :exec
+INSERT INTO wf_outcomes
+  (instance_id, enrollment_id, workflow_name, outcome, message, finished_at_unix)
+VALUES
+  (?, ?, ?, ?, ?, ?)
+ON DUPLICATE KEY
+UPDATE
+  workflow_name = VALUES(workflow_name),
+  outcome = VALUES(outcome),
+  message = VALUES(message),
+  finished_at_unix = VALUES(finished_at_unix);
+
+-- name: GetOutcomes :many
+SELECT
+  enrollment_id,
+  workflow_name,
+  outcome,
+  message,
+  finished_at_unix
+FROM
+  wf_outcomes
+WHERE
+  instance_id = ?
+ORDER BY
+  enrollment_id;
@@ -0,0 +1,105 @@
+-- name: UpsertApproval :exec
+INSERT INTO wf_approvals
+  (id, workflow_name, ids, context, follow_ups, requester, requested_at_unix, expires_at_unix, status, decided_by, decided_at_unix, reason, instance_id, start_error)
+VALUES
+  (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON DUPLICATE KEY
+UPDATE
+  workflow_name = VALUES(workflow_name),
+  ids = VALUES(ids),
+  context = VALUES(context),
+  follow_ups = VALUES(follow_ups),
+  requester = VALUES(requester),
+  requested_at_unix = VALUES(requested_at_unix),
+  expires_at_unix = VALUES(expires_at_unix),
+  status = VALUES(status),
+  decided_by = VALUES(decided_by),
+  decided_at_unix = VALUES(decided_at_unix),
+  reason = VALUES(reason),
+  instance_id = VALUES(instance_id),
+  start_error = VALUES(start_error);
+
+-- name: GetApproval :one
+SELECT
+  id,
+  workflow_name,
+  ids,
+  context,
+  follow_ups,
+  requester,
+  requested_at_unix,
+  expires_at_unix,
+  status,
+  decided_by,
+  decided_at_unix,
+  reason,
+  instance_id,
+  start_error
+FROM
+  wf_approvals
+WHERE
+  id = ?;
+
+-- name: GetApprovals :many
+SELECT
+  id,
+  workflow_name,
+  ids,
+  context,
+  follow_ups,
+  requester,
+  requested_at_unix,
+  expires_at_unix,
+  status,
+  decided_by,
+  decided_at_unix,
+  reason,
+  instance_id,
+  start_error
+FROM
+  wf_approvals
+ORDER BY
+  requested_at_unix, id;
+
+-- name: GetApprovalsByStatus :many
+SELECT
+  id,
+  workflow_name,
+  ids,
+  context,
+  follow_ups,
+  requester,
+  requested_at_unix,
+  expires_at_unix,
+  status,
+  decided_by,
+  decided_at_unix,
+  reason,
+  instance_id,
+  start_error
+FROM
+  wf_approvals
+WHERE
+  status = ?
+ORDER BY
+  requested_at_unix, id;
+
+-- name: GetApprovalStatusAndLock :one
+SELECT
+  status
+FROM
+  wf_approvals
+WHERE
+  id = ?
+FOR UPDATE;
+
+-- name: UpdateApprovalDecision :exec
+UPDATE
+  wf_approvals
+SET
+  status = ?,
+  decided_by = ?,
+  decided_at_unix = ?,
+  reason = ?
+WHERE
+  id = ?;
@@ -4,7 +4,11 @@ SELECT
   context,
   event_context,
   workflow_name,
-  event_type
+  event_type,
+  conditions,
+  delay_seconds,
+  jitter_seconds,
+  follow_ups
 FROM
   wf_events
 WHERE
@@ -12,15 +16,85 @@ WHERE
 
 -- name: GetEventsByType :many
 SELECT
+  event_name,
   context,
   event_context,
   workflow_name,
-  event_type
+  event_type,
+  conditions,
+  delay_seconds,
+  jitter_seconds,
+  follow_ups
 FROM
   wf_events
 WHERE
   event_type = ?;
 
+-- name: GetAllEvents :many
+SELECT
+  event_name,
+  context,
+  event_context,
+  workflow_name,
+  event_type,
+  conditions,
+  delay_seconds,
+  jitter_seconds,
+  follow_ups
+FROM
+  wf_events;
+
+-- name: GetEventStatsByNames :many
+SELECT
+  event_name,
+  fired_count,
+  last_fired_unix,
+  error_count,
+  last_error
+FROM
+  wf_events
+WHERE
+  event_name IN (sqlc.slice('names')) AND
+  fired_count > 0;
+
 -- name: RemoveEvent :exec
 DELETE FROM wf_events WHERE event_name = ?;
 
+
+-- name: UpdateEventFired :exec
+UPDATE
+  wf_events
+SET
+  fired_count = fired_count + 1,
+  last_fired_unix = ?,
+  updated_at = updated_at
+WHERE
+  event_name = ?;
+
+-- name: UpdateEventFiredError :exec
+UPDATE
+  wf_events
+SET
+  fired_count = fired_count + 1,
+  last_fired_unix = ?,
+  error_count = error_count + 1,
+  last_error = ?,
+  updated_at = updated_at
+WHERE
+  event_name = ?;
+
+-- name: UpsertEvent :exec
+INSERT INTO wf_events
+  (event_name, event_type, workflow_name, event_context, context, conditions, delay_seconds, jitter_seconds, follow_ups)
+VALUES
+  (?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON DUPLICATE KEY
+UPDATE
+  workflow_name = VALUES(workflow_name),
+  event_type = VALUES(event_type),
+  event_context = VALUES(event_context),
+  context = VALUES(context),
+  conditions = VALUES(conditions),
+  delay_seconds = VALUES(delay_seconds),
+  jitter_seconds = VALUES(jitter_seconds),
+  follow_ups = VALUES(follow_ups);
@@ -0,0 +1,114 @@
+-- name: CreatePendingStart :exec
+INSERT IGNORE INTO wf_pending_starts
+  (enrollment_id, event_name, workflow_name, context, event_type, event_data, params, follow_ups, start_at_unix)
+VALUES
+  (?, ?, ?, ?, ?, ?, ?, ?, ?);
+
+-- name: DeletePendingStarts :exec
+DELETE FROM
+  wf_pending_starts
+WHERE
+  enrollment_id = ?;
+
+-- name: GetDuePendingStartsAndLock :many
+SELECT
+  enrollment_id,
+  event_name,
+  workflow_name,
+  context,
+  event_type,
+  event_data,
+  params,
+  follow_ups,
+  start_at_unix
+FROM
+  wf_pending_starts
+WHERE
+  start_at_unix <= ?
+FOR UPDATE;
+
+-- name: RemovePendingStart :exec
+DELETE FROM
+  wf_pending_starts
+WHERE
+  enrollment_id = ? AND
+  event_name = ?;
+
+-- name: GetQueuedStartContextsAndLock :many
+SELECT
+  context
+FROM
+  wf_queued_starts
+WHERE
+  enrollment_id = ? AND
+  workflow_name = ?
+FOR UPDATE;
+
+-- name: CreateQueuedStart :exec
+INSERT INTO wf_queued_starts
+  (enrollment_id, workflow_name, context, event_type, event_data, params, follow_ups)
+VALUES
+  (?, ?, ?, ?, ?, ?, ?);
+
+-- name: GetNextQueuedStartAndLock :one
+SELECT
+  id,
+  context,
+  event_type,
+  event_data,
+  params,
+  follow_ups,
+  UNIX_TIMESTAMP(created_at) AS queued_at_unix
+FROM
+  wf_queued_starts
+WHERE
+  enrollment_id = ? AND
+  workflow_name = ?
+ORDER BY
+  id
+LIMIT 1
+FOR UPDATE;
+
+-- name: RemoveQueuedStart :exec
+DELETE FROM
+  wf_queued_starts
+WHERE
+  id = ?;
+
+-- name: DeleteQueuedStarts :exec
+DELETE FROM
+  wf_queued_starts
+WHERE
+  enrollment_id = ?;
+
+-- name: UpsertFollowUps :exec
+INSERT INTO wf_follow_ups
+  (enrollment_id, instance_id, follow_ups)
+VALUES
+  (?, ?, ?)
+ON DUPLICATE KEY
+UPDATE
+  follow_ups = VALUES(follow_ups);
+
+-- name: GetFollowUpsAndLock :one
+SELECT
+  follow_ups
+FROM
+  wf_follow_ups
+WHERE
+  enrollment_id = ? AND
+  instance_id = ?
+FOR UPDATE;
+
+-- name: RemoveFollowUps :exec
+DELETE FROM
+  wf_follow_ups
+WHERE
+  enrollment_id = ? AND
+  instance_id = ?;
+
+-- name: DeleteFollowUps :exec
+DELETE FROM
+  wf_follow_ups
+WHERE
+  enrollment_id = ?;
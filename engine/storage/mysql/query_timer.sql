@@ -0,0 +1,67 @@
+-- name: CreateTimerStep :exec
+INSERT INTO wf_timer_steps
+  (enrollment_id, workflow_name, instance_id, step_name, context, event_type, fire_at_unix)
+VALUES
+  (?, ?, ?, ?, ?, ?, ?);
+
+-- name: GetDueTimerStepsAndLock :many
+SELECT
+  id,
+  enrollment_id,
+  workflow_name,
+  instance_id,
+  step_name,
+  context,
+  event_type,
+  fire_at_unix
+FROM
+  wf_timer_steps
+WHERE
+  fire_at_unix > 0 AND
+  fire_at_unix <= ?
+FOR UPDATE;
+
+-- name: GetEventTimerStepsAndLock :many
+SELECT
+  id,
+  enrollment_id,
+  workflow_name,
+  instance_id,
+  step_name,
+  context,
+  event_type,
+  fire_at_unix
+FROM
+  wf_timer_steps
+WHERE
+  enrollment_id = ? AND
+  event_type = ?
+FOR UPDATE;
+
+-- name: RemoveTimerStepsByIDs :exec
+DELETE FROM
+  wf_timer_steps
+WHERE
+  id IN (sqlc.slice('ids'));
+
+-- name: GetTimerStepIDs :many
+SELECT DISTINCT
+  enrollment_id
+FROM
+  wf_timer_steps
+WHERE
+  workflow_name = ? AND
+  enrollment_id IN (sqlc.slice('ids'));
+
+-- name: DeleteTimerSteps :exec
+DELETE FROM
+  wf_timer_steps
+WHERE
+  enrollment_id = ?;
+
+-- name: DeleteTimerStepsByWorkflow :exec
+DELETE FROM
+  wf_timer_steps
+WHERE
+  enrollment_id = ? AND
+  workflow_name = ?;
@@ -0,0 +1,107 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/micromdm/nanocmd/engine/storage"
+	"github.com/micromdm/nanocmd/engine/storage/mysql/sqlc"
+	"github.com/micromdm/nanocmd/workflow"
+)
+
+// StoreQueuedStart queues a workflow start.
+// See the storage interface type for further docs.
+func (s *MySQLStorage) StoreQueuedStart(ctx context.Context, qs *storage.QueuedStart, max int) error {
+	if qs == nil || qs.EnrollmentID == "" || qs.WorkflowName == "" {
+		return errors.New("invalid queued start")
+	}
+	var params sql.NullString
+	if len(qs.Params) > 0 {
+		paramsBytes, err := json.Marshal(qs.Params)
+		if err != nil {
+			return fmt.Errorf("marshal params: %w", err)
+		}
+		params = sqlNullString(string(paramsBytes))
+	}
+	followUps, err := sqlNullFollowUps(qs.FollowUps)
+	if err != nil {
+		return err
+	}
+	return tx(ctx, s.db, s.q, func(ctx context.Context, _ *sql.Tx, qtx *sqlc.Queries) error {
+		contexts, err := qtx.GetQueuedStartContextsAndLock(ctx, sqlc.GetQueuedStartContextsAndLockParams{
+			EnrollmentID: qs.EnrollmentID,
+			WorkflowName: qs.WorkflowName,
+		})
+		if err != nil {
+			return fmt.Errorf("get queued starts: %w", err)
+		}
+		for _, wfContext := range contexts {
+			if wfContext.String == string(qs.Context) {
+				// coalesce into the already queued start
+				return nil
+			}
+		}
+		if len(contexts) >= max {
+			return storage.ErrQueueFull
+		}
+		return qtx.CreateQueuedStart(ctx, sqlc.CreateQueuedStartParams{
+			EnrollmentID: qs.EnrollmentID,
+			WorkflowName: qs.WorkflowName,
+			Context:      sqlNullString(string(qs.Context)),
+			EventType:    qs.EventFlag.String(),
+			EventData:    qs.EventData,
+			Params:       params,
+			FollowUps:    followUps,
+		})
+	})
+}
+
+// RetrieveNextQueuedStart retrieves and deletes the oldest queued workflow start.
+// See the storage interface type for further docs.
+func (s *MySQLStorage) RetrieveNextQueuedStart(ctx context.Context, id, workflowName string) (*storage.QueuedStart, error) {
+	var qs *storage.QueuedStart
+	err := tx(ctx, s.db, s.q, func(ctx context.Context, _ *sql.Tx, qtx *sqlc.Queries) error {
+		row, err := qtx.GetNextQueuedStartAndLock(ctx, sqlc.GetNextQueuedStartAndLockParams{
+			EnrollmentID: id,
+			WorkflowName: workflowName,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("get next queued start: %w", err)
+		}
+		qs = &storage.QueuedStart{
+			EnrollmentID: id,
+			WorkflowName: workflowName,
+			EventFlag:    workflow.EventFlagForString(row.EventType),
+			EventData:    row.EventData,
+			QueuedAt:     time.Unix(row.QueuedAtUnix, 0),
+		}
+		if row.Context.Valid {
+			qs.Context = []byte(row.Context.String)
+		}
+		if row.Params.Valid {
+			if err = json.Unmarshal([]byte(row.Params.String), &qs.Params); err != nil {
+				return fmt.Errorf("unmarshal params: %w", err)
+			}
+		}
+		if qs.FollowUps, err = followUpsFromSQLNull(row.FollowUps); err != nil {
+			return err
+		}
+		if err = qtx.RemoveQueuedStart(ctx, row.ID); err != nil {
+			return fmt.Errorf("remove queued start: %w", err)
+		}
+		return nil
+	})
+	return qs, err
+}
+
+// CancelQueuedStarts deletes queued workflow starts.
+// See the storage interface type for further docs.
+func (s *MySQLStorage) CancelQueuedStarts(ctx context.Context, id string) error {
+	return s.q.DeleteQueuedStarts(ctx, id)
+}
@@ -0,0 +1,5 @@
+ALTER TABLE wf_events
+    ADD COLUMN fired_count BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN last_fired_unix BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN error_count BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN last_error TEXT NULL;
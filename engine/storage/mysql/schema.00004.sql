@@ -0,0 +1 @@
+ALTER TABLE wf_events ADD COLUMN conditions MEDIUMTEXT NULL AFTER event_type;
@@ -0,0 +1,21 @@
+ALTER TABLE wf_events
+    ADD COLUMN delay_seconds INTEGER NOT NULL DEFAULT 0 AFTER conditions,
+    ADD COLUMN jitter_seconds INTEGER NOT NULL DEFAULT 0 AFTER delay_seconds;
+
+CREATE TABLE wf_pending_starts (
+    enrollment_id VARCHAR(255) NOT NULL,
+    event_name    VARCHAR(255) NOT NULL,
+
+    workflow_name VARCHAR(255) NOT NULL,
+    context       MEDIUMTEXT   NULL,
+    event_type    VARCHAR(63)  NOT NULL,
+    event_data    MEDIUMBLOB   NULL,
+    params        MEDIUMTEXT   NULL,
+    start_at_unix BIGINT       NOT NULL,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    INDEX (start_at_unix),
+
+    PRIMARY KEY (enrollment_id, event_name)
+);
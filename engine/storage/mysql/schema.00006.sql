@@ -0,0 +1,16 @@
+CREATE TABLE wf_queued_starts (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+
+    enrollment_id VARCHAR(255) NOT NULL,
+    workflow_name VARCHAR(255) NOT NULL,
+    context       MEDIUMTEXT   NULL,
+    event_type    VARCHAR(63)  NOT NULL,
+    event_data    MEDIUMBLOB   NULL,
+    params        MEDIUMTEXT   NULL,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    INDEX (enrollment_id, workflow_name),
+
+    PRIMARY KEY (id)
+);
@@ -0,0 +1,19 @@
+ALTER TABLE wf_events
+    ADD COLUMN follow_ups MEDIUMTEXT NULL AFTER jitter_seconds;
+
+ALTER TABLE wf_pending_starts
+    ADD COLUMN follow_ups MEDIUMTEXT NULL AFTER params;
+
+ALTER TABLE wf_queued_starts
+    ADD COLUMN follow_ups MEDIUMTEXT NULL AFTER params;
+
+CREATE TABLE wf_follow_ups (
+    enrollment_id VARCHAR(255) NOT NULL,
+    instance_id   VARCHAR(255) NOT NULL,
+
+    follow_ups MEDIUMTEXT NOT NULL,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (enrollment_id, instance_id)
+);
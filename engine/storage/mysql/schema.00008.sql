@@ -0,0 +1,14 @@
+CREATE TABLE wf_outcomes (
+    instance_id   VARCHAR(255) NOT NULL,
+    enrollment_id VARCHAR(255) NOT NULL,
+    workflow_name VARCHAR(255) NOT NULL,
+    outcome       VARCHAR(31)  NOT NULL,
+    message       TEXT         NULL,
+
+    finished_at_unix BIGINT NOT NULL,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (instance_id, enrollment_id)
+);
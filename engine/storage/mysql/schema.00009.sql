@@ -0,0 +1,17 @@
+CREATE TABLE wf_timer_steps (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+
+    enrollment_id VARCHAR(255) NOT NULL,
+    workflow_name VARCHAR(255) NOT NULL,
+    instance_id   VARCHAR(255) NOT NULL,
+    step_name     VARCHAR(255) NULL,
+    context       MEDIUMTEXT   NULL,
+    fire_at_unix  BIGINT       NOT NULL,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    INDEX (fire_at_unix),
+    INDEX (enrollment_id, workflow_name),
+
+    PRIMARY KEY (id)
+);
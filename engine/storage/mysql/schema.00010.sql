@@ -0,0 +1,3 @@
+ALTER TABLE wf_timer_steps
+    ADD COLUMN event_type VARCHAR(63) NULL AFTER context,
+    ADD INDEX (enrollment_id, event_type);
@@ -0,0 +1,27 @@
+CREATE TABLE wf_approvals (
+    id            VARCHAR(255) NOT NULL,
+    workflow_name VARCHAR(255) NOT NULL,
+    ids           TEXT         NOT NULL,
+    context       MEDIUMTEXT   NULL,
+    follow_ups    TEXT         NULL,
+
+    requester         VARCHAR(255) NOT NULL,
+    requested_at_unix BIGINT       NOT NULL,
+    expires_at_unix   BIGINT       NOT NULL,
+
+    status          VARCHAR(31)  NOT NULL,
+    decided_by      VARCHAR(255) NULL,
+    decided_at_unix BIGINT       NULL,
+    reason          TEXT         NULL,
+
+    instance_id VARCHAR(255) NULL,
+    start_error TEXT         NULL,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    INDEX (status, requested_at_unix),
+    INDEX (requested_at_unix),
+
+    PRIMARY KEY (id)
+);
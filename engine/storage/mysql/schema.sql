@@ -71,6 +71,15 @@ CREATE TABLE wf_events (
     event_context MEDIUMTEXT   NULL,
     workflow_name VARCHAR(255) NOT NULL,
     event_type    VARCHAR(63)  NOT NULL,
+    conditions    MEDIUMTEXT   NULL,
+    delay_seconds  INTEGER NOT NULL DEFAULT 0,
+    jitter_seconds INTEGER NOT NULL DEFAULT 0,
+    follow_ups     MEDIUMTEXT NULL,
+
+    fired_count     BIGINT NOT NULL DEFAULT 0,
+    last_fired_unix BIGINT NOT NULL DEFAULT 0,
+    error_count     BIGINT NOT NULL DEFAULT 0,
+    last_error      TEXT   NULL,
 
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
@@ -98,3 +107,114 @@ CREATE TABLE wf_status (
 
     PRIMARY KEY (enrollment_id, workflow_name)
 );
+
+CREATE TABLE wf_pending_starts (
+    enrollment_id VARCHAR(255) NOT NULL,
+    event_name    VARCHAR(255) NOT NULL,
+
+    workflow_name VARCHAR(255) NOT NULL,
+    context       MEDIUMTEXT   NULL,
+    event_type    VARCHAR(63)  NOT NULL,
+    event_data    MEDIUMBLOB   NULL,
+    params        MEDIUMTEXT   NULL,
+    follow_ups    MEDIUMTEXT   NULL,
+    start_at_unix BIGINT       NOT NULL,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    INDEX (start_at_unix),
+
+    PRIMARY KEY (enrollment_id, event_name)
+);
+
+CREATE TABLE wf_queued_starts (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+
+    enrollment_id VARCHAR(255) NOT NULL,
+    workflow_name VARCHAR(255) NOT NULL,
+    context       MEDIUMTEXT   NULL,
+    event_type    VARCHAR(63)  NOT NULL,
+    event_data    MEDIUMBLOB   NULL,
+    params        MEDIUMTEXT   NULL,
+    follow_ups    MEDIUMTEXT   NULL,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    INDEX (enrollment_id, workflow_name),
+
+    PRIMARY KEY (id)
+);
+
+CREATE TABLE wf_follow_ups (
+    enrollment_id VARCHAR(255) NOT NULL,
+    instance_id   VARCHAR(255) NOT NULL,
+
+    follow_ups MEDIUMTEXT NOT NULL,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (enrollment_id, instance_id)
+);
+
+CREATE TABLE wf_outcomes (
+    instance_id   VARCHAR(255) NOT NULL,
+    enrollment_id VARCHAR(255) NOT NULL,
+    workflow_name VARCHAR(255) NOT NULL,
+    outcome       VARCHAR(31)  NOT NULL,
+    message       TEXT         NULL,
+
+    finished_at_unix BIGINT NOT NULL,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (instance_id, enrollment_id)
+);
+
+CREATE TABLE wf_timer_steps (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+
+    enrollment_id VARCHAR(255) NOT NULL,
+    workflow_name VARCHAR(255) NOT NULL,
+    instance_id   VARCHAR(255) NOT NULL,
+    step_name     VARCHAR(255) NULL,
+    context       MEDIUMTEXT   NULL,
+    event_type    VARCHAR(63)  NULL,
+    fire_at_unix  BIGINT       NOT NULL,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    INDEX (fire_at_unix),
+    INDEX (enrollment_id, workflow_name),
+    INDEX (enrollment_id, event_type),
+
+    PRIMARY KEY (id)
+);
+
+CREATE TABLE wf_approvals (
+    id            VARCHAR(255) NOT NULL,
+    workflow_name VARCHAR(255) NOT NULL,
+    ids           TEXT         NOT NULL,
+    context       MEDIUMTEXT   NULL,
+    follow_ups    TEXT         NULL,
+
+    requester         VARCHAR(255) NOT NULL,
+    requested_at_unix BIGINT       NOT NULL,
+    expires_at_unix   BIGINT       NOT NULL,
+
+    status          VARCHAR(31)  NOT NULL,
+    decided_by      VARCHAR(255) NULL,
+    decided_at_unix BIGINT       NULL,
+    reason          TEXT         NULL,
+
+    instance_id VARCHAR(255) NULL,
+    start_error TEXT         NULL,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    INDEX (status, requested_at_unix),
+    INDEX (requested_at_unix),
+
+    PRIMARY KEY (id)
+);
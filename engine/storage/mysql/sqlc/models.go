@@ -42,14 +42,84 @@ type StepCommand struct {
 	UpdatedAt   sql.NullTime
 }
 
+type WfApproval struct {
+	ID              string
+	WorkflowName    string
+	Ids             string
+	Context         sql.NullString
+	FollowUps       sql.NullString
+	Requester       string
+	RequestedAtUnix int64
+	ExpiresAtUnix   int64
+	Status          string
+	DecidedBy       sql.NullString
+	DecidedAtUnix   sql.NullInt64
+	Reason          sql.NullString
+	InstanceID      sql.NullString
+	StartError      sql.NullString
+	CreatedAt       sql.NullTime
+	UpdatedAt       sql.NullTime
+}
+
 type WfEvent struct {
+	EventName     string
+	Context       sql.NullString
+	EventContext  sql.NullString
+	WorkflowName  string
+	EventType     string
+	Conditions    sql.NullString
+	DelaySeconds  int32
+	JitterSeconds int32
+	FollowUps     sql.NullString
+	FiredCount    int64
+	LastFiredUnix int64
+	ErrorCount    int64
+	LastError     sql.NullString
+	CreatedAt     sql.NullTime
+	UpdatedAt     sql.NullTime
+}
+
+type WfFollowUp struct {
+	EnrollmentID string
+	InstanceID   string
+	FollowUps    string
+	CreatedAt    sql.NullTime
+}
+
+type WfOutcome struct {
+	InstanceID     string
+	EnrollmentID   string
+	WorkflowName   string
+	Outcome        string
+	Message        sql.NullString
+	FinishedAtUnix int64
+	CreatedAt      sql.NullTime
+	UpdatedAt      sql.NullTime
+}
+
+type WfPendingStart struct {
+	EnrollmentID string
 	EventName    string
+	WorkflowName string
 	Context      sql.NullString
-	EventContext sql.NullString
+	EventType    string
+	EventData    []byte
+	Params       sql.NullString
+	FollowUps    sql.NullString
+	StartAtUnix  int64
+	CreatedAt    sql.NullTime
+}
+
+type WfQueuedStart struct {
+	ID           int64
+	EnrollmentID string
 	WorkflowName string
+	Context      sql.NullString
 	EventType    string
+	EventData    []byte
+	Params       sql.NullString
+	FollowUps    sql.NullString
 	CreatedAt    sql.NullTime
-	UpdatedAt    sql.NullTime
 }
 
 type WfStatus struct {
@@ -59,3 +129,15 @@ type WfStatus struct {
 	CreatedAt       sql.NullTime
 	UpdatedAt       sql.NullTime
 }
+
+type WfTimerStep struct {
+	ID           int64
+	EnrollmentID string
+	WorkflowName string
+	InstanceID   string
+	StepName     sql.NullString
+	Context      sql.NullString
+	EventType    sql.NullString
+	FireAtUnix   int64
+	CreatedAt    sql.NullTime
+}
@@ -291,6 +291,58 @@ func (q *Queries) GetIDCommandsByStepIDAndLock(ctx context.Context, arg GetIDCom
 	return items, nil
 }
 
+const getOutcomes = `-- name: GetOutcomes :many
+SELECT
+  enrollment_id,
+  workflow_name,
+  outcome,
+  message,
+  finished_at_unix
+FROM
+  wf_outcomes
+WHERE
+  instance_id = ?
+ORDER BY
+  enrollment_id
+`
+
+type GetOutcomesRow struct {
+	EnrollmentID   string
+	WorkflowName   string
+	Outcome        string
+	Message        sql.NullString
+	FinishedAtUnix int64
+}
+
+func (q *Queries) GetOutcomes(ctx context.Context, instanceID string) ([]GetOutcomesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getOutcomes, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetOutcomesRow
+	for rows.Next() {
+		var i GetOutcomesRow
+		if err := rows.Scan(
+			&i.EnrollmentID,
+			&i.WorkflowName,
+			&i.Outcome,
+			&i.Message,
+			&i.FinishedAtUnix,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getOutstandingIDs = `-- name: GetOutstandingIDs :many
 SELECT DISTINCT
   c.enrollment_id
@@ -343,6 +395,52 @@ func (q *Queries) GetOutstandingIDs(ctx context.Context, arg GetOutstandingIDsPa
 	return items, nil
 }
 
+const getOutstandingInstance = `-- name: GetOutstandingInstance :one
+SELECT
+  EXISTS (
+    SELECT
+      1
+    FROM
+      id_commands c
+      JOIN steps s
+        ON s.id = c.step_id
+    WHERE
+      c.enrollment_id = ? AND
+      c.completed = 0 AND
+      s.workflow_name = ? AND
+      s.instance_id = ?
+  ) OR EXISTS (
+    SELECT
+      1
+    FROM
+      wf_timer_steps t
+    WHERE
+      t.enrollment_id = ? AND
+      t.workflow_name = ? AND
+      t.instance_id = ?
+  ) AS outstanding
+`
+
+type GetOutstandingInstanceParams struct {
+	EnrollmentID string
+	WorkflowName string
+	InstanceID   string
+}
+
+func (q *Queries) GetOutstandingInstance(ctx context.Context, arg GetOutstandingInstanceParams) (sql.NullBool, error) {
+	row := q.db.QueryRowContext(ctx, getOutstandingInstance,
+		arg.EnrollmentID,
+		arg.WorkflowName,
+		arg.InstanceID,
+		arg.EnrollmentID,
+		arg.WorkflowName,
+		arg.InstanceID,
+	)
+	var outstanding sql.NullBool
+	err := row.Scan(&outstanding)
+	return outstanding, err
+}
+
 const getRequestType = `-- name: GetRequestType :one
 SELECT
   request_type
@@ -485,3 +583,37 @@ func (q *Queries) UpdateIDCommandTimestamp(ctx context.Context, arg UpdateIDComm
 	_, err := q.db.ExecContext(ctx, updateIDCommandTimestamp, arg.EnrollmentID, arg.CommandUuid)
 	return err
 }
+
+const upsertOutcome = `-- name: UpsertOutcome :exec
+INSERT INTO wf_outcomes
+  (instance_id, enrollment_id, workflow_name, outcome, message, finished_at_unix)
+VALUES
+  (?, ?, ?, ?, ?, ?)
+ON DUPLICATE KEY
+UPDATE
+  workflow_name = VALUES(workflow_name),
+  outcome = VALUES(outcome),
+  message = VALUES(message),
+  finished_at_unix = VALUES(finished_at_unix)
+`
+
+type UpsertOutcomeParams struct {
+	InstanceID     string
+	EnrollmentID   string
+	WorkflowName   string
+	Outcome        string
+	Message        sql.NullString
+	FinishedAtUnix int64
+}
+
+func (q *Queries) UpsertOutcome(ctx context.Context, arg UpsertOutcomeParams) error {
+	_, err := q.db.ExecContext(ctx, upsertOutcome,
+		arg.InstanceID,
+		arg.EnrollmentID,
+		arg.WorkflowName,
+		arg.Outcome,
+		arg.Message,
+		arg.FinishedAtUnix,
+	)
+	return err
+}
@@ -11,13 +11,138 @@ import (
 	"strings"
 )
 
+const getAllEvents = `-- name: GetAllEvents :many
+SELECT
+  event_name,
+  context,
+  event_context,
+  workflow_name,
+  event_type,
+  conditions,
+  delay_seconds,
+  jitter_seconds,
+  follow_ups
+FROM
+  wf_events
+`
+
+type GetAllEventsRow struct {
+	EventName     string
+	Context       sql.NullString
+	EventContext  sql.NullString
+	WorkflowName  string
+	EventType     string
+	Conditions    sql.NullString
+	DelaySeconds  int32
+	JitterSeconds int32
+	FollowUps     sql.NullString
+}
+
+func (q *Queries) GetAllEvents(ctx context.Context) ([]GetAllEventsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getAllEvents)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAllEventsRow
+	for rows.Next() {
+		var i GetAllEventsRow
+		if err := rows.Scan(
+			&i.EventName,
+			&i.Context,
+			&i.EventContext,
+			&i.WorkflowName,
+			&i.EventType,
+			&i.Conditions,
+			&i.DelaySeconds,
+			&i.JitterSeconds,
+			&i.FollowUps,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getEventStatsByNames = `-- name: GetEventStatsByNames :many
+SELECT
+  event_name,
+  fired_count,
+  last_fired_unix,
+  error_count,
+  last_error
+FROM
+  wf_events
+WHERE
+  event_name IN (/*SLICE:names*/?) AND
+  fired_count > 0
+`
+
+type GetEventStatsByNamesRow struct {
+	EventName     string
+	FiredCount    int64
+	LastFiredUnix int64
+	ErrorCount    int64
+	LastError     sql.NullString
+}
+
+func (q *Queries) GetEventStatsByNames(ctx context.Context, names []string) ([]GetEventStatsByNamesRow, error) {
+	query := getEventStatsByNames
+	var queryParams []interface{}
+	if len(names) > 0 {
+		for _, v := range names {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:names*/?", strings.Repeat(",?", len(names))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:names*/?", "NULL", 1)
+	}
+	rows, err := q.db.QueryContext(ctx, query, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetEventStatsByNamesRow
+	for rows.Next() {
+		var i GetEventStatsByNamesRow
+		if err := rows.Scan(
+			&i.EventName,
+			&i.FiredCount,
+			&i.LastFiredUnix,
+			&i.ErrorCount,
+			&i.LastError,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getEventsByNames = `-- name: GetEventsByNames :many
 SELECT
   event_name,
   context,
   event_context,
   workflow_name,
-  event_type
+  event_type,
+  conditions,
+  delay_seconds,
+  jitter_seconds,
+  follow_ups
 FROM
   wf_events
 WHERE
@@ -25,11 +150,15 @@ WHERE
 `
 
 type GetEventsByNamesRow struct {
-	EventName    string
-	Context      sql.NullString
-	EventContext sql.NullString
-	WorkflowName string
-	EventType    string
+	EventName     string
+	Context       sql.NullString
+	EventContext  sql.NullString
+	WorkflowName  string
+	EventType     string
+	Conditions    sql.NullString
+	DelaySeconds  int32
+	JitterSeconds int32
+	FollowUps     sql.NullString
 }
 
 func (q *Queries) GetEventsByNames(ctx context.Context, names []string) ([]GetEventsByNamesRow, error) {
@@ -57,6 +186,10 @@ func (q *Queries) GetEventsByNames(ctx context.Context, names []string) ([]GetEv
 			&i.EventContext,
 			&i.WorkflowName,
 			&i.EventType,
+			&i.Conditions,
+			&i.DelaySeconds,
+			&i.JitterSeconds,
+			&i.FollowUps,
 		); err != nil {
 			return nil, err
 		}
@@ -73,10 +206,15 @@ func (q *Queries) GetEventsByNames(ctx context.Context, names []string) ([]GetEv
 
 const getEventsByType = `-- name: GetEventsByType :many
 SELECT
+  event_name,
   context,
   event_context,
   workflow_name,
-  event_type
+  event_type,
+  conditions,
+  delay_seconds,
+  jitter_seconds,
+  follow_ups
 FROM
   wf_events
 WHERE
@@ -84,10 +222,15 @@ WHERE
 `
 
 type GetEventsByTypeRow struct {
-	Context      sql.NullString
-	EventContext sql.NullString
-	WorkflowName string
-	EventType    string
+	EventName     string
+	Context       sql.NullString
+	EventContext  sql.NullString
+	WorkflowName  string
+	EventType     string
+	Conditions    sql.NullString
+	DelaySeconds  int32
+	JitterSeconds int32
+	FollowUps     sql.NullString
 }
 
 func (q *Queries) GetEventsByType(ctx context.Context, eventType string) ([]GetEventsByTypeRow, error) {
@@ -100,10 +243,15 @@ func (q *Queries) GetEventsByType(ctx context.Context, eventType string) ([]GetE
 	for rows.Next() {
 		var i GetEventsByTypeRow
 		if err := rows.Scan(
+			&i.EventName,
 			&i.Context,
 			&i.EventContext,
 			&i.WorkflowName,
 			&i.EventType,
+			&i.Conditions,
+			&i.DelaySeconds,
+			&i.JitterSeconds,
+			&i.FollowUps,
 		); err != nil {
 			return nil, err
 		}
@@ -126,3 +274,92 @@ func (q *Queries) RemoveEvent(ctx context.Context, eventName string) error {
 	_, err := q.db.ExecContext(ctx, removeEvent, eventName)
 	return err
 }
+
+const updateEventFired = `-- name: UpdateEventFired :exec
+UPDATE
+  wf_events
+SET
+  fired_count = fired_count + 1,
+  last_fired_unix = ?,
+  updated_at = updated_at
+WHERE
+  event_name = ?
+`
+
+type UpdateEventFiredParams struct {
+	LastFiredUnix int64
+	EventName     string
+}
+
+func (q *Queries) UpdateEventFired(ctx context.Context, arg UpdateEventFiredParams) error {
+	_, err := q.db.ExecContext(ctx, updateEventFired, arg.LastFiredUnix, arg.EventName)
+	return err
+}
+
+const updateEventFiredError = `-- name: UpdateEventFiredError :exec
+UPDATE
+  wf_events
+SET
+  fired_count = fired_count + 1,
+  last_fired_unix = ?,
+  error_count = error_count + 1,
+  last_error = ?,
+  updated_at = updated_at
+WHERE
+  event_name = ?
+`
+
+type UpdateEventFiredErrorParams struct {
+	LastFiredUnix int64
+	LastError     sql.NullString
+	EventName     string
+}
+
+func (q *Queries) UpdateEventFiredError(ctx context.Context, arg UpdateEventFiredErrorParams) error {
+	_, err := q.db.ExecContext(ctx, updateEventFiredError, arg.LastFiredUnix, arg.LastError, arg.EventName)
+	return err
+}
+
+const upsertEvent = `-- name: UpsertEvent :exec
+INSERT INTO wf_events
+  (event_name, event_type, workflow_name, event_context, context, conditions, delay_seconds, jitter_seconds, follow_ups)
+VALUES
+  (?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON DUPLICATE KEY
+UPDATE
+  workflow_name = VALUES(workflow_name),
+  event_type = VALUES(event_type),
+  event_context = VALUES(event_context),
+  context = VALUES(context),
+  conditions = VALUES(conditions),
+  delay_seconds = VALUES(delay_seconds),
+  jitter_seconds = VALUES(jitter_seconds),
+  follow_ups = VALUES(follow_ups)
+`
+
+type UpsertEventParams struct {
+	EventName     string
+	EventType     string
+	WorkflowName  string
+	EventContext  sql.NullString
+	Context       sql.NullString
+	Conditions    sql.NullString
+	DelaySeconds  int32
+	JitterSeconds int32
+	FollowUps     sql.NullString
+}
+
+func (q *Queries) UpsertEvent(ctx context.Context, arg UpsertEventParams) error {
+	_, err := q.db.ExecContext(ctx, upsertEvent,
+		arg.EventName,
+		arg.EventType,
+		arg.WorkflowName,
+		arg.EventContext,
+		arg.Context,
+		arg.Conditions,
+		arg.DelaySeconds,
+		arg.JitterSeconds,
+		arg.FollowUps,
+	)
+	return err
+}
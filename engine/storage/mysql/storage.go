@@ -197,10 +197,39 @@ func (s *MySQLStorage) RetrieveOutstandingWorkflowStatus(ctx context.Context, wo
 	})
 	if err != nil {
 		err = fmt.Errorf("getting outstanding ids (%d): %w", len(ids), err)
+		return
+	}
+	timerIDs, err := s.retrieveTimerStepIDs(ctx, workflowName, ids)
+	if err != nil {
+		err = fmt.Errorf("getting timer step ids (%d): %w", len(ids), err)
+		return
+	}
+	outstanding := make(map[string]struct{}, len(outstandingIDs))
+	for _, id := range outstandingIDs {
+		outstanding[id] = struct{}{}
+	}
+	for _, id := range timerIDs {
+		if _, ok := outstanding[id]; !ok {
+			outstandingIDs = append(outstandingIDs, id)
+		}
 	}
 	return
 }
 
+// RetrieveOutstandingInstanceStatus reports whether id has an outstanding step of instanceID.
+// See the storage interface type for further docs.
+func (s *MySQLStorage) RetrieveOutstandingInstanceStatus(ctx context.Context, workflowName, instanceID, id string) (bool, error) {
+	outstanding, err := s.q.GetOutstandingInstance(ctx, sqlc.GetOutstandingInstanceParams{
+		EnrollmentID: id,
+		WorkflowName: workflowName,
+		InstanceID:   instanceID,
+	})
+	if err != nil {
+		return false, fmt.Errorf("getting outstanding instance status: %w", err)
+	}
+	return outstanding.Bool, nil
+}
+
 // CancelSteps cancels workflow steps for id.
 // See the storage interface type for further docs.
 func (s *MySQLStorage) CancelSteps(ctx context.Context, id, workflowName string) error {
@@ -238,6 +267,18 @@ func (s *MySQLStorage) CancelSteps(ctx context.Context, id, workflowName string)
 				return fmt.Errorf("delete workflow step having no commands (%s): %w", workflowName, err)
 			}
 		}
+
+		if workflowName != "" {
+			err = qtx.DeleteTimerStepsByWorkflow(ctx, sqlc.DeleteTimerStepsByWorkflowParams{
+				EnrollmentID: id,
+				WorkflowName: workflowName,
+			})
+		} else {
+			err = qtx.DeleteTimerSteps(ctx, id)
+		}
+		if err != nil {
+			return fmt.Errorf("delete timer steps: %w", err)
+		}
 		return nil
 	})
 }
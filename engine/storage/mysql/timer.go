@@ -0,0 +1,133 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/micromdm/nanocmd/engine/storage"
+	"github.com/micromdm/nanocmd/engine/storage/mysql/sqlc"
+	"github.com/micromdm/nanocmd/workflow"
+)
+
+// unixOrZero returns the Unix time of t or zero if t is zero.
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+// timeOrZero is the inverse of unixOrZero.
+func timeOrZero(sec int64) time.Time {
+	if sec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0)
+}
+
+// newTimerStep creates a timer step from database columns.
+func newTimerStep(enrollmentID, workflowName, instanceID string, stepName, stepContext, eventType sql.NullString, fireAtUnix int64) *storage.TimerStep {
+	ts := &storage.TimerStep{
+		StepContext: storage.StepContext{
+			WorkflowName: workflowName,
+			InstanceID:   instanceID,
+			Name:         stepName.String,
+		},
+		EnrollmentID: enrollmentID,
+		FireAt:       timeOrZero(fireAtUnix),
+	}
+	if stepContext.Valid {
+		ts.Context = []byte(stepContext.String)
+	}
+	if eventType.Valid {
+		ts.EventFlag = workflow.EventFlagForString(eventType.String)
+	}
+	return ts
+}
+
+// StoreTimerStep stores a timer or event step for later callback.
+// See the storage interface type for further docs.
+func (s *MySQLStorage) StoreTimerStep(ctx context.Context, ts *storage.TimerStep) error {
+	if err := ts.Validate(); err != nil {
+		return err
+	}
+	var eventType sql.NullString
+	if ts.EventFlag != 0 {
+		eventType = sqlNullString(ts.EventFlag.String())
+	}
+	return s.q.CreateTimerStep(ctx, sqlc.CreateTimerStepParams{
+		EnrollmentID: ts.EnrollmentID,
+		WorkflowName: ts.WorkflowName,
+		InstanceID:   ts.InstanceID,
+		StepName:     sqlNullString(ts.Name),
+		Context:      sqlNullString(string(ts.Context)),
+		EventType:    eventType,
+		FireAtUnix:   unixOrZero(ts.FireAt),
+	})
+}
+
+// RetrieveDueTimerSteps retrieves and deletes due timer steps.
+// See the storage interface type for further docs.
+func (s *MySQLStorage) RetrieveDueTimerSteps(ctx context.Context, now time.Time) ([]*storage.TimerStep, error) {
+	var ret []*storage.TimerStep
+	err := tx(ctx, s.db, s.q, func(ctx context.Context, _ *sql.Tx, qtx *sqlc.Queries) error {
+		// event steps without a timeout have a zero fire time
+		rows, err := qtx.GetDueTimerStepsAndLock(ctx, now.Unix())
+		if err != nil {
+			return fmt.Errorf("get due timer steps: %w", err)
+		}
+		var ids []int64
+		for _, row := range rows {
+			ret = append(ret, newTimerStep(row.EnrollmentID, row.WorkflowName, row.InstanceID, row.StepName, row.Context, row.EventType, row.FireAtUnix))
+			ids = append(ids, row.ID)
+		}
+		return removeTimerSteps(ctx, qtx, ids)
+	})
+	return ret, err
+}
+
+// RetrieveEventSteps retrieves and deletes event steps waiting for an event.
+// See the storage interface type for further docs.
+func (s *MySQLStorage) RetrieveEventSteps(ctx context.Context, id string, f workflow.EventFlag) ([]*storage.TimerStep, error) {
+	var ret []*storage.TimerStep
+	err := tx(ctx, s.db, s.q, func(ctx context.Context, _ *sql.Tx, qtx *sqlc.Queries) error {
+		rows, err := qtx.GetEventTimerStepsAndLock(ctx, sqlc.GetEventTimerStepsAndLockParams{
+			EnrollmentID: id,
+			EventType:    sqlNullString(f.String()),
+		})
+		if err != nil {
+			return fmt.Errorf("get event timer steps: %w", err)
+		}
+		var ids []int64
+		for _, row := range rows {
+			ret = append(ret, newTimerStep(row.EnrollmentID, row.WorkflowName, row.InstanceID, row.StepName, row.Context, row.EventType, row.FireAtUnix))
+			ids = append(ids, row.ID)
+		}
+		return removeTimerSteps(ctx, qtx, ids)
+	})
+	return ret, err
+}
+
+// removeTimerSteps deletes the timer steps with row ids.
+func removeTimerSteps(ctx context.Context, qtx *sqlc.Queries, ids []int64) error {
+	if len(ids) < 1 {
+		return nil
+	}
+	if err := qtx.RemoveTimerStepsByIDs(ctx, ids); err != nil {
+		return fmt.Errorf("remove timer steps: %w", err)
+	}
+	return nil
+}
+
+// retrieveTimerStepIDs finds enrollment IDs with timer steps of workflowName from ids.
+func (s *MySQLStorage) retrieveTimerStepIDs(ctx context.Context, workflowName string, ids []string) ([]string, error) {
+	if len(ids) < 1 {
+		return nil, nil
+	}
+	return s.q.GetTimerStepIDs(ctx, sqlc.GetTimerStepIDsParams{
+		WorkflowName: workflowName,
+		Ids:          ids,
+	})
+}
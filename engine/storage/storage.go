@@ -133,6 +133,40 @@ func (se *StepEnqueuingWithConfig) Validate() error {
 	return se.StepEnqueueing.Validate()
 }
 
+// TimerStep is a step without MDM commands for a single enrollment.
+// The workflow is called back with the step context when it fires.
+// If EventFlag is set then the step is an event step instead: it waits
+// for that MDM check-in event from the enrollment and FireAt is its
+// (optional) timeout.
+type TimerStep struct {
+	StepContext
+	EnrollmentID string
+	EventFlag    workflow.EventFlag // check-in event to wait for
+	FireAt       time.Time          // when to call back (or time out) the workflow
+}
+
+var ErrMissingFireAt = errors.New("missing fire at time")
+
+// Validate checks ts for issues.
+func (ts *TimerStep) Validate() error {
+	if ts == nil {
+		return ErrEmptyStorageStep
+	}
+	if err := ts.StepContext.Validate(); err != nil {
+		return fmt.Errorf("timer step context invalid: %w", err)
+	}
+	if ts.EnrollmentID == "" {
+		return ErrMissingIDs
+	}
+	if ts.EventFlag != 0 && !ts.EventFlag.Valid() {
+		return fmt.Errorf("invalid event type: %d", ts.EventFlag)
+	}
+	if ts.EventFlag == 0 && ts.FireAt.IsZero() {
+		return ErrMissingFireAt
+	}
+	return nil
+}
+
 // StepResult represent the results of all of a step's MDM commands.
 // An approximately serialized form of a workflow step result.
 type StepResult struct {
@@ -176,18 +210,254 @@ type Storage interface {
 	// implementation may discard the raw command Plist bytes.
 	StoreStep(context.Context, *StepEnqueuingWithConfig, time.Time) error
 
+	// StoreTimerStep stores a timer or event step for later callback.
+	StoreTimerStep(ctx context.Context, ts *TimerStep) error
+
+	// RetrieveEventSteps fetches the event steps of id waiting for event f.
+	//
+	// Any retrieved event step is assumed to be permanently deleted from storage.
+	RetrieveEventSteps(ctx context.Context, id string, f workflow.EventFlag) ([]*TimerStep, error)
+
 	// RetrieveOutstandingWorkflowStates finds enrollment IDs with an outstanding workflow step from a given set.
+	// Timer and event steps that have not yet fired are outstanding steps.
 	RetrieveOutstandingWorkflowStatus(ctx context.Context, workflowName string, ids []string) (outstandingIDs []string, err error)
 
+	// RetrieveOutstandingInstanceStatus reports whether id has an
+	// outstanding step of workflow instance instanceID of workflowName.
+	// Timer and event steps that have not yet fired are outstanding steps.
+	RetrieveOutstandingInstanceStatus(ctx context.Context, workflowName, instanceID, id string) (bool, error)
+
 	// CancelSteps cancels workflow steps for id.
 	// If workflowName is empty then implementations should cancel all
 	// workflow steps for the id. "NotUntil" (future) workflows steps
-	// should also be canceled.
+	// and timer and event steps should also be canceled.
 	CancelSteps(ctx context.Context, id, workflowName string) error
 
+	// StorePendingStart stores a delayed event subscription workflow start.
+	// If a pending start already exists for the same enrollment ID and
+	// event subscription name then ps is discarded.
+	StorePendingStart(ctx context.Context, ps *PendingStart) error
+
+	// CancelPendingStarts deletes all pending workflow starts for id.
+	CancelPendingStarts(ctx context.Context, id string) error
+
+	QueuedStartStorage
+	FollowUpStorage
+	OutcomeStorage
+	ApprovalStorage
 	WorkflowStatusStorage
 }
 
+// ApprovalStatus is the status of an approval request.
+type ApprovalStatus string
+
+const (
+	ApprovalPending  ApprovalStatus = "pending"
+	ApprovalApproved ApprovalStatus = "approved"
+	ApprovalRejected ApprovalStatus = "rejected"
+	ApprovalExpired  ApprovalStatus = "expired"
+)
+
+// Valid returns true if as is a known approval status.
+func (as ApprovalStatus) Valid() bool {
+	switch as {
+	case ApprovalPending, ApprovalApproved, ApprovalRejected, ApprovalExpired:
+		return true
+	}
+	return false
+}
+
+var (
+	// ErrApprovalNotFound is returned when an approval request does not exist.
+	ErrApprovalNotFound = errors.New("approval request not found")
+
+	// ErrApprovalNotPending is returned when deciding an approval
+	// request that has already been decided.
+	ErrApprovalNotPending = errors.New("approval request not pending")
+)
+
+// ApprovalRequest is a workflow start that waits for approval.
+type ApprovalRequest struct {
+	ID           string     `json:"id"`
+	WorkflowName string     `json:"workflow_name"`
+	IDs          []string   `json:"ids"`
+	Context      string     `json:"context,omitempty"`
+	FollowUps    []FollowUp `json:"follow_ups,omitempty"`
+
+	Requester   string    `json:"requester"`
+	RequestedAt time.Time `json:"requested_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+
+	Status    ApprovalStatus `json:"status"`
+	DecidedBy string         `json:"decided_by,omitempty"`
+	DecidedAt *time.Time     `json:"decided_at,omitempty"`
+	Reason    string         `json:"reason,omitempty"`
+
+	// the workflow instance (or error) of the start after approval
+	InstanceID string `json:"instance_id,omitempty"`
+	StartError string `json:"start_error,omitempty"`
+}
+
+// Validate checks ar for errors.
+func (ar *ApprovalRequest) Validate() error {
+	if ar == nil {
+		return errors.New("empty approval request")
+	}
+	if ar.ID == "" {
+		return errors.New("missing approval request id")
+	}
+	if ar.WorkflowName == "" {
+		return ErrMissingWorkflowName
+	}
+	if len(ar.IDs) < 1 {
+		return ErrMissingIDs
+	}
+	if !ar.Status.Valid() {
+		return fmt.Errorf("invalid approval status: %s", ar.Status)
+	}
+	return nil
+}
+
+type ApprovalStorage interface {
+	// StoreApprovalRequest stores an approval request.
+	// Any existing approval request with the same ID is replaced.
+	StoreApprovalRequest(ctx context.Context, ar *ApprovalRequest) error
+
+	// RetrieveApprovalRequest fetches approval request id.
+	// ErrApprovalNotFound is returned if it does not exist.
+	RetrieveApprovalRequest(ctx context.Context, id string) (*ApprovalRequest, error)
+
+	// RetrieveApprovalRequests fetches the approval requests with status.
+	// All approval requests are returned if status is empty. Returned
+	// requests should be sorted by their requested time.
+	RetrieveApprovalRequests(ctx context.Context, status ApprovalStatus) ([]*ApprovalRequest, error)
+
+	// DecideApprovalRequest records the decision of a pending approval request.
+	// The status, decided by, decided at, and reason of ar are stored.
+	// ErrApprovalNotPending is returned if the stored request is not
+	// pending and ErrApprovalNotFound if it does not exist.
+	DecideApprovalRequest(ctx context.Context, ar *ApprovalRequest) error
+}
+
+// InstanceOutcome is the final outcome of a workflow instance for an enrollment.
+type InstanceOutcome struct {
+	InstanceID   string           `json:"instance_id"`
+	EnrollmentID string           `json:"enrollment_id"`
+	WorkflowName string           `json:"workflow_name"`
+	Outcome      workflow.Outcome `json:"outcome"`
+	Message      string           `json:"message,omitempty"`
+	FinishedAt   time.Time        `json:"finished_at"`
+}
+
+type OutcomeStorage interface {
+	// StoreOutcome stores the outcome of a workflow instance for an enrollment.
+	// Any existing outcome for the same instance and enrollment is replaced.
+	StoreOutcome(ctx context.Context, o *InstanceOutcome) error
+
+	// RetrieveOutcomes fetches the outcomes of workflow instance instanceID.
+	// Returned outcomes should be sorted by enrollment ID.
+	RetrieveOutcomes(ctx context.Context, instanceID string) ([]*InstanceOutcome, error)
+}
+
+// FollowUp is a workflow started for an enrollment when a workflow
+// instance finishes for the enrollment.
+type FollowUp struct {
+	// On are the outcomes of the finished workflow instance that start
+	// the follow-up workflow. Any outcome if empty.
+	On       []workflow.Outcome `json:"on,omitempty"`
+	Workflow string             `json:"workflow"`
+	Context  string             `json:"context,omitempty"`
+}
+
+// Validate checks f for errors.
+func (f *FollowUp) Validate() error {
+	if f == nil {
+		return errors.New("empty follow-up")
+	}
+	if f.Workflow == "" {
+		return ErrMissingWorkflowName
+	}
+	for _, o := range f.On {
+		if !o.Valid() {
+			return fmt.Errorf("invalid outcome: %s", o)
+		}
+	}
+	return nil
+}
+
+// Matches returns true if f should be started for outcome o.
+func (f *FollowUp) Matches(o workflow.Outcome) bool {
+	if len(f.On) < 1 {
+		return true
+	}
+	for _, on := range f.On {
+		if on == o {
+			return true
+		}
+	}
+	return false
+}
+
+type FollowUpStorage interface {
+	// StoreFollowUps stores the follow-up workflows of workflow instance instanceID for ids.
+	StoreFollowUps(ctx context.Context, instanceID string, ids []string, followUps []FollowUp) error
+
+	// RetrieveFollowUps fetches the follow-up workflows of workflow instance instanceID for id.
+	// Returned follow-ups should be nil with no error if none are stored.
+	//
+	// Any retrieved follow-ups are assumed to be permanently deleted from storage.
+	RetrieveFollowUps(ctx context.Context, instanceID, id string) ([]FollowUp, error)
+
+	// CancelFollowUps deletes all follow-up workflows for id.
+	CancelFollowUps(ctx context.Context, id string) error
+}
+
+// ErrQueueFull is returned when too many workflow starts are queued.
+var ErrQueueFull = errors.New("workflow start queue full")
+
+type QueuedStartStorage interface {
+	// StoreQueuedStart queues a workflow start for a single enrollment.
+	// If a start with the same context is already queued for the
+	// enrollment and workflow then qs is discarded. If max starts are
+	// already queued then ErrQueueFull is returned.
+	StoreQueuedStart(ctx context.Context, qs *QueuedStart, max int) error
+
+	// RetrieveNextQueuedStart fetches the oldest queued start of workflowName for id.
+	// Returned start should be nil with no error if no starts are queued.
+	//
+	// Any retrieved queued start is assumed to be permanently deleted from storage.
+	RetrieveNextQueuedStart(ctx context.Context, id, workflowName string) (*QueuedStart, error)
+
+	// CancelQueuedStarts deletes all queued workflow starts for id.
+	CancelQueuedStarts(ctx context.Context, id string) error
+}
+
+// PendingStart is a delayed workflow start of an event subscription for a single enrollment.
+type PendingStart struct {
+	EnrollmentID      string             `json:"enrollment_id"`
+	EventSubscription string             `json:"event_subscription"` // name
+	WorkflowName      string             `json:"workflow_name"`
+	Context           []byte             `json:"context,omitempty"`
+	EventFlag         workflow.EventFlag `json:"event_flag"`
+	EventData         []byte             `json:"event_data,omitempty"` // raw plist of the event data
+	Params            map[string]string  `json:"params,omitempty"`     // MDM context params
+	FollowUps         []FollowUp         `json:"follow_ups,omitempty"`
+	StartAt           time.Time          `json:"start_at"`
+}
+
+// QueuedStart is a workflow start for a single enrollment waiting on
+// an already running instance of the same workflow.
+type QueuedStart struct {
+	EnrollmentID string             `json:"enrollment_id"`
+	WorkflowName string             `json:"workflow_name"`
+	Context      []byte             `json:"context,omitempty"`
+	EventFlag    workflow.EventFlag `json:"event_flag"`
+	EventData    []byte             `json:"event_data,omitempty"` // raw plist of the event data
+	Params       map[string]string  `json:"params,omitempty"`     // MDM context params
+	FollowUps    []FollowUp         `json:"follow_ups,omitempty"`
+	QueuedAt     time.Time          `json:"queued_at"`
+}
+
 // WorkerStorage is used by the workflow engine worker for async (scheduled) actions.
 type WorkerStorage interface {
 	// RetrieveStepsToEnqueue fetches steps to be enqueued that were enqueued "later" with NotUntil.
@@ -211,6 +481,17 @@ type WorkerStorage interface {
 	//
 	// Any retrieved IDs are assumed to have neen successfully APNs pushed to and will be marked so at pushTime.
 	RetrieveAndMarkRePushed(ctx context.Context, ifBefore time.Time, pushTime time.Time) ([]string, error)
+
+	// RetrieveDueTimerSteps fetches timer steps due to fire at or before now.
+	// Event steps whose timeout is at or before now are also fetched.
+	//
+	// Any retrieved timer step is assumed to be permanently deleted from storage.
+	RetrieveDueTimerSteps(ctx context.Context, now time.Time) ([]*TimerStep, error)
+
+	// RetrieveDuePendingStarts fetches pending workflow starts due to start at or before now.
+	//
+	// Any retrieved pending start is assumed to be permanently deleted from storage.
+	RetrieveDuePendingStarts(ctx context.Context, now time.Time) ([]*PendingStart, error)
 }
 
 type AllStorage interface {
@@ -225,6 +506,78 @@ type EventSubscription struct {
 	Workflow     string `json:"workflow"`
 	Context      string `json:"context,omitempty"`
 	EventContext string `json:"event_context,omitempty"`
+
+	// Conditions optionally limit which enrollments the event
+	// subscription starts its workflow for.
+	Conditions *EventConditions `json:"conditions,omitempty"`
+
+	// Delay is the number of seconds to wait before starting the workflow.
+	// Jitter is the maximum number of random seconds added to Delay.
+	// If either is set the workflow start is deferred to the engine worker.
+	Delay  int `json:"delay,omitempty"`
+	Jitter int `json:"jitter,omitempty"`
+
+	// FollowUps are workflows started when the started workflow
+	// instance finishes for the enrollment.
+	FollowUps []FollowUp `json:"follow_ups,omitempty"`
+}
+
+// Deferred returns true if workflow starts of es are delayed.
+func (es *EventSubscription) Deferred() bool {
+	return es != nil && (es.Delay > 0 || es.Jitter > 0)
+}
+
+const (
+	EnrollmentTypeDevice = "device"
+	EnrollmentTypeUser   = "user"
+)
+
+// EventConditions are match conditions of an event subscription.
+// All configured conditions must match for the workflow to start.
+type EventConditions struct {
+	// Params must all equal the MDM request URL parameters.
+	Params map[string]string `json:"params,omitempty"`
+
+	// EnrollmentType is the MDM channel: either "device" or "user".
+	EnrollmentType string `json:"enrollment_type,omitempty"`
+
+	// Model and ProductName match any of the values from the
+	// Authenticate message. A trailing "*" matches a prefix.
+	Model       []string `json:"model,omitempty"`
+	ProductName []string `json:"product_name,omitempty"`
+
+	// MinOSVersion and MaxOSVersion are inclusive bounds of the
+	// OSVersion from the Authenticate message.
+	MinOSVersion string `json:"min_os_version,omitempty"`
+	MaxOSVersion string `json:"max_os_version,omitempty"`
+
+	// Inventory predicates must all match the inventory of the enrollment.
+	Inventory []InventoryPredicate `json:"inventory,omitempty"`
+}
+
+// Inventory predicate operators.
+const (
+	OpEqual          = "eq"
+	OpNotEqual       = "ne"
+	OpLessThan       = "lt"
+	OpLessOrEqual    = "le"
+	OpGreaterThan    = "gt"
+	OpGreaterOrEqual = "ge"
+	OpExists         = "exists"
+)
+
+// InventoryPredicate compares an inventory value to Value.
+// The ordering operators compare values as versions.
+type InventoryPredicate struct {
+	Key   string `json:"key"`
+	Op    string `json:"op"`
+	Value string `json:"value,omitempty"`
+}
+
+// AuthenticateConditions returns true if c has any conditions evaluated
+// against the Authenticate message.
+func (c *EventConditions) AuthenticateConditions() bool {
+	return c != nil && (len(c.Model) > 0 || len(c.ProductName) > 0 || c.MinOSVersion != "" || c.MaxOSVersion != "")
 }
 
 var (
@@ -232,6 +585,34 @@ var (
 	ErrMissingEvent           = errors.New("missing event type")
 )
 
+// Validate checks c for invalid conditions. A nil c is valid.
+func (c *EventConditions) Validate() error {
+	if c == nil {
+		return nil
+	}
+	switch c.EnrollmentType {
+	case "", EnrollmentTypeDevice, EnrollmentTypeUser:
+	default:
+		return fmt.Errorf("invalid enrollment type: %s", c.EnrollmentType)
+	}
+	for i, p := range c.Inventory {
+		if p.Key == "" {
+			return fmt.Errorf("inventory predicate %d: missing key", i)
+		}
+		switch p.Op {
+		case OpEqual, OpNotEqual, OpLessThan, OpLessOrEqual, OpGreaterThan, OpGreaterOrEqual, OpExists:
+		default:
+			return fmt.Errorf("inventory predicate %d: invalid op: %s", i, p.Op)
+		}
+	}
+	return nil
+}
+
+// MaxEventDelay is the maximum delay and the maximum jitter, in seconds,
+// of an event subscription.
+const MaxEventDelay = 30 * 24 * 60 * 60 // 30 days
+
+// Validate checks es for missing or invalid fields.
 func (es *EventSubscription) Validate() error {
 	if es == nil {
 		return ErrEmptyEventSubscription
@@ -245,18 +626,63 @@ func (es *EventSubscription) Validate() error {
 	if es.Workflow == "" {
 		return ErrMissingWorkflowName
 	}
+	if es.Delay < 0 || es.Jitter < 0 {
+		return errors.New("negative delay or jitter")
+	}
+	if es.Delay > MaxEventDelay || es.Jitter > MaxEventDelay {
+		return fmt.Errorf("delay or jitter greater than %d seconds", MaxEventDelay)
+	}
+	if err := es.Conditions.Validate(); err != nil {
+		return fmt.Errorf("conditions: %w", err)
+	}
+	for i := range es.FollowUps {
+		if err := es.FollowUps[i].Validate(); err != nil {
+			return fmt.Errorf("follow-up %d: %w", i, err)
+		}
+	}
 	return nil
 }
 
 // ReadEventSubscriptionStorage describes storage backends that can retrieve and query event subscriptions.
 type ReadEventSubscriptionStorage interface {
 	RetrieveEventSubscriptions(ctx context.Context, names []string) (map[string]*EventSubscription, error)
-	RetrieveEventSubscriptionsByEvent(ctx context.Context, f workflow.EventFlag) ([]*EventSubscription, error)
+	// RetrieveEventSubscriptionsByEvent retrieves event subscriptions
+	// for event f keyed by event subscription name.
+	RetrieveEventSubscriptionsByEvent(ctx context.Context, f workflow.EventFlag) (map[string]*EventSubscription, error)
+	// RetrieveAllEventSubscriptions retrieves all event subscriptions keyed by name.
+	RetrieveAllEventSubscriptions(ctx context.Context) (map[string]*EventSubscription, error)
+}
+
+// EventSubscriptionStats are the run statistics of an event subscription.
+type EventSubscriptionStats struct {
+	// Count is the number of times the subscription fired.
+	// That is: attempted to start its workflow.
+	Count     int64     `json:"count"`
+	LastFired time.Time `json:"last_fired"`
+	// ErrorCount is the number of times starting the workflow failed.
+	ErrorCount int64  `json:"error_count,omitempty"`
+	LastError  string `json:"last_error,omitempty"`
+}
+
+// EventSubscriptionStatsStorage describes storage backends that keep event subscription run statistics.
+type EventSubscriptionStatsStorage interface {
+	// RecordEventSubscriptionFired updates the statistics of the named
+	// event subscription for a workflow start at firedAt.
+	// A non-nil startErr records a failed workflow start.
+	// Nothing is recorded for an event subscription that does not exist
+	// (e.g. one deleted while it fired).
+	RecordEventSubscriptionFired(ctx context.Context, name string, firedAt time.Time, startErr error) error
+
+	// RetrieveEventSubscriptionStats retrieves the statistics of the named event subscriptions.
+	// Event subscriptions that have never fired are not included.
+	RetrieveEventSubscriptionStats(ctx context.Context, names []string) (map[string]*EventSubscriptionStats, error)
 }
 
 // EventSubscriptionStorage describes storage backends that can also write and delete event subscriptions.
+// Deleting an event subscription also deletes its statistics.
 type EventSubscriptionStorage interface {
 	ReadEventSubscriptionStorage
+	EventSubscriptionStatsStorage
 	StoreEventSubscription(ctx context.Context, name string, es *EventSubscription) error
 	DeleteEventSubscription(ctx context.Context, name string) error
 }
@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEventSubscriptionValidateDelay(t *testing.T) {
+	for _, tc := range []struct {
+		delay, jitter int
+		valid         bool
+	}{
+		{0, 0, true},
+		{300, 60, true},
+		{MaxEventDelay, MaxEventDelay, true},
+		{-1, 0, false},
+		{0, -1, false},
+		{MaxEventDelay + 1, 0, false},
+		{0, MaxEventDelay + 1, false},
+		{math.MaxInt, 0, false},
+		{0, math.MaxInt, false},
+	} {
+		es := &EventSubscription{
+			Event:    "Enrollment",
+			Workflow: "wf",
+			Delay:    tc.delay,
+			Jitter:   tc.jitter,
+		}
+		if err := es.Validate(); (err == nil) != tc.valid {
+			t.Errorf("delay=%d jitter=%d: have: %v, want valid: %v", tc.delay, tc.jitter, err, tc.valid)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/micromdm/nanocmd/engine/storage"
+	"github.com/micromdm/nanocmd/workflow"
+)
+
+func testApprovals(t *testing.T, s storage.AllStorage) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+
+	_, err := s.RetrieveApprovalRequest(ctx, "A0")
+	if !errors.Is(err, storage.ErrApprovalNotFound) {
+		t.Errorf("have: %v, want: %v", err, storage.ErrApprovalNotFound)
+	}
+
+	if err = s.StoreApprovalRequest(ctx, &storage.ApprovalRequest{ID: "A0", WorkflowName: "wf", Status: storage.ApprovalPending}); err == nil {
+		t.Error("expected error for missing ids")
+	}
+
+	ar1 := &storage.ApprovalRequest{
+		ID:           "A1",
+		WorkflowName: "wf",
+		IDs:          []string{"EnrollmentID-A1", "EnrollmentID-A2"},
+		Context:      "ctx",
+		FollowUps:    []storage.FollowUp{{On: []workflow.Outcome{workflow.OutcomeSucceeded}, Workflow: "wf2"}},
+		Requester:    "alice",
+		RequestedAt:  now,
+		ExpiresAt:    now.Add(time.Hour),
+		Status:       storage.ApprovalPending,
+	}
+	ar2 := &storage.ApprovalRequest{
+		ID:           "A2",
+		WorkflowName: "wf",
+		IDs:          []string{"EnrollmentID-A3"},
+		Requester:    "alice",
+		RequestedAt:  now.Add(time.Second),
+		ExpiresAt:    now.Add(time.Hour),
+		Status:       storage.ApprovalPending,
+	}
+	for _, ar := range []*storage.ApprovalRequest{ar2, ar1} {
+		if err = s.StoreApprovalRequest(ctx, ar); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ar, err := s.RetrieveApprovalRequest(ctx, "A1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := ar.IDs, ar1.IDs; !reflect.DeepEqual(have, want) {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	if have, want := ar.FollowUps, ar1.FollowUps; !reflect.DeepEqual(have, want) {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	if have, want := ar.Context, "ctx"; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	if !ar.RequestedAt.Equal(ar1.RequestedAt) || !ar.ExpiresAt.Equal(ar1.ExpiresAt) {
+		t.Errorf("have: %v %v, want: %v %v", ar.RequestedAt, ar.ExpiresAt, ar1.RequestedAt, ar1.ExpiresAt)
+	}
+
+	if have, want := ar.Status, storage.ApprovalPending; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	decidedAt := now.Add(time.Minute)
+	decision := &storage.ApprovalRequest{
+		ID:        "A1",
+		Status:    storage.ApprovalApproved,
+		DecidedBy: "bob",
+		DecidedAt: &decidedAt,
+		Reason:    "ok",
+	}
+	if err = s.DecideApprovalRequest(ctx, decision); err != nil {
+		t.Fatal(err)
+	}
+
+	// only pending requests can be decided
+	if err = s.DecideApprovalRequest(ctx, decision); !errors.Is(err, storage.ErrApprovalNotPending) {
+		t.Errorf("have: %v, want: %v", err, storage.ErrApprovalNotPending)
+	}
+
+	decision.ID = "A0"
+	if err = s.DecideApprovalRequest(ctx, decision); !errors.Is(err, storage.ErrApprovalNotFound) {
+		t.Errorf("have: %v, want: %v", err, storage.ErrApprovalNotFound)
+	}
+
+	ar, err = s.RetrieveApprovalRequest(ctx, "A1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := ar.Status, storage.ApprovalApproved; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	if have, want := ar.DecidedBy, "bob"; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	if ar.DecidedAt == nil || !ar.DecidedAt.Equal(decidedAt) {
+		t.Errorf("have: %v, want: %v", ar.DecidedAt, decidedAt)
+	}
+
+	// the requester is unchanged by the decision
+	if have, want := ar.Requester, "alice"; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	// storing replaces the request
+	ar.InstanceID = "I1"
+	if err = s.StoreApprovalRequest(ctx, ar); err != nil {
+		t.Fatal(err)
+	}
+
+	ars, err := s.RetrieveApprovalRequests(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(ars), 2; have != want {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+
+	// sorted by requested time
+	if have, want := ars[0].ID, "A1"; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	if have, want := ars[0].InstanceID, "I1"; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	ars, err = s.RetrieveApprovalRequests(ctx, storage.ApprovalPending)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(ars), 1; have != want {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+
+	if have, want := ars[0].ID, "A2"; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+}
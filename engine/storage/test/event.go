@@ -2,7 +2,10 @@ package test
 
 import (
 	"context"
+	"errors"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/micromdm/nanocmd/engine/storage"
 	"github.com/micromdm/nanocmd/workflow"
@@ -24,6 +27,15 @@ func TestEventStorage(t *testing.T, store storage.EventSubscriptionStorage) {
 		Workflow:     "wf",
 		Context:      "ctx",
 		EventContext: "evCtx",
+		Conditions: &storage.EventConditions{
+			Params:         map[string]string{"site": "hq"},
+			EnrollmentType: storage.EnrollmentTypeDevice,
+			Inventory: []storage.InventoryPredicate{
+				{Key: "os_version", Op: storage.OpGreaterOrEqual, Value: "14.0"},
+			},
+		},
+		Delay:  300,
+		Jitter: 60,
 	}
 
 	testEventData := func(t *testing.T, es *storage.EventSubscription) {
@@ -51,6 +63,18 @@ func TestEventStorage(t *testing.T, store storage.EventSubscriptionStorage) {
 		if have, want := es.EventContext, evTest.EventContext; have != want {
 			t.Errorf("[context] have: %v, want: %v", have, want)
 		}
+
+		if have, want := es.Conditions, evTest.Conditions; !reflect.DeepEqual(have, want) {
+			t.Errorf("[conditions] have: %v, want: %v", have, want)
+		}
+
+		if have, want := es.Delay, evTest.Delay; have != want {
+			t.Errorf("[delay] have: %v, want: %v", have, want)
+		}
+
+		if have, want := es.Jitter, evTest.Jitter; have != want {
+			t.Errorf("[jitter] have: %v, want: %v", have, want)
+		}
 	}
 
 	t.Run("testdata", func(t *testing.T) {
@@ -85,6 +109,91 @@ func TestEventStorage(t *testing.T, store storage.EventSubscriptionStorage) {
 	}
 
 	t.Run("retrieve-by-event", func(t *testing.T) {
-		testEventData(t, eventsList[0])
+		testEventData(t, eventsList["test"])
+	})
+
+	events, err = store.RetrieveAllEventSubscriptions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("retrieve-all", func(t *testing.T) {
+		testEventData(t, events["test"])
+	})
+
+	stats, err := store.RetrieveEventSubscriptionStats(ctx, []string{"test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(stats), 0; have != want {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+
+	firedAt := time.Now().Truncate(time.Second)
+	if err = store.RecordEventSubscriptionFired(ctx, "test", firedAt.Add(-time.Minute), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.RecordEventSubscriptionFired(ctx, "test", firedAt, errors.New("test error")); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err = store.RetrieveEventSubscriptionStats(ctx, []string{"test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("stats", func(t *testing.T) {
+		if stats["test"] == nil {
+			t.Fatal("nil event subscription stats")
+		}
+		if have, want := stats["test"].Count, int64(2); have != want {
+			t.Errorf("[count] have: %v, want: %v", have, want)
+		}
+		if have, want := stats["test"].LastFired, firedAt; !have.Equal(want) {
+			t.Errorf("[last fired] have: %v, want: %v", have, want)
+		}
+		if have, want := stats["test"].ErrorCount, int64(1); have != want {
+			t.Errorf("[error count] have: %v, want: %v", have, want)
+		}
+		if have, want := stats["test"].LastError, "test error"; have != want {
+			t.Errorf("[last error] have: %v, want: %v", have, want)
+		}
 	})
+
+	if err = store.DeleteEventSubscription(ctx, "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err = store.RetrieveAllEventSubscriptions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := events["test"]; ok {
+		t.Error("expected deleted event subscription")
+	}
+
+	stats, err = store.RetrieveEventSubscriptionStats(ctx, []string{"test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(stats), 0; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	// firing after deletion should not recreate the statistics
+	if err = store.RecordEventSubscriptionFired(ctx, "test", firedAt, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err = store.RetrieveEventSubscriptionStats(ctx, []string{"test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(stats), 0; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
 }
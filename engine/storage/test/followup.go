@@ -0,0 +1,65 @@
+package test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/micromdm/nanocmd/engine/storage"
+	"github.com/micromdm/nanocmd/workflow"
+)
+
+func testFollowUps(t *testing.T, s storage.AllStorage) {
+	ctx := context.Background()
+
+	followUps := []storage.FollowUp{
+		{On: []workflow.Outcome{workflow.OutcomeSucceeded}, Workflow: "wf.next", Context: "ctx1"},
+		{Workflow: "wf.always"},
+	}
+
+	if err := s.StoreFollowUps(ctx, "I1", []string{"EnrollmentID-F1", "EnrollmentID-F2"}, followUps); err != nil {
+		t.Fatal(err)
+	}
+
+	fu, err := s.RetrieveFollowUps(ctx, "I1", "EnrollmentID-F1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := fu, followUps; !reflect.DeepEqual(have, want) {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	// retrieval deletes the follow-ups
+	fu, err = s.RetrieveFollowUps(ctx, "I1", "EnrollmentID-F1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fu != nil {
+		t.Errorf("expected nil follow-ups, have: %v", fu)
+	}
+
+	// unknown instance
+	fu, err = s.RetrieveFollowUps(ctx, "I2", "EnrollmentID-F2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fu != nil {
+		t.Errorf("expected nil follow-ups, have: %v", fu)
+	}
+
+	if err = s.CancelFollowUps(ctx, "EnrollmentID-F2"); err != nil {
+		t.Fatal(err)
+	}
+
+	fu, err = s.RetrieveFollowUps(ctx, "I1", "EnrollmentID-F2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fu != nil {
+		t.Errorf("expected nil follow-ups after cancel, have: %v", fu)
+	}
+}
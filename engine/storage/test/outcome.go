@@ -0,0 +1,65 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/micromdm/nanocmd/engine/storage"
+	"github.com/micromdm/nanocmd/workflow"
+)
+
+func testOutcomes(t *testing.T, s storage.AllStorage) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+
+	for _, o := range []*storage.InstanceOutcome{
+		{InstanceID: "I1", EnrollmentID: "EnrollmentID-O2", WorkflowName: "wf", Outcome: workflow.OutcomeSucceeded, FinishedAt: now},
+		{InstanceID: "I1", EnrollmentID: "EnrollmentID-O1", WorkflowName: "wf", Outcome: workflow.OutcomeSucceeded, FinishedAt: now},
+		// replaces the previous outcome
+		{InstanceID: "I1", EnrollmentID: "EnrollmentID-O1", WorkflowName: "wf", Outcome: workflow.OutcomeFailed, Message: "failed", FinishedAt: now},
+		{InstanceID: "I2", EnrollmentID: "EnrollmentID-O1", WorkflowName: "wf", Outcome: workflow.OutcomeTimedOut, FinishedAt: now},
+	} {
+		if err := s.StoreOutcome(ctx, o); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := s.StoreOutcome(ctx, &storage.InstanceOutcome{EnrollmentID: "EnrollmentID-O1"}); err == nil {
+		t.Error("expected error for missing instance ID")
+	}
+
+	outcomes, err := s.RetrieveOutcomes(ctx, "I1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(outcomes), 2; have != want {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+
+	if have, want := outcomes[0].EnrollmentID, "EnrollmentID-O1"; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	if have, want := outcomes[0].Outcome, workflow.OutcomeFailed; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	if have, want := outcomes[0].Message, "failed"; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	if !outcomes[0].FinishedAt.Equal(now) {
+		t.Errorf("have: %v, want: %v", outcomes[0].FinishedAt, now)
+	}
+
+	outcomes, err = s.RetrieveOutcomes(ctx, "I3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(outcomes) != 0 {
+		t.Errorf("expected no outcomes, have: %d", len(outcomes))
+	}
+}
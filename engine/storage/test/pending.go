@@ -0,0 +1,84 @@
+package test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/micromdm/nanocmd/engine/storage"
+	"github.com/micromdm/nanocmd/workflow"
+)
+
+func testPendingStarts(t *testing.T, s storage.AllStorage) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+
+	psTest := &storage.PendingStart{
+		EnrollmentID:      "EnrollmentID-P1",
+		EventSubscription: "enroll",
+		WorkflowName:      "wf",
+		Context:           []byte("ctx"),
+		EventFlag:         workflow.EventEnrollment,
+		EventData:         []byte("<plist/>"),
+		Params:            map[string]string{"site": "hq"},
+		StartAt:           now.Add(-time.Minute),
+	}
+
+	for _, ps := range []*storage.PendingStart{
+		psTest,
+		// same enrollment and event subscription: should be discarded
+		{EnrollmentID: "EnrollmentID-P1", EventSubscription: "enroll", WorkflowName: "wf2", StartAt: now.Add(time.Hour)},
+		{EnrollmentID: "EnrollmentID-P2", EventSubscription: "enroll", WorkflowName: "wf", StartAt: now.Add(time.Hour)},
+		{EnrollmentID: "EnrollmentID-P3", EventSubscription: "enroll", WorkflowName: "wf", StartAt: now.Add(-time.Minute)},
+	} {
+		if err := s.StorePendingStart(ctx, ps); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := s.CancelPendingStarts(ctx, "EnrollmentID-P3"); err != nil {
+		t.Fatal(err)
+	}
+
+	starts, err := s.RetrieveDuePendingStarts(ctx, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(starts), 1; have != want {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+
+	if have, want := starts[0], psTest; !have.StartAt.Equal(want.StartAt) {
+		t.Errorf("[start at] have: %v, want: %v", have.StartAt, want.StartAt)
+	} else {
+		have.StartAt = want.StartAt
+		if !reflect.DeepEqual(have, want) {
+			t.Errorf("have: %v, want: %v", have, want)
+		}
+	}
+
+	// retrieved pending starts are deleted
+	starts, err = s.RetrieveDuePendingStarts(ctx, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(starts), 0; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	starts, err = s.RetrieveDuePendingStarts(ctx, now.Add(2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(starts), 1; have != want {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+
+	if have, want := starts[0].EnrollmentID, "EnrollmentID-P2"; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+}
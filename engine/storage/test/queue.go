@@ -0,0 +1,114 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/micromdm/nanocmd/engine/storage"
+	"github.com/micromdm/nanocmd/workflow"
+)
+
+func testQueuedStarts(t *testing.T, s storage.AllStorage) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+
+	qsTest := &storage.QueuedStart{
+		EnrollmentID: "EnrollmentID-Q1",
+		WorkflowName: "wf",
+		Context:      []byte("ctx1"),
+		EventFlag:    workflow.EventEnrollment,
+		EventData:    []byte("<plist/>"),
+		Params:       map[string]string{"site": "hq"},
+		FollowUps:    []storage.FollowUp{{Workflow: "wf.next"}},
+		QueuedAt:     now,
+	}
+
+	for _, qs := range []*storage.QueuedStart{
+		qsTest,
+		// same context: should be coalesced
+		{EnrollmentID: "EnrollmentID-Q1", WorkflowName: "wf", Context: []byte("ctx1"), QueuedAt: now},
+		{EnrollmentID: "EnrollmentID-Q1", WorkflowName: "wf", Context: []byte("ctx2"), QueuedAt: now.Add(time.Second)},
+		// different workflow
+		{EnrollmentID: "EnrollmentID-Q1", WorkflowName: "wf2", QueuedAt: now},
+		{EnrollmentID: "EnrollmentID-Q2", WorkflowName: "wf", QueuedAt: now},
+	} {
+		if err := s.StoreQueuedStart(ctx, qs, 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := s.StoreQueuedStart(ctx, &storage.QueuedStart{EnrollmentID: "EnrollmentID-Q1", WorkflowName: "wf", Context: []byte("ctx3"), QueuedAt: now}, 2)
+	if !errors.Is(err, storage.ErrQueueFull) {
+		t.Errorf("have: %v, want: %v", err, storage.ErrQueueFull)
+	}
+
+	// a coalesced start does not fill the queue
+	if err = s.StoreQueuedStart(ctx, &storage.QueuedStart{EnrollmentID: "EnrollmentID-Q1", WorkflowName: "wf", Context: []byte("ctx2"), QueuedAt: now}, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	qs, err := s.RetrieveNextQueuedStart(ctx, "EnrollmentID-Q1", "wf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if qs == nil {
+		t.Fatal("nil queued start")
+	}
+
+	if have, want := qs.QueuedAt.IsZero(), false; have != want {
+		t.Errorf("[queued at zero] have: %v, want: %v", have, want)
+	}
+	qs.QueuedAt = qsTest.QueuedAt
+	if have, want := qs, qsTest; !reflect.DeepEqual(have, want) {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	qs, err = s.RetrieveNextQueuedStart(ctx, "EnrollmentID-Q1", "wf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if qs == nil {
+		t.Fatal("nil queued start")
+	}
+
+	if have, want := string(qs.Context), "ctx2"; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	// retrieved queued starts are deleted
+	qs, err = s.RetrieveNextQueuedStart(ctx, "EnrollmentID-Q1", "wf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if qs != nil {
+		t.Errorf("expected nil queued start, have: %v", qs)
+	}
+
+	if err = s.CancelQueuedStarts(ctx, "EnrollmentID-Q1"); err != nil {
+		t.Fatal(err)
+	}
+
+	qs, err = s.RetrieveNextQueuedStart(ctx, "EnrollmentID-Q1", "wf2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if qs != nil {
+		t.Errorf("expected nil queued start, have: %v", qs)
+	}
+
+	qs, err = s.RetrieveNextQueuedStart(ctx, "EnrollmentID-Q2", "wf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if qs == nil {
+		t.Error("nil queued start")
+	}
+}
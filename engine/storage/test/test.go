@@ -43,6 +43,30 @@ func TestEngineStorage(t *testing.T, newStorage func() storage.AllStorage) {
 		TestEventStorage(t, s)
 	})
 
+	t.Run("testPendingStarts", func(t *testing.T) {
+		testPendingStarts(t, newStorage())
+	})
+
+	t.Run("testQueuedStarts", func(t *testing.T) {
+		testQueuedStarts(t, newStorage())
+	})
+
+	t.Run("testFollowUps", func(t *testing.T) {
+		testFollowUps(t, newStorage())
+	})
+
+	t.Run("testOutcomes", func(t *testing.T) {
+		testOutcomes(t, newStorage())
+	})
+
+	t.Run("testTimerSteps", func(t *testing.T) {
+		testTimerSteps(t, newStorage())
+	})
+
+	t.Run("testApprovals", func(t *testing.T) {
+		testApprovals(t, newStorage())
+	})
+
 	ctx := context.Background()
 
 	t.Run("testEventStatus", func(t *testing.T) {
@@ -407,6 +431,16 @@ func testOutstanding(t *testing.T, s storage.AllStorage) {
 		t.Errorf("have: %v, want: %v: %v", have, want, outstandingIDs)
 	}
 
+	for _, instanceID := range []string{enq.InstanceID, "InstanceID-other"} {
+		outstanding, err := s.RetrieveOutstandingInstanceStatus(ctx, enq.WorkflowName, instanceID, enq.IDs[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have, want := outstanding, instanceID == enq.InstanceID; have != want {
+			t.Errorf("instance %s: have: %v, want: %v", instanceID, have, want)
+		}
+	}
+
 	// complete one of the commands
 	_, err = s.StoreCommandResponseAndRetrieveCompletedStep(ctx, enq.IDs[0], &storage.StepCommandResult{
 		CommandUUID:  "UUID-1",
@@ -427,6 +461,14 @@ func testOutstanding(t *testing.T, s storage.AllStorage) {
 		t.Fatalf("have: %v, want: %v: %v", have, want, outstandingIDs)
 	}
 
+	outstanding, err := s.RetrieveOutstandingInstanceStatus(ctx, enq.WorkflowName, enq.InstanceID, enq.IDs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outstanding {
+		t.Error("expected completed instance to not be outstanding")
+	}
+
 	err = s.CancelSteps(ctx, outstandingIDs[0], "invalid.workflow.name")
 	if err != nil {
 		t.Fatal(err)
@@ -0,0 +1,183 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/micromdm/nanocmd/engine/storage"
+	"github.com/micromdm/nanocmd/workflow"
+)
+
+func testTimerSteps(t *testing.T, s storage.AllStorage) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+
+	for _, ts := range []*storage.TimerStep{
+		{StepContext: storage.StepContext{WorkflowName: "wf", InstanceID: "I1", Name: "wait", Context: []byte("ctx")}, EnrollmentID: "EnrollmentID-T1", FireAt: now},
+		{StepContext: storage.StepContext{WorkflowName: "wf", InstanceID: "I2"}, EnrollmentID: "EnrollmentID-T2", FireAt: now.Add(time.Hour)},
+		{StepContext: storage.StepContext{WorkflowName: "wf2", InstanceID: "I3"}, EnrollmentID: "EnrollmentID-T3", FireAt: now.Add(time.Hour)},
+	} {
+		if err := s.StoreTimerStep(ctx, ts); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := s.StoreTimerStep(ctx, &storage.TimerStep{StepContext: storage.StepContext{WorkflowName: "wf", InstanceID: "I4"}, EnrollmentID: "EnrollmentID-T4"}); err == nil {
+		t.Error("expected error for missing fire at time")
+	}
+
+	// timer steps are outstanding steps
+	ids, err := s.RetrieveOutstandingWorkflowStatus(ctx, "wf", []string{"EnrollmentID-T1", "EnrollmentID-T2", "EnrollmentID-T3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(ids), 2; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	steps, err := s.RetrieveDueTimerSteps(ctx, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(steps), 1; have != want {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+
+	if have, want := steps[0].EnrollmentID, "EnrollmentID-T1"; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	if have, want := steps[0].Name, "wait"; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	if have, want := string(steps[0].Context), "ctx"; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	if !steps[0].FireAt.Equal(now) {
+		t.Errorf("have: %v, want: %v", steps[0].FireAt, now)
+	}
+
+	// retrieval deletes the timer steps
+	steps, err = s.RetrieveDueTimerSteps(ctx, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(steps) != 0 {
+		t.Errorf("expected no timer steps, have: %d", len(steps))
+	}
+
+	if err = s.CancelSteps(ctx, "EnrollmentID-T2", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = s.CancelSteps(ctx, "EnrollmentID-T3", "wf"); err != nil {
+		t.Fatal(err)
+	}
+
+	steps, err = s.RetrieveDueTimerSteps(ctx, now.Add(2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only the timer step of the other workflow remains
+	if have, want := len(steps), 1; have != want {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+
+	if have, want := steps[0].EnrollmentID, "EnrollmentID-T3"; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	testEventSteps(t, s)
+}
+
+func testEventSteps(t *testing.T, s storage.AllStorage) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+
+	if err := s.StoreTimerStep(ctx, &storage.TimerStep{StepContext: storage.StepContext{WorkflowName: "wf", InstanceID: "I5"}, EnrollmentID: "EnrollmentID-E1", EventFlag: 1 << 10}); err == nil {
+		t.Error("expected error for invalid event flag")
+	}
+
+	for _, ts := range []*storage.TimerStep{
+		// an event step without a timeout
+		{StepContext: storage.StepContext{WorkflowName: "wf", InstanceID: "I5", Name: "wait", Context: []byte("ctx")}, EnrollmentID: "EnrollmentID-E1", EventFlag: workflow.EventTokenUpdate},
+		// an event step with a timeout
+		{StepContext: storage.StepContext{WorkflowName: "wf", InstanceID: "I6"}, EnrollmentID: "EnrollmentID-E2", EventFlag: workflow.EventAuthenticate, FireAt: now},
+	} {
+		if err := s.StoreTimerStep(ctx, ts); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// event steps are outstanding steps
+	ids, err := s.RetrieveOutstandingWorkflowStatus(ctx, "wf", []string{"EnrollmentID-E1", "EnrollmentID-E2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(ids), 2; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	// only the event step with a timeout is due
+	steps, err := s.RetrieveDueTimerSteps(ctx, now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(steps), 1; have != want {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+
+	if have, want := steps[0].EventFlag, workflow.EventAuthenticate; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	// a different event does not match
+	steps, err = s.RetrieveEventSteps(ctx, "EnrollmentID-E1", workflow.EventEnrollment)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(steps) != 0 {
+		t.Errorf("expected no event steps, have: %d", len(steps))
+	}
+
+	steps, err = s.RetrieveEventSteps(ctx, "EnrollmentID-E1", workflow.EventTokenUpdate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(steps), 1; have != want {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+
+	if have, want := steps[0].Name, "wait"; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	if have, want := string(steps[0].Context), "ctx"; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	if !steps[0].FireAt.IsZero() {
+		t.Errorf("expected zero fire at, have: %v", steps[0].FireAt)
+	}
+
+	// retrieval deletes the event steps
+	steps, err = s.RetrieveEventSteps(ctx, "EnrollmentID-E1", workflow.EventTokenUpdate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(steps) != 0 {
+		t.Errorf("expected no event steps, have: %d", len(steps))
+	}
+}
@@ -0,0 +1,198 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/micromdm/nanocmd/engine/storage"
+	"github.com/micromdm/nanocmd/logkeys"
+	"github.com/micromdm/nanocmd/workflow"
+
+	"github.com/micromdm/nanolib/log"
+	"github.com/micromdm/nanolib/log/ctxlog"
+)
+
+// ErrInvalidWaitEvent is returned when an event step waits for an unsupported event.
+var ErrInvalidWaitEvent = errors.New("invalid wait event")
+
+// timerStep returns true if se is a timer step.
+// That is a step without commands that is delayed with NotUntil.
+func timerStep(se *workflow.StepEnqueueing) bool {
+	return se != nil && len(se.Commands) < 1 && se.WaitEvent == 0 && !se.NotUntil.IsZero()
+}
+
+// eventStep returns true if se is an event step.
+// That is a step without commands that waits for a check-in event.
+func eventStep(se *workflow.StepEnqueueing) bool {
+	return se != nil && len(se.Commands) < 1 && se.WaitEvent != 0
+}
+
+// waitableEvent returns true if event steps can wait for f.
+// These are the events of MDM check-in messages.
+func waitableEvent(f workflow.EventFlag) bool {
+	switch f {
+	case workflow.EventAuthenticate, workflow.EventTokenUpdate, workflow.EventEnrollment, workflow.EventCheckOut:
+		return true
+	}
+	return false
+}
+
+// storeTimerSteps stores a timer or event step of se for each of its enrollment IDs.
+func (e *Engine) storeTimerSteps(ctx context.Context, n workflow.Namer, se *workflow.StepEnqueueing) error {
+	if len(se.IDs) < 1 {
+		return storage.ErrMissingIDs
+	}
+	fireAt := se.NotUntil
+	if eventStep(se) {
+		if !waitableEvent(se.WaitEvent) {
+			return fmt.Errorf("%w: %s", ErrInvalidWaitEvent, se.WaitEvent)
+		}
+		// event steps fire (time out) at their optional timeout
+		fireAt = se.Timeout
+	}
+	var rawContext []byte
+	if se.Context != nil {
+		var err error
+		if rawContext, err = se.Context.MarshalBinary(); err != nil {
+			return fmt.Errorf("marshal context: %w", err)
+		}
+	}
+	for _, id := range se.IDs {
+		ts := &storage.TimerStep{
+			StepContext: storage.StepContext{
+				WorkflowName: n.Name(),
+				InstanceID:   se.InstanceID,
+				Name:         se.Name,
+				Context:      rawContext,
+			},
+			EnrollmentID: id,
+			EventFlag:    se.WaitEvent,
+			FireAt:       fireAt,
+		}
+		if err := e.storage.StoreTimerStep(ctx, ts); err != nil {
+			return fmt.Errorf("storing timer step for %s: %w", id, err)
+		}
+	}
+	ctxlog.Logger(ctx, e.logger).Debug(
+		logkeys.Message, "stored timer step",
+		logkeys.Event, se.WaitEvent,
+		logkeys.InstanceID, se.InstanceID,
+		logkeys.WorkflowName, n.Name(),
+		logkeys.StepName, se.Name,
+		logkeys.FirstEnrollmentID, se.IDs[0],
+		logkeys.GenericCount, len(se.IDs),
+		"fire_at", fireAt,
+	)
+	return nil
+}
+
+// processTimerSteps calls back workflows with their due timer steps.
+// Due event steps are delivered to their workflows as timed out.
+func (w *Worker) processTimerSteps(ctx context.Context) error {
+	steps, err := w.storage.RetrieveDueTimerSteps(ctx, time.Now())
+	if err != nil {
+		return fmt.Errorf("retrieving due timer steps: %w", err)
+	}
+
+	finisher := w.finisher // w is shadowed by the workflow below
+	for _, step := range steps {
+		stepLogger := w.logger.With(
+			logkeys.Message, "timer step",
+			logkeys.InstanceID, step.InstanceID,
+			logkeys.WorkflowName, step.WorkflowName,
+			logkeys.StepName, step.Name,
+			logkeys.EnrollmentID, step.EnrollmentID,
+		)
+		w := w.wff.Workflow(step.WorkflowName)
+		if w == nil {
+			stepLogger.Info(logkeys.Error, NewErrNoSuchWorkflow(step.WorkflowName))
+			continue
+		}
+
+		stepResult, err := workflowStepResultFromStorageTimerStep(step, w)
+		if err != nil {
+			stepLogger.Info(logkeys.Error, err)
+			continue
+		}
+
+		timedOut := step.EventFlag != 0
+		if timedOut {
+			// the event step did not see its event in time
+			stepLogger = stepLogger.With(logkeys.Event, step.EventFlag)
+			err = w.StepTimeout(ctx, stepResult)
+		} else {
+			// call back the workflow as a completed step without command results
+			err = w.StepCompleted(ctx, stepResult)
+		}
+		if err != nil {
+			stepLogger.Info(logkeys.Error, err, "timed_out", timedOut)
+		} else {
+			stepLogger.Debug("timed_out", timedOut)
+		}
+
+		if finisher != nil {
+			finisher.StepFinished(ctx, w, stepResult, err, timedOut)
+		}
+	}
+	return nil
+}
+
+// waitingStep is an event step and the event it waited for.
+type waitingStep struct {
+	step  *storage.TimerStep
+	event *workflow.Event
+}
+
+// retrieveEventSteps retrieves the event steps of id waiting for any of events.
+// Errors are logged.
+func (e *Engine) retrieveEventSteps(ctx context.Context, logger log.Logger, id string, events []*workflow.Event) []waitingStep {
+	var waiting []waitingStep
+	for _, event := range events {
+		steps, err := e.storage.RetrieveEventSteps(ctx, id, event.EventFlag)
+		if err != nil {
+			logger.Info(
+				logkeys.Message, "retrieving event steps",
+				logkeys.Event, event.EventFlag,
+				logkeys.Error, err,
+			)
+			continue
+		}
+		for _, step := range steps {
+			waiting = append(waiting, waitingStep{step: step, event: event})
+		}
+	}
+	return waiting
+}
+
+// completeEventSteps calls back workflows with the events their event steps waited for.
+// Errors are logged.
+func (e *Engine) completeEventSteps(ctx context.Context, logger log.Logger, waiting []waitingStep) {
+	for _, ws := range waiting {
+		stepLogger := logger.With(
+			logkeys.InstanceID, ws.step.InstanceID,
+			logkeys.WorkflowName, ws.step.WorkflowName,
+			logkeys.StepName, ws.step.Name,
+			logkeys.Event, ws.event.EventFlag,
+		)
+		w := e.Workflow(ws.step.WorkflowName)
+		if w == nil {
+			stepLogger.Info(logkeys.Error, NewErrNoSuchWorkflow(ws.step.WorkflowName))
+			continue
+		}
+		stepResult, err := workflowStepResultFromStorageTimerStep(ws.step, w)
+		if err != nil {
+			stepLogger.Info(logkeys.Message, "converting event step", logkeys.Error, err)
+			continue
+		}
+		stepResult.Event = ws.event
+		err = w.StepCompleted(ctx, stepResult)
+		if err != nil {
+			stepLogger.Info(logkeys.Message, "completing event step", logkeys.Error, err)
+		} else {
+			stepLogger.Debug(logkeys.Message, "completed event step")
+		}
+		e.StepFinished(ctx, w, stepResult, err, false)
+	}
+}
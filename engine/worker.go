@@ -19,14 +19,46 @@ type WorkflowFinder interface {
 	Workflow(name string) workflow.Workflow
 }
 
+// PendingStarter starts the workflows of delayed event subscription starts.
+type PendingStarter interface {
+	StartPending(ctx context.Context, ps *storage.PendingStart) (string, error)
+}
+
+// StepFinisher is notified when a workflow step has finished.
+// For example to start queued or follow-up workflows.
+type StepFinisher interface {
+	StepFinished(ctx context.Context, w workflow.Workflow, stepResult *workflow.StepResult, stepErr error, timedOut bool)
+}
+
+// Scheduler starts the workflows of due schedules.
+type Scheduler interface {
+	RunSchedules(ctx context.Context, now time.Time) error
+}
+
+// RolloutRunner starts the next waves of due rollouts.
+type RolloutRunner interface {
+	RunRollouts(ctx context.Context, now time.Time) error
+}
+
+// ApprovalExpirer expires pending approval requests.
+type ApprovalExpirer interface {
+	ExpireApprovals(ctx context.Context, now time.Time) error
+}
+
 // Worker polls storage backends for timed events on an interval.
-// Examples include step timeouts, delayed steps (NotUntil), and
-// re-pushes.
+// Examples include step timeouts, delayed steps (NotUntil), timer
+// steps, and re-pushes.
 type Worker struct {
-	wff      WorkflowFinder
-	storage  storage.WorkerStorage
-	enqueuer PushEnqueuer
-	logger   log.Logger
+	wff       WorkflowFinder
+	storage   storage.WorkerStorage
+	enqueuer  PushEnqueuer
+	starter   PendingStarter
+	finisher  StepFinisher
+	scheduler Scheduler
+	rollouts  RolloutRunner
+	expirer   ApprovalExpirer
+	observer  StepObserver
+	logger    log.Logger
 
 	// duration is the interval at which the worker will wake up to
 	// continue polling the storage backend for data to take action on.
@@ -61,6 +93,46 @@ func WithWorkerRePushDuration(d time.Duration) WorkerOption {
 	}
 }
 
+// WithWorkerPendingStarter configures the worker to start the
+// workflows of delayed event subscriptions using starter.
+func WithWorkerPendingStarter(starter PendingStarter) WorkerOption {
+	return func(w *Worker) {
+		w.starter = starter
+	}
+}
+
+// WithWorkerStepFinisher configures the worker to notify finisher
+// when steps time out.
+func WithWorkerStepFinisher(finisher StepFinisher) WorkerOption {
+	return func(w *Worker) {
+		w.finisher = finisher
+	}
+}
+
+// WithWorkerScheduler configures the worker to start the workflows
+// of due schedules using scheduler.
+func WithWorkerScheduler(scheduler Scheduler) WorkerOption {
+	return func(w *Worker) {
+		w.scheduler = scheduler
+	}
+}
+
+// WithWorkerRolloutRunner configures the worker to start the next
+// waves of due rollouts using rollouts.
+func WithWorkerRolloutRunner(rollouts RolloutRunner) WorkerOption {
+	return func(w *Worker) {
+		w.rollouts = rollouts
+	}
+}
+
+// WithWorkerApprovalExpirer configures the worker to expire pending
+// approval requests using expirer.
+func WithWorkerApprovalExpirer(expirer ApprovalExpirer) WorkerOption {
+	return func(w *Worker) {
+		w.expirer = expirer
+	}
+}
+
 func NewWorker(wff WorkflowFinder, storage storage.WorkerStorage, enqueuer PushEnqueuer, opts ...WorkerOption) *Worker {
 	w := &Worker{
 		wff:      wff,
@@ -83,6 +155,9 @@ func (w *Worker) RunOnce(ctx context.Context) error {
 	if err != nil {
 		return logAndError(err, w.logger, "processing enqueueings")
 	}
+	if err = w.processTimerSteps(ctx); err != nil {
+		return logAndError(err, w.logger, "processing timer steps")
+	}
 	if err = w.processTimeouts(ctx); err != nil {
 		return logAndError(err, w.logger, "processing timeouts")
 	}
@@ -91,6 +166,26 @@ func (w *Worker) RunOnce(ctx context.Context) error {
 			return logAndError(err, w.logger, "processing repushes")
 		}
 	}
+	if w.starter != nil {
+		if err = w.processPendingStarts(ctx); err != nil {
+			return logAndError(err, w.logger, "processing pending starts")
+		}
+	}
+	if w.scheduler != nil {
+		if err = w.scheduler.RunSchedules(ctx, time.Now()); err != nil {
+			return logAndError(err, w.logger, "processing schedules")
+		}
+	}
+	if w.rollouts != nil {
+		if err = w.rollouts.RunRollouts(ctx, time.Now()); err != nil {
+			return logAndError(err, w.logger, "processing rollouts")
+		}
+	}
+	if w.expirer != nil {
+		if err = w.expirer.ExpireApprovals(ctx, time.Now()); err != nil {
+			return logAndError(err, w.logger, "expiring approvals")
+		}
+	}
 	return nil
 }
 
@@ -157,6 +252,8 @@ func (w *Worker) processTimeouts(ctx context.Context) error {
 		return fmt.Errorf("retrieving timed-out steps: %w", err)
 	}
 
+	observer := w.observer // w is shadowed by the workflow below
+	finisher := w.finisher
 	for _, step := range steps {
 		stepLogger := w.logger.With(
 			logkeys.Message, "step timeout",
@@ -184,11 +281,20 @@ func (w *Worker) processTimeouts(ctx context.Context) error {
 		}
 
 		// send the timeout notification
-		if err = w.StepTimeout(ctx, stepResult); err != nil {
+		err = w.StepTimeout(ctx, stepResult)
+		if err != nil {
 			stepLogger.Info(logkeys.Error, err)
 		} else {
 			stepLogger.Debug()
 		}
+
+		if observer != nil {
+			observer.StepObserved(ctx, step.InstanceID, step.WorkflowName, step.IDs[0], stepErrored(stepResult), true)
+		}
+
+		if finisher != nil {
+			finisher.StepFinished(ctx, w, stepResult, err, true)
+		}
 	}
 	return nil
 }
@@ -213,3 +319,26 @@ func (w *Worker) processRePushes(ctx context.Context) error {
 	)
 	return nil
 }
+
+func (w *Worker) processPendingStarts(ctx context.Context) error {
+	starts, err := w.storage.RetrieveDuePendingStarts(ctx, time.Now())
+	if err != nil {
+		return fmt.Errorf("retrieving pending starts: %w", err)
+	}
+
+	for _, ps := range starts {
+		logger := w.logger.With(
+			logkeys.Message, "starting pending workflow",
+			logkeys.EnrollmentID, ps.EnrollmentID,
+			logkeys.WorkflowName, ps.WorkflowName,
+			"name", ps.EventSubscription,
+		)
+		instanceID, err := w.starter.StartPending(ctx, ps)
+		if err != nil {
+			logger.Info(logkeys.InstanceID, instanceID, logkeys.Error, err)
+		} else {
+			logger.Debug(logkeys.InstanceID, instanceID)
+		}
+	}
+	return nil
+}
@@ -11,6 +11,7 @@ require (
 	github.com/micromdm/plist v0.2.2
 	github.com/peterbourgon/diskv/v3 v3.0.1
 	github.com/smallstep/pkcs7 v0.2.1
+	software.sslmate.com/src/go-pkcs12 v0.5.0
 )
 
 require (
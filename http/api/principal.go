@@ -0,0 +1,159 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/micromdm/nanocmd/logkeys"
+
+	"github.com/micromdm/nanolib/log"
+	"github.com/micromdm/nanolib/log/ctxlog"
+)
+
+// Permissions of API principals.
+// Permissions ending in ":*" grant all permissions with that prefix.
+const (
+	// PermAll grants all permissions.
+	PermAll = "*"
+
+	PermInventoryRead       = "inventory:read"
+	PermProfilesManage      = "profiles:manage"
+	PermSubscriptionsManage = "subscriptions:manage"
+	PermCmdPlansManage      = "cmdplans:manage"
+	PermGroupsManage        = "groups:manage"
+	PermBaselinesManage     = "baselines:manage"
+	PermSchedulesManage     = "schedules:manage"
+	PermRolloutsManage      = "rollouts:manage"
+	PermMaintenanceManage   = "maintenance:manage"
+	PermWorkflowsRead       = "workflows:read"
+	PermApprovalsRead       = "approvals:read"
+	PermApprovalsDecide     = "approvals:decide"
+	PermAuditRead           = "audit:read"
+	PermFileVaultPRKRead    = "filevault:prk:read"
+	PermLockPINRead         = "lock:pin:read"
+
+	// PermWorkflowStartPrefix prefixes the workflow name for the
+	// permission to start that workflow. See PermWorkflowStart.
+	PermWorkflowStartPrefix = "workflows:start:"
+)
+
+// PermWorkflowStart returns the permission to start workflow name.
+func PermWorkflowStart(name string) string {
+	return PermWorkflowStartPrefix + name
+}
+
+type principalKey struct{}
+
+// principal is an authenticated API principal.
+type principal struct {
+	name        string
+	permissions []string
+}
+
+// WithPrincipal returns a copy of ctx with the authenticated API
+// principal name and its permissions. The principal name is also
+// added to the context logger with the "principal" key.
+func WithPrincipal(ctx context.Context, name string, permissions ...string) context.Context {
+	ctx = context.WithValue(ctx, principalKey{}, &principal{name: name, permissions: permissions})
+	return ctxlog.AddFunc(ctx, func(ctx context.Context) []interface{} {
+		if name := Principal(ctx); name != "" {
+			return []interface{}{"principal", name}
+		}
+		return nil
+	})
+}
+
+// Principal returns the authenticated API principal name from ctx.
+// An empty string is returned if no principal is present.
+func Principal(ctx context.Context) string {
+	p, _ := ctx.Value(principalKey{}).(*principal)
+	if p == nil {
+		return ""
+	}
+	return p.name
+}
+
+// permits returns true if granted grants permission.
+func permits(granted, permission string) bool {
+	if granted == PermAll || granted == permission {
+		return true
+	}
+	if strings.HasSuffix(granted, ":*") {
+		return strings.HasPrefix(permission, granted[:len(granted)-1])
+	}
+	return false
+}
+
+// Permits returns true if any of the granted permissions grant permission.
+func Permits(granted []string, permission string) bool {
+	for _, g := range granted {
+		if permits(g, permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// Permitted returns true if the API principal of ctx has permission.
+// False is returned if no principal is present.
+func Permitted(ctx context.Context, permission string) bool {
+	p, _ := ctx.Value(principalKey{}).(*principal)
+	if p == nil {
+		return false
+	}
+	return Permits(p.permissions, permission)
+}
+
+// CheckWorkflowStart returns a *PermissionError if the API principal
+// of ctx lacks the permission to start any of the workflow names.
+// Intended for handlers that cause workflows to be started later
+// (e.g. follow-up workflows or schedules).
+func CheckWorkflowStart(ctx context.Context, names ...string) error {
+	for _, name := range names {
+		if permission := PermWorkflowStart(name); !Permitted(ctx, permission) {
+			return &PermissionError{Principal: Principal(ctx), Permission: permission}
+		}
+	}
+	return nil
+}
+
+// NewPrincipalHandler sets the API principal of requests to name with permissions.
+// Intended to be wrapped by an authentication handler for name.
+func NewPrincipalHandler(next http.Handler, name string, permissions ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), name, permissions...)))
+	})
+}
+
+// RequirePermissionFunc only hands off to next if the API principal
+// has the permission returned by permFn for the request. Otherwise it
+// logs and responds with an HTTP 403 JSON error.
+func RequirePermissionFunc(next http.Handler, permFn func(*http.Request) string, logger log.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		permission := permFn(r)
+		if !Permitted(r.Context(), permission) {
+			err := &PermissionError{Principal: Principal(r.Context()), Permission: permission}
+			ctxlog.Logger(r.Context(), logger).Info(logkeys.Message, "checking permission", logkeys.Error, err)
+			JSONError(w, err, http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequirePermission only hands off to next if the API principal has
+// permission. Otherwise it logs and responds with an HTTP 403 JSON error.
+func RequirePermission(next http.Handler, permission string, logger log.Logger) http.Handler {
+	return RequirePermissionFunc(next, func(*http.Request) string { return permission }, logger)
+}
+
+// PermissionError is returned when an API principal lacks a permission.
+type PermissionError struct {
+	Principal  string
+	Permission string
+}
+
+func (e *PermissionError) Error() string {
+	return "permission denied: " + e.Permission
+}
@@ -0,0 +1,143 @@
+// Package apikey authenticates API requests using named, hashed API keys.
+package apikey
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/micromdm/nanocmd/http/api"
+)
+
+var (
+	ErrMissingName = errors.New("missing credential name")
+	ErrInvalidHash = errors.New("invalid key hash")
+)
+
+// Credential is a named API key with permissions.
+// The API key itself is not stored: only its SHA-256 hash.
+type Credential struct {
+	Name        string   `json:"name"`
+	KeySHA256   string   `json:"key_sha256"`
+	Permissions []string `json:"permissions"`
+}
+
+// Validate checks c for errors.
+func (c *Credential) Validate() error {
+	if c == nil || c.Name == "" {
+		return ErrMissingName
+	}
+	if b, err := hex.DecodeString(c.KeySHA256); err != nil || len(b) != sha256.Size {
+		return fmt.Errorf("%w: %s", ErrInvalidHash, c.Name)
+	}
+	return nil
+}
+
+// HashKey returns the hex-encoded SHA-256 hash of key.
+// API keys should be long random strings as they are not salted.
+func HashKey(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(h[:])
+}
+
+// Parse parses a JSON list of credentials from r.
+func Parse(r io.Reader) ([]Credential, error) {
+	var creds []Credential
+	if err := json.NewDecoder(r).Decode(&creds); err != nil {
+		return nil, fmt.Errorf("decoding credentials: %w", err)
+	}
+	return creds, nil
+}
+
+// ReadFile parses a JSON list of credentials from the file at path.
+func ReadFile(path string) ([]Credential, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Authenticator authenticates API keys of credentials.
+type Authenticator struct {
+	creds map[string]Credential
+}
+
+// New creates a new authenticator for creds.
+// Credential names must be unique.
+func New(creds ...Credential) (*Authenticator, error) {
+	a := &Authenticator{creds: make(map[string]Credential)}
+	for _, c := range creds {
+		if err := c.Validate(); err != nil {
+			return nil, err
+		}
+		c.KeySHA256 = strings.ToLower(c.KeySHA256)
+		if _, ok := a.creds[c.Name]; ok {
+			return nil, fmt.Errorf("duplicate credential name: %s", c.Name)
+		}
+		a.creds[c.Name] = c
+	}
+	return a, nil
+}
+
+// Authenticate returns the credential of name if key matches its hash.
+func (a *Authenticator) Authenticate(name, key string) (Credential, bool) {
+	c, ok := a.creds[name]
+	if !ok {
+		// hash anyway to not leak valid names through timing
+		HashKey(key)
+		return Credential{}, false
+	}
+	if subtle.ConstantTimeCompare([]byte(HashKey(key)), []byte(c.KeySHA256)) != 1 {
+		return Credential{}, false
+	}
+	return c, true
+}
+
+// Len returns the number of credentials.
+func (a *Authenticator) Len() int {
+	return len(a.creds)
+}
+
+// Permitted returns the sorted names of the credentials with permission.
+func (a *Authenticator) Permitted(permission string) []string {
+	var names []string
+	for name, c := range a.creds {
+		if api.Permits(c.Permissions, permission) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewBasicAuthHandler is an HTTP Basic authentication middleware.
+// The username is the credential name and the password is its API key.
+// The credential name and permissions are set as the API principal of
+// the request before handing off to next. Otherwise it responds with
+// an HTTP 401 setting the WWW-Authenticate header using realm.
+func NewBasicAuthHandler(next http.Handler, a *Authenticator, realm string) http.HandlerFunc {
+	rc := `Basic realm="` + realm + `"`
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, p, ok := r.BasicAuth()
+		var c Credential
+		if ok {
+			c, ok = a.Authenticate(u, p)
+		}
+		if !ok {
+			w.Header().Set("Www-Authenticate", rc)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(api.WithPrincipal(r.Context(), c.Name, c.Permissions...)))
+	}
+}
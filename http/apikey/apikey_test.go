@@ -0,0 +1,112 @@
+package apikey
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/micromdm/nanocmd/http/api"
+
+	"github.com/micromdm/nanolib/log"
+)
+
+func TestBasicAuthHandler(t *testing.T) {
+	creds, err := Parse(strings.NewReader(`[
+  {"name": "helpdesk", "key_sha256": "` + strings.ToUpper(HashKey("s3cret")) + `", "permissions": ["inventory:read", "workflows:start:*"]},
+  {"name": "auditor", "key_sha256": "` + HashKey("other") + `", "permissions": ["approvals:read"]}
+]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = New(append(creds, creds[0])...); err == nil {
+		t.Error("expected error for duplicate credential name")
+	}
+
+	if _, err = New(Credential{Name: "bad", KeySHA256: "abc"}); err == nil {
+		t.Error("expected error for invalid key hash")
+	}
+
+	a, err := New(creds...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var principal string
+	var h http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal = api.Principal(r.Context())
+	})
+	h = api.RequirePermission(h, api.PermInventoryRead, log.NopLogger)
+	h = NewBasicAuthHandler(h, a, "test")
+
+	for _, test := range []struct {
+		user   string
+		key    string
+		status int
+	}{
+		{"helpdesk", "s3cret", http.StatusOK},
+		{"helpdesk", "wrong", http.StatusUnauthorized},
+		{"nobody", "s3cret", http.StatusUnauthorized},
+		{"auditor", "other", http.StatusForbidden},
+	} {
+		principal = ""
+		r := httptest.NewRequest("GET", "/", nil)
+		r.SetBasicAuth(test.user, test.key)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if have, want := w.Code, test.status; have != want {
+			t.Errorf("%s: have: %v, want: %v", test.user, have, want)
+		}
+
+		if test.status == http.StatusOK && principal != test.user {
+			t.Errorf("%s: have: %v, want: %v", test.user, principal, test.user)
+		}
+	}
+}
+
+func TestPermitted(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	ctx := api.WithPrincipal(r.Context(), "helpdesk", api.PermInventoryRead, api.PermWorkflowStart("io.micromdm.wf.devinfolog.v1"), "profiles:*")
+
+	for _, test := range []struct {
+		perm string
+		ok   bool
+	}{
+		{api.PermInventoryRead, true},
+		{api.PermWorkflowStart("io.micromdm.wf.devinfolog.v1"), true},
+		{api.PermWorkflowStart("io.micromdm.wf.lock.v1"), false},
+		{api.PermProfilesManage, true},
+		{api.PermGroupsManage, false},
+	} {
+		if have, want := api.Permitted(ctx, test.perm), test.ok; have != want {
+			t.Errorf("%s: have: %v, want: %v", test.perm, have, want)
+		}
+	}
+
+	if api.Permitted(r.Context(), api.PermInventoryRead) {
+		t.Error("expected no permission without principal")
+	}
+}
+
+func TestAuthenticatorPermitted(t *testing.T) {
+	a, err := New(
+		Credential{Name: "helpdesk", KeySHA256: HashKey("a"), Permissions: []string{api.PermWorkflowStart("wf.a")}},
+		Credential{Name: "security", KeySHA256: HashKey("b"), Permissions: []string{"approvals:*"}},
+		Credential{Name: "admin", KeySHA256: HashKey("c"), Permissions: []string{api.PermAll}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := a.Len(), 3; have != want {
+		t.Errorf("len: have: %d, want: %d", have, want)
+	}
+	if have, want := a.Permitted(api.PermApprovalsDecide), []string{"admin", "security"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("permitted: have: %v, want: %v", have, want)
+	}
+	if have := a.Permitted(api.PermWorkflowStart("wf.b")); !reflect.DeepEqual(have, []string{"admin"}) {
+		t.Errorf("permitted: have: %v", have)
+	}
+}
@@ -0,0 +1,104 @@
+// Package audit records audit events of API requests and engine actions.
+package audit
+
+import (
+	"context"
+	"time"
+
+	"github.com/micromdm/nanocmd/logkeys"
+	"github.com/micromdm/nanocmd/subsystem/audit/storage"
+	"github.com/micromdm/nanocmd/utils/uuid"
+
+	"github.com/micromdm/nanolib/log"
+	"github.com/micromdm/nanolib/log/ctxlog"
+)
+
+// Actors of engine-initiated actions.
+const (
+	ActorEngine    = "engine"
+	ActorScheduler = "scheduler"
+	ActorRollout   = "rollout"
+)
+
+// Auditor records audit events to storage.
+type Auditor struct {
+	store  storage.Storage
+	logger log.Logger
+	ider   uuid.IDer
+}
+
+// Option configures an Auditor.
+type Option func(*Auditor)
+
+// WithLogger configures the logger of the auditor.
+func WithLogger(logger log.Logger) Option {
+	return func(a *Auditor) {
+		a.logger = logger
+	}
+}
+
+// New creates a new auditor that stores audit events in store.
+func New(store storage.Storage, opts ...Option) *Auditor {
+	a := &Auditor{
+		store:  store,
+		logger: log.NopLogger,
+		ider:   uuid.NewUUID(),
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// Audit stores the audit event e.
+// The ID and time of e are set if empty. The outcome of e is set
+// from its error if empty. Storage errors are logged, not returned,
+// so that auditing does not interfere with the audited action.
+func (a *Auditor) Audit(ctx context.Context, e *storage.Event) {
+	if e == nil {
+		return
+	}
+	if e.ID == "" {
+		e.ID = a.ider.ID()
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if e.Outcome == "" {
+		e.Outcome = storage.OutcomeSuccess
+		if e.Error != "" {
+			e.Outcome = storage.OutcomeFailure
+		}
+	}
+	if err := a.store.StoreEvent(ctx, e); err != nil {
+		ctxlog.Logger(ctx, a.logger).Info(
+			logkeys.Message, "storing audit event",
+			"audit_action", e.Action,
+			logkeys.Error, err,
+		)
+	}
+}
+
+// ErrorString returns the string of err or an empty string if err is nil.
+func ErrorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+type eventKey struct{}
+
+// NewContext returns a copy of ctx with the in-flight audit event e.
+func NewContext(ctx context.Context, e *storage.Event) context.Context {
+	return context.WithValue(ctx, eventKey{}, e)
+}
+
+// Annotate calls fn with the in-flight audit event of ctx, if present.
+// Handlers use this to add details only they know (e.g. the instance
+// ID of a started workflow) to the audit event of their request.
+func Annotate(ctx context.Context, fn func(e *storage.Event)) {
+	if e, ok := ctx.Value(eventKey{}).(*storage.Event); ok && e != nil {
+		fn(e)
+	}
+}
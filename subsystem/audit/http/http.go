@@ -0,0 +1,307 @@
+// Package http contains HTTP handlers for recording and querying the audit log.
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/micromdm/nanocmd/http/api"
+	"github.com/micromdm/nanocmd/logkeys"
+	"github.com/micromdm/nanocmd/subsystem/audit"
+	"github.com/micromdm/nanocmd/subsystem/audit/storage"
+
+	"github.com/micromdm/nanolib/log"
+	"github.com/micromdm/nanolib/log/ctxlog"
+)
+
+// DefaultLimit is the default maximum number of audit events returned.
+const DefaultLimit = 100
+
+var ErrInvalidLimit = errors.New("invalid limit")
+
+// maxErrorBody is the maximum size of error response bodies captured.
+const maxErrorBody = 4096
+
+// maxBody is the maximum size of request bodies copied into audit
+// events. Larger bodies are only recorded by their digest.
+const maxBody = 4096
+
+// maxBodyDrain is the maximum size of request body remainders read for
+// the digest after the handler finishes.
+const maxBodyDrain = 256 << 10
+
+// sensitiveKeys are the substrings of JSON keys whose values are
+// redacted from request bodies.
+var sensitiveKeys = []string{"password", "passcode", "secret", "pin", "prk", "key", "token"}
+
+// Auditor records audit events.
+type Auditor interface {
+	Audit(ctx context.Context, e *storage.Event)
+}
+
+// recorder captures the status code and the error body of a response.
+type recorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	if r.status >= 400 && r.body.Len() < maxErrorBody {
+		n := maxErrorBody - r.body.Len()
+		if n > len(b) {
+			n = len(b)
+		}
+		r.body.Write(b[:n])
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// bodyReader digests the request body and copies its start as it is read.
+type bodyReader struct {
+	io.ReadCloser
+	hash hash.Hash
+	body bytes.Buffer
+	eof  bool
+}
+
+func newBodyReader(rc io.ReadCloser) *bodyReader {
+	return &bodyReader{ReadCloser: rc, hash: sha256.New()}
+}
+
+func (b *bodyReader) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.hash.Write(p[:n])
+	if b.body.Len() <= maxBody {
+		// copy one byte past maxBody to tell a full copy from a partial one
+		c := maxBody + 1 - b.body.Len()
+		if c > n {
+			c = n
+		}
+		b.body.Write(p[:c])
+	}
+	if errors.Is(err, io.EOF) {
+		b.eof = true
+	}
+	return n, err
+}
+
+// annotate sets the body and digest of the request body to e.
+// The remainder of the body not read by the handler is read first.
+// Nothing is set if the body could not be read completely.
+func (b *bodyReader) annotate(e *storage.Event) {
+	if !b.eof {
+		io.Copy(io.Discard, io.LimitReader(b, maxBodyDrain))
+	}
+	if !b.eof {
+		return
+	}
+	e.BodySHA256 = hex.EncodeToString(b.hash.Sum(nil))
+	if b.body.Len() <= maxBody {
+		e.Body = redactBody(b.body.Bytes())
+	}
+}
+
+// redactBody returns body with the values of sensitive JSON keys
+// replaced. An empty string is returned if body is not JSON.
+func redactBody(body []byte) string {
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return ""
+	}
+	redacted, err := json.Marshal(redact(v))
+	if err != nil {
+		return ""
+	}
+	return string(redacted)
+}
+
+// redact replaces the values of sensitive keys of the JSON value v.
+func redact(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, kv := range v {
+			if sensitiveKey(k) {
+				v[k] = "REDACTED"
+			} else {
+				v[k] = redact(kv)
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = redact(v[i])
+		}
+	}
+	return v
+}
+
+func sensitiveKey(k string) bool {
+	k = strings.ToLower(k)
+	for _, s := range sensitiveKeys {
+		if strings.Contains(k, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// responseError returns the error message of an error response body.
+// The JSON "error" value of the body is used if present.
+func responseError(status int, body []byte) string {
+	jsonErr := &struct {
+		Err string `json:"error"`
+	}{}
+	if err := json.Unmarshal(body, jsonErr); err == nil && jsonErr.Err != "" {
+		return jsonErr.Err
+	}
+	return http.StatusText(status)
+}
+
+// NewAuditHandler records an audit event for requests with mutating
+// HTTP methods (i.e. not GET, HEAD, or OPTIONS) after handing off to next.
+// See NewRequestAuditHandler.
+func NewAuditHandler(next http.Handler, auditor Auditor) http.HandlerFunc {
+	audited := NewRequestAuditHandler(next, auditor)
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+		default:
+			audited.ServeHTTP(w, r)
+		}
+	}
+}
+
+// NewRequestAuditHandler records an audit event for every request
+// after handing off to next. The actor is the API principal and the
+// action is the HTTP method and request path. The "id" query parameters
+// are the target IDs and all query parameters are the parameters.
+// Request bodies are recorded by their SHA-256 digest and small JSON
+// bodies are copied with the values of sensitive keys redacted. The
+// outcome is a failure for HTTP error status codes. Handlers of next
+// may add details to the audit event with audit.Annotate.
+func NewRequestAuditHandler(next http.Handler, auditor Auditor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		e := &storage.Event{
+			Time:      time.Now(),
+			Actor:     api.Principal(r.Context()),
+			Action:    r.Method + " " + r.URL.Path,
+			TargetIDs: q["id"],
+		}
+		if len(q) > 0 {
+			e.Params = q
+		}
+		var body *bodyReader
+		if r.Body != nil && r.Body != http.NoBody {
+			body = newBodyReader(r.Body)
+			r.Body = body
+		}
+		rec := &recorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r.WithContext(audit.NewContext(r.Context(), e)))
+		if body != nil {
+			body.annotate(e)
+		}
+		e.Status = rec.status
+		if e.Status == 0 {
+			e.Status = http.StatusOK
+		}
+		if e.Status >= 400 {
+			e.Outcome = storage.OutcomeFailure
+			if e.Error == "" {
+				e.Error = responseError(e.Status, rec.body.Bytes())
+			}
+		}
+		if e.Actor == "" {
+			e.Actor = "unknown"
+		}
+		auditor.Audit(r.Context(), e)
+	}
+}
+
+// parseTime parses the RFC 3339 query parameter key of r, if present.
+func parseTime(r *http.Request, key string) (time.Time, error) {
+	v := r.URL.Query().Get(key)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return t, fmt.Errorf("parsing %s: %w", key, err)
+	}
+	return t, nil
+}
+
+// GetEventsHandler returns an HTTP handler that queries audit events.
+// Events are selected by the "actor", "action" (prefix), "id",
+// "workflow", and "instance_id" query parameters and by the RFC 3339
+// "since" and "until" query parameters. At most "limit" events are
+// returned, most recent first. The limit defaults to DefaultLimit.
+func GetEventsHandler(store storage.ReadStorage, logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := ctxlog.Logger(r.Context(), logger)
+		v := r.URL.Query()
+		q := &storage.Query{
+			Actor:        v.Get("actor"),
+			Action:       v.Get("action"),
+			TargetID:     v.Get("id"),
+			WorkflowName: v.Get("workflow"),
+			InstanceID:   v.Get("instance_id"),
+			Limit:        DefaultLimit,
+		}
+		var err error
+		if q.Since, err = parseTime(r, "since"); err == nil {
+			q.Until, err = parseTime(r, "until")
+		}
+		if err == nil && v.Get("limit") != "" {
+			if q.Limit, err = strconv.Atoi(v.Get("limit")); err == nil && q.Limit < 1 {
+				err = fmt.Errorf("%w: %d", ErrInvalidLimit, q.Limit)
+			}
+		}
+		if err != nil {
+			logger.Info(logkeys.Message, "parsing query", logkeys.Error, err)
+			api.JSONError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		events, err := store.RetrieveEvents(r.Context(), q)
+		if err != nil {
+			logger.Info(logkeys.Message, "retrieve audit events", logkeys.Error, err)
+			api.JSONError(w, err, 0)
+			return
+		}
+		if events == nil {
+			events = []*storage.Event{}
+		}
+		logger.Debug(
+			logkeys.Message, "retrieved audit events",
+			logkeys.GenericCount, len(events),
+		)
+		w.Header().Set("Content-Type", "application/json")
+		if err = json.NewEncoder(w).Encode(events); err != nil {
+			logger.Info(logkeys.Message, "encoding json to body", logkeys.Error, err)
+			return
+		}
+	}
+}
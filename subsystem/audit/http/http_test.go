@@ -0,0 +1,151 @@
+package http
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/micromdm/nanocmd/http/api"
+	"github.com/micromdm/nanocmd/subsystem/audit"
+	"github.com/micromdm/nanocmd/subsystem/audit/storage"
+	"github.com/micromdm/nanocmd/subsystem/audit/storage/inmem"
+
+	"github.com/micromdm/nanolib/log"
+)
+
+func TestAuditHandler(t *testing.T) {
+	s := inmem.New()
+	var h http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("fail") != "" {
+			api.JSONError(w, errors.New("test failure"), http.StatusBadRequest)
+			return
+		}
+		audit.Annotate(r.Context(), func(e *storage.Event) { e.InstanceID = "inst1" })
+		w.WriteHeader(http.StatusNoContent)
+	})
+	h = NewAuditHandler(h, audit.New(s))
+	h = api.NewPrincipalHandler(h, "alice", api.PermAll)
+
+	for _, test := range []struct {
+		method string
+		target string
+	}{
+		{"GET", "/v1/profiles"},
+		{"POST", "/v1/workflow/wf/start?id=AAA&id=BBB"},
+		{"PUT", "/v1/profiles?fail=1"},
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(test.method, test.target, nil))
+	}
+
+	ctx := context.Background()
+	events, err := s.RetrieveEvents(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 2, len(events); have != want {
+		t.Fatalf("events: have: %d, want: %d", have, want)
+	}
+
+	e := events[1]
+	if want, have := "POST /v1/workflow/wf/start", e.Action; have != want {
+		t.Errorf("action: have: %q, want: %q", have, want)
+	}
+	if want, have := "alice", e.Actor; have != want {
+		t.Errorf("actor: have: %q, want: %q", have, want)
+	}
+	if want, have := 2, len(e.TargetIDs); have != want {
+		t.Errorf("target ids: have: %d, want: %d", have, want)
+	}
+	if want, have := "inst1", e.InstanceID; have != want {
+		t.Errorf("instance id: have: %q, want: %q", have, want)
+	}
+	if want, have := storage.OutcomeSuccess, e.Outcome; have != want {
+		t.Errorf("outcome: have: %q, want: %q", have, want)
+	}
+
+	e = events[0]
+	if want, have := storage.OutcomeFailure, e.Outcome; have != want {
+		t.Errorf("outcome: have: %q, want: %q", have, want)
+	}
+	if want, have := http.StatusBadRequest, e.Status; have != want {
+		t.Errorf("status: have: %d, want: %d", have, want)
+	}
+	if want, have := "test failure", e.Error; have != want {
+		t.Errorf("error: have: %q, want: %q", have, want)
+	}
+
+	// query the events
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/v1/audit?id=BBB", nil)
+	GetEventsHandler(s, log.NopLogger).ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status: have: %d, want: %d", w.Code, http.StatusOK)
+	}
+	events = nil
+	if err = json.NewDecoder(w.Body).Decode(&events); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 1, len(events); have != want {
+		t.Errorf("events: have: %d, want: %d", have, want)
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("GET", "/v1/audit?limit=0", nil)
+	GetEventsHandler(s, log.NopLogger).ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status: have: %d, want: %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestAuditHandlerBody(t *testing.T) {
+	s := inmem.New()
+	var h http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// only read part of the body
+		r.Body.Read(make([]byte, 4))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	h = NewAuditHandler(h, audit.New(s))
+
+	large := `{"a":"` + strings.Repeat("x", maxBody) + `"}`
+	for _, body := range []string{
+		`{"name":"wifi","lock_pin":"123456","items":[{"api_key":"abc"}]}`,
+		"<plist/>",
+		large,
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("PUT", "/v1/test", strings.NewReader(body)))
+	}
+
+	events, err := s.RetrieveEvents(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 3, len(events); have != want {
+		t.Fatalf("events: have: %d, want: %d", have, want)
+	}
+
+	// events are most recent first
+	for i, test := range []struct {
+		body string
+		raw  string
+	}{
+		{"", large},
+		{"", "<plist/>"},
+		{`{"items":[{"api_key":"REDACTED"}],"lock_pin":"REDACTED","name":"wifi"}`, `{"name":"wifi","lock_pin":"123456","items":[{"api_key":"abc"}]}`},
+	} {
+		if want, have := test.body, events[i].Body; have != want {
+			t.Errorf("%d: body: have: %q, want: %q", i, have, want)
+		}
+		sum := sha256.Sum256([]byte(test.raw))
+		if want, have := hex.EncodeToString(sum[:]), events[i].BodySHA256; have != want {
+			t.Errorf("%d: body digest: have: %q, want: %q", i, have, want)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+// Package diskv implements an audit log storage backend backed by an on-disk key-value store.
+package diskv
+
+import (
+	"github.com/micromdm/nanocmd/subsystem/audit/storage/kv"
+
+	"github.com/micromdm/nanolib/storage/kv/kvdiskv"
+	"github.com/peterbourgon/diskv/v3"
+)
+
+// Diskv is an audit log storage backend backed by an on-disk key-value store.
+type Diskv struct {
+	*kv.KV
+}
+
+// New creates a new initialized audit log data store.
+func New(path string) *Diskv {
+	return &Diskv{
+		KV: kv.New(kvdiskv.New(diskv.New(diskv.Options{
+			BasePath:     path,
+			Transform:    kvdiskv.FlatTransform,
+			CacheSizeMax: 1024 * 1024,
+		}))),
+	}
+}
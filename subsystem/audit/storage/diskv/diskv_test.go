@@ -0,0 +1,12 @@
+package diskv
+
+import (
+	"testing"
+
+	"github.com/micromdm/nanocmd/subsystem/audit/storage"
+	"github.com/micromdm/nanocmd/subsystem/audit/storage/test"
+)
+
+func TestDiskv(t *testing.T) {
+	test.TestAuditStorage(t, func() storage.Storage { return New(t.TempDir()) })
+}
@@ -0,0 +1,12 @@
+package inmem
+
+import (
+	"testing"
+
+	"github.com/micromdm/nanocmd/subsystem/audit/storage"
+	"github.com/micromdm/nanocmd/subsystem/audit/storage/test"
+)
+
+func TestInMem(t *testing.T) {
+	test.TestAuditStorage(t, func() storage.Storage { return New() })
+}
@@ -0,0 +1,95 @@
+// Package kv implements an audit log storage backend using JSON with key-value storage.
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/micromdm/nanocmd/subsystem/audit/storage"
+
+	"github.com/micromdm/nanolib/storage/kv"
+)
+
+// KV is an audit log storage backend using JSON with key-value storage.
+type KV struct {
+	b kv.Bucket
+}
+
+func New(b kv.Bucket) *KV {
+	return &KV{b: b}
+}
+
+// eventKey returns the key of e.
+// Keys sort in the time order of events.
+func eventKey(e *storage.Event) string {
+	return fmt.Sprintf("%020d.%s", e.Time.UnixNano(), e.ID)
+}
+
+// StoreEvent marshals e into JSON and stores it.
+func (s *KV) StoreEvent(ctx context.Context, e *storage.Event) error {
+	if err := e.Validate(); err != nil {
+		return err
+	}
+	raw, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return s.b.Set(ctx, eventKey(e), raw)
+}
+
+// keyTime returns the event time encoded in key.
+func keyTime(key string) (time.Time, error) {
+	if len(key) < 20 {
+		return time.Time{}, fmt.Errorf("invalid event key: %s", key)
+	}
+	nsec, err := strconv.ParseInt(key[:20], 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid event key: %s: %w", key, err)
+	}
+	return time.Unix(0, nsec), nil
+}
+
+// RetrieveEvents unmarshals the stored JSON of events selected by q.
+// Events are returned most recent first. As the keys of events encode
+// their time only the events within the since and until times of q
+// are retrieved and unmarshalled.
+func (s *KV) RetrieveEvents(ctx context.Context, q *storage.Query) ([]*storage.Event, error) {
+	keys := kv.AllKeys(ctx, s.b)
+	sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+	var r []*storage.Event
+	for _, key := range keys {
+		if q != nil && (!q.Since.IsZero() || !q.Until.IsZero()) {
+			t, err := keyTime(key)
+			if err != nil {
+				return r, err
+			}
+			if !q.Until.IsZero() && !t.Before(q.Until) {
+				continue
+			}
+			if !q.Since.IsZero() && t.Before(q.Since) {
+				// all remaining events are older
+				break
+			}
+		}
+		raw, err := s.b.Get(ctx, key)
+		if err != nil {
+			return r, fmt.Errorf("getting event: %s: %w", key, err)
+		}
+		e := new(storage.Event)
+		if err = json.Unmarshal(raw, e); err != nil {
+			return r, fmt.Errorf("unmarshal event: %s: %w", key, err)
+		}
+		if !q.Matches(e) {
+			continue
+		}
+		r = append(r, e)
+		if q != nil && q.Limit > 0 && len(r) >= q.Limit {
+			break
+		}
+	}
+	return r, nil
+}
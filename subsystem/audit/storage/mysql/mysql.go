@@ -0,0 +1,244 @@
+// Package mysql implements an audit log storage backend using MySQL.
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/micromdm/nanocmd/subsystem/audit/storage"
+)
+
+// Schema contains the MySQL schema for the audit log storage.
+//
+//go:embed schema.sql
+var Schema string
+
+// MySQLStorage implements an audit log storage.Storage using MySQL.
+type MySQLStorage struct {
+	db *sql.DB
+}
+
+type config struct {
+	driver string
+	dsn    string
+	db     *sql.DB
+}
+
+// Option allows configuring a MySQLStorage.
+type Option func(*config)
+
+// WithDSN sets the storage MySQL data source name.
+func WithDSN(dsn string) Option {
+	return func(c *config) {
+		c.dsn = dsn
+	}
+}
+
+// WithDriver sets a custom MySQL driver for the storage.
+//
+// Default driver is "mysql".
+// Value is ignored if WithDB is used.
+func WithDriver(driver string) Option {
+	return func(c *config) {
+		c.driver = driver
+	}
+}
+
+// WithDB sets a custom MySQL *sql.DB to the storage.
+//
+// If set, driver passed via WithDriver is ignored.
+func WithDB(db *sql.DB) Option {
+	return func(c *config) {
+		c.db = db
+	}
+}
+
+// New creates and returns a new MySQLStorage.
+func New(opts ...Option) (*MySQLStorage, error) {
+	cfg := &config{driver: "mysql"}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	var err error
+	if cfg.db == nil {
+		cfg.db, err = sql.Open(cfg.driver, cfg.dsn)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err = cfg.db.Ping(); err != nil {
+		return nil, err
+	}
+	return &MySQLStorage{db: cfg.db}, nil
+}
+
+func sqlNullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+const eventColumns = `
+  id,
+  time_unix_nano,
+  actor,
+  action,
+  target_ids,
+  workflow_name,
+  instance_id,
+  params,
+  body,
+  body_sha256,
+  status,
+  outcome,
+  error`
+
+// StoreEvent stores an audit event.
+// See the storage interface type for further docs.
+func (s *MySQLStorage) StoreEvent(ctx context.Context, e *storage.Event) error {
+	if err := e.Validate(); err != nil {
+		return err
+	}
+	targetIDs := e.TargetIDs
+	if targetIDs == nil {
+		targetIDs = []string{}
+	}
+	targetIDsBytes, err := json.Marshal(targetIDs)
+	if err != nil {
+		return fmt.Errorf("marshal target ids: %w", err)
+	}
+	var params sql.NullString
+	if len(e.Params) > 0 {
+		paramsBytes, err := json.Marshal(e.Params)
+		if err != nil {
+			return fmt.Errorf("marshal params: %w", err)
+		}
+		params = sql.NullString{String: string(paramsBytes), Valid: true}
+	}
+	_, err = s.db.ExecContext(
+		ctx,
+		`INSERT INTO subsystem_audit_events (`+eventColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`,
+		e.ID,
+		e.Time.UnixNano(),
+		e.Actor,
+		e.Action,
+		string(targetIDsBytes),
+		sqlNullString(e.WorkflowName),
+		sqlNullString(e.InstanceID),
+		params,
+		sqlNullString(e.Body),
+		sqlNullString(e.BodySHA256),
+		e.Status,
+		e.Outcome,
+		sqlNullString(e.Error),
+	)
+	return err
+}
+
+// escapeLike escapes the LIKE pattern characters of s.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// RetrieveEvents retrieves audit events selected by q.
+// See the storage interface type for further docs.
+func (s *MySQLStorage) RetrieveEvents(ctx context.Context, q *storage.Query) ([]*storage.Event, error) {
+	if q == nil {
+		q = new(storage.Query)
+	}
+	var where []string
+	var args []interface{}
+	add := func(clause string, arg interface{}) {
+		where = append(where, clause)
+		args = append(args, arg)
+	}
+	if q.Actor != "" {
+		add("actor = ?", q.Actor)
+	}
+	if q.Action != "" {
+		add("action LIKE ?", escapeLike(q.Action)+"%")
+	}
+	if q.TargetID != "" {
+		add("JSON_CONTAINS(target_ids, JSON_QUOTE(?))", q.TargetID)
+	}
+	if q.WorkflowName != "" {
+		add("workflow_name = ?", q.WorkflowName)
+	}
+	if q.InstanceID != "" {
+		add("instance_id = ?", q.InstanceID)
+	}
+	if !q.Since.IsZero() {
+		add("time_unix_nano >= ?", q.Since.UnixNano())
+	}
+	if !q.Until.IsZero() {
+		add("time_unix_nano < ?", q.Until.UnixNano())
+	}
+	query := `SELECT` + eventColumns + ` FROM subsystem_audit_events`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	query += ` ORDER BY time_unix_nano DESC, id DESC`
+	if q.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, q.Limit)
+	}
+	rows, err := s.db.QueryContext(ctx, query+";", args...)
+	if err != nil {
+		return nil, fmt.Errorf("select audit events: %w", err)
+	}
+	defer rows.Close()
+	var r []*storage.Event
+	for rows.Next() {
+		var (
+			e            storage.Event
+			timeUnixNano int64
+			targetIDs    string
+			workflowName sql.NullString
+			instanceID   sql.NullString
+			params       sql.NullString
+			body         sql.NullString
+			bodySHA256   sql.NullString
+			errStr       sql.NullString
+		)
+		err = rows.Scan(
+			&e.ID,
+			&timeUnixNano,
+			&e.Actor,
+			&e.Action,
+			&targetIDs,
+			&workflowName,
+			&instanceID,
+			&params,
+			&body,
+			&bodySHA256,
+			&e.Status,
+			&e.Outcome,
+			&errStr,
+		)
+		if err != nil {
+			return r, fmt.Errorf("scan audit event: %w", err)
+		}
+		e.Time = time.Unix(0, timeUnixNano)
+		e.WorkflowName = workflowName.String
+		e.InstanceID = instanceID.String
+		e.Body = body.String
+		e.BodySHA256 = bodySHA256.String
+		e.Error = errStr.String
+		if err = json.Unmarshal([]byte(targetIDs), &e.TargetIDs); err != nil {
+			return r, fmt.Errorf("unmarshal target ids: %w", err)
+		}
+		if len(e.TargetIDs) < 1 {
+			e.TargetIDs = nil
+		}
+		if params.Valid {
+			if err = json.Unmarshal([]byte(params.String), &e.Params); err != nil {
+				return r, fmt.Errorf("unmarshal params: %w", err)
+			}
+		}
+		r = append(r, &e)
+	}
+	return r, rows.Err()
+}
@@ -0,0 +1,25 @@
+package mysql
+
+import (
+	"os"
+	"testing"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/micromdm/nanocmd/subsystem/audit/storage"
+	"github.com/micromdm/nanocmd/subsystem/audit/storage/test"
+)
+
+func TestMySQLStorage(t *testing.T) {
+	testDSN := os.Getenv("NANOCMD_MYSQL_STORAGE_TEST_DSN")
+	if testDSN == "" {
+		t.Skip("NANOCMD_MYSQL_STORAGE_TEST_DSN not set")
+	}
+
+	test.TestAuditStorage(t, func() storage.Storage {
+		s, err := New(WithDSN(testDSN))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	})
+}
@@ -0,0 +1,25 @@
+CREATE TABLE subsystem_audit_events (
+    id             VARCHAR(255) NOT NULL,
+    time_unix_nano BIGINT NOT NULL,
+
+    actor         VARCHAR(255) NOT NULL,
+    action        VARCHAR(1024) NOT NULL,
+    target_ids    JSON NOT NULL,
+    workflow_name VARCHAR(255) NULL,
+    instance_id   VARCHAR(255) NULL,
+    params        JSON NULL,
+    body          TEXT NULL,
+    body_sha256   CHAR(64) NULL,
+    status        INTEGER NOT NULL DEFAULT 0,
+    outcome       VARCHAR(31) NOT NULL,
+    error         TEXT NULL,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (id),
+
+    INDEX (time_unix_nano),
+    INDEX (actor),
+    INDEX (workflow_name),
+    INDEX (instance_id)
+);
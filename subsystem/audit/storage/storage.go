@@ -0,0 +1,137 @@
+// Package storage defines types and interfaces supporting the audit log.
+package storage
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoID     = errors.New("no audit event id")
+	ErrNoTime   = errors.New("no audit event time")
+	ErrNoActor  = errors.New("no audit event actor")
+	ErrNoAction = errors.New("no audit event action")
+)
+
+// Outcomes of audited actions.
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// Event is a record of an audited action.
+type Event struct {
+	ID   string    `json:"id"`
+	Time time.Time `json:"time"`
+
+	// Actor is the API principal name or the name of the component
+	// (e.g. "engine" or "scheduler") that performed the action.
+	Actor string `json:"actor"`
+
+	// Action is the performed action.
+	// For API requests this is the HTTP method and request path
+	// (e.g. "PUT /v1/profiles").
+	Action string `json:"action"`
+
+	TargetIDs    []string `json:"target_ids,omitempty"`
+	WorkflowName string   `json:"workflow_name,omitempty"`
+	InstanceID   string   `json:"instance_id,omitempty"`
+
+	// Params are the parameters of the action.
+	// For API requests these are the URL query parameters.
+	Params map[string][]string `json:"params,omitempty"`
+
+	// Body is a copy of the JSON body of API requests with the values
+	// of sensitive keys redacted. It is empty for large or non-JSON
+	// bodies. BodySHA256 is the hex SHA-256 digest of the request body.
+	Body       string `json:"body,omitempty"`
+	BodySHA256 string `json:"body_sha256,omitempty"`
+
+	// Status is the HTTP response status code of API requests.
+	Status  int    `json:"status,omitempty"`
+	Outcome string `json:"outcome"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Validate checks e for errors.
+func (e *Event) Validate() error {
+	switch {
+	case e == nil || e.ID == "":
+		return ErrNoID
+	case e.Time.IsZero():
+		return ErrNoTime
+	case e.Actor == "":
+		return ErrNoActor
+	case e.Action == "":
+		return ErrNoAction
+	}
+	return nil
+}
+
+// Query selects audit events.
+// Empty or zero fields select all events.
+type Query struct {
+	Actor string
+
+	// Action selects events with actions starting with Action.
+	Action string
+
+	// TargetID selects events with TargetID as one of their target IDs.
+	TargetID string
+
+	WorkflowName string
+	InstanceID   string
+
+	// Since selects events at or after Since.
+	Since time.Time
+
+	// Until selects events before Until.
+	Until time.Time
+
+	// Limit is the maximum number of events to select.
+	Limit int
+}
+
+// Matches returns true if e is selected by q.
+// The Limit of q is not considered.
+func (q *Query) Matches(e *Event) bool {
+	if q == nil {
+		return true
+	}
+	if e == nil {
+		return false
+	}
+	if (q.Actor != "" && q.Actor != e.Actor) ||
+		(q.WorkflowName != "" && q.WorkflowName != e.WorkflowName) ||
+		(q.InstanceID != "" && q.InstanceID != e.InstanceID) ||
+		!strings.HasPrefix(e.Action, q.Action) ||
+		(!q.Since.IsZero() && e.Time.Before(q.Since)) ||
+		(!q.Until.IsZero() && !e.Time.Before(q.Until)) {
+		return false
+	}
+	if q.TargetID == "" {
+		return true
+	}
+	for _, id := range e.TargetIDs {
+		if id == q.TargetID {
+			return true
+		}
+	}
+	return false
+}
+
+type ReadStorage interface {
+	// RetrieveEvents retrieves audit events selected by q.
+	// Events are returned most recent first.
+	RetrieveEvents(ctx context.Context, q *Query) ([]*Event, error)
+}
+
+type Storage interface {
+	ReadStorage
+
+	// StoreEvent stores an audit event.
+	// Audit events are never modified once stored.
+	StoreEvent(ctx context.Context, e *Event) error
+}
@@ -0,0 +1,133 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/micromdm/nanocmd/subsystem/audit/storage"
+)
+
+func TestAuditStorage(t *testing.T, newStorage func() storage.Storage) {
+	s := newStorage()
+	ctx := context.Background()
+
+	err := s.StoreEvent(ctx, &storage.Event{ID: "x", Time: time.Now()})
+	if !errors.Is(err, storage.ErrNoActor) {
+		t.Errorf("expected error: %v, got: %v", storage.ErrNoActor, err)
+	}
+
+	now := time.Now().Truncate(time.Second)
+
+	events := []*storage.Event{
+		{
+			ID:        "1",
+			Time:      now.Add(-time.Hour),
+			Actor:     "alice",
+			Action:    "PUT /v1/profiles",
+			TargetIDs: []string{},
+			Params:    map[string][]string{"name": {"wifi"}},
+			Status:    204,
+			Outcome:   storage.OutcomeSuccess,
+		},
+		{
+			ID:           "2",
+			Time:         now.Add(-time.Minute),
+			Actor:        "bob",
+			Action:       "POST /v1/workflow/io.micromdm.wf.lock.v1/start",
+			TargetIDs:    []string{"AAA", "BBB"},
+			WorkflowName: "io.micromdm.wf.lock.v1",
+			InstanceID:   "inst1",
+			Body:         `{"pin":"REDACTED"}`,
+			BodySHA256:   "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+			Status:       200,
+			Outcome:      storage.OutcomeSuccess,
+		},
+		{
+			ID:           "3",
+			Time:         now,
+			Actor:        "scheduler",
+			Action:       "schedule.start",
+			TargetIDs:    []string{"BBB"},
+			WorkflowName: "io.micromdm.wf.inventory.v1",
+			Params:       map[string][]string{"schedule": {"nightly"}},
+			Outcome:      storage.OutcomeFailure,
+			Error:        "no ids",
+		},
+	}
+	for _, e := range events {
+		if err = s.StoreEvent(ctx, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, test := range []struct {
+		name string
+		q    *storage.Query
+		ids  []string
+	}{
+		{"nil", nil, []string{"3", "2", "1"}},
+		{"all", &storage.Query{}, []string{"3", "2", "1"}},
+		{"limit", &storage.Query{Limit: 2}, []string{"3", "2"}},
+		{"actor", &storage.Query{Actor: "alice"}, []string{"1"}},
+		{"action", &storage.Query{Action: "POST /v1/workflow/"}, []string{"2"}},
+		{"target", &storage.Query{TargetID: "BBB"}, []string{"3", "2"}},
+		{"target-limit", &storage.Query{TargetID: "BBB", Limit: 1}, []string{"3"}},
+		{"workflow", &storage.Query{WorkflowName: "io.micromdm.wf.lock.v1"}, []string{"2"}},
+		{"instance", &storage.Query{InstanceID: "inst1"}, []string{"2"}},
+		{"since", &storage.Query{Since: now.Add(-time.Minute)}, []string{"3", "2"}},
+		{"until", &storage.Query{Until: now.Add(-time.Minute)}, []string{"1"}},
+		{"none", &storage.Query{Actor: "mallory"}, nil},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := s.RetrieveEvents(ctx, test.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			for _, e := range r {
+				ids = append(ids, e.ID)
+			}
+			if !reflect.DeepEqual(ids, test.ids) {
+				t.Errorf("ids: have: %v, want: %v", ids, test.ids)
+			}
+		})
+	}
+
+	r, err := s.RetrieveEvents(ctx, &storage.Query{InstanceID: "inst1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r) != 1 {
+		t.Fatalf("expected 1 event, got: %d", len(r))
+	}
+	have, want := r[0], events[1]
+	if !have.Time.Equal(want.Time) {
+		t.Errorf("time: have: %v, want: %v", have.Time, want.Time)
+	}
+	if have.Actor != want.Actor || have.Action != want.Action || have.Status != want.Status || have.Outcome != want.Outcome {
+		t.Errorf("event mismatch: have: %+v, want: %+v", have, want)
+	}
+	if !reflect.DeepEqual(have.TargetIDs, want.TargetIDs) {
+		t.Errorf("target ids: have: %v, want: %v", have.TargetIDs, want.TargetIDs)
+	}
+	if have.Body != want.Body || have.BodySHA256 != want.BodySHA256 {
+		t.Errorf("body: have: %q (%s), want: %q (%s)", have.Body, have.BodySHA256, want.Body, want.BodySHA256)
+	}
+
+	r, err = s.RetrieveEvents(ctx, &storage.Query{Actor: "scheduler"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r) != 1 {
+		t.Fatalf("expected 1 event, got: %d", len(r))
+	}
+	if have, want := r[0].Params, events[2].Params; !reflect.DeepEqual(have, want) {
+		t.Errorf("params: have: %v, want: %v", have, want)
+	}
+	if have, want := r[0].Error, events[2].Error; have != want {
+		t.Errorf("error: have: %v, want: %v", have, want)
+	}
+}
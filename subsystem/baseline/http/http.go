@@ -0,0 +1,131 @@
+// Package http contains HTTP handlers for working with profile baselines.
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/micromdm/nanocmd/http/api"
+	"github.com/micromdm/nanocmd/logkeys"
+	"github.com/micromdm/nanocmd/subsystem/baseline/storage"
+
+	"github.com/alexedwards/flow"
+	"github.com/micromdm/nanolib/log"
+	"github.com/micromdm/nanolib/log/ctxlog"
+)
+
+var (
+	ErrNoName = errors.New("no name provided")
+)
+
+// GetBaselinesHandler returns an HTTP handler that fetches baselines.
+// All baselines are returned unless "name" query parameters are provided.
+func GetBaselinesHandler(store storage.ReadStorage, logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := ctxlog.Logger(r.Context(), logger)
+		baselines, err := store.RetrieveBaselines(r.Context(), r.URL.Query()["name"])
+		if err != nil {
+			logger.Info(logkeys.Message, "retrieve baselines", logkeys.Error, err)
+			api.JSONError(w, err, 0)
+			return
+		}
+		logger.Debug(
+			logkeys.Message, "retrieved baselines",
+			logkeys.GenericCount, len(baselines),
+		)
+		w.Header().Set("Content-Type", "application/json")
+		if err = json.NewEncoder(w).Encode(baselines); err != nil {
+			logger.Info(logkeys.Message, "encoding json to body", logkeys.Error, err)
+			return
+		}
+	}
+}
+
+// GetHandler returns an HTTP handler that fetches a baseline.
+func GetHandler(store storage.ReadStorage, logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := ctxlog.Logger(r.Context(), logger)
+		name := flow.Param(r.Context(), "name")
+		if name == "" {
+			logger.Info(logkeys.Message, "name parameter", logkeys.Error, ErrNoName)
+			api.JSONError(w, ErrNoName, http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("name", name)
+		baselines, err := store.RetrieveBaselines(r.Context(), []string{name})
+		if err != nil {
+			logger.Info(logkeys.Message, "retrieve baseline", logkeys.Error, err)
+			api.JSONError(w, err, 0)
+			return
+		}
+
+		logger.Debug(logkeys.Message, "retrieved baseline")
+		w.Header().Set("Content-Type", "application/json")
+		if err = json.NewEncoder(w).Encode(baselines[name]); err != nil {
+			logger.Info(logkeys.Message, "encoding json to body", logkeys.Error, err)
+			return
+		}
+	}
+}
+
+// PutHandler returns an HTTP handler for uploading a baseline.
+func PutHandler(store storage.Storage, logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := ctxlog.Logger(r.Context(), logger)
+		name := flow.Param(r.Context(), "name")
+		if name == "" {
+			logger.Info(logkeys.Message, "name parameter", logkeys.Error, ErrNoName)
+			api.JSONError(w, ErrNoName, http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("name", name)
+		baseline := new(storage.Baseline)
+		err := json.NewDecoder(r.Body).Decode(baseline)
+		if err != nil {
+			logger.Info(logkeys.Message, "decoding body", logkeys.Error, err)
+			api.JSONError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		if err = baseline.Validate(); err != nil {
+			logger.Info(logkeys.Message, "validating baseline", logkeys.Error, err)
+			api.JSONError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		if err = store.StoreBaseline(r.Context(), name, baseline); err != nil {
+			logger.Info(logkeys.Message, "storing baseline", logkeys.Error, err)
+			api.JSONError(w, err, 0)
+			return
+		}
+
+		logger.Debug(logkeys.Message, "stored baseline")
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// DeleteHandler returns an HTTP handler for deleting a baseline.
+func DeleteHandler(store storage.Storage, logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := ctxlog.Logger(r.Context(), logger)
+		name := flow.Param(r.Context(), "name")
+		if name == "" {
+			logger.Info(logkeys.Message, "name parameter", logkeys.Error, ErrNoName)
+			api.JSONError(w, ErrNoName, http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("name", name)
+		if err := store.DeleteBaseline(r.Context(), name); err != nil {
+			logger.Info(logkeys.Message, "deleting baseline", logkeys.Error, err)
+			api.JSONError(w, err, 0)
+			return
+		}
+
+		logger.Debug(logkeys.Message, "deleted baseline")
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
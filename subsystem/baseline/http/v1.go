@@ -0,0 +1,66 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/micromdm/nanocmd/http/api"
+	"github.com/micromdm/nanocmd/subsystem/baseline/storage"
+	"github.com/micromdm/nanolib/log"
+)
+
+// Mux can register HTTP handlers.
+// Ostensibly this supports flow router.
+type Mux interface {
+	// Handle registers the handler for the given pattern.
+	Handle(pattern string, handler http.Handler, methods ...string)
+}
+
+// HandleAPIv1 registers the various API handlers into mux.
+// API endpoint paths are prepended with prefix.
+// Authentication or any other layered handlers are not present.
+// They are assumed to be layered with mux, possibly at the Handle call.
+// Each handler requires the API principal to have the "baselines:manage" permission.
+// If prefix is empty and these handlers are used in sub-paths then
+// handlers should have that sub-path stripped from the request.
+// The logger is adorned with a "handler" key of the endpoint name.
+func HandleAPIv1(prefix string, mux Mux, logger log.Logger, s storage.Storage) {
+	mux.Handle(
+		prefix+"/baseline/:name",
+		api.RequirePermission(
+			GetHandler(s, logger.With("handler", "get-baseline")),
+			api.PermBaselinesManage,
+			logger,
+		),
+		"GET",
+	)
+
+	mux.Handle(
+		prefix+"/baseline/:name",
+		api.RequirePermission(
+			PutHandler(s, logger.With("handler", "put-baseline")),
+			api.PermBaselinesManage,
+			logger,
+		),
+		"PUT",
+	)
+
+	mux.Handle(
+		prefix+"/baseline/:name",
+		api.RequirePermission(
+			DeleteHandler(s, logger.With("handler", "delete-baseline")),
+			api.PermBaselinesManage,
+			logger,
+		),
+		"DELETE",
+	)
+
+	mux.Handle(
+		prefix+"/baselines",
+		api.RequirePermission(
+			GetBaselinesHandler(s, logger.With("handler", "get-baselines")),
+			api.PermBaselinesManage,
+			logger,
+		),
+		"GET",
+	)
+}
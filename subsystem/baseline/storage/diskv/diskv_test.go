@@ -0,0 +1,12 @@
+package diskv
+
+import (
+	"testing"
+
+	"github.com/micromdm/nanocmd/subsystem/baseline/storage"
+	"github.com/micromdm/nanocmd/subsystem/baseline/storage/test"
+)
+
+func TestDiskv(t *testing.T) {
+	test.TestBaselineStorage(t, func() storage.Storage { return New(t.TempDir()) })
+}
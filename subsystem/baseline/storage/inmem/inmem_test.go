@@ -0,0 +1,12 @@
+package inmem
+
+import (
+	"testing"
+
+	"github.com/micromdm/nanocmd/subsystem/baseline/storage"
+	"github.com/micromdm/nanocmd/subsystem/baseline/storage/test"
+)
+
+func TestInMem(t *testing.T) {
+	test.TestBaselineStorage(t, func() storage.Storage { return New() })
+}
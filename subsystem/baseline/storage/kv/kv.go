@@ -0,0 +1,59 @@
+// Package kv implements a baseline storage backend using JSON with key-value storage.
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/micromdm/nanocmd/subsystem/baseline/storage"
+
+	"github.com/micromdm/nanolib/storage/kv"
+)
+
+// KV is a baseline storage backend using JSON with key-value storage.
+type KV struct {
+	b kv.Bucket
+}
+
+func New(b kv.Bucket) *KV {
+	return &KV{b: b}
+}
+
+// RetrieveBaselines unmarshals the JSON stored using names and returns the baselines.
+// All baselines are returned if no names are provided.
+func (s *KV) RetrieveBaselines(ctx context.Context, names []string) (map[string]*storage.Baseline, error) {
+	if len(names) < 1 {
+		names = kv.AllKeys(ctx, s.b)
+	}
+	r := make(map[string]*storage.Baseline)
+	for _, name := range names {
+		raw, err := s.b.Get(ctx, name)
+		if errors.Is(err, kv.ErrKeyNotFound) {
+			return r, fmt.Errorf("%w: %s: %v", storage.ErrBaselineNotFound, name, err)
+		} else if err != nil {
+			return r, err
+		}
+		b := new(storage.Baseline)
+		if err = json.Unmarshal(raw, b); err != nil {
+			return r, fmt.Errorf("unmarshal baseline: %s: %w", name, err)
+		}
+		r[name] = b
+	}
+	return r, nil
+}
+
+// StoreBaseline marshals b into JSON and stores it using name.
+func (s *KV) StoreBaseline(ctx context.Context, name string, b *storage.Baseline) error {
+	raw, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+	return s.b.Set(ctx, name, raw)
+}
+
+// DeleteBaseline deletes the JSON stored using name.
+func (s *KV) DeleteBaseline(ctx context.Context, name string) error {
+	return s.b.Delete(ctx, name)
+}
@@ -0,0 +1,84 @@
+// Package storage defines types and interfaces supporting profile baselines.
+package storage
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrBaselineNotFound = errors.New("baseline not found")
+	ErrNoProfiles       = errors.New("no profiles to install or remove")
+	ErrNoAssignment     = errors.New("no groups or params assigned")
+)
+
+// Baseline is the desired state of profiles for a set of enrollments.
+// A baseline applies to an enrollment if the enrollment is a member of
+// any of the baseline groups or if the enrollment's MDM URL parameters
+// match all of the baseline params.
+type Baseline struct {
+	// Install contains the names of profiles (in profile storage) that should be installed.
+	Install []string `json:"install,omitempty"`
+	// Remove contains the names of profiles (in profile storage) that must be removed.
+	Remove []string `json:"remove,omitempty"`
+
+	// Groups assigns the baseline to members of these groups.
+	Groups []string `json:"groups,omitempty"`
+	// Params assigns the baseline to enrollments with these MDM URL parameters.
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// Validate checks that b has profiles and an assignment.
+func (b *Baseline) Validate() error {
+	if b == nil {
+		return errors.New("nil baseline")
+	}
+	if len(b.Install) < 1 && len(b.Remove) < 1 {
+		return ErrNoProfiles
+	}
+	if len(b.Groups) < 1 && len(b.Params) < 1 {
+		return ErrNoAssignment
+	}
+	return nil
+}
+
+// Applies reports whether b is assigned to an enrollment that is a
+// member of groups and that has the MDM URL parameters params.
+func (b *Baseline) Applies(groups []string, params map[string]string) bool {
+	if b == nil {
+		return false
+	}
+	for _, assigned := range b.Groups {
+		for _, group := range groups {
+			if assigned == group {
+				return true
+			}
+		}
+	}
+	if len(b.Params) < 1 {
+		return false
+	}
+	for k, v := range b.Params {
+		if pv, ok := params[k]; !ok || pv != v {
+			return false
+		}
+	}
+	return true
+}
+
+type ReadStorage interface {
+	// RetrieveBaselines returns the baselines by name.
+	// All baselines are returned if no names are provided.
+	// ErrBaselineNotFound is returned for any name that hasn't been stored.
+	RetrieveBaselines(ctx context.Context, names []string) (map[string]*Baseline, error)
+}
+
+type Storage interface {
+	ReadStorage
+
+	// StoreBaseline stores (replaces) the named baseline.
+	StoreBaseline(ctx context.Context, name string, b *Baseline) error
+
+	// DeleteBaseline deletes the named baseline.
+	DeleteBaseline(ctx context.Context, name string) error
+}
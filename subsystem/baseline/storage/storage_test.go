@@ -0,0 +1,31 @@
+package storage
+
+import "testing"
+
+func TestApplies(t *testing.T) {
+	b := &Baseline{
+		Groups: []string{"staff", "lab"},
+		Params: map[string]string{"dept": "eng", "site": "hq"},
+	}
+	for _, test := range []struct {
+		groups []string
+		params map[string]string
+		want   bool
+	}{
+		{nil, nil, false},
+		{[]string{"lab"}, nil, true},
+		{[]string{"other"}, map[string]string{"dept": "eng"}, false},
+		{nil, map[string]string{"dept": "eng", "site": "hq", "x": "y"}, true},
+		{nil, map[string]string{"dept": "eng", "site": "branch"}, false},
+	} {
+		if have, want := b.Applies(test.groups, test.params), test.want; have != want {
+			t.Errorf("groups: %v, params: %v: have: %v, want: %v", test.groups, test.params, have, want)
+		}
+	}
+
+	// no params means only groups assign the baseline
+	b = &Baseline{Groups: []string{"staff"}}
+	if b.Applies(nil, map[string]string{"dept": "eng"}) {
+		t.Error("expected baseline to not apply")
+	}
+}
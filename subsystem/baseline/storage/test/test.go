@@ -0,0 +1,60 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/micromdm/nanocmd/subsystem/baseline/storage"
+)
+
+func TestBaselineStorage(t *testing.T, newStorage func() storage.Storage) {
+	s := newStorage()
+	ctx := context.Background()
+
+	b := &storage.Baseline{
+		Install: []string{"wifi", "dock"},
+		Remove:  []string{"legacy"},
+		Groups:  []string{"staff"},
+	}
+
+	err := s.StoreBaseline(ctx, "base1", b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.StoreBaseline(ctx, "base2", &storage.Baseline{
+		Install: []string{"lab"},
+		Params:  map[string]string{"group": "lab"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baselines, err := s.RetrieveBaselines(ctx, []string{"base1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := baselines["base1"], b; !reflect.DeepEqual(have, want) {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	baselines, err = s.RetrieveBaselines(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := len(baselines), 2; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	err = s.DeleteBaseline(ctx, "base1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.RetrieveBaselines(ctx, []string{"base1"})
+	if !errors.Is(err, storage.ErrBaselineNotFound) {
+		t.Errorf("expected ErrBaselineNotFound, have: %v", err)
+	}
+}
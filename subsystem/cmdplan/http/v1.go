@@ -3,6 +3,7 @@ package http
 import (
 	"net/http"
 
+	"github.com/micromdm/nanocmd/http/api"
 	"github.com/micromdm/nanocmd/subsystem/cmdplan/storage"
 	"github.com/micromdm/nanolib/log"
 )
@@ -18,19 +19,28 @@ type Mux interface {
 // API endpoint paths are prepended with prefix.
 // Authentication or any other layered handlers are not present.
 // They are assumed to be layered with mux, possibly at the Handle call.
+// Each handler requires the API principal to have the "cmdplans:manage" permission.
 // If prefix is empty and these handlers are used in sub-paths then
 // handlers should have that sub-path stripped from the request.
 // The logger is adorned with a "handler" key of the endpoint name.
 func HandleAPIv1(prefix string, mux Mux, logger log.Logger, s storage.Storage) {
 	mux.Handle(
 		prefix+"/cmdplan/:name",
-		GetHandler(s, logger.With("handler", "get-cmdplan")),
+		api.RequirePermission(
+			GetHandler(s, logger.With("handler", "get-cmdplan")),
+			api.PermCmdPlansManage,
+			logger,
+		),
 		"GET",
 	)
 
 	mux.Handle(
 		prefix+"/cmdplan/:name",
-		PutHandler(s, logger.With("handler", "put-cmdplan")),
+		api.RequirePermission(
+			PutHandler(s, logger.With("handler", "put-cmdplan")),
+			api.PermCmdPlansManage,
+			logger,
+		),
 		"PUT",
 	)
 }
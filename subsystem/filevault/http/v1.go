@@ -2,6 +2,9 @@ package http
 
 import (
 	"net/http"
+
+	"github.com/micromdm/nanocmd/http/api"
+	"github.com/micromdm/nanolib/log"
 )
 
 // Mux can register HTTP handlers.
@@ -15,8 +18,13 @@ type Mux interface {
 // API endpoint paths are prepended with prefix.
 // Authentication or any other layered handlers are not present.
 // They are assumed to be layered with mux, possibly at the Handle call.
+// Each handler requires the API principal to have the "profiles:manage" permission.
 // If prefix is empty and these handlers are used in sub-paths then
 // handlers should have that sub-path stripped from the request.
-func HandleAPIv1(prefix string, mux Mux) {
-	mux.Handle(prefix+"/fvenable/profiletemplate", GetProfileTemplate(), "GET")
+func HandleAPIv1(prefix string, mux Mux, logger log.Logger) {
+	mux.Handle(
+		prefix+"/fvenable/profiletemplate",
+		api.RequirePermission(GetProfileTemplate(), api.PermProfilesManage, logger),
+		"GET",
+	)
 }
@@ -0,0 +1,63 @@
+// Package secretprk implements retrieving and storing PRKs encrypted at rest.
+package secretprk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/micromdm/nanocmd/subsystem/filevault/storage/invprk"
+	invstorage "github.com/micromdm/nanocmd/subsystem/inventory/storage"
+	"github.com/micromdm/nanocmd/subsystem/secret"
+	"github.com/micromdm/nanocmd/subsystem/secret/storage"
+)
+
+// SecretStorage stores and retrieves secrets encrypted at rest.
+type SecretStorage interface {
+	StoreSecret(ctx context.Context, id, kind, secret string) error
+	RetrieveSecret(ctx context.Context, id, kind string) (string, error)
+}
+
+// SecretPRK retrieves and stores PRKs in secret storage.
+// The time of escrow is recorded in inventory. PRKs stored in plaintext
+// in inventory by earlier versions are migrated when retrieved.
+type SecretPRK struct {
+	s   SecretStorage
+	inv invstorage.Storage
+}
+
+func New(s SecretStorage, inv invstorage.Storage) *SecretPRK {
+	return &SecretPRK{s: s, inv: inv}
+}
+
+// StorePRK encrypts and stores prk for id.
+// Any plaintext PRK in inventory is removed.
+func (s *SecretPRK) StorePRK(ctx context.Context, id, prk string) error {
+	if err := s.s.StoreSecret(ctx, id, secret.KindPRK, prk); err != nil {
+		return fmt.Errorf("store secret: %w", err)
+	}
+	return s.inv.StoreInventoryValues(ctx, id, invstorage.Values{
+		invstorage.KeyLastSource:  "SecretPRK",
+		invstorage.KeyModified:    time.Now(),
+		invstorage.KeyPRKEscrowed: time.Now(),
+		invstorage.KeyPRK:         nil,
+	})
+}
+
+// RetrievePRK retrieves and decrypts the PRK of id.
+func (s *SecretPRK) RetrievePRK(ctx context.Context, id string) (string, error) {
+	prk, err := s.s.RetrieveSecret(ctx, id, secret.KindPRK)
+	if err == nil || !errors.Is(err, storage.ErrSecretNotFound) {
+		return prk, err
+	}
+	// migrate any plaintext PRK of earlier versions
+	prk, legacyErr := invprk.NewInvPRK(s.inv).RetrievePRK(ctx, id)
+	if legacyErr != nil {
+		return "", err
+	}
+	if err = s.StorePRK(ctx, id, prk); err != nil {
+		return prk, fmt.Errorf("migrating plaintext PRK: %w", err)
+	}
+	return prk, nil
+}
@@ -0,0 +1,63 @@
+package secretprk
+
+import (
+	"context"
+	"testing"
+
+	"github.com/micromdm/nanocmd/subsystem/filevault/storage/invprk"
+	invstorage "github.com/micromdm/nanocmd/subsystem/inventory/storage"
+	invinmem "github.com/micromdm/nanocmd/subsystem/inventory/storage/inmem"
+	"github.com/micromdm/nanocmd/subsystem/secret"
+	"github.com/micromdm/nanocmd/subsystem/secret/storage/inmem"
+)
+
+func TestSecretPRK(t *testing.T) {
+	ctx := context.Background()
+	inv := invinmem.New()
+	kek, err := secret.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyring, err := secret.NewKeyring(kek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New(secret.New(inmem.New(), keyring), inv)
+
+	if err = s.StorePRK(ctx, "ID1", "PRK-321-ZYX"); err != nil {
+		t.Fatal(err)
+	}
+	prk, err := s.RetrievePRK(ctx, "ID1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := prk, "PRK-321-ZYX"; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	// migrate a plaintext PRK
+	if err = invprk.NewInvPRK(inv).StorePRK(ctx, "ID2", "PRK-LEGACY"); err != nil {
+		t.Fatal(err)
+	}
+	prk, err = s.RetrievePRK(ctx, "ID2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := prk, "PRK-LEGACY"; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+	idVals, err := inv.RetrieveInventory(ctx, &invstorage.SearchOptions{IDs: []string{"ID2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := idVals["ID2"][invstorage.KeyPRK]; v != nil {
+		t.Errorf("plaintext PRK not removed: %v", v)
+	}
+	if _, ok := idVals["ID2"][invstorage.KeyPRKEscrowed]; !ok {
+		t.Error("PRK escrow time not recorded")
+	}
+
+	if _, err = s.RetrievePRK(ctx, "ID3"); err == nil {
+		t.Error("expected error for missing PRK")
+	}
+}
@@ -0,0 +1,109 @@
+// Package http contains HTTP handlers for working with enrollment groups.
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/micromdm/nanocmd/http/api"
+	"github.com/micromdm/nanocmd/logkeys"
+	"github.com/micromdm/nanocmd/subsystem/group/storage"
+
+	"github.com/alexedwards/flow"
+	"github.com/micromdm/nanolib/log"
+	"github.com/micromdm/nanolib/log/ctxlog"
+)
+
+var (
+	ErrNoName = errors.New("no name provided")
+)
+
+// GetHandler returns an HTTP handler that fetches the enrollment IDs of a group.
+func GetHandler(store storage.ReadStorage, logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := ctxlog.Logger(r.Context(), logger)
+		name := flow.Param(r.Context(), "name")
+		if name == "" {
+			logger.Info(logkeys.Message, "name parameter", logkeys.Error, ErrNoName)
+			api.JSONError(w, ErrNoName, http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("name", name)
+		ids, err := store.RetrieveGroupMembers(r.Context(), name)
+		if err != nil {
+			logger.Info(logkeys.Message, "retrieve group members", logkeys.Error, err)
+			api.JSONError(w, err, 0)
+			return
+		}
+
+		logger.Debug(
+			logkeys.Message, "retrieved group members",
+			logkeys.GenericCount, len(ids),
+		)
+		w.Header().Set("Content-Type", "application/json")
+		if err = json.NewEncoder(w).Encode(ids); err != nil {
+			logger.Info(logkeys.Message, "encoding json to body", logkeys.Error, err)
+			return
+		}
+	}
+}
+
+// PutHandler returns an HTTP handler for uploading the enrollment IDs of a group.
+// The body is a JSON array of enrollment IDs.
+func PutHandler(store storage.Storage, logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := ctxlog.Logger(r.Context(), logger)
+		name := flow.Param(r.Context(), "name")
+		if name == "" {
+			logger.Info(logkeys.Message, "name parameter", logkeys.Error, ErrNoName)
+			api.JSONError(w, ErrNoName, http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("name", name)
+		var ids []string
+		err := json.NewDecoder(r.Body).Decode(&ids)
+		if err != nil {
+			logger.Info(logkeys.Message, "decoding body", logkeys.Error, err)
+			api.JSONError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		if err = store.StoreGroupMembers(r.Context(), name, ids); err != nil {
+			logger.Info(logkeys.Message, "storing group members", logkeys.Error, err)
+			api.JSONError(w, err, 0)
+			return
+		}
+
+		logger.Debug(
+			logkeys.Message, "stored group members",
+			logkeys.GenericCount, len(ids),
+		)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// DeleteHandler returns an HTTP handler for deleting a group.
+func DeleteHandler(store storage.Storage, logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := ctxlog.Logger(r.Context(), logger)
+		name := flow.Param(r.Context(), "name")
+		if name == "" {
+			logger.Info(logkeys.Message, "name parameter", logkeys.Error, ErrNoName)
+			api.JSONError(w, ErrNoName, http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("name", name)
+		if err := store.DeleteGroup(r.Context(), name); err != nil {
+			logger.Info(logkeys.Message, "deleting group", logkeys.Error, err)
+			api.JSONError(w, err, 0)
+			return
+		}
+
+		logger.Debug(logkeys.Message, "deleted group")
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
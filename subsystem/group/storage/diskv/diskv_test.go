@@ -0,0 +1,12 @@
+package diskv
+
+import (
+	"testing"
+
+	"github.com/micromdm/nanocmd/subsystem/group/storage"
+	"github.com/micromdm/nanocmd/subsystem/group/storage/test"
+)
+
+func TestDiskv(t *testing.T) {
+	test.TestGroupStorage(t, func() storage.Storage { return New(t.TempDir()) })
+}
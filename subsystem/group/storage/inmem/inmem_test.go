@@ -0,0 +1,12 @@
+package inmem
+
+import (
+	"testing"
+
+	"github.com/micromdm/nanocmd/subsystem/group/storage"
+	"github.com/micromdm/nanocmd/subsystem/group/storage/test"
+)
+
+func TestInMem(t *testing.T) {
+	test.TestGroupStorage(t, func() storage.Storage { return New() })
+}
@@ -0,0 +1,73 @@
+// Package kv implements a group storage backend using JSON with key-value storage.
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/micromdm/nanocmd/subsystem/group/storage"
+
+	"github.com/micromdm/nanolib/storage/kv"
+)
+
+// KV is a group storage backend using JSON with key-value storage.
+type KV struct {
+	b kv.Bucket
+}
+
+func New(b kv.Bucket) *KV {
+	return &KV{b: b}
+}
+
+// RetrieveGroupMembers unmarshals the JSON stored using name and returns the enrollment IDs.
+func (s *KV) RetrieveGroupMembers(ctx context.Context, name string) ([]string, error) {
+	if name == "" {
+		return nil, storage.ErrNoName
+	}
+	raw, err := s.b.Get(ctx, name)
+	if errors.Is(err, kv.ErrKeyNotFound) {
+		return nil, fmt.Errorf("%w: %s", storage.ErrGroupNotFound, name)
+	} else if err != nil {
+		return nil, err
+	}
+	var ids []string
+	return ids, json.Unmarshal(raw, &ids)
+}
+
+// RetrieveGroups returns the names of the groups that id is a member of.
+// Note this traverses every group in the key-value store.
+func (s *KV) RetrieveGroups(ctx context.Context, id string) ([]string, error) {
+	var names []string
+	for _, name := range kv.AllKeys(ctx, s.b) {
+		ids, err := s.RetrieveGroupMembers(ctx, name)
+		if err != nil {
+			return names, fmt.Errorf("retrieving group members: %s: %w", name, err)
+		}
+		for _, member := range ids {
+			if member == id {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	return names, nil
+}
+
+// StoreGroupMembers marshals ids into JSON and stores it using name.
+func (s *KV) StoreGroupMembers(ctx context.Context, name string, ids []string) error {
+	if name == "" {
+		return storage.ErrNoName
+	}
+	raw, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+	return s.b.Set(ctx, name, raw)
+}
+
+// DeleteGroup deletes the JSON stored using name.
+func (s *KV) DeleteGroup(ctx context.Context, name string) error {
+	return s.b.Delete(ctx, name)
+}
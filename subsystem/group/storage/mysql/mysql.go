@@ -0,0 +1,20 @@
+// Package mysql implements a group storage backend backed by a MySQL key-value table.
+package mysql
+
+import (
+	"database/sql"
+
+	"github.com/micromdm/nanocmd/subsystem/group/storage/kv"
+	"github.com/micromdm/nanocmd/utils/kvmysql"
+)
+
+// MySQL is a group storage backend backed by a MySQL key-value table.
+// See the kvmysql package for the table schema.
+type MySQL struct {
+	*kv.KV
+}
+
+// New creates a new group data store using the key-value table of db.
+func New(db *sql.DB) *MySQL {
+	return &MySQL{KV: kv.New(kvmysql.New(db, "group"))}
+}
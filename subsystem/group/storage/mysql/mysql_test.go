@@ -0,0 +1,26 @@
+package mysql
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/micromdm/nanocmd/subsystem/group/storage"
+	"github.com/micromdm/nanocmd/subsystem/group/storage/test"
+)
+
+func TestMySQL(t *testing.T) {
+	testDSN := os.Getenv("NANOCMD_MYSQL_STORAGE_TEST_DSN")
+	if testDSN == "" {
+		t.Skip("NANOCMD_MYSQL_STORAGE_TEST_DSN not set")
+	}
+
+	db, err := sql.Open("mysql", testDSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	test.TestGroupStorage(t, func() storage.Storage { return New(db) })
+}
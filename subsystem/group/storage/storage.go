@@ -0,0 +1,32 @@
+// Package storage defines types and interfaces supporting enrollment groups.
+package storage
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrNoName        = errors.New("no group name supplied")
+	ErrGroupNotFound = errors.New("group not found")
+)
+
+type ReadStorage interface {
+	// RetrieveGroupMembers returns the enrollment IDs of the named group.
+	// ErrGroupNotFound is returned if the group hasn't been stored.
+	RetrieveGroupMembers(ctx context.Context, name string) ([]string, error)
+
+	// RetrieveGroups returns the names of the groups that id is a member of.
+	// An empty slice is returned if id is not a member of any group.
+	RetrieveGroups(ctx context.Context, id string) ([]string, error)
+}
+
+type Storage interface {
+	ReadStorage
+
+	// StoreGroupMembers stores (replaces) the enrollment IDs of the named group.
+	StoreGroupMembers(ctx context.Context, name string, ids []string) error
+
+	// DeleteGroup deletes the named group.
+	DeleteGroup(ctx context.Context, name string) error
+}
@@ -0,0 +1,61 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/micromdm/nanocmd/subsystem/group/storage"
+)
+
+func TestGroupStorage(t *testing.T, newStorage func() storage.Storage) {
+	s := newStorage()
+	ctx := context.Background()
+
+	err := s.StoreGroupMembers(ctx, "staff", []string{"AA11BB22", "CC33DD44"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.StoreGroupMembers(ctx, "lab", []string{"AA11BB22"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids, err := s.RetrieveGroupMembers(ctx, "staff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := ids, []string{"AA11BB22", "CC33DD44"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	names, err := s.RetrieveGroups(ctx, "AA11BB22")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(names)
+	if have, want := names, []string{"lab", "staff"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	names, err = s.RetrieveGroups(ctx, "EE55FF66")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := len(names), 0; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	err = s.DeleteGroup(ctx, "staff")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.RetrieveGroupMembers(ctx, "staff")
+	if !errors.Is(err, storage.ErrGroupNotFound) {
+		t.Fatalf("have: %v, want: %v", err, storage.ErrGroupNotFound)
+	}
+}
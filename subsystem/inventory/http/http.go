@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/micromdm/nanocmd/http/api"
 	"github.com/micromdm/nanocmd/logkeys"
@@ -15,11 +17,14 @@ import (
 )
 
 var (
-	ErrNoIDs     = errors.New("no IDs provided")
-	ErrNoStorage = errors.New("no storage backend")
+	ErrNoIDs       = errors.New("no IDs provided")
+	ErrNoStorage   = errors.New("no storage backend")
+	ErrNoBundleID  = errors.New("no bundle ID provided")
+	ErrInvalidDays = errors.New("invalid days")
 )
 
 // RetrieveInventory returns an HTTP handler that retrieves inventory data for enrollment IDs.
+// Secret values (see storage.SecretKeys) are omitted.
 func RetrieveInventory(store storage.ReadStorage, logger log.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		logger := ctxlog.Logger(r.Context(), logger)
@@ -50,6 +55,11 @@ func RetrieveInventory(store storage.ReadStorage, logger log.Logger) http.Handle
 		logger.Debug(
 			logkeys.Message, "retrieved inventory",
 		)
+		for _, values := range idValues {
+			for _, key := range storage.SecretKeys {
+				delete(values, key)
+			}
+		}
 		w.Header().Set("Content-type", "application/json")
 		err = json.NewEncoder(w).Encode(idValues)
 		if err != nil {
@@ -58,3 +68,90 @@ func RetrieveInventory(store storage.ReadStorage, logger log.Logger) http.Handle
 		}
 	}
 }
+
+// SearchApps returns an HTTP handler that finds enrollments with an installed application.
+// The "bundle_id" query parameter is required and the optional "max_version"
+// parameter limits results to applications at or below that version.
+func SearchApps(store storage.ReadAppStorage, logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := ctxlog.Logger(r.Context(), logger)
+		if store == nil {
+			logger.Info(logkeys.Message, "search apps", logkeys.Error, ErrNoStorage)
+			api.JSONError(w, ErrNoStorage, 0)
+			return
+		}
+
+		opts := &storage.AppSearchOptions{
+			Identifier: r.URL.Query().Get("bundle_id"),
+			MaxVersion: r.URL.Query().Get("max_version"),
+		}
+		if opts.Identifier == "" {
+			logger.Info(logkeys.Message, "parameters", logkeys.Error, ErrNoBundleID)
+			api.JSONError(w, ErrNoBundleID, http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("bundle_id", opts.Identifier, "max_version", opts.MaxVersion)
+		idApps, err := store.SearchApps(r.Context(), opts)
+		if err != nil {
+			logger.Info(logkeys.Message, "search apps", logkeys.Error, err)
+			api.JSONError(w, err, 0)
+			return
+		}
+		logger.Debug(
+			logkeys.Message, "searched apps",
+			logkeys.GenericCount, len(idApps),
+		)
+		w.Header().Set("Content-type", "application/json")
+		err = json.NewEncoder(w).Encode(idApps)
+		if err != nil {
+			logger.Info(logkeys.Message, "encode response", logkeys.Error, err)
+			return
+		}
+	}
+}
+
+// SearchExpiringCerts returns an HTTP handler that finds installed
+// certificates which expire within the "days" query parameter.
+// Already expired certificates are included.
+func SearchExpiringCerts(store storage.ReadCertStorage, logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := ctxlog.Logger(r.Context(), logger)
+		if store == nil {
+			logger.Info(logkeys.Message, "search certs", logkeys.Error, ErrNoStorage)
+			api.JSONError(w, ErrNoStorage, 0)
+			return
+		}
+
+		days, err := strconv.Atoi(r.URL.Query().Get("days"))
+		if err == nil && days < 0 {
+			err = ErrInvalidDays
+		}
+		if err != nil {
+			logger.Info(logkeys.Message, "parameters", logkeys.Error, err)
+			api.JSONError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("days", days)
+		opts := &storage.CertSearchOptions{
+			ExpiresBefore: time.Now().AddDate(0, 0, days),
+		}
+		idCerts, err := store.SearchCerts(r.Context(), opts)
+		if err != nil {
+			logger.Info(logkeys.Message, "search certs", logkeys.Error, err)
+			api.JSONError(w, err, 0)
+			return
+		}
+		logger.Debug(
+			logkeys.Message, "searched certs",
+			logkeys.GenericCount, len(idCerts),
+		)
+		w.Header().Set("Content-type", "application/json")
+		err = json.NewEncoder(w).Encode(idCerts)
+		if err != nil {
+			logger.Info(logkeys.Message, "encode response", logkeys.Error, err)
+			return
+		}
+	}
+}
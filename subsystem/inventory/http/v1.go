@@ -3,6 +3,7 @@ package http
 import (
 	"net/http"
 
+	"github.com/micromdm/nanocmd/http/api"
 	"github.com/micromdm/nanocmd/subsystem/inventory/storage"
 	"github.com/micromdm/nanolib/log"
 )
@@ -18,13 +19,38 @@ type Mux interface {
 // API endpoint paths are prepended with prefix.
 // Authentication or any other layered handlers are not present.
 // They are assumed to be layered with mux, possibly at the Handle call.
+// Each handler requires the API principal to have the "inventory:read" permission.
 // If prefix is empty and these handlers are used in sub-paths then
 // handlers should have that sub-path stripped from the request.
 // The logger is adorned with a "handler" key of the endpoint name.
-func HandleAPIv1(prefix string, mux Mux, logger log.Logger, s storage.ReadStorage) {
+func HandleAPIv1(prefix string, mux Mux, logger log.Logger, s storage.ReadStorage, apps storage.ReadAppStorage, certs storage.ReadCertStorage) {
 	mux.Handle(
 		"/inventory",
-		RetrieveInventory(s, logger.With("handler", "get-inventory")),
+		api.RequirePermission(
+			RetrieveInventory(s, logger.With("handler", "get-inventory")),
+			api.PermInventoryRead,
+			logger,
+		),
+		"GET",
+	)
+
+	mux.Handle(
+		prefix+"/inventory/apps",
+		api.RequirePermission(
+			SearchApps(apps, logger.With("handler", "search-apps")),
+			api.PermInventoryRead,
+			logger,
+		),
+		"GET",
+	)
+
+	mux.Handle(
+		prefix+"/inventory/certs/expiring",
+		api.RequirePermission(
+			SearchExpiringCerts(certs, logger.With("handler", "search-expiring-certs")),
+			api.PermInventoryRead,
+			logger,
+		),
 		"GET",
 	)
 }
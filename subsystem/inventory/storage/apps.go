@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrNoAppIdentifier = errors.New("no app identifier supplied")
+
+// App is a normalized installed application of an enrollment.
+type App struct {
+	Identifier   string    `json:"identifier"`
+	Name         string    `json:"name,omitempty"`
+	ShortVersion string    `json:"short_version,omitempty"`
+	Version      string    `json:"version,omitempty"`
+	BundleSize   int       `json:"bundle_size,omitempty"`
+	Source       string    `json:"source,omitempty"`
+	Modified     time.Time `json:"modified"`
+}
+
+// DisplayVersion returns the preferred version string of the app.
+// The "short" (marketing) version is preferred to the bundle version.
+func (a *App) DisplayVersion() string {
+	if a.ShortVersion != "" {
+		return a.ShortVersion
+	}
+	return a.Version
+}
+
+// AppSearchOptions is a query for installed applications.
+type AppSearchOptions struct {
+	// Identifier is the bundle ID of the application. Required.
+	Identifier string
+
+	// MaxVersion limits results to applications whose version is at
+	// or below this version. Versions are compared with [App.DisplayVersion].
+	// If empty any version matches.
+	MaxVersion string
+}
+
+type ReadAppStorage interface {
+	// RetrieveApps returns the installed applications mapped by enrollment ID.
+	// If no IDs are provided an ErrNoIDs should be returned.
+	// IDs with no installed application data should be omitted from the output.
+	RetrieveApps(ctx context.Context, ids []string) (map[string][]App, error)
+
+	// SearchApps finds enrollments which have installed applications matching opt.
+	// The matching application is returned mapped by enrollment ID.
+	SearchApps(ctx context.Context, opt *AppSearchOptions) (map[string]App, error)
+}
+
+type AppStorage interface {
+	ReadAppStorage
+
+	// StoreApps stores (replaces) the full installed application list for an enrollment ID.
+	StoreApps(ctx context.Context, id string, apps []App) error
+}
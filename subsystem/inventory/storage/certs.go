@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrNoExpiry = errors.New("no expiry supplied")
+
+// Certificate is a normalized certificate installed on an enrollment.
+type Certificate struct {
+	CommonName string    `json:"common_name,omitempty"`
+	Subject    string    `json:"subject,omitempty"`
+	Issuer     string    `json:"issuer,omitempty"`
+	NotAfter   time.Time `json:"not_after"`
+	IsIdentity bool      `json:"is_identity"`
+	SHA256     string    `json:"sha256"` // hex-encoded hash of the DER certificate
+	Modified   time.Time `json:"modified"`
+}
+
+// CertSearchOptions is a query for installed certificates.
+type CertSearchOptions struct {
+	// ExpiresBefore matches certificates whose NotAfter is before this time.
+	// Already expired certificates are included. Required.
+	ExpiresBefore time.Time
+}
+
+type ReadCertStorage interface {
+	// RetrieveCerts returns the installed certificates mapped by enrollment ID.
+	// If no IDs are provided an ErrNoIDs should be returned.
+	// IDs with no certificate data should be omitted from the output.
+	RetrieveCerts(ctx context.Context, ids []string) (map[string][]Certificate, error)
+
+	// SearchCerts finds installed certificates matching opt.
+	// Matching certificates are returned mapped by enrollment ID.
+	SearchCerts(ctx context.Context, opt *CertSearchOptions) (map[string][]Certificate, error)
+}
+
+type CertStorage interface {
+	ReadCertStorage
+
+	// StoreCerts stores (replaces) the full certificate list for an enrollment ID.
+	StoreCerts(ctx context.Context, id string, certs []Certificate) error
+}
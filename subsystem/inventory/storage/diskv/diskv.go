@@ -2,6 +2,8 @@
 package diskv
 
 import (
+	"path/filepath"
+
 	"github.com/micromdm/nanocmd/subsystem/inventory/storage/kv"
 
 	"github.com/micromdm/nanolib/storage/kv/kvdiskv"
@@ -12,15 +14,28 @@ import (
 // Diskv is an inventory subsystem backend which uses diskv as the key-value store.
 type Diskv struct {
 	*kv.KV
+	*kv.AppKV
+	*kv.CertKV
+	*kv.InstalledProfileKV
+}
+
+func newDiskv(path string) *diskv.Diskv {
+	return diskv.New(diskv.Options{
+		BasePath:     path,
+		Transform:    kvdiskv.FlatTransform,
+		CacheSizeMax: 1024 * 1024,
+	})
 }
 
 // New creates a new profile store at on disk at path.
+// Installed application, certificate, and profile lists are stored in
+// the "apps", "certs", and "profiles" sub-directories, respectively.
 func New(path string) *Diskv {
 	return &Diskv{
-		KV: kv.New(kvtxn.New(kvdiskv.New(diskv.New(diskv.Options{
-			BasePath:     path,
-			Transform:    kvdiskv.FlatTransform,
-			CacheSizeMax: 1024 * 1024,
-		})))),
+		KV:     kv.New(kvtxn.New(kvdiskv.New(newDiskv(path)))),
+		AppKV:  kv.NewAppKV(kvdiskv.New(newDiskv(filepath.Join(path, "apps")))),
+		CertKV: kv.NewCertKV(kvdiskv.New(newDiskv(filepath.Join(path, "certs")))),
+
+		InstalledProfileKV: kv.NewInstalledProfileKV(kvdiskv.New(newDiskv(filepath.Join(path, "profiles")))),
 	}
 }
@@ -9,4 +9,7 @@ import (
 
 func TestDiskv(t *testing.T) {
 	test.TestStorage(t, func() storage.Storage { return New(t.TempDir()) })
+	test.TestAppStorage(t, func() storage.AppStorage { return New(t.TempDir()) })
+	test.TestCertStorage(t, func() storage.CertStorage { return New(t.TempDir()) })
+	test.TestInstalledProfileStorage(t, func() storage.InstalledProfileStorage { return New(t.TempDir()) })
 }
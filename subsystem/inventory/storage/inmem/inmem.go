@@ -11,9 +11,18 @@ import (
 // InMem is an in-memory inventory subsystem storage system backend.
 type InMem struct {
 	*kv.KV
+	*kv.AppKV
+	*kv.CertKV
+	*kv.InstalledProfileKV
 }
 
 // New creates a new inventory subsystem storage system backend.
 func New() *InMem {
-	return &InMem{KV: kv.New(kvtxn.New(kvmap.New()))}
+	return &InMem{
+		KV:     kv.New(kvtxn.New(kvmap.New())),
+		AppKV:  kv.NewAppKV(kvmap.New()),
+		CertKV: kv.NewCertKV(kvmap.New()),
+
+		InstalledProfileKV: kv.NewInstalledProfileKV(kvmap.New()),
+	}
 }
@@ -9,4 +9,7 @@ import (
 
 func TestInMem(t *testing.T) {
 	test.TestStorage(t, func() storage.Storage { return New() })
+	test.TestAppStorage(t, func() storage.AppStorage { return New() })
+	test.TestCertStorage(t, func() storage.CertStorage { return New() })
+	test.TestInstalledProfileStorage(t, func() storage.InstalledProfileStorage { return New() })
 }
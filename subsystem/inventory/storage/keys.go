@@ -10,7 +10,8 @@ const (
 	KeyEthernetMAC  = "ethernet_mac"  // string
 	KeySIPEnabled   = "sip_enabled"   // bool
 	KeyFDEEnabled   = "fde_enabled"   // bool
-	KeyPRK          = "prk"           // string
+	KeyPRK          = "prk"           // string (legacy plaintext, see SecretKeys)
+	KeyPRKEscrowed  = "prk_escrowed"  // time.Time
 	KeySupervised   = "supervised"    // bool
 	KeyLastSource   = "last_source"   // string
 	KeyModified     = "modified"      // time.Time
@@ -19,3 +20,22 @@ const (
 	KeySupportsLOM  = "supports_lom"  // bool
 	KeyAppleSilicon = "apple_silicon" // bool
 )
+
+// Keys of the Authenticate message attributes of an enrollment.
+// These are recorded by the engine for evaluating event subscription
+// conditions of events other than Authenticate.
+const (
+	KeyAuthenticateModel       = "authenticate_model"        // string
+	KeyAuthenticateProductName = "authenticate_product_name" // string
+	KeyAuthenticateOSVersion   = "authenticate_os_version"   // string
+)
+
+// KeyLockPIN is the key of the DeviceLock PIN of the lock workflow.
+const KeyLockPIN = "io.micromdm.wf.lock.v1.pin" // string (legacy plaintext, see SecretKeys)
+
+// SecretKeys are the keys of secret values.
+// Secrets are now encrypted at rest by the secret subsystem instead.
+// These keys only hold plaintext secrets stored by earlier versions
+// until they are migrated at startup. They are omitted from inventory
+// API responses.
+var SecretKeys = []string{KeyPRK, KeyLockPIN}
@@ -0,0 +1,94 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/micromdm/nanocmd/subsystem/inventory/storage"
+	"github.com/micromdm/nanocmd/utils/version"
+
+	"github.com/micromdm/nanolib/storage/kv"
+)
+
+// AppKV is an installed application inventory storage backend using a key-value store.
+type AppKV struct {
+	b kv.KeysTraversingBucket
+}
+
+// NewAppKV creates a new installed application inventory storage backend.
+func NewAppKV(b kv.KeysTraversingBucket) *AppKV {
+	return &AppKV{b: b}
+}
+
+func (s *AppKV) getApps(ctx context.Context, id string) ([]storage.App, error) {
+	jsonApps, err := s.b.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	var apps []storage.App
+	if err = json.Unmarshal(jsonApps, &apps); err != nil {
+		return nil, fmt.Errorf("unmarshal apps for %s: %w", id, err)
+	}
+	return apps, nil
+}
+
+// RetrieveApps returns the installed applications mapped by enrollment ID.
+func (s *AppKV) RetrieveApps(ctx context.Context, ids []string) (map[string][]storage.App, error) {
+	if len(ids) < 1 {
+		return nil, storage.ErrNoIDs
+	}
+	r := make(map[string][]storage.App)
+	for _, id := range ids {
+		apps, err := s.getApps(ctx, id)
+		if errors.Is(err, kv.ErrKeyNotFound) {
+			continue
+		} else if err != nil {
+			return r, fmt.Errorf("getting apps for %s: %w", id, err)
+		}
+		r[id] = apps
+	}
+	return r, nil
+}
+
+// SearchApps finds enrollments which have installed applications matching opt.
+// Note this traverses every enrollment in the key-value store.
+func (s *AppKV) SearchApps(ctx context.Context, opt *storage.AppSearchOptions) (map[string]storage.App, error) {
+	if opt == nil || opt.Identifier == "" {
+		return nil, storage.ErrNoAppIdentifier
+	}
+	r := make(map[string]storage.App)
+	for _, id := range kv.AllKeys(ctx, s.b) {
+		apps, err := s.getApps(ctx, id)
+		if errors.Is(err, kv.ErrKeyNotFound) {
+			// possibly deleted while traversing
+			continue
+		} else if err != nil {
+			return r, fmt.Errorf("getting apps for %s: %w", id, err)
+		}
+		for _, app := range apps {
+			if app.Identifier != opt.Identifier {
+				continue
+			}
+			if opt.MaxVersion != "" && version.Compare(app.DisplayVersion(), opt.MaxVersion) > 0 {
+				continue
+			}
+			r[id] = app
+			break
+		}
+	}
+	return r, nil
+}
+
+// StoreApps stores (replaces) the full installed application list for an enrollment ID.
+func (s *AppKV) StoreApps(ctx context.Context, id string, apps []storage.App) error {
+	if id == "" {
+		return storage.ErrNoIDs
+	}
+	jsonApps, err := json.Marshal(apps)
+	if err != nil {
+		return fmt.Errorf("marshal apps: %w", err)
+	}
+	return s.b.Set(ctx, id, jsonApps)
+}
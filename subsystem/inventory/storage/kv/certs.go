@@ -0,0 +1,89 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/micromdm/nanocmd/subsystem/inventory/storage"
+
+	"github.com/micromdm/nanolib/storage/kv"
+)
+
+// CertKV is a certificate inventory storage backend using a key-value store.
+type CertKV struct {
+	b kv.KeysTraversingBucket
+}
+
+// NewCertKV creates a new certificate inventory storage backend.
+func NewCertKV(b kv.KeysTraversingBucket) *CertKV {
+	return &CertKV{b: b}
+}
+
+func (s *CertKV) getCerts(ctx context.Context, id string) ([]storage.Certificate, error) {
+	jsonCerts, err := s.b.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	var certs []storage.Certificate
+	if err = json.Unmarshal(jsonCerts, &certs); err != nil {
+		return nil, fmt.Errorf("unmarshal certs for %s: %w", id, err)
+	}
+	return certs, nil
+}
+
+// RetrieveCerts returns the installed certificates mapped by enrollment ID.
+func (s *CertKV) RetrieveCerts(ctx context.Context, ids []string) (map[string][]storage.Certificate, error) {
+	if len(ids) < 1 {
+		return nil, storage.ErrNoIDs
+	}
+	r := make(map[string][]storage.Certificate)
+	for _, id := range ids {
+		certs, err := s.getCerts(ctx, id)
+		if errors.Is(err, kv.ErrKeyNotFound) {
+			continue
+		} else if err != nil {
+			return r, fmt.Errorf("getting certs for %s: %w", id, err)
+		}
+		r[id] = certs
+	}
+	return r, nil
+}
+
+// SearchCerts finds installed certificates matching opt.
+// Note this traverses every enrollment in the key-value store.
+func (s *CertKV) SearchCerts(ctx context.Context, opt *storage.CertSearchOptions) (map[string][]storage.Certificate, error) {
+	if opt == nil || opt.ExpiresBefore.IsZero() {
+		return nil, storage.ErrNoExpiry
+	}
+	r := make(map[string][]storage.Certificate)
+	for _, id := range kv.AllKeys(ctx, s.b) {
+		certs, err := s.getCerts(ctx, id)
+		if errors.Is(err, kv.ErrKeyNotFound) {
+			// possibly deleted while traversing
+			continue
+		} else if err != nil {
+			return r, fmt.Errorf("getting certs for %s: %w", id, err)
+		}
+		for _, cert := range certs {
+			if cert.NotAfter.IsZero() || !cert.NotAfter.Before(opt.ExpiresBefore) {
+				continue
+			}
+			r[id] = append(r[id], cert)
+		}
+	}
+	return r, nil
+}
+
+// StoreCerts stores (replaces) the full certificate list for an enrollment ID.
+func (s *CertKV) StoreCerts(ctx context.Context, id string, certs []storage.Certificate) error {
+	if id == "" {
+		return storage.ErrNoIDs
+	}
+	jsonCerts, err := json.Marshal(certs)
+	if err != nil {
+		return fmt.Errorf("marshal certs: %w", err)
+	}
+	return s.b.Set(ctx, id, jsonCerts)
+}
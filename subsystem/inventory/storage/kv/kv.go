@@ -15,12 +15,12 @@ import (
 
 // KV is an inventory subsystem storage backend using a key-value store.
 type KV struct {
-	b  kv.TxnCRUDBucket
+	b  kv.TxnBucketWithCRUD
 	mu sync.RWMutex
 }
 
 // New creates a new inventory subsystem backend.
-func New(b kv.TxnCRUDBucket) *KV {
+func New(b kv.TxnBucketWithCRUD) *KV {
 	return &KV{b: b}
 }
 
@@ -52,6 +52,37 @@ func (s *KV) RetrieveInventory(ctx context.Context, opt *storage.SearchOptions)
 	return r, nil
 }
 
+// RetrieveInventoryKeys returns the non-nil values of keys mapped by
+// enrollment ID. Note this traverses every enrollment in the key-value store.
+func (s *KV) RetrieveInventoryKeys(ctx context.Context, keys []string) (map[string]storage.Values, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r := make(map[string]storage.Values)
+	for _, id := range kv.AllKeys(ctx, s.b) {
+		jsonValues, err := s.b.Get(ctx, id)
+		if err != nil {
+			return r, fmt.Errorf("getting values for %s: %w", id, err)
+		}
+
+		var values storage.Values
+		if err = json.Unmarshal(jsonValues, &values); err != nil {
+			return r, fmt.Errorf("unmarshal values for %s: %w", id, err)
+		}
+
+		found := make(storage.Values)
+		for _, k := range keys {
+			if v := values[k]; v != nil {
+				found[k] = v
+			}
+		}
+		if len(found) > 0 {
+			r[id] = found
+		}
+	}
+	return r, nil
+}
+
 // StoreInventoryValues stores inventory data about the specified ID.
 func (s *KV) StoreInventoryValues(ctx context.Context, id string, newValues storage.Values) error {
 	if id == "" {
@@ -0,0 +1,57 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/micromdm/nanocmd/subsystem/inventory/storage"
+
+	"github.com/micromdm/nanolib/storage/kv"
+)
+
+// InstalledProfileKV is an installed profile inventory storage backend using a key-value store.
+type InstalledProfileKV struct {
+	b kv.KeysTraversingBucket
+}
+
+// NewInstalledProfileKV creates a new installed profile inventory storage backend.
+func NewInstalledProfileKV(b kv.KeysTraversingBucket) *InstalledProfileKV {
+	return &InstalledProfileKV{b: b}
+}
+
+// RetrieveInstalledProfiles returns the installed profiles mapped by enrollment ID.
+// If no IDs are provided then every enrollment in the key-value store is returned.
+func (s *InstalledProfileKV) RetrieveInstalledProfiles(ctx context.Context, ids []string) (map[string][]storage.InstalledProfile, error) {
+	if len(ids) < 1 {
+		ids = kv.AllKeys(ctx, s.b)
+	}
+	r := make(map[string][]storage.InstalledProfile)
+	for _, id := range ids {
+		jsonProfiles, err := s.b.Get(ctx, id)
+		if errors.Is(err, kv.ErrKeyNotFound) {
+			continue
+		} else if err != nil {
+			return r, fmt.Errorf("getting profiles for %s: %w", id, err)
+		}
+		var profiles []storage.InstalledProfile
+		if err = json.Unmarshal(jsonProfiles, &profiles); err != nil {
+			return r, fmt.Errorf("unmarshal profiles for %s: %w", id, err)
+		}
+		r[id] = profiles
+	}
+	return r, nil
+}
+
+// StoreInstalledProfiles stores (replaces) the full installed profile list for an enrollment ID.
+func (s *InstalledProfileKV) StoreInstalledProfiles(ctx context.Context, id string, profiles []storage.InstalledProfile) error {
+	if id == "" {
+		return storage.ErrNoIDs
+	}
+	jsonProfiles, err := json.Marshal(profiles)
+	if err != nil {
+		return fmt.Errorf("marshal profiles: %w", err)
+	}
+	return s.b.Set(ctx, id, jsonProfiles)
+}
@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// InstalledProfile is a normalized configuration profile installed on an enrollment.
+type InstalledProfile struct {
+	Identifier  string `json:"identifier"`
+	UUID        string `json:"uuid"`
+	DisplayName string `json:"display_name,omitempty"`
+
+	// Signer is the subject of the (first) profile signing certificate.
+	// Empty if the profile was not signed.
+	Signer string `json:"signer,omitempty"`
+
+	// Managed reports whether the profile was installed by MDM.
+	// Nil if the enrollment did not report this.
+	Managed *bool `json:"managed,omitempty"`
+
+	Modified time.Time `json:"modified"`
+}
+
+type ReadInstalledProfileStorage interface {
+	// RetrieveInstalledProfiles returns the installed profiles mapped by enrollment ID.
+	// If no IDs are provided then all enrollments are returned.
+	// IDs with no installed profile data should be omitted from the output.
+	RetrieveInstalledProfiles(ctx context.Context, ids []string) (map[string][]InstalledProfile, error)
+}
+
+type InstalledProfileStorage interface {
+	ReadInstalledProfileStorage
+
+	// StoreInstalledProfiles stores (replaces) the full installed profile list for an enrollment ID.
+	StoreInstalledProfiles(ctx context.Context, id string, profiles []InstalledProfile) error
+}
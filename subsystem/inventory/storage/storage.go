@@ -26,8 +26,17 @@ type ReadStorage interface {
 	RetrieveInventory(ctx context.Context, opt *SearchOptions) (map[string]Values, error)
 }
 
+// KeysSearcher finds inventory data by key.
+type KeysSearcher interface {
+	// RetrieveInventoryKeys returns the values of keys mapped by
+	// enrollment ID for every enrollment that has a non-nil value for
+	// any of keys. Only the non-nil values of keys are returned.
+	RetrieveInventoryKeys(ctx context.Context, keys []string) (map[string]Values, error)
+}
+
 type Storage interface {
 	ReadStorage
+	KeysSearcher
 
 	// StoreInventoryValues stores inventory data about the specified ID.
 	StoreInventoryValues(ctx context.Context, id string, values Values) error
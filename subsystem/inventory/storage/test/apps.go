@@ -0,0 +1,94 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/micromdm/nanocmd/subsystem/inventory/storage"
+)
+
+func TestAppStorage(t *testing.T, newStorage func() storage.AppStorage) {
+	s := newStorage()
+	ctx := context.Background()
+
+	_, err := s.RetrieveApps(ctx, nil)
+	if err == nil {
+		t.Error("expected error for no IDs")
+	}
+
+	err = s.StoreApps(ctx, "AA11BB22", []storage.App{
+		{Identifier: "com.example.a", ShortVersion: "1.2.0"},
+		{Identifier: "com.example.b", Version: "20"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.StoreApps(ctx, "CC33DD44", []storage.App{
+		{Identifier: "com.example.a", ShortVersion: "1.10"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idApps, err := s.RetrieveApps(ctx, []string{"AA11BB22", "EE55FF66"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(idApps), 1; have != want {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+
+	if have, want := len(idApps["AA11BB22"]), 2; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	_, err = s.SearchApps(ctx, &storage.AppSearchOptions{})
+	if err == nil {
+		t.Error("expected error for no identifier")
+	}
+
+	for _, test := range []struct {
+		opt  *storage.AppSearchOptions
+		want []string
+	}{
+		{&storage.AppSearchOptions{Identifier: "com.example.a"}, []string{"AA11BB22", "CC33DD44"}},
+		{&storage.AppSearchOptions{Identifier: "com.example.a", MaxVersion: "1.9"}, []string{"AA11BB22"}},
+		{&storage.AppSearchOptions{Identifier: "com.example.a", MaxVersion: "1.2"}, []string{"AA11BB22"}},
+		{&storage.AppSearchOptions{Identifier: "com.example.a", MaxVersion: "1.1"}, nil},
+		{&storage.AppSearchOptions{Identifier: "com.example.b", MaxVersion: "20"}, []string{"AA11BB22"}},
+		{&storage.AppSearchOptions{Identifier: "com.example.c"}, nil},
+	} {
+		idApp, err := s.SearchApps(ctx, test.opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have, want := len(idApp), len(test.want); have != want {
+			t.Errorf("%s <= %s: have: %v, want: %v", test.opt.Identifier, test.opt.MaxVersion, have, want)
+		}
+		for _, id := range test.want {
+			if app, ok := idApp[id]; !ok {
+				t.Errorf("%s <= %s: expected id in results: %s", test.opt.Identifier, test.opt.MaxVersion, id)
+			} else if have, want := app.Identifier, test.opt.Identifier; have != want {
+				t.Errorf("have: %v, want: %v", have, want)
+			}
+		}
+	}
+
+	// replace the app list
+	err = s.StoreApps(ctx, "AA11BB22", []storage.App{
+		{Identifier: "com.example.b", Version: "21"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idApp, err := s.SearchApps(ctx, &storage.AppSearchOptions{Identifier: "com.example.a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := idApp["AA11BB22"]; ok {
+		t.Error("expected replaced app list to not contain app")
+	}
+}
@@ -0,0 +1,72 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/micromdm/nanocmd/subsystem/inventory/storage"
+)
+
+func TestCertStorage(t *testing.T, newStorage func() storage.CertStorage) {
+	s := newStorage()
+	ctx := context.Background()
+
+	_, err := s.RetrieveCerts(ctx, nil)
+	if err == nil {
+		t.Error("expected error for no IDs")
+	}
+
+	now := time.Now()
+
+	err = s.StoreCerts(ctx, "AA11BB22", []storage.Certificate{
+		{CommonName: "expired", NotAfter: now.Add(-time.Hour), SHA256: "01"},
+		{CommonName: "soon", NotAfter: now.Add(24 * time.Hour), SHA256: "02", IsIdentity: true},
+		{CommonName: "later", NotAfter: now.Add(90 * 24 * time.Hour), SHA256: "03"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.StoreCerts(ctx, "CC33DD44", []storage.Certificate{
+		{CommonName: "later", NotAfter: now.Add(90 * 24 * time.Hour), SHA256: "03"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idCerts, err := s.RetrieveCerts(ctx, []string{"AA11BB22", "EE55FF66"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := len(idCerts), 1; have != want {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+	if have, want := len(idCerts["AA11BB22"]), 3; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	_, err = s.SearchCerts(ctx, &storage.CertSearchOptions{})
+	if err == nil {
+		t.Error("expected error for no expiry")
+	}
+
+	idCerts, err = s.SearchCerts(ctx, &storage.CertSearchOptions{ExpiresBefore: now.Add(7 * 24 * time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := len(idCerts), 1; have != want {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+	if have, want := len(idCerts["AA11BB22"]), 2; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	idCerts, err = s.SearchCerts(ctx, &storage.CertSearchOptions{ExpiresBefore: now.Add(365 * 24 * time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := len(idCerts), 2; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+}
@@ -0,0 +1,51 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/micromdm/nanocmd/subsystem/inventory/storage"
+)
+
+func TestInstalledProfileStorage(t *testing.T, newStorage func() storage.InstalledProfileStorage) {
+	s := newStorage()
+	ctx := context.Background()
+
+	err := s.StoreInstalledProfiles(ctx, "AA11BB22", []storage.InstalledProfile{
+		{Identifier: "com.example.a", UUID: "A-1"},
+		{Identifier: "com.example.b", UUID: "B-1", Signer: "CN=signer"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.StoreInstalledProfiles(ctx, "CC33DD44", []storage.InstalledProfile{
+		{Identifier: "com.example.a", UUID: "A-2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idProfiles, err := s.RetrieveInstalledProfiles(ctx, []string{"AA11BB22", "EE55FF66"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := len(idProfiles), 1; have != want {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+	if have, want := len(idProfiles["AA11BB22"]), 2; have != want {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+	if have, want := idProfiles["AA11BB22"][1].Signer, "CN=signer"; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	// retrieve all
+	idProfiles, err = s.RetrieveInstalledProfiles(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := len(idProfiles), 2; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+}
@@ -45,6 +45,19 @@ func TestStorage(t *testing.T, newStorage func() storage.Storage) {
 		}
 	}
 
+	err = s.StoreInventoryValues(ctx, "CC33DD44", storage.Values{"a": nil, "b": "bye"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idVals, err = s.RetrieveInventoryKeys(ctx, []string{"a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(idVals) != 1 || len(idVals[id]) != 1 || idVals[id]["a"] != "hi" {
+		t.Errorf("unexpected key search result: %v", idVals)
+	}
+
 	err = s.DeleteInventory(ctx, id)
 	if err != nil {
 		t.Fatal(err)
@@ -0,0 +1,117 @@
+// Package finder finds the maintenance windows of enrollments.
+package finder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	groupstorage "github.com/micromdm/nanocmd/subsystem/group/storage"
+	"github.com/micromdm/nanocmd/subsystem/maintenance/storage"
+)
+
+// Finder finds the maintenance windows of enrollments.
+type Finder struct {
+	store  storage.ReadStorage
+	groups groupstorage.ReadStorage
+}
+
+type Option func(*Finder)
+
+// WithGroupStorage configures the group storage used to resolve the
+// group memberships of enrollments for group-assigned windows.
+func WithGroupStorage(groups groupstorage.ReadStorage) Option {
+	return func(f *Finder) {
+		f.groups = groups
+	}
+}
+
+func New(store storage.ReadStorage, opts ...Option) *Finder {
+	f := &Finder{store: store}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// NextWindow returns t if t is inside any maintenance window of
+// enrollment id. Otherwise the start of the earliest maintenance
+// window of id after t is returned. If id has no maintenance windows
+// then t is returned.
+func (f *Finder) NextWindow(ctx context.Context, id string, t time.Time) (time.Time, error) {
+	next, err := f.NextWindows(ctx, []string{id}, t)
+	if err != nil {
+		return t, err
+	}
+	return next[id], nil
+}
+
+// NextWindows returns the next maintenance window of each of ids
+// (see NextWindow). The windows and the members of the groups they
+// are assigned to are only retrieved once for all of ids.
+func (f *Finder) NextWindows(ctx context.Context, ids []string, t time.Time) (map[string]time.Time, error) {
+	next := make(map[string]time.Time, len(ids))
+	for _, id := range ids {
+		next[id] = t
+	}
+	windows, err := f.store.RetrieveWindows(ctx, nil)
+	if err != nil {
+		return next, fmt.Errorf("retrieving windows: %w", err)
+	}
+	if len(windows) < 1 {
+		return next, nil
+	}
+	groups, err := f.memberships(ctx, windows)
+	if err != nil {
+		return next, err
+	}
+	for _, id := range ids {
+		var idNext time.Time
+		for name, w := range windows {
+			if !w.Applies(id, groups[id]) {
+				continue
+			}
+			wNext, err := w.Next(t)
+			if err != nil {
+				return next, fmt.Errorf("window %s: %w", name, err)
+			}
+			if idNext.IsZero() || wNext.Before(idNext) {
+				idNext = wNext
+			}
+		}
+		if !idNext.IsZero() {
+			next[id] = idNext
+		}
+	}
+	return next, nil
+}
+
+// memberships returns the groups of enrollment IDs for the groups
+// that windows are assigned to.
+func (f *Finder) memberships(ctx context.Context, windows map[string]*storage.Window) (map[string][]string, error) {
+	if f.groups == nil {
+		return nil, nil
+	}
+	groups := make(map[string][]string)
+	seen := make(map[string]bool)
+	for _, w := range windows {
+		for _, group := range w.Groups {
+			if seen[group] {
+				continue
+			}
+			seen[group] = true
+			members, err := f.groups.RetrieveGroupMembers(ctx, group)
+			if errors.Is(err, groupstorage.ErrGroupNotFound) {
+				// the window applies to no members of a deleted group
+				continue
+			} else if err != nil {
+				return nil, fmt.Errorf("retrieving group members: %s: %w", group, err)
+			}
+			for _, id := range members {
+				groups[id] = append(groups[id], group)
+			}
+		}
+	}
+	return groups, nil
+}
@@ -0,0 +1,85 @@
+package finder
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	groupinmem "github.com/micromdm/nanocmd/subsystem/group/storage/inmem"
+	"github.com/micromdm/nanocmd/subsystem/maintenance/storage"
+	"github.com/micromdm/nanocmd/subsystem/maintenance/storage/inmem"
+)
+
+func TestNextWindow(t *testing.T) {
+	ctx := context.Background()
+	store := inmem.New()
+	groups := groupinmem.New()
+	f := New(store, WithGroupStorage(groups))
+
+	// a Wednesday
+	now := time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC)
+
+	next, err := f.NextWindow(ctx, "AAA111", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !next.Equal(now) {
+		t.Errorf("have: %v, want: %v", next, now)
+	}
+
+	err = groups.StoreGroupMembers(ctx, "staff", []string{"AAA111"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.StoreWindow(ctx, "nightly", &storage.Window{Start: "22:00", End: "23:00", Groups: []string{"staff"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.StoreWindow(ctx, "lunch", &storage.Window{Start: "13:00", End: "14:00", IDs: []string{"AAA111"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.StoreWindow(ctx, "other", &storage.Window{Start: "12:30", End: "13:00", IDs: []string{"BBB222"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the earliest applicable window
+	next, err = f.NextWindow(ctx, "AAA111", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := next, time.Date(2024, time.May, 1, 13, 0, 0, 0, time.UTC); !have.Equal(want) {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	// enrollments without windows are unaffected
+	next, err = f.NextWindow(ctx, "CCC333", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !next.Equal(now) {
+		t.Errorf("have: %v, want: %v", next, now)
+	}
+
+	// windows of deleted groups apply to no enrollments
+	err = store.StoreWindow(ctx, "deleted", &storage.Window{Start: "12:15", End: "13:00", Groups: []string{"deleted"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nextWindows, err := f.NextWindows(ctx, []string{"AAA111", "BBB222", "CCC333"}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, want := range map[string]time.Time{
+		"AAA111": time.Date(2024, time.May, 1, 13, 0, 0, 0, time.UTC),
+		"BBB222": time.Date(2024, time.May, 1, 12, 30, 0, 0, time.UTC),
+		"CCC333": now,
+	} {
+		if have := nextWindows[id]; !have.Equal(want) {
+			t.Errorf("%s: have: %v, want: %v", id, have, want)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// Package http contains HTTP handlers for working with maintenance windows.
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/micromdm/nanocmd/http/api"
+	"github.com/micromdm/nanocmd/logkeys"
+	"github.com/micromdm/nanocmd/subsystem/maintenance/storage"
+
+	"github.com/alexedwards/flow"
+	"github.com/micromdm/nanolib/log"
+	"github.com/micromdm/nanolib/log/ctxlog"
+)
+
+var (
+	ErrNoName = errors.New("no name provided")
+)
+
+// GetWindowsHandler returns an HTTP handler that fetches maintenance windows.
+// All windows are returned unless "name" query parameters are provided.
+func GetWindowsHandler(store storage.ReadStorage, logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := ctxlog.Logger(r.Context(), logger)
+		windows, err := store.RetrieveWindows(r.Context(), r.URL.Query()["name"])
+		if err != nil {
+			logger.Info(logkeys.Message, "retrieve windows", logkeys.Error, err)
+			api.JSONError(w, err, 0)
+			return
+		}
+		logger.Debug(
+			logkeys.Message, "retrieved windows",
+			logkeys.GenericCount, len(windows),
+		)
+		w.Header().Set("Content-Type", "application/json")
+		if err = json.NewEncoder(w).Encode(windows); err != nil {
+			logger.Info(logkeys.Message, "encoding json to body", logkeys.Error, err)
+			return
+		}
+	}
+}
+
+// GetHandler returns an HTTP handler that fetches a maintenance window.
+func GetHandler(store storage.ReadStorage, logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := ctxlog.Logger(r.Context(), logger)
+		name := flow.Param(r.Context(), "name")
+		if name == "" {
+			logger.Info(logkeys.Message, "name parameter", logkeys.Error, ErrNoName)
+			api.JSONError(w, ErrNoName, http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("name", name)
+		windows, err := store.RetrieveWindows(r.Context(), []string{name})
+		if err != nil {
+			logger.Info(logkeys.Message, "retrieve maintenance window", logkeys.Error, err)
+			api.JSONError(w, err, 0)
+			return
+		}
+
+		logger.Debug(logkeys.Message, "retrieved maintenance window")
+		w.Header().Set("Content-Type", "application/json")
+		if err = json.NewEncoder(w).Encode(windows[name]); err != nil {
+			logger.Info(logkeys.Message, "encoding json to body", logkeys.Error, err)
+			return
+		}
+	}
+}
+
+// PutHandler returns an HTTP handler for uploading a maintenance window.
+func PutHandler(store storage.Storage, logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := ctxlog.Logger(r.Context(), logger)
+		name := flow.Param(r.Context(), "name")
+		if name == "" {
+			logger.Info(logkeys.Message, "name parameter", logkeys.Error, ErrNoName)
+			api.JSONError(w, ErrNoName, http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("name", name)
+		window := new(storage.Window)
+		err := json.NewDecoder(r.Body).Decode(window)
+		if err != nil {
+			logger.Info(logkeys.Message, "decoding body", logkeys.Error, err)
+			api.JSONError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		if err = window.Validate(); err != nil {
+			logger.Info(logkeys.Message, "validating maintenance window", logkeys.Error, err)
+			api.JSONError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		if err = store.StoreWindow(r.Context(), name, window); err != nil {
+			logger.Info(logkeys.Message, "storing maintenance window", logkeys.Error, err)
+			api.JSONError(w, err, 0)
+			return
+		}
+
+		logger.Debug(logkeys.Message, "stored maintenance window")
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// DeleteHandler returns an HTTP handler for deleting a maintenance window.
+func DeleteHandler(store storage.Storage, logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := ctxlog.Logger(r.Context(), logger)
+		name := flow.Param(r.Context(), "name")
+		if name == "" {
+			logger.Info(logkeys.Message, "name parameter", logkeys.Error, ErrNoName)
+			api.JSONError(w, ErrNoName, http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("name", name)
+		if err := store.DeleteWindow(r.Context(), name); err != nil {
+			logger.Info(logkeys.Message, "deleting maintenance window", logkeys.Error, err)
+			api.JSONError(w, err, 0)
+			return
+		}
+
+		logger.Debug(logkeys.Message, "deleted maintenance window")
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
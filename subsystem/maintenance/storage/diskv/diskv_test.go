@@ -0,0 +1,12 @@
+package diskv
+
+import (
+	"testing"
+
+	"github.com/micromdm/nanocmd/subsystem/maintenance/storage"
+	"github.com/micromdm/nanocmd/subsystem/maintenance/storage/test"
+)
+
+func TestDiskv(t *testing.T) {
+	test.TestWindowStorage(t, func() storage.Storage { return New(t.TempDir()) })
+}
@@ -0,0 +1,17 @@
+// Package inmem implements a maintenance window storage backend backed by an in-memory key-value store.
+package inmem
+
+import (
+	"github.com/micromdm/nanocmd/subsystem/maintenance/storage/kv"
+
+	"github.com/micromdm/nanolib/storage/kv/kvmap"
+)
+
+// InMem is a maintenance window storage backend backed by an in-memory key-value store.
+type InMem struct {
+	*kv.KV
+}
+
+func New() *InMem {
+	return &InMem{KV: kv.New(kvmap.New())}
+}
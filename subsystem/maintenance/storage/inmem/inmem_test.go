@@ -0,0 +1,12 @@
+package inmem
+
+import (
+	"testing"
+
+	"github.com/micromdm/nanocmd/subsystem/maintenance/storage"
+	"github.com/micromdm/nanocmd/subsystem/maintenance/storage/test"
+)
+
+func TestInMem(t *testing.T) {
+	test.TestWindowStorage(t, func() storage.Storage { return New() })
+}
@@ -0,0 +1,59 @@
+// Package kv implements a maintenance window storage backend using JSON with key-value storage.
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/micromdm/nanocmd/subsystem/maintenance/storage"
+
+	"github.com/micromdm/nanolib/storage/kv"
+)
+
+// KV is a maintenance window storage backend using JSON with key-value storage.
+type KV struct {
+	b kv.Bucket
+}
+
+func New(b kv.Bucket) *KV {
+	return &KV{b: b}
+}
+
+// RetrieveWindows unmarshals the JSON stored using names and returns the windows.
+// All windows are returned if no names are provided.
+func (s *KV) RetrieveWindows(ctx context.Context, names []string) (map[string]*storage.Window, error) {
+	if len(names) < 1 {
+		names = kv.AllKeys(ctx, s.b)
+	}
+	r := make(map[string]*storage.Window)
+	for _, name := range names {
+		raw, err := s.b.Get(ctx, name)
+		if errors.Is(err, kv.ErrKeyNotFound) {
+			return r, fmt.Errorf("%w: %s: %v", storage.ErrWindowNotFound, name, err)
+		} else if err != nil {
+			return r, err
+		}
+		w := new(storage.Window)
+		if err = json.Unmarshal(raw, w); err != nil {
+			return r, fmt.Errorf("unmarshal window: %s: %w", name, err)
+		}
+		r[name] = w
+	}
+	return r, nil
+}
+
+// StoreWindow marshals w into JSON and stores it using name.
+func (s *KV) StoreWindow(ctx context.Context, name string, w *storage.Window) error {
+	raw, err := json.Marshal(w)
+	if err != nil {
+		return err
+	}
+	return s.b.Set(ctx, name, raw)
+}
+
+// DeleteWindow deletes the JSON stored using name.
+func (s *KV) DeleteWindow(ctx context.Context, name string) error {
+	return s.b.Delete(ctx, name)
+}
@@ -0,0 +1,20 @@
+// Package mysql implements a maintenance window storage backend backed by a MySQL key-value table.
+package mysql
+
+import (
+	"database/sql"
+
+	"github.com/micromdm/nanocmd/subsystem/maintenance/storage/kv"
+	"github.com/micromdm/nanocmd/utils/kvmysql"
+)
+
+// MySQL is a maintenance window storage backend backed by a MySQL key-value table.
+// See the kvmysql package for the table schema.
+type MySQL struct {
+	*kv.KV
+}
+
+// New creates a new maintenance window data store using the key-value table of db.
+func New(db *sql.DB) *MySQL {
+	return &MySQL{KV: kv.New(kvmysql.New(db, "maintenance"))}
+}
@@ -0,0 +1,168 @@
+// Package storage defines types and interfaces supporting maintenance windows.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrWindowNotFound = errors.New("maintenance window not found")
+	ErrNoAssignment   = errors.New("no ids or groups assigned")
+	ErrInvalidDay     = errors.New("invalid day")
+	ErrInvalidTime    = errors.New("invalid time")
+	ErrEmptyWindow    = errors.New("start and end are equal")
+)
+
+// timeLayout is the layout of window start and end times.
+const timeLayout = "15:04"
+
+var days = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Window is a recurring period of time that disruptive MDM commands
+// may be sent to enrollments in.
+// A window applies to an enrollment if the enrollment ID is one of its
+// IDs or if the enrollment is a member of any of its groups.
+type Window struct {
+	// Days are the days of the week the window starts on (e.g. "mon").
+	// Every day if empty.
+	Days []string `json:"days,omitempty"`
+	// Start and End are the times of day (e.g. "22:00") of the window.
+	// If End is before Start then the window ends on the next day.
+	Start string `json:"start"`
+	End   string `json:"end"`
+	// Location is the IANA time zone of the window. Defaults to UTC.
+	Location string `json:"location,omitempty"`
+
+	// IDs assigns the window to these enrollment IDs.
+	IDs []string `json:"ids,omitempty"`
+	// Groups assigns the window to members of these groups.
+	Groups []string `json:"groups,omitempty"`
+}
+
+// Validate checks w for errors.
+func (w *Window) Validate() error {
+	if w == nil {
+		return errors.New("nil window")
+	}
+	for _, d := range w.Days {
+		if _, ok := days[strings.ToLower(d)]; !ok {
+			return fmt.Errorf("%w: %s", ErrInvalidDay, d)
+		}
+	}
+	start, err := time.Parse(timeLayout, w.Start)
+	if err != nil {
+		return fmt.Errorf("%w: start: %v", ErrInvalidTime, err)
+	}
+	end, err := time.Parse(timeLayout, w.End)
+	if err != nil {
+		return fmt.Errorf("%w: end: %v", ErrInvalidTime, err)
+	}
+	if start.Equal(end) {
+		return ErrEmptyWindow
+	}
+	if _, err := time.LoadLocation(w.Location); err != nil {
+		return fmt.Errorf("loading location: %w", err)
+	}
+	if len(w.IDs) < 1 && len(w.Groups) < 1 {
+		return ErrNoAssignment
+	}
+	return nil
+}
+
+// Applies reports whether w is assigned to enrollment id that is a
+// member of groups.
+func (w *Window) Applies(id string, groups []string) bool {
+	if w == nil {
+		return false
+	}
+	for _, wID := range w.IDs {
+		if wID == id {
+			return true
+		}
+	}
+	for _, assigned := range w.Groups {
+		for _, group := range groups {
+			if assigned == group {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (w *Window) startsOn(d time.Weekday) bool {
+	if len(w.Days) < 1 {
+		return true
+	}
+	for _, day := range w.Days {
+		if days[strings.ToLower(day)] == d {
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns t if t is inside the window. Otherwise the start of the
+// next window after t is returned.
+func (w *Window) Next(t time.Time) (time.Time, error) {
+	start, err := time.Parse(timeLayout, w.Start)
+	if err != nil {
+		return t, fmt.Errorf("%w: start: %v", ErrInvalidTime, err)
+	}
+	end, err := time.Parse(timeLayout, w.End)
+	if err != nil {
+		return t, fmt.Errorf("%w: end: %v", ErrInvalidTime, err)
+	}
+	duration := end.Sub(start)
+	if duration <= 0 {
+		duration += 24 * time.Hour
+	}
+	loc, err := time.LoadLocation(w.Location)
+	if err != nil {
+		return t, fmt.Errorf("loading location: %w", err)
+	}
+	lt := t.In(loc)
+	// start from the day before in case a window spans midnight
+	for d := -1; d <= 7; d++ {
+		ws := time.Date(lt.Year(), lt.Month(), lt.Day()+d, start.Hour(), start.Minute(), 0, 0, loc)
+		if !w.startsOn(ws.Weekday()) {
+			continue
+		}
+		if !t.Before(ws) && t.Before(ws.Add(duration)) {
+			return t, nil
+		}
+		if ws.After(t) {
+			return ws, nil
+		}
+	}
+	return t, errors.New("no window found")
+}
+
+type ReadStorage interface {
+	// RetrieveWindows returns the maintenance windows by name.
+	// All windows are returned if no names are provided.
+	// ErrWindowNotFound is returned for any name that hasn't been stored.
+	RetrieveWindows(ctx context.Context, names []string) (map[string]*Window, error)
+}
+
+type Storage interface {
+	ReadStorage
+
+	// StoreWindow stores (replaces) the named maintenance window.
+	StoreWindow(ctx context.Context, name string, w *Window) error
+
+	// DeleteWindow deletes the named maintenance window.
+	DeleteWindow(ctx context.Context, name string) error
+}
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	w := &Window{
+		Days:     []string{"sat"},
+		Start:    "22:00",
+		End:      "04:00",
+		Location: "America/New_York",
+	}
+	if err := w.Validate(); err == nil {
+		t.Error("expected error for no assignment")
+	}
+	loc, err := time.LoadLocation(w.Location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		t    time.Time
+		want time.Time
+	}{
+		// a Wednesday
+		{
+			time.Date(2024, time.May, 1, 12, 0, 0, 0, loc),
+			time.Date(2024, time.May, 4, 22, 0, 0, 0, loc),
+		},
+		// inside the window on Saturday
+		{
+			time.Date(2024, time.May, 4, 23, 0, 0, 0, loc),
+			time.Date(2024, time.May, 4, 23, 0, 0, 0, loc),
+		},
+		// inside the window after midnight on Sunday
+		{
+			time.Date(2024, time.May, 5, 3, 59, 0, 0, loc),
+			time.Date(2024, time.May, 5, 3, 59, 0, 0, loc),
+		},
+		// just after the window ends
+		{
+			time.Date(2024, time.May, 5, 4, 0, 0, 0, loc),
+			time.Date(2024, time.May, 11, 22, 0, 0, 0, loc),
+		},
+		// the time zone of t doesn't matter
+		{
+			time.Date(2024, time.May, 5, 3, 0, 0, 0, time.UTC),
+			time.Date(2024, time.May, 5, 3, 0, 0, 0, time.UTC),
+		},
+	} {
+		have, err := w.Next(test.t)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !have.Equal(test.want) {
+			t.Errorf("t: %v: have: %v, want: %v", test.t, have, test.want)
+		}
+	}
+}
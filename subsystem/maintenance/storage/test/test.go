@@ -0,0 +1,63 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/micromdm/nanocmd/subsystem/maintenance/storage"
+)
+
+func TestWindowStorage(t *testing.T, newStorage func() storage.Storage) {
+	s := newStorage()
+	ctx := context.Background()
+
+	w := &storage.Window{
+		Days:     []string{"sat", "sun"},
+		Start:    "22:00",
+		End:      "04:00",
+		Location: "America/Los_Angeles",
+		Groups:   []string{"staff"},
+	}
+
+	err := s.StoreWindow(ctx, "weekend", w)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.StoreWindow(ctx, "lab", &storage.Window{
+		Start: "01:00",
+		End:   "02:00",
+		IDs:   []string{"AAA111"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	windows, err := s.RetrieveWindows(ctx, []string{"weekend"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := windows["weekend"], w; !reflect.DeepEqual(have, want) {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	windows, err = s.RetrieveWindows(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := len(windows), 2; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	err = s.DeleteWindow(ctx, "weekend")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.RetrieveWindows(ctx, []string{"weekend"})
+	if !errors.Is(err, storage.ErrWindowNotFound) {
+		t.Errorf("expected ErrWindowNotFound, have: %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/micromdm/nanocmd/http/api"
+	"github.com/micromdm/nanocmd/logkeys"
+	invstorage "github.com/micromdm/nanocmd/subsystem/inventory/storage"
+	"github.com/micromdm/nanocmd/subsystem/profile/storage"
+
+	"github.com/micromdm/nanolib/log"
+	"github.com/micromdm/nanolib/log/ctxlog"
+)
+
+// OutdatedProfile is an installed profile whose UUID does not match profile storage.
+type OutdatedProfile struct {
+	Name          string `json:"name"` // name of the profile in profile storage
+	Identifier    string `json:"identifier"`
+	InstalledUUID string `json:"installed_uuid"`
+	UUID          string `json:"uuid"` // UUID of the profile in profile storage
+}
+
+// Drift is the difference between the installed profiles of an enrollment and profile storage.
+type Drift struct {
+	Outdated []OutdatedProfile             `json:"outdated,omitempty"`
+	Unknown  []invstorage.InstalledProfile `json:"unknown,omitempty"`
+}
+
+// drift compares the installed profiles to the stored profile infos.
+// Installed profiles are outdated if a stored profile has the same
+// identifier but no stored profile has the same UUID. Installed profiles
+// are unknown if no stored profile has the same identifier. Profiles
+// that are reported as not managed are never unknown.
+// Returns nil if there is no drift.
+func drift(infos map[string]storage.ProfileInfo, installed []invstorage.InstalledProfile) *Drift {
+	byIdentifier := make(map[string][]string)
+	for name, info := range infos {
+		byIdentifier[info.Identifier] = append(byIdentifier[info.Identifier], name)
+	}
+
+	d := new(Drift)
+profiles:
+	for _, profile := range installed {
+		names := byIdentifier[profile.Identifier]
+		if len(names) < 1 {
+			if profile.Managed == nil || *profile.Managed {
+				d.Unknown = append(d.Unknown, profile)
+			}
+			continue
+		}
+		for _, name := range names {
+			if infos[name].UUID == profile.UUID {
+				continue profiles
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			d.Outdated = append(d.Outdated, OutdatedProfile{
+				Name:          name,
+				Identifier:    profile.Identifier,
+				InstalledUUID: profile.UUID,
+				UUID:          infos[name].UUID,
+			})
+		}
+	}
+
+	if len(d.Outdated) < 1 && len(d.Unknown) < 1 {
+		return nil
+	}
+	return d
+}
+
+// GetDriftHandler returns an HTTP handler that reports the difference
+// between the installed profiles of enrollments and profile storage.
+// Only enrollments with drift are included in the report. Enrollments
+// can be limited with the "id" query parameter.
+func GetDriftHandler(store storage.ReadStorage, invStore invstorage.ReadInstalledProfileStorage, logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := ctxlog.Logger(r.Context(), logger)
+		if invStore == nil {
+			logger.Info(logkeys.Message, "drift report", logkeys.Error, ErrNoStorage)
+			api.JSONError(w, ErrNoStorage, 0)
+			return
+		}
+
+		infos, err := store.RetrieveProfileInfos(r.Context(), nil)
+		if err != nil {
+			logger.Info(logkeys.Message, "retrieve profiles", logkeys.Error, err)
+			api.JSONError(w, err, 0)
+			return
+		}
+
+		idProfiles, err := invStore.RetrieveInstalledProfiles(r.Context(), r.URL.Query()["id"])
+		if err != nil {
+			logger.Info(logkeys.Message, "retrieve installed profiles", logkeys.Error, err)
+			api.JSONError(w, err, 0)
+			return
+		}
+
+		report := make(map[string]*Drift)
+		for id, profiles := range idProfiles {
+			if d := drift(infos, profiles); d != nil {
+				report[id] = d
+			}
+		}
+
+		logger.Debug(
+			logkeys.Message, "drift report",
+			"enrollments", len(idProfiles),
+			logkeys.GenericCount, len(report),
+		)
+		w.Header().Set("Content-Type", "application/json")
+		err = json.NewEncoder(w).Encode(report)
+		if err != nil {
+			logger.Info(logkeys.Message, "encoding json", logkeys.Error, err)
+			return
+		}
+	}
+}
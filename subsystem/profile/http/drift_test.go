@@ -0,0 +1,55 @@
+package http
+
+import (
+	"testing"
+
+	invstorage "github.com/micromdm/nanocmd/subsystem/inventory/storage"
+	"github.com/micromdm/nanocmd/subsystem/profile/storage"
+)
+
+func TestDrift(t *testing.T) {
+	infos := map[string]storage.ProfileInfo{
+		"a":  {Identifier: "com.example.a", UUID: "A-2"},
+		"b":  {Identifier: "com.example.b", UUID: "B-1"},
+		"b2": {Identifier: "com.example.b", UUID: "B-2"},
+	}
+
+	notManaged := false
+
+	d := drift(infos, []invstorage.InstalledProfile{
+		{Identifier: "com.example.b", UUID: "B-2"},
+		{Identifier: "com.example.enroll", UUID: "E-1", Managed: &notManaged},
+	})
+	if d != nil {
+		t.Errorf("expected no drift: %v", d)
+	}
+
+	d = drift(infos, []invstorage.InstalledProfile{
+		{Identifier: "com.example.a", UUID: "A-1"},
+		{Identifier: "com.example.b", UUID: "B-1"},
+		{Identifier: "com.example.c", UUID: "C-1"},
+	})
+	if d == nil {
+		t.Fatal("expected drift")
+	}
+
+	if have, want := len(d.Outdated), 1; have != want {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+	if have, want := d.Outdated[0].Name, "a"; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+	if have, want := d.Outdated[0].InstalledUUID, "A-1"; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+	if have, want := d.Outdated[0].UUID, "A-2"; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	if have, want := len(d.Unknown), 1; have != want {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+	if have, want := d.Unknown[0].Identifier, "com.example.c"; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+}
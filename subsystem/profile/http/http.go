@@ -2,13 +2,17 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"sort"
 
 	"github.com/micromdm/nanocmd/http/api"
 	"github.com/micromdm/nanocmd/logkeys"
+	"github.com/micromdm/nanocmd/subsystem/profile/render"
 	"github.com/micromdm/nanocmd/subsystem/profile/storage"
 	"github.com/micromdm/nanocmd/utils/mobileconfig"
 
@@ -41,8 +45,45 @@ var (
 	ErrEmptyName  = errors.New("empty name")
 	ErrNoSuchName = errors.New("no such name")
 	ErrEmptyBody  = errors.New("empty body")
+	ErrNoStorage  = errors.New("no storage backend")
+
+	ErrTemplateTopLevel = errors.New("template variables in top-level PayloadIdentifier or PayloadUUID")
+	ErrLint             = errors.New("profile has lint errors")
 )
 
+// lintResponse is the JSON body returned for profile lint problems.
+type lintResponse struct {
+	Err      string                `json:"error,omitempty"`
+	Problems mobileconfig.Problems `json:"problems"`
+}
+
+// lint checks mc for problems including top-level identifier collisions
+// with profiles stored under other names.
+func lint(ctx context.Context, store storage.ReadStorage, name string, mc mobileconfig.Mobileconfig, payload *mobileconfig.Payload) (mobileconfig.Problems, error) {
+	problems, err := mc.Lint()
+	if err != nil {
+		return nil, fmt.Errorf("linting profile: %w", err)
+	}
+	infos, err := store.RetrieveProfileInfos(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("retrieving profile infos: %w", err)
+	}
+	names := make([]string, 0, len(infos))
+	for otherName, info := range infos {
+		if otherName != name && info.Identifier == payload.PayloadIdentifier {
+			names = append(names, otherName)
+		}
+	}
+	sort.Strings(names)
+	for _, otherName := range names {
+		problems = append(problems, mobileconfig.Problem{
+			Severity: mobileconfig.SeverityError,
+			Message:  fmt.Sprintf("PayloadIdentifier %q collides with profile %q", payload.PayloadIdentifier, otherName),
+		})
+	}
+	return problems, nil
+}
+
 // DeleteProfileHandler returns an HTTP handler that deletes a named profile.
 func DeleteProfileHandler(store storage.Storage, logger log.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -95,7 +136,13 @@ func GetProfileHandler(store storage.ReadStorage, logger log.Logger) http.Handle
 }
 
 // StoreProfileHandler returns an HTTP handler that uploads a named raw profile.
-func StoreProfileHandler(store storage.Storage, logger log.Logger) http.HandlerFunc {
+// Unsigned profiles are signed with signer if it is not nil. Profile
+// templates are not signed as they would no longer be renderable.
+// If signAtInstall is true then unsigned profiles are instead stored
+// unsigned and the signer is recorded as they are signed when installed.
+// Any lint problems are returned in the response body. If the "strict"
+// query parameter is true then profiles with lint errors are rejected.
+func StoreProfileHandler(store storage.Storage, signer *mobileconfig.Signer, signAtInstall bool, logger log.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		logger := ctxlog.Logger(r.Context(), logger)
 		name := flow.Param(r.Context(), "name")
@@ -105,6 +152,11 @@ func StoreProfileHandler(store storage.Storage, logger log.Logger) http.HandlerF
 			return
 		}
 		logger = logger.With("name", name)
+		if err := storage.ValidateName(name); err != nil {
+			logger.Info(logkeys.Message, "name check", logkeys.Error, err)
+			api.JSONError(w, err, http.StatusBadRequest)
+			return
+		}
 		raw, err := io.ReadAll(r.Body)
 		if err != nil {
 			logger.Info(logkeys.Message, "reading body", logkeys.Error, err)
@@ -117,17 +169,54 @@ func StoreProfileHandler(store storage.Storage, logger log.Logger) http.HandlerF
 			return
 		}
 		mc := mobileconfig.Mobileconfig(raw)
-		payload, _, err := mc.Parse()
+		payload, signed, err := mc.Parse()
 		if err != nil {
 			logger.Info(logkeys.Message, "parsing mobileconfig", logkeys.Error, err)
 			api.JSONError(w, err, http.StatusBadRequest)
 			return
 		}
+		// the top-level identifier and UUID are compared against the
+		// installed profiles so they can't vary per-enrollment.
+		if render.IsTemplate([]byte(payload.PayloadIdentifier)) || render.IsTemplate([]byte(payload.PayloadUUID)) {
+			logger.Info(logkeys.Message, "checking template", logkeys.Error, ErrTemplateTopLevel)
+			api.JSONError(w, ErrTemplateTopLevel, http.StatusBadRequest)
+			return
+		}
+		problems, err := lint(r.Context(), store, name, mc, payload)
+		if err != nil {
+			logger.Info(logkeys.Message, "lint profile", logkeys.Error, err)
+			api.JSONError(w, err, 0)
+			return
+		}
+		if r.URL.Query().Get("strict") == "true" && problems.HasErrors() {
+			logger.Info(logkeys.Message, "lint profile", logkeys.Error, ErrLint)
+			writeLintResponse(w, &lintResponse{Err: ErrLint.Error(), Problems: problems}, http.StatusBadRequest, logger)
+			return
+		}
+		if !signed && signer != nil && !signAtInstall && !render.IsTemplate(raw) {
+			if mc, err = signer.Sign(mc); err != nil {
+				logger.Info(logkeys.Message, "signing mobileconfig", logkeys.Error, err)
+				api.JSONError(w, err, 0)
+				return
+			}
+			signed = true
+		}
 		info := storage.ProfileInfo{
 			Identifier: payload.PayloadIdentifier,
 			UUID:       payload.PayloadUUID,
 		}
-		err = store.StoreProfile(r.Context(), name, info, raw)
+		if signed {
+			crt, err := mc.SignerCertificate()
+			if err != nil {
+				logger.Info(logkeys.Message, "mobileconfig signer", logkeys.Error, err)
+				api.JSONError(w, err, http.StatusBadRequest)
+				return
+			}
+			info.Signer = crt.Subject.String()
+		} else if signer != nil && signAtInstall {
+			info.Signer = signer.Certificate().Subject.String()
+		}
+		err = store.StoreProfile(r.Context(), name, info, mc)
 		if err != nil {
 			logger.Info(logkeys.Message, "store profile", logkeys.Error, err)
 			api.JSONError(w, err, 0)
@@ -137,7 +226,21 @@ func StoreProfileHandler(store storage.Storage, logger log.Logger) http.HandlerF
 			logkeys.Message, "store profile",
 			"identifier", info.Identifier,
 			"uuid", info.UUID,
+			"signer", info.Signer,
+			"problems", len(problems),
 		)
+		if len(problems) > 0 {
+			writeLintResponse(w, &lintResponse{Problems: problems}, http.StatusOK, logger)
+			return
+		}
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
+
+func writeLintResponse(w http.ResponseWriter, resp *lintResponse, statusCode int, logger log.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logger.Info(logkeys.Message, "encoding json", logkeys.Error, err)
+	}
+}
@@ -0,0 +1,112 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/micromdm/nanocmd/subsystem/profile/storage/inmem"
+	"github.com/micromdm/nanocmd/utils/cryptoutil"
+	"github.com/micromdm/nanocmd/utils/mobileconfig"
+
+	"github.com/alexedwards/flow"
+	"github.com/micromdm/nanolib/log"
+)
+
+func TestStoreProfileLint(t *testing.T) {
+	mux := flow.New()
+	mux.Handle("/profile/:name", StoreProfileHandler(inmem.New(), nil, false, log.NopLogger), "PUT")
+
+	b, err := os.ReadFile("../../../utils/mobileconfig/testdata/lint.mobileconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	put := func(url string) (*httptest.ResponseRecorder, *lintResponse) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("PUT", url, bytes.NewReader(b)))
+		resp := new(lintResponse)
+		if rec.Body.Len() > 0 {
+			if err := json.Unmarshal(rec.Body.Bytes(), resp); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return rec, resp
+	}
+
+	rec, resp := put("/profile/lint1?strict=true")
+	if have, want := rec.Code, http.StatusBadRequest; have != want {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+	if have, want := resp.Err, ErrLint.Error(); have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	rec, resp = put("/profile/lint1")
+	if have, want := rec.Code, http.StatusOK; have != want {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+	if have, want := len(resp.Problems), 5; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	// same top-level identifier under another name
+	rec, resp = put("/profile/lint2")
+	if have, want := rec.Code, http.StatusOK; have != want {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+	if have, want := len(resp.Problems), 6; have != want {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+	if have, want := resp.Problems[5].Message, `PayloadIdentifier "com.example.lint" collides with profile "lint1"`; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+}
+
+func TestStoreProfileSignAtInstall(t *testing.T) {
+	key, cert, err := cryptoutil.SelfSignedRSAKeypair("profile signer", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := mobileconfig.NewSigner(cert, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile("../../../utils/mobileconfig/testdata/test.mobileconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, signAtInstall := range []bool{false, true} {
+		store := inmem.New()
+		mux := flow.New()
+		mux.Handle("/profile/:name", StoreProfileHandler(store, signer, signAtInstall, log.NopLogger), "PUT")
+
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("PUT", "/profile/test", bytes.NewReader(b)))
+		if rec.Code != http.StatusOK && rec.Code != http.StatusNoContent {
+			t.Fatalf("sign at install %v: unexpected status: %v", signAtInstall, rec.Code)
+		}
+
+		infos, err := store.RetrieveProfileInfos(context.Background(), []string{"test"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have, want := infos["test"].Signer, cert.Subject.String(); have != want {
+			t.Errorf("sign at install %v: have: %v, want: %v", signAtInstall, have, want)
+		}
+
+		raws, err := store.RetrieveRawProfiles(context.Background(), []string{"test"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have, want := mobileconfig.Mobileconfig(raws["test"]).Signed(), !signAtInstall; have != want {
+			t.Errorf("sign at install %v: signed: have: %v, want: %v", signAtInstall, have, want)
+		}
+	}
+}
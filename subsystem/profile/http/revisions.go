@@ -0,0 +1,102 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/micromdm/nanocmd/http/api"
+	"github.com/micromdm/nanocmd/logkeys"
+	"github.com/micromdm/nanocmd/subsystem/profile/storage"
+
+	"github.com/alexedwards/flow"
+	"github.com/micromdm/nanolib/log"
+	"github.com/micromdm/nanolib/log/ctxlog"
+)
+
+var ErrInvalidRevision = errors.New("invalid revision")
+
+// nameRevParams returns the name and revision path parameters of r.
+func nameRevParams(r *http.Request) (string, int, error) {
+	name := flow.Param(r.Context(), "name")
+	if name == "" {
+		return name, 0, ErrEmptyName
+	}
+	rev, err := strconv.Atoi(flow.Param(r.Context(), "rev"))
+	if err != nil || rev < 1 {
+		return name, 0, ErrInvalidRevision
+	}
+	return name, rev, nil
+}
+
+// GetRevisionsHandler returns an HTTP handler that returns the revisions of a named profile.
+func GetRevisionsHandler(store storage.ReadStorage, logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := ctxlog.Logger(r.Context(), logger)
+		name := flow.Param(r.Context(), "name")
+		if name == "" {
+			logger.Info(logkeys.Message, "name check", logkeys.Error, ErrEmptyName)
+			api.JSONError(w, ErrEmptyName, http.StatusBadRequest)
+			return
+		}
+		logger = logger.With("name", name)
+		revs, err := store.RetrieveRevisions(r.Context(), name)
+		if err != nil {
+			logger.Info(logkeys.Message, "retrieve revisions", logkeys.Error, err)
+			api.JSONError(w, err, 0)
+			return
+		}
+		logger.Debug(logkeys.Message, "retrieve revisions", "length", len(revs))
+		w.Header().Set("Content-Type", "application/json")
+		err = json.NewEncoder(w).Encode(revs)
+		if err != nil {
+			logger.Info(logkeys.Message, "encoding json", logkeys.Error, err)
+			return
+		}
+	}
+}
+
+// GetRevisionHandler returns an HTTP handler that returns a revision of a named raw profile.
+func GetRevisionHandler(store storage.ReadStorage, logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := ctxlog.Logger(r.Context(), logger)
+		name, rev, err := nameRevParams(r)
+		if err != nil {
+			logger.Info(logkeys.Message, "parameter check", logkeys.Error, err)
+			api.JSONError(w, err, http.StatusBadRequest)
+			return
+		}
+		logger = logger.With("name", name, "revision", rev)
+		raw, err := store.RetrieveRawRevision(r.Context(), name, rev)
+		if err != nil {
+			logger.Info(logkeys.Message, "retrieve revision", logkeys.Error, err)
+			api.JSONError(w, err, 0)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-apple-aspen-config")
+		w.Write(raw)
+	}
+}
+
+// ActivateRevisionHandler returns an HTTP handler that replaces a named profile with one of its revisions.
+func ActivateRevisionHandler(store storage.Storage, logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := ctxlog.Logger(r.Context(), logger)
+		name, rev, err := nameRevParams(r)
+		if err != nil {
+			logger.Info(logkeys.Message, "parameter check", logkeys.Error, err)
+			api.JSONError(w, err, http.StatusBadRequest)
+			return
+		}
+		logger = logger.With("name", name, "revision", rev)
+		err = store.ActivateRevision(r.Context(), name, rev)
+		if err != nil {
+			logger.Info(logkeys.Message, "activate revision", logkeys.Error, err)
+			api.JSONError(w, err, 0)
+			return
+		}
+		logger.Debug(logkeys.Message, "activate revision")
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -3,7 +3,10 @@ package http
 import (
 	"net/http"
 
+	"github.com/micromdm/nanocmd/http/api"
+	invstorage "github.com/micromdm/nanocmd/subsystem/inventory/storage"
 	"github.com/micromdm/nanocmd/subsystem/profile/storage"
+	"github.com/micromdm/nanocmd/utils/mobileconfig"
 	"github.com/micromdm/nanolib/log"
 )
 
@@ -22,31 +25,90 @@ type Mux interface {
 // API endpoint paths are prepended with prefix.
 // Authentication or any other layered handlers are not present.
 // They are assumed to be layered with mux, possibly at the Handle call.
+// Each handler requires the API principal to have the "profiles:manage" permission.
 // If prefix is empty and these handlers are used in sub-paths then
 // handlers should have that sub-path stripped from the request.
 // The logger is adorned with a "handler" key of the endpoint name.
-func HandleAPIv1(prefix string, mux Mux, logger log.Logger, s storage.Storage) {
+// Uploaded profiles are signed with signer if it is not nil, or when
+// installed if signAtInstall is true (see StoreProfileHandler).
+func HandleAPIv1(prefix string, mux Mux, logger log.Logger, s storage.Storage, inv invstorage.ReadInstalledProfileStorage, signer *mobileconfig.Signer, signAtInstall bool) {
 	mux.Handle(
 		prefix+"/profile/:name",
-		StoreProfileHandler(s, logger.With("handler", "put-profile")),
+		api.RequirePermission(
+			StoreProfileHandler(s, signer, signAtInstall, logger.With("handler", "put-profile")),
+			api.PermProfilesManage,
+			logger,
+		),
 		"PUT",
 	)
 
 	mux.Handle(
 		prefix+"/profile/:name",
-		GetProfileHandler(s, logger.With("handler", "get-profile")),
+		api.RequirePermission(
+			GetProfileHandler(s, logger.With("handler", "get-profile")),
+			api.PermProfilesManage,
+			logger,
+		),
 		"GET",
 	)
 
 	mux.Handle(
 		prefix+"/profile/:name",
-		DeleteProfileHandler(s, logger.With("handler", "delete-profile")),
+		api.RequirePermission(
+			DeleteProfileHandler(s, logger.With("handler", "delete-profile")),
+			api.PermProfilesManage,
+			logger,
+		),
 		"DELETE",
 	)
 
+	mux.Handle(
+		prefix+"/profile/:name/revisions",
+		api.RequirePermission(
+			GetRevisionsHandler(s, logger.With("handler", "get-profile-revisions")),
+			api.PermProfilesManage,
+			logger,
+		),
+		"GET",
+	)
+
+	mux.Handle(
+		prefix+"/profile/:name/revision/:rev",
+		api.RequirePermission(
+			GetRevisionHandler(s, logger.With("handler", "get-profile-revision")),
+			api.PermProfilesManage,
+			logger,
+		),
+		"GET",
+	)
+
+	mux.Handle(
+		prefix+"/profile/:name/revision/:rev/activate",
+		api.RequirePermission(
+			ActivateRevisionHandler(s, logger.With("handler", "activate-profile-revision")),
+			api.PermProfilesManage,
+			logger,
+		),
+		"POST",
+	)
+
 	mux.Handle(
 		prefix+"/profiles",
-		GetProfilesHandler(s, logger.With("handler", "get-profiles")),
+		api.RequirePermission(
+			GetProfilesHandler(s, logger.With("handler", "get-profiles")),
+			api.PermProfilesManage,
+			logger,
+		),
+		"GET",
+	)
+
+	mux.Handle(
+		prefix+"/profiles/drift",
+		api.RequirePermission(
+			GetDriftHandler(s, inv, logger.With("handler", "get-profiles-drift")),
+			api.PermProfilesManage,
+			logger,
+		),
 		"GET",
 	)
 }
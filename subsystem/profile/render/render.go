@@ -0,0 +1,184 @@
+// Package render renders stored profiles as templates using per-enrollment variables.
+package render
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	invstorage "github.com/micromdm/nanocmd/subsystem/inventory/storage"
+	"github.com/micromdm/nanocmd/utils/mobileconfig"
+)
+
+// Template variable names.
+// MDM URL parameters are available with the ParamPrefix prefix.
+// For example the parameter "site" is the template variable "param.site".
+const (
+	VarEnrollmentID = "enrollment_id"
+	VarSerialNumber = "serial_number"
+	VarDeviceName   = "device_name"
+	ParamPrefix     = "param."
+)
+
+var (
+	ErrMissingVariables = errors.New("missing template variables")
+	ErrNoInventory      = errors.New("no inventory storage")
+)
+
+// varRegexp matches template variables of the form "{{ name }}".
+var varRegexp = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.\-]+)\s*\}\}`)
+
+// inventoryVars maps template variable names to inventory keys.
+var inventoryVars = map[string]string{
+	VarSerialNumber: invstorage.KeySerialNumber,
+	VarDeviceName:   invstorage.KeyDeviceName,
+}
+
+// known reports whether name is a known template variable.
+// Unknown variables are not rendered and are left as-is.
+func known(name string) bool {
+	if name == VarEnrollmentID || strings.HasPrefix(name, ParamPrefix) {
+		return true
+	}
+	_, ok := inventoryVars[name]
+	return ok
+}
+
+// Variables returns the sorted, unique template variable names in raw.
+func Variables(raw []byte) []string {
+	var ret []string
+	seen := make(map[string]struct{})
+	for _, m := range varRegexp.FindAllSubmatch(raw, -1) {
+		name := string(m[1])
+		if _, ok := seen[name]; ok || !known(name) {
+			continue
+		}
+		seen[name] = struct{}{}
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// IsTemplate reports whether raw contains template variables.
+func IsTemplate(raw []byte) bool {
+	return len(Variables(raw)) > 0
+}
+
+// Renderer renders profile templates.
+type Renderer struct {
+	inv    invstorage.ReadStorage
+	signer *mobileconfig.Signer
+}
+
+type Option func(*Renderer)
+
+// WithSigner signs unsigned profiles with signer after rendering.
+func WithSigner(signer *mobileconfig.Signer) Option {
+	return func(r *Renderer) {
+		r.signer = signer
+	}
+}
+
+// New creates a new Renderer. If inv is nil then rendering templates
+// that use inventory variables will fail.
+func New(inv invstorage.ReadStorage, opts ...Option) *Renderer {
+	r := &Renderer{inv: inv}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// values assembles the template variable values of vars for enrollment id.
+func (r *Renderer) values(ctx context.Context, id string, params map[string]string, vars []string) (map[string]string, error) {
+	values := make(map[string]string)
+	var inv invstorage.Values
+	for _, name := range vars {
+		switch {
+		case name == VarEnrollmentID:
+			if id != "" {
+				values[name] = id
+			}
+		case strings.HasPrefix(name, ParamPrefix):
+			if v, ok := params[name[len(ParamPrefix):]]; ok {
+				values[name] = v
+			}
+		default:
+			if inv == nil {
+				if r == nil || r.inv == nil {
+					return nil, fmt.Errorf("%w: variable: %s", ErrNoInventory, name)
+				}
+				idValues, err := r.inv.RetrieveInventory(ctx, &invstorage.SearchOptions{IDs: []string{id}})
+				if err != nil {
+					return nil, fmt.Errorf("retrieving inventory: %w", err)
+				}
+				inv = idValues[id]
+				if inv == nil {
+					inv = make(invstorage.Values)
+				}
+			}
+			if v, ok := inv[inventoryVars[name]].(string); ok && v != "" {
+				values[name] = v
+			}
+		}
+	}
+	return values, nil
+}
+
+// Render renders the template variables in raw for enrollment id with
+// MDM URL parameters params. Variable values are XML-escaped. Profiles
+// that are not XML (e.g. signed profiles) are returned unmodified. An
+// error wrapping ErrMissingVariables is returned listing all variables
+// without values. If configured, the rendered profile is then signed.
+func (r *Renderer) Render(ctx context.Context, id string, params map[string]string, raw []byte) ([]byte, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(raw), []byte("<")) {
+		return raw, nil
+	}
+	rendered, err := r.render(ctx, id, params, raw)
+	if err != nil || r == nil || r.signer == nil {
+		return rendered, err
+	}
+	signed, err := r.signer.Sign(rendered)
+	if err != nil {
+		return nil, fmt.Errorf("signing profile: %w", err)
+	}
+	return signed, nil
+}
+
+// render renders the template variables in raw.
+func (r *Renderer) render(ctx context.Context, id string, params map[string]string, raw []byte) ([]byte, error) {
+	vars := Variables(raw)
+	if len(vars) < 1 {
+		return raw, nil
+	}
+	values, err := r.values(ctx, id, params, vars)
+	if err != nil {
+		return nil, err
+	}
+	var missing []string
+	for _, name := range vars {
+		if _, ok := values[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrMissingVariables, strings.Join(missing, ", "))
+	}
+	return varRegexp.ReplaceAllFunc(raw, func(m []byte) []byte {
+		name := string(varRegexp.FindSubmatch(m)[1])
+		v, ok := values[name]
+		if !ok {
+			// unknown variable
+			return m
+		}
+		buf := new(bytes.Buffer)
+		xml.EscapeText(buf, []byte(v))
+		return buf.Bytes()
+	}), nil
+}
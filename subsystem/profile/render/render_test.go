@@ -0,0 +1,121 @@
+package render
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	invstorage "github.com/micromdm/nanocmd/subsystem/inventory/storage"
+	"github.com/micromdm/nanocmd/subsystem/inventory/storage/inmem"
+	"github.com/micromdm/nanocmd/utils/cryptoutil"
+	"github.com/micromdm/nanocmd/utils/mobileconfig"
+)
+
+const tmpl = `<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0">
+<dict>
+	<key>Serial</key>
+	<string>{{serial_number}}</string>
+	<key>Name</key>
+	<string>{{ device_name }}</string>
+	<key>ID</key>
+	<string>{{enrollment_id}}</string>
+	<key>Site</key>
+	<string>{{param.site}}</string>
+	<key>Other</key>
+	<string>{{other}}</string>
+</dict>
+</plist>`
+
+const rendered = `<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0">
+<dict>
+	<key>Serial</key>
+	<string>ZTMXJQTLFX</string>
+	<key>Name</key>
+	<string>Jane&#39;s Mac &amp; Co</string>
+	<key>ID</key>
+	<string>AAABBBCCC111222333</string>
+	<key>Site</key>
+	<string>hq</string>
+	<key>Other</key>
+	<string>{{other}}</string>
+</dict>
+</plist>`
+
+func TestVariables(t *testing.T) {
+	want := []string{"device_name", "enrollment_id", "param.site", "serial_number"}
+	if have := Variables([]byte(tmpl)); !reflect.DeepEqual(want, have) {
+		t.Errorf("want: %v, have: %v", want, have)
+	}
+	if IsTemplate([]byte("<plist>{{other}}</plist>")) {
+		t.Error("expected not a template")
+	}
+}
+
+func TestRender(t *testing.T) {
+	ctx := context.Background()
+	id := "AAABBBCCC111222333"
+
+	inv := inmem.New()
+	r := New(inv)
+
+	_, err := r.Render(ctx, id, map[string]string{"site": "hq"}, []byte(tmpl))
+	if !errors.Is(err, ErrMissingVariables) {
+		t.Fatalf("expected missing variables error, have: %v", err)
+	}
+	if have, want := err.Error(), "missing template variables: device_name, serial_number"; have != want {
+		t.Errorf("have: %q, want: %q", have, want)
+	}
+
+	err = inv.StoreInventoryValues(ctx, id, invstorage.Values{
+		invstorage.KeySerialNumber: "ZTMXJQTLFX",
+		invstorage.KeyDeviceName:   "Jane's Mac & Co",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := r.Render(ctx, id, map[string]string{"site": "hq"}, []byte(tmpl))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := string(out), rendered; have != want {
+		t.Errorf("have: %s, want: %s", have, want)
+	}
+
+	// no inventory storage
+	_, err = New(nil).Render(ctx, id, nil, []byte(tmpl))
+	if !errors.Is(err, ErrNoInventory) {
+		t.Errorf("expected no inventory error, have: %v", err)
+	}
+}
+
+func TestRenderSign(t *testing.T) {
+	key, cert, err := cryptoutil.SelfSignedRSAKeypair("profile signer", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := mobileconfig.NewSigner(cert, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := New(nil, WithSigner(signer))
+
+	out, err := r.Render(context.Background(), "AAABBBCCC111222333", nil, []byte(`<?xml version="1.0"?><plist>{{enrollment_id}}</plist>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mc := mobileconfig.Mobileconfig(out)
+	if !mc.Signed() {
+		t.Fatal("expected signed profile")
+	}
+	crt, err := mc.SignerCertificate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := crt.Subject.CommonName, "profile signer"; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+}
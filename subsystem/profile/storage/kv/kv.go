@@ -5,6 +5,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
+	"sync"
 
 	"github.com/micromdm/nanocmd/subsystem/profile/storage"
 
@@ -15,11 +17,14 @@ const (
 	keyPfxUUID = "uuid."
 	keyPfxID   = "id."
 	keyPfxRaw  = "raw."
+	keyPfxSig  = "signer."
+	keyPfxRev  = "rev."
 )
 
 // KV is a profile storage backend using a key-value store.
 type KV struct {
-	b kv.KeysPrefixTraversingBucket
+	mu sync.Mutex // serializes revision numbering
+	b  kv.KeysPrefixTraversingBucket
 }
 
 func New(b kv.KeysPrefixTraversingBucket) *KV {
@@ -51,9 +56,23 @@ func (s *KV) RetrieveProfileInfos(ctx context.Context, names []string) (map[stri
 			return r, err
 		}
 
+		// profiles stored before signers were recorded won't have one
+		signer, err := s.b.Get(ctx, keyPfxSig+name)
+		if err != nil && !errors.Is(err, kv.ErrKeyNotFound) {
+			return r, err
+		}
+
+		// as well profiles stored before revisions were kept
+		rev, err := s.getInt(ctx, keyPfxRev+name)
+		if err != nil {
+			return r, err
+		}
+
 		r[name] = storage.ProfileInfo{
 			Identifier: string(id),
 			UUID:       string(uuid),
+			Signer:     string(signer),
+			Revision:   rev,
 		}
 	}
 	return r, nil
@@ -78,19 +97,43 @@ func (s *KV) RetrieveRawProfiles(ctx context.Context, names []string) (map[strin
 }
 
 // StoreProfile stores a raw profile and associated info in the key-value store by name.
+// A new revision is created which becomes the active revision.
 func (s *KV) StoreProfile(ctx context.Context, name string, info storage.ProfileInfo, raw []byte) error {
+	if err := storage.ValidateName(name); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rev, err := s.storeRevision(ctx, name, info, raw)
+	if err != nil {
+		return fmt.Errorf("storing revision: %w", err)
+	}
+	return s.storeActive(ctx, name, info, raw, rev)
+}
+
+// storeActive stores the active profile of name.
+func (s *KV) storeActive(ctx context.Context, name string, info storage.ProfileInfo, raw []byte, rev int) error {
 	return kv.SetMap(ctx, s.b, map[string][]byte{
 		keyPfxID + name:   []byte(info.Identifier),
 		keyPfxUUID + name: []byte(info.UUID),
 		keyPfxRaw + name:  raw,
+		keyPfxSig + name:  []byte(info.Signer),
+		keyPfxRev + name:  []byte(strconv.Itoa(rev)),
 	})
 }
 
-// DeleteProfile deletes a profile from the key-value store by name.
+// DeleteProfile deletes a profile and its revisions from the key-value store by name.
 func (s *KV) DeleteProfile(ctx context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.deleteRevisions(ctx, name); err != nil {
+		return fmt.Errorf("deleting revisions: %w", err)
+	}
 	return kv.DeleteSlice(ctx, s.b, []string{
 		keyPfxID + name,
 		keyPfxUUID + name,
 		keyPfxRaw + name,
+		keyPfxSig + name,
+		keyPfxRev + name,
 	})
 }
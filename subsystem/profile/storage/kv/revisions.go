@@ -0,0 +1,149 @@
+package kv
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/micromdm/nanocmd/subsystem/profile/storage"
+
+	"github.com/micromdm/nanolib/storage/kv"
+)
+
+const (
+	keyPfxRevLast = "revlast."
+	keyPfxRevMeta = "revmeta."
+	keyPfxRevRaw  = "revraw."
+)
+
+func revKey(pfx, name string, rev int) string {
+	return pfx + name + "." + strconv.Itoa(rev)
+}
+
+// getInt returns the integer value of key or zero if it doesn't exist.
+func (s *KV) getInt(ctx context.Context, key string) (int, error) {
+	v, err := s.b.Get(ctx, key)
+	if errors.Is(err, kv.ErrKeyNotFound) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(string(v))
+}
+
+// storeRevision stores a new revision of name and returns its number.
+// The caller must hold the lock.
+func (s *KV) storeRevision(ctx context.Context, name string, info storage.ProfileInfo, raw []byte) (int, error) {
+	last, err := s.getInt(ctx, keyPfxRevLast+name)
+	if err != nil {
+		return 0, err
+	}
+	hash := sha256.Sum256(raw)
+	rev := storage.Revision{
+		Revision:   last + 1,
+		Identifier: info.Identifier,
+		UUID:       info.UUID,
+		Signer:     info.Signer,
+		Uploaded:   time.Now(),
+		Size:       len(raw),
+		SHA256:     hex.EncodeToString(hash[:]),
+	}
+	meta, err := json.Marshal(&rev)
+	if err != nil {
+		return 0, err
+	}
+	return rev.Revision, kv.SetMap(ctx, s.b, map[string][]byte{
+		revKey(keyPfxRevMeta, name, rev.Revision): meta,
+		revKey(keyPfxRevRaw, name, rev.Revision):  raw,
+		keyPfxRevLast + name:                      []byte(strconv.Itoa(rev.Revision)),
+	})
+}
+
+// retrieveRevision returns the metadata of revision rev of name.
+func (s *KV) retrieveRevision(ctx context.Context, name string, rev int) (*storage.Revision, error) {
+	meta, err := s.b.Get(ctx, revKey(keyPfxRevMeta, name, rev))
+	if errors.Is(err, kv.ErrKeyNotFound) {
+		return nil, fmt.Errorf("%w: %s: %d", storage.ErrRevisionNotFound, name, rev)
+	} else if err != nil {
+		return nil, err
+	}
+	r := new(storage.Revision)
+	return r, json.Unmarshal(meta, r)
+}
+
+// RetrieveRevisions returns the revisions of the named profile in ascending order.
+func (s *KV) RetrieveRevisions(ctx context.Context, name string) ([]storage.Revision, error) {
+	ok, err := s.b.Has(ctx, keyPfxID+name)
+	if err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, fmt.Errorf("%w: %s", storage.ErrProfileNotFound, name)
+	}
+	active, err := s.getInt(ctx, keyPfxRev+name)
+	if err != nil {
+		return nil, err
+	}
+	last, err := s.getInt(ctx, keyPfxRevLast+name)
+	if err != nil {
+		return nil, err
+	}
+	var ret []storage.Revision
+	for i := 1; i <= last; i++ {
+		rev, err := s.retrieveRevision(ctx, name, i)
+		if errors.Is(err, storage.ErrRevisionNotFound) {
+			continue
+		} else if err != nil {
+			return ret, err
+		}
+		rev.Active = i == active
+		ret = append(ret, *rev)
+	}
+	return ret, nil
+}
+
+// RetrieveRawRevision returns the raw profile bytes of revision rev of the named profile.
+func (s *KV) RetrieveRawRevision(ctx context.Context, name string, rev int) ([]byte, error) {
+	raw, err := s.b.Get(ctx, revKey(keyPfxRevRaw, name, rev))
+	if errors.Is(err, kv.ErrKeyNotFound) {
+		return nil, fmt.Errorf("%w: %s: %d", storage.ErrRevisionNotFound, name, rev)
+	}
+	return raw, err
+}
+
+// ActivateRevision replaces the named profile with revision rev.
+func (s *KV) ActivateRevision(ctx context.Context, name string, rev int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, err := s.retrieveRevision(ctx, name, rev)
+	if err != nil {
+		return err
+	}
+	raw, err := s.RetrieveRawRevision(ctx, name, rev)
+	if err != nil {
+		return err
+	}
+	info := storage.ProfileInfo{
+		Identifier: r.Identifier,
+		UUID:       r.UUID,
+		Signer:     r.Signer,
+	}
+	return s.storeActive(ctx, name, info, raw, rev)
+}
+
+// deleteRevisions deletes all revisions of name.
+func (s *KV) deleteRevisions(ctx context.Context, name string) error {
+	last, err := s.getInt(ctx, keyPfxRevLast+name)
+	if err != nil {
+		return err
+	}
+	keys := []string{keyPfxRevLast + name}
+	for i := 1; i <= last; i++ {
+		keys = append(keys, revKey(keyPfxRevMeta, name, i), revKey(keyPfxRevRaw, name, i))
+	}
+	return kv.DeleteSlice(ctx, s.b, keys)
+}
@@ -119,6 +119,8 @@ func (s *MySQLStorage) RetrieveProfileInfos(ctx context.Context, names []string)
 			ret[dbpi.Name] = storage.ProfileInfo{
 				Identifier: dbpi.ProfileID,
 				UUID:       dbpi.ProfileUuid,
+				Signer:     dbpi.Signer,
+				Revision:   int(dbpi.Revision),
 			}
 		}
 		for _, name := range names {
@@ -137,6 +139,8 @@ func (s *MySQLStorage) RetrieveProfileInfos(ctx context.Context, names []string)
 			ret[dbpi.Name] = storage.ProfileInfo{
 				Identifier: dbpi.ProfileID,
 				UUID:       dbpi.ProfileUuid,
+				Signer:     dbpi.Signer,
+				Revision:   int(dbpi.Revision),
 			}
 		}
 	}
@@ -145,27 +149,52 @@ func (s *MySQLStorage) RetrieveProfileInfos(ctx context.Context, names []string)
 
 // StoreProfile stores a raw profile and associated info in the profile storage by name from MySQL.
 // It is up to the caller to make sure info is correctly populated and matches the raw profile bytes.
+// A new revision is created which becomes the active revision.
 func (s *MySQLStorage) StoreProfile(ctx context.Context, name string, info storage.ProfileInfo, raw []byte) error {
-	_, err := s.db.ExecContext(
+	if err := storage.ValidateName(name); err != nil {
+		return err
+	}
+	return tx(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		rev, err := storeRevision(ctx, s.q.WithTx(tx), name, info, raw)
+		if err != nil {
+			return fmt.Errorf("storing revision: %w", err)
+		}
+		return storeActive(ctx, tx, name, info, raw, rev)
+	})
+}
+
+// storeActive stores the active profile of name.
+func storeActive(ctx context.Context, tx *sql.Tx, name string, info storage.ProfileInfo, raw []byte, rev int) error {
+	_, err := tx.ExecContext(
 		ctx, `
 INSERT INTO subsystem_profiles 
-	(name, profile_id, profile_uuid, raw_profile)
+	(name, profile_id, profile_uuid, raw_profile, signer, revision)
 VALUES 
-	(?, ?, ?, ?) as new
+	(?, ?, ?, ?, ?, ?) as new
 ON DUPLICATE KEY UPDATE 
 	profile_id = new.profile_id,
 	profile_uuid = new.profile_uuid,
-	raw_profile = new.raw_profile;`,
+	raw_profile = new.raw_profile,
+	signer = new.signer,
+	revision = new.revision;`,
 		name,
 		info.Identifier,
 		info.UUID,
 		raw,
+		info.Signer,
+		rev,
 	)
 	return err
 }
 
-// DeleteProfile deletes a profile from profile storage by name from MySQL.
+// DeleteProfile deletes a profile and its revisions from profile storage by name from MySQL.
 // ErrProfileNotFound is returned for a name that hasn't been stored.
 func (s *MySQLStorage) DeleteProfile(ctx context.Context, name string) error {
-	return s.q.DeleteProfile(ctx, name)
+	return tx(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		qtx := s.q.WithTx(tx)
+		if err := qtx.DeleteRevisions(ctx, name); err != nil {
+			return err
+		}
+		return qtx.DeleteProfile(ctx, name)
+	})
 }
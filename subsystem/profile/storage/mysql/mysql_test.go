@@ -10,9 +10,9 @@ import (
 )
 
 func TestMySQLStorage(t *testing.T) {
-	testDSN := os.Getenv("NANODEP_MYSQL_STORAGE_TEST_DSN")
+	testDSN := os.Getenv("NANOCMD_MYSQL_STORAGE_TEST_DSN")
 	if testDSN == "" {
-		t.Skip("NANODEP_MYSQL_STORAGE_TEST_DSN not set")
+		t.Skip("NANOCMD_MYSQL_STORAGE_TEST_DSN not set")
 	}
 
 	test.TestProfileStorage(t, func() (storage.Storage, error) {
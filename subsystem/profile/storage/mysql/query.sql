@@ -2,7 +2,9 @@
 SELECT
   name,
   profile_id,
-  profile_uuid
+  profile_uuid,
+  signer,
+  revision
 FROM
   subsystem_profiles;
 
@@ -10,7 +12,9 @@ FROM
 SELECT
   name,
   profile_id,
-  profile_uuid
+  profile_uuid,
+  signer,
+  revision
 FROM
   subsystem_profiles
 WHERE
@@ -27,3 +31,66 @@ WHERE
 
 -- name: DeleteProfile :exec
 DELETE FROM subsystem_profiles WHERE name = ?;
+
+-- name: DeleteRevisions :exec
+DELETE FROM subsystem_profile_revisions WHERE name = ?;
+
+-- name: GetNextRevision :one
+SELECT
+  CAST(COALESCE(MAX(revision), 0) + 1 AS SIGNED) AS next_revision
+FROM
+  subsystem_profile_revisions
+WHERE
+  name = ?
+FOR UPDATE;
+
+-- name: InsertRevision :exec
+INSERT INTO subsystem_profile_revisions
+  (name, revision, profile_id, profile_uuid, signer, raw_profile, size, sha256)
+VALUES
+  (?, ?, ?, ?, ?, ?, ?, ?);
+
+-- name: GetActiveRevision :one
+SELECT
+  revision
+FROM
+  subsystem_profiles
+WHERE
+  name = ?;
+
+-- name: GetRevisions :many
+SELECT
+  revision,
+  profile_id,
+  profile_uuid,
+  signer,
+  size,
+  sha256,
+  created_at
+FROM
+  subsystem_profile_revisions
+WHERE
+  name = ?
+ORDER BY
+  revision;
+
+-- name: GetRevision :one
+SELECT
+  profile_id,
+  profile_uuid,
+  signer,
+  raw_profile
+FROM
+  subsystem_profile_revisions
+WHERE
+  name = ? AND
+  revision = ?;
+
+-- name: GetRawRevision :one
+SELECT
+  raw_profile
+FROM
+  subsystem_profile_revisions
+WHERE
+  name = ? AND
+  revision = ?;
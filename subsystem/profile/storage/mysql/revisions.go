@@ -0,0 +1,115 @@
+package mysql
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/micromdm/nanocmd/subsystem/profile/storage"
+	"github.com/micromdm/nanocmd/subsystem/profile/storage/mysql/sqlc"
+)
+
+// txcb executes SQL within transactions when wrapped in tx().
+type txcb func(ctx context.Context, tx *sql.Tx) error
+
+// tx wraps g in transactions using db.
+// If g returns an err the transaction will be rolled back; otherwise committed.
+func tx(ctx context.Context, db *sql.DB, g txcb) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("tx begin: %w", err)
+	}
+	if err = g(ctx, tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("tx rollback: %w; while trying to handle error: %v", rbErr, err)
+		}
+		return fmt.Errorf("tx rolled back: %w", err)
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("tx commit: %w", err)
+	}
+	return nil
+}
+
+// storeRevision stores a new revision of name and returns its number.
+func storeRevision(ctx context.Context, q *sqlc.Queries, name string, info storage.ProfileInfo, raw []byte) (int, error) {
+	rev, err := q.GetNextRevision(ctx, name)
+	if err != nil {
+		return 0, err
+	}
+	hash := sha256.Sum256(raw)
+	err = q.InsertRevision(ctx, sqlc.InsertRevisionParams{
+		Name:        name,
+		Revision:    int32(rev),
+		ProfileID:   info.Identifier,
+		ProfileUuid: info.UUID,
+		Signer:      info.Signer,
+		RawProfile:  raw,
+		Size:        int32(len(raw)),
+		Sha256:      hex.EncodeToString(hash[:]),
+	})
+	return int(rev), err
+}
+
+// RetrieveRevisions returns the revisions of the named profile in ascending order from MySQL.
+// ErrProfileNotFound is returned if the name hasn't been stored.
+func (s *MySQLStorage) RetrieveRevisions(ctx context.Context, name string) ([]storage.Revision, error) {
+	active, err := s.q.GetActiveRevision(ctx, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", storage.ErrProfileNotFound, name)
+	} else if err != nil {
+		return nil, err
+	}
+
+	r, err := s.q.GetRevisions(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []storage.Revision
+	for _, dbrev := range r {
+		ret = append(ret, storage.Revision{
+			Revision:   int(dbrev.Revision),
+			Identifier: dbrev.ProfileID,
+			UUID:       dbrev.ProfileUuid,
+			Signer:     dbrev.Signer,
+			Size:       int(dbrev.Size),
+			SHA256:     dbrev.Sha256,
+			Uploaded:   dbrev.CreatedAt.Time,
+			Active:     dbrev.Revision == active,
+		})
+	}
+	return ret, nil
+}
+
+// RetrieveRawRevision returns the raw profile bytes of revision rev of the named profile from MySQL.
+// ErrRevisionNotFound is returned if the revision hasn't been stored.
+func (s *MySQLStorage) RetrieveRawRevision(ctx context.Context, name string, rev int) ([]byte, error) {
+	raw, err := s.q.GetRawRevision(ctx, sqlc.GetRawRevisionParams{Name: name, Revision: int32(rev)})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s: %d", storage.ErrRevisionNotFound, name, rev)
+	}
+	return raw, err
+}
+
+// ActivateRevision replaces the named profile with revision rev in MySQL.
+// ErrRevisionNotFound is returned if the revision hasn't been stored.
+func (s *MySQLStorage) ActivateRevision(ctx context.Context, name string, rev int) error {
+	return tx(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		dbrev, err := s.q.WithTx(tx).GetRevision(ctx, sqlc.GetRevisionParams{Name: name, Revision: int32(rev)})
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: %s: %d", storage.ErrRevisionNotFound, name, rev)
+		} else if err != nil {
+			return err
+		}
+		info := storage.ProfileInfo{
+			Identifier: dbrev.ProfileID,
+			UUID:       dbrev.ProfileUuid,
+			Signer:     dbrev.Signer,
+		}
+		return storeActive(ctx, tx, name, info, dbrev.RawProfile, rev)
+	})
+}
@@ -0,0 +1,2 @@
+ALTER TABLE subsystem_profiles
+    ADD COLUMN signer VARCHAR(1024) NOT NULL DEFAULT '' AFTER raw_profile;
@@ -0,0 +1,28 @@
+ALTER TABLE subsystem_profiles
+    ADD COLUMN revision INTEGER NOT NULL DEFAULT 0 AFTER signer;
+
+CREATE TABLE subsystem_profile_revisions (
+    name     VARCHAR(255) NOT NULL,
+    revision INTEGER NOT NULL,
+
+    profile_id   VARCHAR(255) NOT NULL,
+    profile_uuid VARCHAR(255) NOT NULL,
+    signer       VARCHAR(1024) NOT NULL DEFAULT '',
+    raw_profile  MEDIUMTEXT NOT NULL,
+    size         INTEGER NOT NULL,
+    sha256       CHAR(64) NOT NULL,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (name, revision)
+);
+
+-- existing profiles become their first revision
+INSERT INTO subsystem_profile_revisions
+    (name, revision, profile_id, profile_uuid, signer, raw_profile, size, sha256, created_at)
+SELECT
+    name, 1, profile_id, profile_uuid, signer, raw_profile, LENGTH(raw_profile), SHA2(raw_profile, 256), updated_at
+FROM
+    subsystem_profiles;
+
+UPDATE subsystem_profiles SET revision = 1, updated_at = updated_at;
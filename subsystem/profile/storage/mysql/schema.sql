@@ -4,9 +4,27 @@ CREATE TABLE subsystem_profiles (
     profile_id   VARCHAR(255) NOT NULL,
     profile_uuid VARCHAR(255) NOT NULL,
     raw_profile  MEDIUMTEXT NOT NULL,
+    signer       VARCHAR(1024) NOT NULL DEFAULT '',
+    revision     INTEGER NOT NULL DEFAULT 0,
 
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
 
     PRIMARY KEY (name)
 );
+
+CREATE TABLE subsystem_profile_revisions (
+    name     VARCHAR(255) NOT NULL,
+    revision INTEGER NOT NULL,
+
+    profile_id   VARCHAR(255) NOT NULL,
+    profile_uuid VARCHAR(255) NOT NULL,
+    signer       VARCHAR(1024) NOT NULL DEFAULT '',
+    raw_profile  MEDIUMTEXT NOT NULL,
+    size         INTEGER NOT NULL,
+    sha256       CHAR(64) NOT NULL,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (name, revision)
+);
@@ -13,6 +13,20 @@ type SubsystemProfile struct {
 	ProfileID   string
 	ProfileUuid string
 	RawProfile  []byte
+	Signer      string
+	Revision    int32
 	CreatedAt   sql.NullTime
 	UpdatedAt   sql.NullTime
 }
+
+type SubsystemProfileRevision struct {
+	Name        string
+	Revision    int32
+	ProfileID   string
+	ProfileUuid string
+	Signer      string
+	RawProfile  []byte
+	Size        int32
+	Sha256      string
+	CreatedAt   sql.NullTime
+}
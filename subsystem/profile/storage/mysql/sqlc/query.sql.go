@@ -7,6 +7,7 @@ package sqlc
 
 import (
 	"context"
+	"database/sql"
 	"strings"
 )
 
@@ -19,11 +20,38 @@ func (q *Queries) DeleteProfile(ctx context.Context, name string) error {
 	return err
 }
 
+const deleteRevisions = `-- name: DeleteRevisions :exec
+DELETE FROM subsystem_profile_revisions WHERE name = ?
+`
+
+func (q *Queries) DeleteRevisions(ctx context.Context, name string) error {
+	_, err := q.db.ExecContext(ctx, deleteRevisions, name)
+	return err
+}
+
+const getActiveRevision = `-- name: GetActiveRevision :one
+SELECT
+  revision
+FROM
+  subsystem_profiles
+WHERE
+  name = ?
+`
+
+func (q *Queries) GetActiveRevision(ctx context.Context, name string) (int32, error) {
+	row := q.db.QueryRowContext(ctx, getActiveRevision, name)
+	var revision int32
+	err := row.Scan(&revision)
+	return revision, err
+}
+
 const getAllProfileInfos = `-- name: GetAllProfileInfos :many
 SELECT
   name,
   profile_id,
-  profile_uuid
+  profile_uuid,
+  signer,
+  revision
 FROM
   subsystem_profiles
 `
@@ -32,6 +60,8 @@ type GetAllProfileInfosRow struct {
 	Name        string
 	ProfileID   string
 	ProfileUuid string
+	Signer      string
+	Revision    int32
 }
 
 func (q *Queries) GetAllProfileInfos(ctx context.Context) ([]GetAllProfileInfosRow, error) {
@@ -43,7 +73,13 @@ func (q *Queries) GetAllProfileInfos(ctx context.Context) ([]GetAllProfileInfosR
 	var items []GetAllProfileInfosRow
 	for rows.Next() {
 		var i GetAllProfileInfosRow
-		if err := rows.Scan(&i.Name, &i.ProfileID, &i.ProfileUuid); err != nil {
+		if err := rows.Scan(
+			&i.Name,
+			&i.ProfileID,
+			&i.ProfileUuid,
+			&i.Signer,
+			&i.Revision,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -57,11 +93,30 @@ func (q *Queries) GetAllProfileInfos(ctx context.Context) ([]GetAllProfileInfosR
 	return items, nil
 }
 
+const getNextRevision = `-- name: GetNextRevision :one
+SELECT
+  CAST(COALESCE(MAX(revision), 0) + 1 AS SIGNED) AS next_revision
+FROM
+  subsystem_profile_revisions
+WHERE
+  name = ?
+FOR UPDATE
+`
+
+func (q *Queries) GetNextRevision(ctx context.Context, name string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getNextRevision, name)
+	var next_revision int64
+	err := row.Scan(&next_revision)
+	return next_revision, err
+}
+
 const getProfileInfos = `-- name: GetProfileInfos :many
 SELECT
   name,
   profile_id,
-  profile_uuid
+  profile_uuid,
+  signer,
+  revision
 FROM
   subsystem_profiles
 WHERE
@@ -72,6 +127,8 @@ type GetProfileInfosRow struct {
 	Name        string
 	ProfileID   string
 	ProfileUuid string
+	Signer      string
+	Revision    int32
 }
 
 func (q *Queries) GetProfileInfos(ctx context.Context, names []string) ([]GetProfileInfosRow, error) {
@@ -93,7 +150,13 @@ func (q *Queries) GetProfileInfos(ctx context.Context, names []string) ([]GetPro
 	var items []GetProfileInfosRow
 	for rows.Next() {
 		var i GetProfileInfosRow
-		if err := rows.Scan(&i.Name, &i.ProfileID, &i.ProfileUuid); err != nil {
+		if err := rows.Scan(
+			&i.Name,
+			&i.ProfileID,
+			&i.ProfileUuid,
+			&i.Signer,
+			&i.Revision,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -154,3 +217,152 @@ func (q *Queries) GetRawProfiles(ctx context.Context, names []string) ([]GetRawP
 	}
 	return items, nil
 }
+
+const getRawRevision = `-- name: GetRawRevision :one
+SELECT
+  raw_profile
+FROM
+  subsystem_profile_revisions
+WHERE
+  name = ? AND
+  revision = ?
+`
+
+type GetRawRevisionParams struct {
+	Name     string
+	Revision int32
+}
+
+func (q *Queries) GetRawRevision(ctx context.Context, arg GetRawRevisionParams) ([]byte, error) {
+	row := q.db.QueryRowContext(ctx, getRawRevision, arg.Name, arg.Revision)
+	var raw_profile []byte
+	err := row.Scan(&raw_profile)
+	return raw_profile, err
+}
+
+const getRevision = `-- name: GetRevision :one
+SELECT
+  profile_id,
+  profile_uuid,
+  signer,
+  raw_profile
+FROM
+  subsystem_profile_revisions
+WHERE
+  name = ? AND
+  revision = ?
+`
+
+type GetRevisionParams struct {
+	Name     string
+	Revision int32
+}
+
+type GetRevisionRow struct {
+	ProfileID   string
+	ProfileUuid string
+	Signer      string
+	RawProfile  []byte
+}
+
+func (q *Queries) GetRevision(ctx context.Context, arg GetRevisionParams) (GetRevisionRow, error) {
+	row := q.db.QueryRowContext(ctx, getRevision, arg.Name, arg.Revision)
+	var i GetRevisionRow
+	err := row.Scan(
+		&i.ProfileID,
+		&i.ProfileUuid,
+		&i.Signer,
+		&i.RawProfile,
+	)
+	return i, err
+}
+
+const getRevisions = `-- name: GetRevisions :many
+SELECT
+  revision,
+  profile_id,
+  profile_uuid,
+  signer,
+  size,
+  sha256,
+  created_at
+FROM
+  subsystem_profile_revisions
+WHERE
+  name = ?
+ORDER BY
+  revision
+`
+
+type GetRevisionsRow struct {
+	Revision    int32
+	ProfileID   string
+	ProfileUuid string
+	Signer      string
+	Size        int32
+	Sha256      string
+	CreatedAt   sql.NullTime
+}
+
+func (q *Queries) GetRevisions(ctx context.Context, name string) ([]GetRevisionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getRevisions, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRevisionsRow
+	for rows.Next() {
+		var i GetRevisionsRow
+		if err := rows.Scan(
+			&i.Revision,
+			&i.ProfileID,
+			&i.ProfileUuid,
+			&i.Signer,
+			&i.Size,
+			&i.Sha256,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const insertRevision = `-- name: InsertRevision :exec
+INSERT INTO subsystem_profile_revisions
+  (name, revision, profile_id, profile_uuid, signer, raw_profile, size, sha256)
+VALUES
+  (?, ?, ?, ?, ?, ?, ?, ?)
+`
+
+type InsertRevisionParams struct {
+	Name        string
+	Revision    int32
+	ProfileID   string
+	ProfileUuid string
+	Signer      string
+	RawProfile  []byte
+	Size        int32
+	Sha256      string
+}
+
+func (q *Queries) InsertRevision(ctx context.Context, arg InsertRevisionParams) error {
+	_, err := q.db.ExecContext(ctx, insertRevision,
+		arg.Name,
+		arg.Revision,
+		arg.ProfileID,
+		arg.ProfileUuid,
+		arg.Signer,
+		arg.RawProfile,
+		arg.Size,
+		arg.Sha256,
+	)
+	return err
+}
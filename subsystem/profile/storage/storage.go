@@ -4,19 +4,62 @@ package storage
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 )
 
 var (
-	ErrProfileNotFound = errors.New("profile not found")
-	ErrNoNames         = errors.New("no profile names supplied")
+	ErrProfileNotFound  = errors.New("profile not found")
+	ErrNoNames          = errors.New("no profile names supplied")
+	ErrRevisionNotFound = errors.New("profile revision not found")
+	ErrInvalidName      = errors.New("invalid profile name")
 )
 
+// RevisionSeparator separates a profile name from a pinned revision
+// number (e.g. "name@2"). Profile names can not contain it.
+const RevisionSeparator = "@"
+
+// ValidateName checks that name can be used to store a profile.
+func ValidateName(name string) error {
+	if name == "" {
+		return fmt.Errorf("%w: empty name", ErrInvalidName)
+	}
+	if strings.Contains(name, RevisionSeparator) {
+		return fmt.Errorf("%w: contains %q: %s", ErrInvalidName, RevisionSeparator, name)
+	}
+	return nil
+}
+
 // ProfileInfo is metadata about an Apple Configuration profile.
 // It is meant to be taken/parsed directly from an actual profile.
 // See https://developer.apple.com/documentation/devicemanagement/toplevel
 type ProfileInfo struct {
 	Identifier string `json:"identifier"` // top-level PayloadIdentifier of the profile.
 	UUID       string `json:"uuid"`       // top-level PayloadUUID of the profile.
+
+	// Signer is the subject of the certificate that signed the stored
+	// profile. Empty if the stored profile is not signed.
+	Signer string `json:"signer,omitempty"`
+
+	// Revision is the active revision number of the stored profile.
+	// It is assigned by the storage backend and is ignored when storing.
+	// Zero if the profile was stored before revisions were kept.
+	Revision int `json:"revision,omitempty"`
+}
+
+// Revision is metadata about a stored revision of a named profile.
+type Revision struct {
+	Revision   int       `json:"revision"`
+	Identifier string    `json:"identifier"` // top-level PayloadIdentifier of the profile revision.
+	UUID       string    `json:"uuid"`       // top-level PayloadUUID of the profile revision.
+	Signer     string    `json:"signer,omitempty"`
+	Uploaded   time.Time `json:"uploaded"`
+	Size       int       `json:"size"`   // size of the raw profile in bytes.
+	SHA256     string    `json:"sha256"` // hex-encoded SHA-256 hash of the raw profile.
+
+	// Active is true if the revision is the current stored profile.
+	Active bool `json:"active"`
 }
 
 // Valid checks the validity of the profile metadata.
@@ -35,6 +78,16 @@ type ReadRawStorage interface {
 	RetrieveRawProfiles(ctx context.Context, names []string) (map[string][]byte, error)
 }
 
+type ReadRevisionStorage interface {
+	// RetrieveRevisions returns the revisions of the named profile in ascending order.
+	// ErrProfileNotFound is returned if the name hasn't been stored.
+	RetrieveRevisions(ctx context.Context, name string) ([]Revision, error)
+
+	// RetrieveRawRevision returns the raw profile bytes of revision rev of the named profile.
+	// ErrRevisionNotFound is returned if the revision hasn't been stored.
+	RetrieveRawRevision(ctx context.Context, name string, rev int) ([]byte, error)
+}
+
 type ReadStorage interface {
 	// RetrieveProfileInfos returns the profile metadata by name.
 	// Implementations have the choice to return all profile metadata if
@@ -43,6 +96,7 @@ type ReadStorage interface {
 	RetrieveProfileInfos(ctx context.Context, names []string) (map[string]ProfileInfo, error)
 
 	ReadRawStorage
+	ReadRevisionStorage
 }
 
 type Storage interface {
@@ -50,10 +104,17 @@ type Storage interface {
 
 	// StoreProfile stores a raw profile and associated info in the profile storage by name.
 	// It is up to the caller to make sure info is correctly populated
-	// and matches the raw profile bytes.
+	// and matches the raw profile bytes. A new revision is created
+	// for each stored profile and becomes the active revision.
+	// ErrInvalidName is returned if name is not valid (see ValidateName).
 	StoreProfile(ctx context.Context, name string, info ProfileInfo, raw []byte) error
 
-	// DeleteProfile deletes a profile from profile storage by name.
+	// ActivateRevision replaces the named profile with revision rev.
+	// No new revision is created.
+	// ErrRevisionNotFound is returned if the revision hasn't been stored.
+	ActivateRevision(ctx context.Context, name string, rev int) error
+
+	// DeleteProfile deletes a profile and its revisions from profile storage by name.
 	// ErrProfileNotFound is returned for a name that hasn't been stored.
 	DeleteProfile(ctx context.Context, name string) error
 }
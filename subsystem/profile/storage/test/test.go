@@ -17,9 +17,15 @@ func TestProfileStorage(t *testing.T, newStorage func() (storage.Storage, error)
 	}
 	ctx := context.Background()
 
-	info := storage.ProfileInfo{Identifier: "com.test", UUID: "01AB"}
+	info := storage.ProfileInfo{Identifier: "com.test", UUID: "01AB", Signer: "CN=test"}
 	raw := []byte("23CD")
 
+	// names can not be mistaken for names pinned to a revision
+	err = s.StoreProfile(ctx, "test@1", info, raw)
+	if !errors.Is(err, storage.ErrInvalidName) {
+		t.Errorf("have: %v, want: %v", err, storage.ErrInvalidName)
+	}
+
 	err = s.StoreProfile(ctx, "test", info, raw)
 	if err != nil {
 		t.Fatal(err)
@@ -35,6 +41,9 @@ func TestProfileStorage(t *testing.T, newStorage func() (storage.Storage, error)
 		t.Error("key not found after retrieval")
 	}
 
+	// the revision is assigned by storage
+	info.Revision = 1
+
 	if !reflect.DeepEqual(info, info2) {
 		t.Error("info not equal")
 	}
@@ -76,6 +85,8 @@ func TestProfileStorage(t *testing.T, newStorage func() (storage.Storage, error)
 		t.Fatal("expected ErrNoNames")
 	}
 
+	testRevisions(t, s, info, raw)
+
 	err = s.DeleteProfile(ctx, "test")
 	if err != nil {
 		t.Fatal(err)
@@ -86,4 +97,96 @@ func TestProfileStorage(t *testing.T, newStorage func() (storage.Storage, error)
 		t.Fatal("expected ErrProfileNotFound")
 	}
 
+	_, err = s.RetrieveRawRevision(ctx, "test", 1)
+	if !errors.Is(err, storage.ErrRevisionNotFound) {
+		t.Fatal("expected ErrRevisionNotFound")
+	}
+
+}
+
+// testRevisions tests profile revisions of the "test" profile already
+// stored as revision 1 with info and raw.
+func testRevisions(t *testing.T, s storage.Storage, info storage.ProfileInfo, raw []byte) {
+	ctx := context.Background()
+
+	info2 := storage.ProfileInfo{Identifier: "com.test", UUID: "02EF"}
+	raw2 := []byte("45GH")
+
+	err := s.StoreProfile(ctx, "test", info2, raw2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	revs, err := s.RetrieveRevisions(ctx, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := len(revs), 2; have != want {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+	if have, want := revs[0].UUID, info.UUID; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+	if have, want := revs[0].Size, len(raw); have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+	if revs[0].Active || !revs[1].Active {
+		t.Error("expected second revision to be active")
+	}
+	if revs[0].SHA256 == "" || revs[0].SHA256 == revs[1].SHA256 {
+		t.Error("invalid revision hashes")
+	}
+
+	rawRev, err := s.RetrieveRawRevision(ctx, "test", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(raw, rawRev) {
+		t.Error("raw revision not equal")
+	}
+
+	_, err = s.RetrieveRawRevision(ctx, "test", 3)
+	if !errors.Is(err, storage.ErrRevisionNotFound) {
+		t.Fatal("expected ErrRevisionNotFound")
+	}
+
+	_, err = s.RetrieveRevisions(ctx, "missing")
+	if !errors.Is(err, storage.ErrProfileNotFound) {
+		t.Fatal("expected ErrProfileNotFound")
+	}
+
+	// roll back to the first revision
+	err = s.ActivateRevision(ctx, "test", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	infos, err := s.RetrieveProfileInfos(ctx, []string{"test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(info, infos["test"]) {
+		t.Error("info not equal after activating revision")
+	}
+
+	raws, err := s.RetrieveRawProfiles(ctx, []string{"test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(raw, raws["test"]) {
+		t.Error("raw not equal after activating revision")
+	}
+
+	revs, err = s.RetrieveRevisions(ctx, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revs) != 2 || !revs[0].Active || revs[1].Active {
+		t.Error("expected first revision to be active")
+	}
+
+	err = s.ActivateRevision(ctx, "test", 3)
+	if !errors.Is(err, storage.ErrRevisionNotFound) {
+		t.Fatal("expected ErrRevisionNotFound")
+	}
 }
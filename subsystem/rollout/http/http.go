@@ -0,0 +1,220 @@
+// Package http contains HTTP handlers for working with staged workflow rollouts.
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/micromdm/nanocmd/engine"
+	"github.com/micromdm/nanocmd/http/api"
+	"github.com/micromdm/nanocmd/logkeys"
+	"github.com/micromdm/nanocmd/subsystem/rollout/manager"
+	"github.com/micromdm/nanocmd/subsystem/rollout/storage"
+
+	"github.com/alexedwards/flow"
+	"github.com/micromdm/nanolib/log"
+	"github.com/micromdm/nanolib/log/ctxlog"
+)
+
+var (
+	ErrNoName                = errors.New("no name provided")
+	ErrWorkflowNotRegistered = errors.New("workflow not registered")
+)
+
+// WorkflowNameChecker checks that a workflow name is registered and
+// whether it requires approval to start.
+type WorkflowNameChecker interface {
+	WorkflowRegistered(name string) bool
+	ApprovalRequired(name string) bool
+}
+
+// Controller creates and controls rollouts.
+type Controller interface {
+	CreateRollout(ctx context.Context, name string, r *storage.Rollout) error
+	PauseRollout(ctx context.Context, name string) error
+	ResumeRollout(ctx context.Context, name string) error
+	AbortRollout(ctx context.Context, name string) error
+	DeleteRollout(ctx context.Context, name string) error
+}
+
+// GetRolloutsHandler returns an HTTP handler that fetches rollouts.
+// All rollouts are returned unless "name" query parameters are provided.
+func GetRolloutsHandler(store storage.ReadStorage, logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := ctxlog.Logger(r.Context(), logger)
+		rollouts, err := store.RetrieveRollouts(r.Context(), r.URL.Query()["name"])
+		if err != nil {
+			logger.Info(logkeys.Message, "retrieve rollouts", logkeys.Error, err)
+			api.JSONError(w, err, 0)
+			return
+		}
+		logger.Debug(
+			logkeys.Message, "retrieved rollouts",
+			logkeys.GenericCount, len(rollouts),
+		)
+		w.Header().Set("Content-Type", "application/json")
+		if err = json.NewEncoder(w).Encode(rollouts); err != nil {
+			logger.Info(logkeys.Message, "encoding json to body", logkeys.Error, err)
+			return
+		}
+	}
+}
+
+// GetHandler returns an HTTP handler that fetches a rollout.
+func GetHandler(store storage.ReadStorage, logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := ctxlog.Logger(r.Context(), logger)
+		name := flow.Param(r.Context(), "name")
+		if name == "" {
+			logger.Info(logkeys.Message, "name parameter", logkeys.Error, ErrNoName)
+			api.JSONError(w, ErrNoName, http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("name", name)
+		rollouts, err := store.RetrieveRollouts(r.Context(), []string{name})
+		if err != nil {
+			logger.Info(logkeys.Message, "retrieve rollout", logkeys.Error, err)
+			api.JSONError(w, err, 0)
+			return
+		}
+
+		logger.Debug(logkeys.Message, "retrieved rollout")
+		w.Header().Set("Content-Type", "application/json")
+		if err = json.NewEncoder(w).Encode(rollouts[name]); err != nil {
+			logger.Info(logkeys.Message, "encoding json to body", logkeys.Error, err)
+			return
+		}
+	}
+}
+
+// PutHandler returns an HTTP handler for creating a rollout.
+// A rollout can only be replaced once it has completed or been aborted.
+// The API principal must have the permission to start the workflow of
+// the rollout.
+func PutHandler(ctl Controller, chk WorkflowNameChecker, logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := ctxlog.Logger(r.Context(), logger)
+		name := flow.Param(r.Context(), "name")
+		if name == "" {
+			logger.Info(logkeys.Message, "name parameter", logkeys.Error, ErrNoName)
+			api.JSONError(w, ErrNoName, http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("name", name)
+		rollout := new(storage.Rollout)
+		err := json.NewDecoder(r.Body).Decode(rollout)
+		if err != nil {
+			logger.Info(logkeys.Message, "decoding body", logkeys.Error, err)
+			api.JSONError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		if err = rollout.Validate(); err != nil {
+			logger.Info(logkeys.Message, "validating rollout", logkeys.Error, err)
+			api.JSONError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With(logkeys.WorkflowName, rollout.Workflow)
+		if !chk.WorkflowRegistered(rollout.Workflow) {
+			logger.Info(logkeys.Message, "checking workflow name", logkeys.Error, ErrWorkflowNotRegistered)
+			api.JSONError(w, ErrWorkflowNotRegistered, http.StatusBadRequest)
+			return
+		} else if chk.ApprovalRequired(rollout.Workflow) {
+			err := fmt.Errorf("%w: %s", engine.ErrWorkflowRequiresApproval, rollout.Workflow)
+			logger.Info(logkeys.Message, "checking workflow name", logkeys.Error, err)
+			api.JSONError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		if err = api.CheckWorkflowStart(r.Context(), rollout.Workflow); err != nil {
+			logger.Info(logkeys.Message, "checking workflow permission", logkeys.Error, err)
+			api.JSONError(w, err, http.StatusForbidden)
+			return
+		}
+
+		if err = ctl.CreateRollout(r.Context(), name, rollout); err != nil {
+			logger.Info(logkeys.Message, "creating rollout", logkeys.Error, err)
+			var statusCode int
+			if errors.Is(err, manager.ErrRolloutActive) || errors.Is(err, manager.ErrNoIDs) {
+				statusCode = http.StatusBadRequest
+			}
+			api.JSONError(w, err, statusCode)
+			return
+		}
+
+		logger.Debug(logkeys.Message, "created rollout", logkeys.GenericCount, len(rollout.IDs))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// controlHandler returns an HTTP handler that calls fn with the rollout name.
+func controlHandler(fn func(context.Context, string) error, action string, logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := ctxlog.Logger(r.Context(), logger)
+		name := flow.Param(r.Context(), "name")
+		if name == "" {
+			logger.Info(logkeys.Message, "name parameter", logkeys.Error, ErrNoName)
+			api.JSONError(w, ErrNoName, http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("name", name)
+		if err := fn(r.Context(), name); err != nil {
+			logger.Info(logkeys.Message, action, logkeys.Error, err)
+			var statusCode int
+			if errors.Is(err, manager.ErrInvalidStatus) {
+				statusCode = http.StatusBadRequest
+			}
+			api.JSONError(w, err, statusCode)
+			return
+		}
+
+		logger.Debug(logkeys.Message, action)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// PauseHandler returns an HTTP handler for pausing a running rollout.
+func PauseHandler(ctl Controller, logger log.Logger) http.HandlerFunc {
+	return controlHandler(ctl.PauseRollout, "pause rollout", logger)
+}
+
+// ResumeHandler returns an HTTP handler for resuming a paused rollout.
+func ResumeHandler(ctl Controller, logger log.Logger) http.HandlerFunc {
+	return controlHandler(ctl.ResumeRollout, "resume rollout", logger)
+}
+
+// AbortHandler returns an HTTP handler for aborting a running or paused rollout.
+func AbortHandler(ctl Controller, logger log.Logger) http.HandlerFunc {
+	return controlHandler(ctl.AbortRollout, "abort rollout", logger)
+}
+
+// DeleteHandler returns an HTTP handler for deleting a rollout.
+// Deleting a running rollout stops any further waves.
+func DeleteHandler(ctl Controller, logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := ctxlog.Logger(r.Context(), logger)
+		name := flow.Param(r.Context(), "name")
+		if name == "" {
+			logger.Info(logkeys.Message, "name parameter", logkeys.Error, ErrNoName)
+			api.JSONError(w, ErrNoName, http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("name", name)
+		if err := ctl.DeleteRollout(r.Context(), name); err != nil {
+			logger.Info(logkeys.Message, "deleting rollout", logkeys.Error, err)
+			api.JSONError(w, err, 0)
+			return
+		}
+
+		logger.Debug(logkeys.Message, "deleted rollout")
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,56 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/micromdm/nanocmd/http/api"
+	"github.com/micromdm/nanocmd/subsystem/rollout/storage"
+
+	"github.com/alexedwards/flow"
+	"github.com/micromdm/nanolib/log"
+)
+
+type testChecker struct{}
+
+func (testChecker) WorkflowRegistered(string) bool { return true }
+
+func (testChecker) ApprovalRequired(string) bool { return false }
+
+type testController struct {
+	Controller
+	created []string
+}
+
+func (c *testController) CreateRollout(_ context.Context, name string, _ *storage.Rollout) error {
+	c.created = append(c.created, name)
+	return nil
+}
+
+func TestPutRolloutPermission(t *testing.T) {
+	ctl := new(testController)
+	mux := flow.New()
+	mux.Handle("/v1/rollout/:name", PutHandler(ctl, testChecker{}, log.NopLogger), "PUT")
+
+	body := `{"workflow": "wf.a", "ids": ["AAA"]}`
+	for _, test := range []struct {
+		perms  []string
+		status int
+	}{
+		{[]string{api.PermRolloutsManage}, http.StatusForbidden},
+		{[]string{api.PermRolloutsManage, api.PermWorkflowStart("wf.a")}, http.StatusNoContent},
+	} {
+		h := api.NewPrincipalHandler(mux, "alice", test.perms...)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("PUT", "/v1/rollout/r1", strings.NewReader(body)))
+		if have, want := w.Code, test.status; have != want {
+			t.Errorf("%v: status: have: %d, want: %d", test.perms, have, want)
+		}
+	}
+	if have, want := len(ctl.created), 1; have != want {
+		t.Errorf("created: have: %d, want: %d", have, want)
+	}
+}
@@ -0,0 +1,96 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/micromdm/nanocmd/http/api"
+	"github.com/micromdm/nanocmd/subsystem/rollout/storage"
+	"github.com/micromdm/nanolib/log"
+)
+
+// Mux can register HTTP handlers.
+// Ostensibly this supports flow router.
+type Mux interface {
+	// Handle registers the handler for the given pattern.
+	Handle(pattern string, handler http.Handler, methods ...string)
+}
+
+// HandleAPIv1 registers the various API handlers into mux.
+// API endpoint paths are prepended with prefix.
+// Authentication or any other layered handlers are not present.
+// They are assumed to be layered with mux, possibly at the Handle call.
+// Each handler requires the API principal to have the "rollouts:manage" permission.
+// If prefix is empty and these handlers are used in sub-paths then
+// handlers should have that sub-path stripped from the request.
+// The logger is adorned with a "handler" key of the endpoint name.
+func HandleAPIv1(prefix string, mux Mux, logger log.Logger, s storage.ReadStorage, ctl Controller, chk WorkflowNameChecker) {
+	mux.Handle(
+		prefix+"/rollout/:name",
+		api.RequirePermission(
+			GetHandler(s, logger.With("handler", "get-rollout")),
+			api.PermRolloutsManage,
+			logger,
+		),
+		"GET",
+	)
+
+	mux.Handle(
+		prefix+"/rollout/:name",
+		api.RequirePermission(
+			PutHandler(ctl, chk, logger.With("handler", "put-rollout")),
+			api.PermRolloutsManage,
+			logger,
+		),
+		"PUT",
+	)
+
+	mux.Handle(
+		prefix+"/rollout/:name",
+		api.RequirePermission(
+			DeleteHandler(ctl, logger.With("handler", "delete-rollout")),
+			api.PermRolloutsManage,
+			logger,
+		),
+		"DELETE",
+	)
+
+	mux.Handle(
+		prefix+"/rollout/:name/pause",
+		api.RequirePermission(
+			PauseHandler(ctl, logger.With("handler", "pause-rollout")),
+			api.PermRolloutsManage,
+			logger,
+		),
+		"POST",
+	)
+
+	mux.Handle(
+		prefix+"/rollout/:name/resume",
+		api.RequirePermission(
+			ResumeHandler(ctl, logger.With("handler", "resume-rollout")),
+			api.PermRolloutsManage,
+			logger,
+		),
+		"POST",
+	)
+
+	mux.Handle(
+		prefix+"/rollout/:name/abort",
+		api.RequirePermission(
+			AbortHandler(ctl, logger.With("handler", "abort-rollout")),
+			api.PermRolloutsManage,
+			logger,
+		),
+		"POST",
+	)
+
+	mux.Handle(
+		prefix+"/rollouts",
+		api.RequirePermission(
+			GetRolloutsHandler(s, logger.With("handler", "get-rollouts")),
+			api.PermRolloutsManage,
+			logger,
+		),
+		"GET",
+	)
+}
@@ -0,0 +1,455 @@
+// Package manager starts and controls staged workflow rollouts.
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/micromdm/nanocmd/logkeys"
+	"github.com/micromdm/nanocmd/subsystem/audit"
+	auditstorage "github.com/micromdm/nanocmd/subsystem/audit/storage"
+	groupstorage "github.com/micromdm/nanocmd/subsystem/group/storage"
+	"github.com/micromdm/nanocmd/subsystem/rollout/storage"
+	"github.com/micromdm/nanocmd/workflow"
+
+	"github.com/micromdm/nanolib/log"
+)
+
+var (
+	ErrNoGroupStorage = errors.New("no group storage")
+	ErrNoIDs          = errors.New("no enrollment IDs")
+	ErrRolloutActive  = errors.New("rollout is running or paused")
+	ErrInvalidStatus  = errors.New("invalid rollout status")
+)
+
+// Auditor records audit events.
+type Auditor interface {
+	Audit(ctx context.Context, e *auditstorage.Event)
+}
+
+type WorkflowStarter interface {
+	StartWorkflow(ctx context.Context, name string, context []byte, ids []string, e *workflow.Event, mdmCtx *workflow.MDMContext) (string, error)
+}
+
+// Manager starts the waves of rollouts and tracks the outcomes of the
+// workflow steps they started.
+type Manager struct {
+	// mu serializes updates of rollout states
+	mu sync.Mutex
+
+	store   storage.Storage
+	starter WorkflowStarter
+	groups  groupstorage.ReadStorage
+	auditor Auditor
+	logger  log.Logger
+}
+
+type Option func(*Manager)
+
+func WithLogger(logger log.Logger) Option {
+	return func(m *Manager) {
+		m.logger = logger
+	}
+}
+
+// WithGroupStorage configures the group storage used to resolve the
+// members of rollouts that target a group.
+func WithGroupStorage(groups groupstorage.ReadStorage) Option {
+	return func(m *Manager) {
+		m.groups = groups
+	}
+}
+
+// WithAuditor configures an auditor for recording the starts of rollout waves.
+func WithAuditor(auditor Auditor) Option {
+	return func(m *Manager) {
+		m.auditor = auditor
+	}
+}
+
+func New(store storage.Storage, starter WorkflowStarter, opts ...Option) *Manager {
+	m := &Manager{
+		store:   store,
+		starter: starter,
+		logger:  log.NopLogger,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// retrieveRollout retrieves the named rollout.
+func (m *Manager) retrieveRollout(ctx context.Context, name string) (*storage.Rollout, error) {
+	rollouts, err := m.store.RetrieveRollouts(ctx, []string{name})
+	if err != nil {
+		return nil, err
+	}
+	r, ok := rollouts[name]
+	if !ok || r == nil {
+		return nil, fmt.Errorf("%w: %s", storage.ErrRolloutNotFound, name)
+	}
+	return r, nil
+}
+
+// CreateRollout stores and starts the named rollout.
+// Any group is resolved into its members. The first wave is started
+// by the next call to RunRollouts.
+func (m *Manager) CreateRollout(ctx context.Context, name string, r *storage.Rollout) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	if r.Group != "" {
+		if m.groups == nil {
+			return ErrNoGroupStorage
+		}
+		ids, err := m.groups.RetrieveGroupMembers(ctx, r.Group)
+		if err != nil {
+			return fmt.Errorf("retrieving group members: %s: %w", r.Group, err)
+		}
+		r.IDs = ids
+	}
+	if len(r.IDs) < 1 {
+		return ErrNoIDs
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	existing, err := m.retrieveRollout(ctx, name)
+	if err != nil && !errors.Is(err, storage.ErrRolloutNotFound) {
+		return err
+	}
+	if existing != nil && existing.State != nil {
+		if s := existing.State.Status; s == storage.StatusRunning || s == storage.StatusPaused {
+			return ErrRolloutActive
+		}
+	}
+	if existing != nil {
+		// clear the instance associations of the old rollout
+		if err = m.store.DeleteRollout(ctx, name); err != nil {
+			return fmt.Errorf("deleting rollout: %w", err)
+		}
+	}
+	r.State = &storage.State{
+		Status:     storage.StatusRunning,
+		NextWaveAt: time.Now(),
+	}
+	return m.store.StoreRollout(ctx, name, r)
+}
+
+// setStatus changes the status of the named rollout if its current
+// status is one of from.
+func (m *Manager) setStatus(ctx context.Context, name, status, reason string, from ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, err := m.retrieveRollout(ctx, name)
+	if err != nil {
+		return err
+	}
+	if r.State == nil {
+		return ErrInvalidStatus
+	}
+	var ok bool
+	for _, s := range from {
+		if r.State.Status == s {
+			ok = true
+			break
+		}
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrInvalidStatus, r.State.Status)
+	}
+	if status == storage.StatusRunning {
+		r.State.ResumedCounts = r.State.Counts
+	}
+	r.State.Status = status
+	r.State.Reason = reason
+	return m.store.StoreRollout(ctx, name, r)
+}
+
+// PauseRollout pauses the named running rollout.
+func (m *Manager) PauseRollout(ctx context.Context, name string) error {
+	return m.setStatus(ctx, name, storage.StatusPaused, "paused by API", storage.StatusRunning)
+}
+
+// ResumeRollout resumes the named paused rollout.
+// The failure threshold only considers steps observed after resuming.
+func (m *Manager) ResumeRollout(ctx context.Context, name string) error {
+	return m.setStatus(ctx, name, storage.StatusRunning, "", storage.StatusPaused)
+}
+
+// AbortRollout aborts the named running or paused rollout.
+// No further waves are started. Already started workflows continue.
+func (m *Manager) AbortRollout(ctx context.Context, name string) error {
+	return m.setStatus(ctx, name, storage.StatusAborted, "aborted by API", storage.StatusRunning, storage.StatusPaused)
+}
+
+// DeleteRollout deletes the named rollout.
+// No further waves are started. Already started workflows continue.
+func (m *Manager) DeleteRollout(ctx context.Context, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.store.DeleteRollout(ctx, name)
+}
+
+// waveDue returns true if the next wave of s is due at now.
+// The next wave is only due once the previous wave finished.
+func waveDue(s *storage.State, now time.Time) bool {
+	if s == nil || s.Status != storage.StatusRunning {
+		return false
+	}
+	if len(s.Waves) > 0 && s.Waves[len(s.Waves)-1].FinishedAt.IsZero() {
+		return false
+	}
+	return !s.NextWaveAt.After(now)
+}
+
+// RunRollouts starts the next waves of running rollouts that are due at now.
+func (m *Manager) RunRollouts(ctx context.Context, now time.Time) error {
+	rollouts, err := m.store.RetrieveRollouts(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("retrieving rollouts: %w", err)
+	}
+	for name, r := range rollouts {
+		if !waveDue(r.State, now) {
+			continue
+		}
+		logger := m.logger.With("name", name, logkeys.WorkflowName, r.Workflow)
+		if err = m.startWave(ctx, name, now, logger); err != nil {
+			logger.Info(logkeys.Message, "starting rollout wave", logkeys.Error, err)
+		}
+	}
+	return nil
+}
+
+// startWave starts the next wave of the named rollout.
+// The rollout is paused if the wave fails to start.
+func (m *Manager) startWave(ctx context.Context, name string, now time.Time, logger log.Logger) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// re-retrieve the rollout as it may have changed
+	r, err := m.retrieveRollout(ctx, name)
+	if err != nil {
+		return err
+	}
+	if !waveDue(r.State, now) {
+		return nil
+	}
+
+	sizes := r.WaveSizes()
+	wave := len(r.State.Waves)
+	if wave >= len(sizes) {
+		r.State.Status = storage.StatusCompleted
+		return m.store.StoreRollout(ctx, name, r)
+	}
+	var offset int
+	for _, size := range sizes[:wave] {
+		offset += size
+	}
+	ids := r.IDs[offset : offset+sizes[wave]]
+
+	logger = logger.With(
+		"wave", wave+1,
+		logkeys.FirstEnrollmentID, ids[0],
+		logkeys.GenericCount, len(ids),
+	)
+	instanceID, err := m.starter.StartWorkflow(ctx, r.Workflow, []byte(r.Context), ids, nil, nil)
+	if m.auditor != nil {
+		m.auditor.Audit(ctx, &auditstorage.Event{
+			Actor:        audit.ActorRollout,
+			Action:       "rollout.start",
+			TargetIDs:    ids,
+			WorkflowName: r.Workflow,
+			InstanceID:   instanceID,
+			Params: map[string][]string{
+				"rollout": {name},
+				"wave":    {strconv.Itoa(wave + 1)},
+			},
+			Error: audit.ErrorString(err),
+		})
+	}
+	if err != nil {
+		logger.Info(logkeys.Message, "starting workflow", logkeys.InstanceID, instanceID, logkeys.Error, err)
+		r.State.Status = storage.StatusPaused
+		r.State.Reason = fmt.Sprintf("starting wave %d: %v", wave+1, err)
+		return m.store.StoreRollout(ctx, name, r)
+	}
+	logger.Debug(logkeys.Message, "started rollout wave", logkeys.InstanceID, instanceID)
+
+	r.State.Waves = append(r.State.Waves, storage.WaveState{
+		StartedAt:  now,
+		InstanceID: instanceID,
+		Count:      len(ids),
+	})
+	if wave+1 >= len(sizes) {
+		r.State.Status = storage.StatusCompleted
+	}
+	// the wait starts once the wave finished (see InstanceFinished)
+	r.State.NextWaveAt = time.Time{}
+	return m.store.StoreRollout(ctx, name, r)
+}
+
+// waveOf returns the index of the started wave of r that contains
+// the enrollment id or -1 if no started wave contains it.
+func waveOf(r *storage.Rollout, id string) int {
+	if r.State == nil {
+		return -1
+	}
+	var offset int
+	for i, w := range r.State.Waves {
+		if offset+w.Count > len(r.IDs) {
+			break
+		}
+		for _, waveID := range r.IDs[offset : offset+w.Count] {
+			if waveID == id {
+				return i
+			}
+		}
+		offset += w.Count
+	}
+	return -1
+}
+
+// rolloutOf returns the name of the rollout that started the workflow
+// instanceID for id. Instances that were not associated with a rollout
+// when its wave started (e.g. because the start was queued) are
+// resolved to the rollout of the workflow with the most recently
+// started wave containing id. An empty name is returned if no rollout
+// started the instance.
+func (m *Manager) rolloutOf(ctx context.Context, instanceID, workflowName, id string) (string, error) {
+	name, err := m.store.RetrieveRolloutByInstance(ctx, instanceID)
+	if err != nil || name != "" {
+		return name, err
+	}
+	rollouts, err := m.store.RetrieveRollouts(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	var startedAt time.Time
+	for rName, r := range rollouts {
+		if r.Workflow != workflowName {
+			continue
+		}
+		wave := waveOf(r, id)
+		if wave < 0 {
+			continue
+		}
+		if name == "" || r.State.Waves[wave].StartedAt.After(startedAt) {
+			name = rName
+			startedAt = r.State.Waves[wave].StartedAt
+		}
+	}
+	return name, nil
+}
+
+// thresholdExceeded returns true if the failure rate of the steps
+// observed since r was last resumed is above r's failure threshold.
+func thresholdExceeded(r *storage.Rollout) bool {
+	if r.FailureThreshold < 1 {
+		return false
+	}
+	c, base := r.State.Counts, r.State.ResumedCounts
+	steps := c.Steps - base.Steps
+	failed := c.Errors - base.Errors + c.Timeouts - base.Timeouts
+	if steps < 1 || steps < r.MinSteps {
+		return false
+	}
+	return failed*100 > r.FailureThreshold*steps
+}
+
+// StepObserved counts the step outcome of a workflow started by a
+// rollout. Running rollouts are paused if the failure threshold is
+// exceeded. Steps of workflows not started by rollouts are ignored.
+func (m *Manager) StepObserved(ctx context.Context, instanceID, workflowName, id string, errored, timedOut bool) {
+	logger := m.logger.With(
+		logkeys.InstanceID, instanceID,
+		logkeys.WorkflowName, workflowName,
+		logkeys.EnrollmentID, id,
+	)
+	name, err := m.rolloutOf(ctx, instanceID, workflowName, id)
+	if err != nil {
+		logger.Info(logkeys.Message, "retrieving rollout of instance", logkeys.Error, err)
+		return
+	} else if name == "" {
+		return
+	}
+	logger = logger.With("name", name)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, err := m.retrieveRollout(ctx, name)
+	if err != nil {
+		logger.Info(logkeys.Message, "retrieving rollout", logkeys.Error, err)
+		return
+	}
+	if r.State == nil {
+		return
+	}
+	r.State.Counts.Steps++
+	if timedOut {
+		r.State.Counts.Timeouts++
+	} else if errored {
+		r.State.Counts.Errors++
+	}
+	if r.State.Status == storage.StatusRunning && thresholdExceeded(r) {
+		r.State.Status = storage.StatusPaused
+		r.State.Reason = fmt.Sprintf("failure threshold of %d%% exceeded", r.FailureThreshold)
+		logger.Info(logkeys.Message, "pausing rollout", "reason", r.State.Reason)
+	}
+	if err = m.store.StoreRollout(ctx, name, r); err != nil {
+		logger.Info(logkeys.Message, "storing rollout", logkeys.Error, err)
+	}
+}
+
+// InstanceFinished records that the workflow started by a rollout
+// finished for id. Once the workflow finished for every enrollment ID
+// of a wave the wait for the next wave starts. Instances of workflows
+// not started by rollouts are ignored.
+func (m *Manager) InstanceFinished(ctx context.Context, instanceID, workflowName, id string, _ workflow.Outcome) {
+	logger := m.logger.With(
+		logkeys.InstanceID, instanceID,
+		logkeys.WorkflowName, workflowName,
+		logkeys.EnrollmentID, id,
+	)
+	name, err := m.rolloutOf(ctx, instanceID, workflowName, id)
+	if err != nil {
+		logger.Info(logkeys.Message, "retrieving rollout of instance", logkeys.Error, err)
+		return
+	} else if name == "" {
+		return
+	}
+	logger = logger.With("name", name)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, err := m.retrieveRollout(ctx, name)
+	if err != nil {
+		logger.Info(logkeys.Message, "retrieving rollout", logkeys.Error, err)
+		return
+	}
+	wave := waveOf(r, id)
+	if wave < 0 {
+		return
+	}
+	ws := &r.State.Waves[wave]
+	for _, finishedID := range ws.FinishedIDs {
+		if finishedID == id {
+			return
+		}
+	}
+	ws.FinishedIDs = append(ws.FinishedIDs, id)
+	if len(ws.FinishedIDs) >= ws.Count && ws.FinishedAt.IsZero() {
+		ws.FinishedAt = time.Now()
+		if wave == len(r.State.Waves)-1 {
+			r.State.NextWaveAt = ws.FinishedAt.Add(time.Duration(r.Wait) * time.Second)
+		}
+		logger.Debug(logkeys.Message, "rollout wave finished", "wave", wave+1)
+	}
+	if err = m.store.StoreRollout(ctx, name, r); err != nil {
+		logger.Info(logkeys.Message, "storing rollout", logkeys.Error, err)
+	}
+}
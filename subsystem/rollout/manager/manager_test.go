@@ -0,0 +1,170 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/micromdm/nanocmd/subsystem/rollout/storage"
+	"github.com/micromdm/nanocmd/subsystem/rollout/storage/inmem"
+	"github.com/micromdm/nanocmd/workflow"
+)
+
+type starter struct {
+	ids []string
+	ct  int
+}
+
+func (s *starter) StartWorkflow(_ context.Context, _ string, _ []byte, ids []string, _ *workflow.Event, _ *workflow.MDMContext) (string, error) {
+	s.ids = append(s.ids, ids...)
+	s.ct++
+	return fmt.Sprintf("inst%d", s.ct), nil
+}
+
+func TestRollout(t *testing.T) {
+	ctx := context.Background()
+	store := inmem.New()
+	st := new(starter)
+	m := New(store, st)
+
+	err := m.CreateRollout(ctx, "r1", &storage.Rollout{
+		Workflow:         "wf",
+		IDs:              []string{"A", "B", "C", "D", "E", "F", "G", "H", "I", "J"},
+		Waves:            []storage.Wave{{Size: 2}, {Percent: 30}},
+		Wait:             3600,
+		FailureThreshold: 50,
+		MinSteps:         2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state := func() *storage.State {
+		t.Helper()
+		rollouts, err := store.RetrieveRollouts(ctx, []string{"r1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rollouts["r1"].State
+	}
+
+	now := time.Now()
+	if err = m.RunRollouts(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+	if have, want := len(st.ids), 2; have != want {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+
+	// the wave has not finished
+	if err = m.RunRollouts(ctx, now.Add(2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if have, want := len(st.ids), 2; have != want {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+
+	// an unrelated instance is ignored
+	m.StepObserved(ctx, "other", "wf", "Z", true, false)
+
+	// one error is under the minimum number of steps
+	m.StepObserved(ctx, "inst1", "wf", "A", true, false)
+	if have, want := state().Status, storage.StatusRunning; have != want {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+	m.StepObserved(ctx, "inst1", "wf", "B", false, true)
+	s := state()
+	if have, want := s.Status, storage.StatusPaused; have != want {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+
+	// the wait between waves starts once every instance of the wave finished
+	m.InstanceFinished(ctx, "inst1", "wf", "A", workflow.OutcomeFailed)
+	m.InstanceFinished(ctx, "inst1", "wf", "A", workflow.OutcomeFailed)
+	if !state().Waves[0].FinishedAt.IsZero() {
+		t.Error("wave finished before all of its instances")
+	}
+	m.InstanceFinished(ctx, "inst1", "wf", "B", workflow.OutcomeTimedOut)
+	s = state()
+	if s.Waves[0].FinishedAt.IsZero() {
+		t.Fatal("wave not finished")
+	}
+	if have, want := s.NextWaveAt, s.Waves[0].FinishedAt.Add(time.Hour); !have.Equal(want) {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+	if have, want := s.Counts, (storage.Counts{Steps: 2, Errors: 1, Timeouts: 1}); have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	// paused rollouts do not start waves
+	if err = m.RunRollouts(ctx, now.Add(2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if have, want := len(st.ids), 2; have != want {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+
+	if err = m.PauseRollout(ctx, "r1"); !errors.Is(err, ErrInvalidStatus) {
+		t.Errorf("expected ErrInvalidStatus, have: %v", err)
+	}
+
+	if err = m.ResumeRollout(ctx, "r1"); err != nil {
+		t.Fatal(err)
+	}
+	if err = m.RunRollouts(ctx, now.Add(2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if have, want := len(st.ids), 5; have != want {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+
+	// the failure threshold only considers steps after resuming
+	m.StepObserved(ctx, "inst2", "wf", "C", false, false)
+	m.StepObserved(ctx, "inst2", "wf", "D", false, false)
+	if have, want := state().Status, storage.StatusRunning; have != want {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+
+	// instances not associated with the rollout (e.g. of queued
+	// starts) are resolved by workflow and enrollment ID
+	m.StepObserved(ctx, "queued", "wf", "E", true, false)
+	if have, want := state().Counts.Errors, 2; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+	for _, id := range []string{"C", "D"} {
+		m.InstanceFinished(ctx, "inst2", "wf", id, workflow.OutcomeSucceeded)
+	}
+	if err = m.RunRollouts(ctx, now.Add(4*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if have, want := len(st.ids), 5; have != want {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+	m.InstanceFinished(ctx, "queued", "wf", "E", workflow.OutcomeFailed)
+	m.InstanceFinished(ctx, "other", "other.wf", "F", workflow.OutcomeSucceeded)
+
+	if err = m.CreateRollout(ctx, "r1", &storage.Rollout{Workflow: "wf", IDs: []string{"A"}}); !errors.Is(err, ErrRolloutActive) {
+		t.Errorf("expected ErrRolloutActive, have: %v", err)
+	}
+
+	// the final wave contains the remaining IDs
+	if err = m.RunRollouts(ctx, now.Add(4*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if have, want := len(st.ids), 10; have != want {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+	s = state()
+	if have, want := s.Status, storage.StatusCompleted; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+	if have, want := len(s.Waves), 3; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	if err = m.AbortRollout(ctx, "r1"); !errors.Is(err, ErrInvalidStatus) {
+		t.Errorf("expected ErrInvalidStatus, have: %v", err)
+	}
+}
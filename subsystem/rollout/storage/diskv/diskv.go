@@ -0,0 +1,34 @@
+// Package diskv implements a rollout storage backend backed by an on-disk key-value store.
+package diskv
+
+import (
+	"path/filepath"
+
+	"github.com/micromdm/nanocmd/subsystem/rollout/storage/kv"
+
+	"github.com/micromdm/nanolib/storage/kv/kvdiskv"
+	"github.com/peterbourgon/diskv/v3"
+)
+
+// Diskv is a rollout storage backend backed by an on-disk key-value store.
+type Diskv struct {
+	*kv.KV
+}
+
+func newBucket(path string) *kvdiskv.KVDiskv {
+	return kvdiskv.New(diskv.New(diskv.Options{
+		BasePath:     path,
+		Transform:    kvdiskv.FlatTransform,
+		CacheSizeMax: 1024 * 1024,
+	}))
+}
+
+// New creates a new initialized rollout data store.
+func New(path string) *Diskv {
+	return &Diskv{
+		KV: kv.New(
+			newBucket(filepath.Join(path, "rollout")),
+			newBucket(filepath.Join(path, "instance")),
+		),
+	}
+}
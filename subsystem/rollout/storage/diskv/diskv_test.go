@@ -0,0 +1,12 @@
+package diskv
+
+import (
+	"testing"
+
+	"github.com/micromdm/nanocmd/subsystem/rollout/storage"
+	"github.com/micromdm/nanocmd/subsystem/rollout/storage/test"
+)
+
+func TestDiskv(t *testing.T) {
+	test.TestRolloutStorage(t, func() storage.Storage { return New(t.TempDir()) })
+}
@@ -0,0 +1,109 @@
+// Package kv implements a rollout storage backend using JSON with key-value storage.
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/micromdm/nanocmd/subsystem/rollout/storage"
+
+	"github.com/micromdm/nanolib/storage/kv"
+)
+
+// KV is a rollout storage backend using JSON with key-value storage.
+type KV struct {
+	mu        sync.Mutex
+	rollouts  kv.Bucket
+	instances kv.Bucket
+}
+
+func New(rollouts, instances kv.Bucket) *KV {
+	return &KV{rollouts: rollouts, instances: instances}
+}
+
+func (s *KV) retrieveRollout(ctx context.Context, name string) (*storage.Rollout, error) {
+	raw, err := s.rollouts.Get(ctx, name)
+	if errors.Is(err, kv.ErrKeyNotFound) {
+		return nil, fmt.Errorf("%w: %s: %v", storage.ErrRolloutNotFound, name, err)
+	} else if err != nil {
+		return nil, err
+	}
+	r := new(storage.Rollout)
+	if err = json.Unmarshal(raw, r); err != nil {
+		return nil, fmt.Errorf("unmarshal rollout: %s: %w", name, err)
+	}
+	return r, nil
+}
+
+// RetrieveRollouts unmarshals the JSON stored using names and returns the rollouts.
+// All rollouts are returned if no names are provided.
+func (s *KV) RetrieveRollouts(ctx context.Context, names []string) (map[string]*storage.Rollout, error) {
+	if len(names) < 1 {
+		names = kv.AllKeys(ctx, s.rollouts)
+	}
+	ret := make(map[string]*storage.Rollout)
+	for _, name := range names {
+		r, err := s.retrieveRollout(ctx, name)
+		if err != nil {
+			return ret, err
+		}
+		ret[name] = r
+	}
+	return ret, nil
+}
+
+// RetrieveRolloutByInstance returns the rollout name stored using instanceID.
+func (s *KV) RetrieveRolloutByInstance(ctx context.Context, instanceID string) (string, error) {
+	raw, err := s.instances.Get(ctx, instanceID)
+	if errors.Is(err, kv.ErrKeyNotFound) {
+		return "", nil
+	}
+	return string(raw), err
+}
+
+// StoreRollout marshals r into JSON and stores it using name.
+func (s *KV) StoreRollout(ctx context.Context, name string, r *storage.Rollout) error {
+	raw, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.State != nil {
+		for _, w := range r.State.Waves {
+			if w.InstanceID == "" {
+				continue
+			}
+			if err = s.instances.Set(ctx, w.InstanceID, []byte(name)); err != nil {
+				return fmt.Errorf("storing instance: %w", err)
+			}
+		}
+	}
+	return s.rollouts.Set(ctx, name, raw)
+}
+
+// DeleteRollout deletes the JSON stored using name and its instance associations.
+func (s *KV) DeleteRollout(ctx context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, err := s.retrieveRollout(ctx, name)
+	if errors.Is(err, storage.ErrRolloutNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if r.State != nil {
+		for _, w := range r.State.Waves {
+			if w.InstanceID == "" {
+				continue
+			}
+			if err = s.instances.Delete(ctx, w.InstanceID); err != nil {
+				return fmt.Errorf("deleting instance: %w", err)
+			}
+		}
+	}
+	return s.rollouts.Delete(ctx, name)
+}
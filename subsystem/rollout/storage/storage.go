@@ -0,0 +1,183 @@
+// Package storage defines types and interfaces supporting staged workflow rollouts.
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrRolloutNotFound = errors.New("rollout not found")
+	ErrNoWorkflow      = errors.New("no workflow name")
+	ErrNoTargets       = errors.New("exactly one of ids or group required")
+	ErrInvalidWave     = errors.New("wave requires exactly one of size or percent")
+	ErrInvalidPercent  = errors.New("percent must be between 1 and 100")
+	ErrInvalidWait     = errors.New("invalid wait")
+	ErrInvalidMinSteps = errors.New("invalid minimum steps")
+)
+
+// Wave is the size of a rollout wave as either a fixed number of
+// enrollment IDs or a percentage of all the rollout enrollment IDs.
+type Wave struct {
+	Size    int `json:"size,omitempty"`
+	Percent int `json:"percent,omitempty"`
+}
+
+// Rollout starts a workflow for a set of enrollment IDs in waves.
+type Rollout struct {
+	Workflow string `json:"workflow"`
+	Context  string `json:"context,omitempty"`
+
+	// IDs are the enrollment IDs to start the workflow for.
+	IDs []string `json:"ids,omitempty"`
+	// Group is the name of an enrollment group to start the workflow for.
+	// The group members are resolved into IDs when the rollout is created.
+	Group string `json:"group,omitempty"`
+
+	// Waves are the sizes of the waves. Any enrollment IDs remaining
+	// after the waves are started in a final wave.
+	Waves []Wave `json:"waves,omitempty"`
+	// Wait is the number of seconds to wait between waves.
+	Wait int `json:"wait,omitempty"`
+
+	// FailureThreshold is the percentage of errored or timed-out steps
+	// above which the rollout is paused. Zero disables the threshold.
+	FailureThreshold int `json:"failure_threshold,omitempty"`
+	// MinSteps is the number of steps that must complete before the
+	// failure threshold is checked.
+	MinSteps int `json:"min_steps,omitempty"`
+
+	// State is the progress of the rollout. It is maintained by the
+	// rollout manager and is not user-settable.
+	State *State `json:"state,omitempty"`
+}
+
+// Validate checks r for errors.
+func (r *Rollout) Validate() error {
+	if r == nil {
+		return errors.New("nil rollout")
+	}
+	if r.Workflow == "" {
+		return ErrNoWorkflow
+	}
+	if (len(r.IDs) < 1) == (r.Group == "") {
+		return ErrNoTargets
+	}
+	for _, w := range r.Waves {
+		if (w.Size > 0) == (w.Percent > 0) || w.Size < 0 || w.Percent < 0 {
+			return ErrInvalidWave
+		}
+		if w.Percent > 100 {
+			return ErrInvalidPercent
+		}
+	}
+	if r.Wait < 0 {
+		return ErrInvalidWait
+	}
+	if r.FailureThreshold < 0 || r.FailureThreshold > 100 {
+		return ErrInvalidPercent
+	}
+	if r.MinSteps < 0 {
+		return ErrInvalidMinSteps
+	}
+	return nil
+}
+
+// WaveSizes returns the number of enrollment IDs in each wave.
+// Percentages are rounded up and every wave has at least one ID.
+func (r *Rollout) WaveSizes() []int {
+	var sizes []int
+	remaining := len(r.IDs)
+	for _, w := range r.Waves {
+		if remaining < 1 {
+			break
+		}
+		size := w.Size
+		if w.Percent > 0 {
+			size = (len(r.IDs)*w.Percent + 99) / 100
+		}
+		if size < 1 {
+			size = 1
+		} else if size > remaining {
+			size = remaining
+		}
+		sizes = append(sizes, size)
+		remaining -= size
+	}
+	if remaining > 0 {
+		sizes = append(sizes, remaining)
+	}
+	return sizes
+}
+
+const (
+	StatusRunning   = "running"
+	StatusPaused    = "paused"
+	StatusAborted   = "aborted"
+	StatusCompleted = "completed"
+)
+
+// Counts are the observed outcomes of rollout workflow steps.
+type Counts struct {
+	Steps    int `json:"steps"`
+	Errors   int `json:"errors"`
+	Timeouts int `json:"timeouts"`
+}
+
+// WaveState is a started rollout wave.
+type WaveState struct {
+	StartedAt  time.Time `json:"started_at"`
+	InstanceID string    `json:"instance_id,omitempty"`
+	Count      int       `json:"count"`
+
+	// FinishedIDs are the enrollment IDs of the wave whose workflow
+	// instance finished. FinishedAt is when the last of them finished.
+	FinishedIDs []string  `json:"finished_ids,omitempty"`
+	FinishedAt  time.Time `json:"finished_at,omitempty"`
+}
+
+// State is the progress of a rollout.
+type State struct {
+	// Status is one of running, paused, aborted, or completed.
+	// A rollout is completed once all of its waves have started.
+	Status string `json:"status"`
+	// Reason is why the rollout was paused or aborted.
+	Reason string `json:"reason,omitempty"`
+
+	// Waves are the started waves.
+	Waves []WaveState `json:"waves,omitempty"`
+	// NextWaveAt is the earliest time the next wave starts.
+	// It is set once the previous wave finished.
+	NextWaveAt time.Time `json:"next_wave_at,omitempty"`
+
+	// Counts are the step outcomes of all waves.
+	Counts Counts `json:"counts"`
+	// ResumedCounts are the step outcomes when the rollout was last
+	// resumed. The failure threshold only considers later steps.
+	ResumedCounts Counts `json:"resumed_counts"`
+}
+
+type ReadStorage interface {
+	// RetrieveRollouts returns the rollouts by name.
+	// All rollouts are returned if no names are provided.
+	// ErrRolloutNotFound is returned for any name that hasn't been stored.
+	RetrieveRollouts(ctx context.Context, names []string) (map[string]*Rollout, error)
+
+	// RetrieveRolloutByInstance returns the name of the rollout that
+	// started workflow instance instanceID.
+	// An empty name is returned if no rollout started the instance.
+	RetrieveRolloutByInstance(ctx context.Context, instanceID string) (string, error)
+}
+
+type Storage interface {
+	ReadStorage
+
+	// StoreRollout stores (replaces) the named rollout.
+	// The workflow instance IDs of the rollout wave states are
+	// associated with the rollout name.
+	StoreRollout(ctx context.Context, name string, r *Rollout) error
+
+	// DeleteRollout deletes the named rollout.
+	DeleteRollout(ctx context.Context, name string) error
+}
@@ -0,0 +1,25 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWaveSizes(t *testing.T) {
+	ids := make([]string, 25)
+	for _, test := range []struct {
+		waves []Wave
+		want  []int
+	}{
+		{nil, []int{25}},
+		{[]Wave{{Size: 5}, {Size: 10}}, []int{5, 10, 10}},
+		{[]Wave{{Percent: 10}, {Percent: 50}, {Percent: 100}}, []int{3, 13, 9}},
+		{[]Wave{{Size: 30}, {Size: 5}}, []int{25}},
+		{[]Wave{{Percent: 1}}, []int{1, 24}},
+	} {
+		r := &Rollout{IDs: ids, Waves: test.waves}
+		if have, want := r.WaveSizes(), test.want; !reflect.DeepEqual(have, want) {
+			t.Errorf("waves: %v: have: %v, want: %v", test.waves, have, want)
+		}
+	}
+}
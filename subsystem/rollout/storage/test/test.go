@@ -0,0 +1,94 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/micromdm/nanocmd/subsystem/rollout/storage"
+)
+
+func TestRolloutStorage(t *testing.T, newStorage func() storage.Storage) {
+	s := newStorage()
+	ctx := context.Background()
+
+	r := &storage.Rollout{
+		Workflow: "io.micromdm.wf.profile.v1",
+		IDs:      []string{"AAA111", "BBB222", "CCC333"},
+		Waves:    []storage.Wave{{Size: 1}},
+		Wait:     3600,
+		State: &storage.State{
+			Status: storage.StatusRunning,
+			Waves: []storage.WaveState{
+				{StartedAt: time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC), InstanceID: "inst1", Count: 1},
+			},
+			NextWaveAt: time.Date(2024, time.May, 1, 13, 0, 0, 0, time.UTC),
+			Counts:     storage.Counts{Steps: 1, Errors: 1},
+		},
+	}
+
+	err := s.StoreRollout(ctx, "r1", r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.StoreRollout(ctx, "r2", &storage.Rollout{
+		Workflow: "io.micromdm.wf.profile.v1",
+		Group:    "staff",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rollouts, err := s.RetrieveRollouts(ctx, []string{"r1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := rollouts["r1"], r; !reflect.DeepEqual(have, want) {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	rollouts, err = s.RetrieveRollouts(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := len(rollouts), 2; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	name, err := s.RetrieveRolloutByInstance(ctx, "inst1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := name, "r1"; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	name, err = s.RetrieveRolloutByInstance(ctx, "inst2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := name, ""; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	err = s.DeleteRollout(ctx, "r1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.RetrieveRollouts(ctx, []string{"r1"})
+	if !errors.Is(err, storage.ErrRolloutNotFound) {
+		t.Errorf("expected ErrRolloutNotFound, have: %v", err)
+	}
+
+	name, err = s.RetrieveRolloutByInstance(ctx, "inst1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := name, ""; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+}
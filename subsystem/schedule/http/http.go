@@ -0,0 +1,203 @@
+// Package http contains HTTP handlers for working with workflow schedules.
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/micromdm/nanocmd/engine"
+	"github.com/micromdm/nanocmd/http/api"
+	"github.com/micromdm/nanocmd/logkeys"
+	"github.com/micromdm/nanocmd/subsystem/schedule/storage"
+
+	"github.com/alexedwards/flow"
+	"github.com/micromdm/nanolib/log"
+	"github.com/micromdm/nanolib/log/ctxlog"
+)
+
+var (
+	ErrNoName                = errors.New("no name provided")
+	ErrWorkflowNotRegistered = errors.New("workflow not registered")
+)
+
+// WorkflowNameChecker checks that a workflow name is registered and
+// whether it requires approval to start.
+type WorkflowNameChecker interface {
+	WorkflowRegistered(name string) bool
+	ApprovalRequired(name string) bool
+}
+
+// GetSchedulesHandler returns an HTTP handler that fetches schedules.
+// All schedules are returned unless "name" query parameters are provided.
+func GetSchedulesHandler(store storage.ReadStorage, logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := ctxlog.Logger(r.Context(), logger)
+		schedules, err := store.RetrieveSchedules(r.Context(), r.URL.Query()["name"])
+		if err != nil {
+			logger.Info(logkeys.Message, "retrieve schedules", logkeys.Error, err)
+			api.JSONError(w, err, 0)
+			return
+		}
+		logger.Debug(
+			logkeys.Message, "retrieved schedules",
+			logkeys.GenericCount, len(schedules),
+		)
+		w.Header().Set("Content-Type", "application/json")
+		if err = json.NewEncoder(w).Encode(schedules); err != nil {
+			logger.Info(logkeys.Message, "encoding json to body", logkeys.Error, err)
+			return
+		}
+	}
+}
+
+// GetHandler returns an HTTP handler that fetches a schedule.
+func GetHandler(store storage.ReadStorage, logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := ctxlog.Logger(r.Context(), logger)
+		name := flow.Param(r.Context(), "name")
+		if name == "" {
+			logger.Info(logkeys.Message, "name parameter", logkeys.Error, ErrNoName)
+			api.JSONError(w, ErrNoName, http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("name", name)
+		schedules, err := store.RetrieveSchedules(r.Context(), []string{name})
+		if err != nil {
+			logger.Info(logkeys.Message, "retrieve schedule", logkeys.Error, err)
+			api.JSONError(w, err, 0)
+			return
+		}
+
+		logger.Debug(logkeys.Message, "retrieved schedule")
+		w.Header().Set("Content-Type", "application/json")
+		if err = json.NewEncoder(w).Encode(schedules[name]); err != nil {
+			logger.Info(logkeys.Message, "encoding json to body", logkeys.Error, err)
+			return
+		}
+	}
+}
+
+// GetRunsHandler returns an HTTP handler that fetches the run history of a schedule.
+func GetRunsHandler(store storage.ReadStorage, logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := ctxlog.Logger(r.Context(), logger)
+		name := flow.Param(r.Context(), "name")
+		if name == "" {
+			logger.Info(logkeys.Message, "name parameter", logkeys.Error, ErrNoName)
+			api.JSONError(w, ErrNoName, http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("name", name)
+		runs, err := store.RetrieveScheduleRuns(r.Context(), name)
+		if err != nil {
+			logger.Info(logkeys.Message, "retrieve schedule runs", logkeys.Error, err)
+			api.JSONError(w, err, 0)
+			return
+		}
+		if runs == nil {
+			runs = []*storage.Run{}
+		}
+
+		logger.Debug(
+			logkeys.Message, "retrieved schedule runs",
+			logkeys.GenericCount, len(runs),
+		)
+		w.Header().Set("Content-Type", "application/json")
+		if err = json.NewEncoder(w).Encode(runs); err != nil {
+			logger.Info(logkeys.Message, "encoding json to body", logkeys.Error, err)
+			return
+		}
+	}
+}
+
+// PutHandler returns an HTTP handler for uploading a schedule.
+// The next run time is computed from the current time so missed runs
+// of a replaced schedule are not run. The API principal must have the
+// permission to start the workflow of the schedule.
+func PutHandler(store storage.Storage, chk WorkflowNameChecker, logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := ctxlog.Logger(r.Context(), logger)
+		name := flow.Param(r.Context(), "name")
+		if name == "" {
+			logger.Info(logkeys.Message, "name parameter", logkeys.Error, ErrNoName)
+			api.JSONError(w, ErrNoName, http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("name", name)
+		sched := new(storage.Schedule)
+		err := json.NewDecoder(r.Body).Decode(sched)
+		if err != nil {
+			logger.Info(logkeys.Message, "decoding body", logkeys.Error, err)
+			api.JSONError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		if err = sched.Validate(); err != nil {
+			logger.Info(logkeys.Message, "validating schedule", logkeys.Error, err)
+			api.JSONError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With(logkeys.WorkflowName, sched.Workflow)
+		if !chk.WorkflowRegistered(sched.Workflow) {
+			logger.Info(logkeys.Message, "checking workflow name", logkeys.Error, ErrWorkflowNotRegistered)
+			api.JSONError(w, ErrWorkflowNotRegistered, http.StatusBadRequest)
+			return
+		} else if chk.ApprovalRequired(sched.Workflow) {
+			err := fmt.Errorf("%w: %s", engine.ErrWorkflowRequiresApproval, sched.Workflow)
+			logger.Info(logkeys.Message, "checking workflow name", logkeys.Error, err)
+			api.JSONError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		if err = api.CheckWorkflowStart(r.Context(), sched.Workflow); err != nil {
+			logger.Info(logkeys.Message, "checking workflow permission", logkeys.Error, err)
+			api.JSONError(w, err, http.StatusForbidden)
+			return
+		}
+
+		if sched.Next, err = sched.NextAfter(time.Now()); err != nil {
+			logger.Info(logkeys.Message, "next run time", logkeys.Error, err)
+			api.JSONError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		if err = store.StoreSchedule(r.Context(), name, sched); err != nil {
+			logger.Info(logkeys.Message, "storing schedule", logkeys.Error, err)
+			api.JSONError(w, err, 0)
+			return
+		}
+
+		logger.Debug(logkeys.Message, "stored schedule", "next", sched.Next)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// DeleteHandler returns an HTTP handler for deleting a schedule.
+func DeleteHandler(store storage.Storage, logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := ctxlog.Logger(r.Context(), logger)
+		name := flow.Param(r.Context(), "name")
+		if name == "" {
+			logger.Info(logkeys.Message, "name parameter", logkeys.Error, ErrNoName)
+			api.JSONError(w, ErrNoName, http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("name", name)
+		if err := store.DeleteSchedule(r.Context(), name); err != nil {
+			logger.Info(logkeys.Message, "deleting schedule", logkeys.Error, err)
+			api.JSONError(w, err, 0)
+			return
+		}
+
+		logger.Debug(logkeys.Message, "deleted schedule")
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,51 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/micromdm/nanocmd/http/api"
+	"github.com/micromdm/nanocmd/subsystem/schedule/storage/inmem"
+
+	"github.com/alexedwards/flow"
+	"github.com/micromdm/nanolib/log"
+)
+
+type testChecker struct{}
+
+func (testChecker) WorkflowRegistered(string) bool { return true }
+
+func (testChecker) ApprovalRequired(string) bool { return false }
+
+func TestPutSchedulePermission(t *testing.T) {
+	store := inmem.New()
+	mux := flow.New()
+	mux.Handle("/v1/schedule/:name", PutHandler(store, testChecker{}, log.NopLogger), "PUT")
+
+	body := `{"cron": "0 2 * * *", "workflow": "wf.a", "ids": ["AAA"]}`
+	for _, test := range []struct {
+		perms  []string
+		status int
+		stored int
+	}{
+		{[]string{api.PermSchedulesManage}, http.StatusForbidden, 0},
+		{[]string{api.PermSchedulesManage, api.PermWorkflowStart("wf.a")}, http.StatusNoContent, 1},
+	} {
+		h := api.NewPrincipalHandler(mux, "alice", test.perms...)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("PUT", "/v1/schedule/s1", strings.NewReader(body)))
+		if have, want := w.Code, test.status; have != want {
+			t.Errorf("%v: status: have: %d, want: %d", test.perms, have, want)
+		}
+		scheds, err := store.RetrieveSchedules(context.Background(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have, want := len(scheds), test.stored; have != want {
+			t.Errorf("%v: stored: have: %d, want: %d", test.perms, have, want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/micromdm/nanocmd/http/api"
+	"github.com/micromdm/nanocmd/subsystem/schedule/storage"
+	"github.com/micromdm/nanolib/log"
+)
+
+// Mux can register HTTP handlers.
+// Ostensibly this supports flow router.
+type Mux interface {
+	// Handle registers the handler for the given pattern.
+	Handle(pattern string, handler http.Handler, methods ...string)
+}
+
+// HandleAPIv1 registers the various API handlers into mux.
+// API endpoint paths are prepended with prefix.
+// Authentication or any other layered handlers are not present.
+// They are assumed to be layered with mux, possibly at the Handle call.
+// Each handler requires the API principal to have the "schedules:manage" permission.
+// If prefix is empty and these handlers are used in sub-paths then
+// handlers should have that sub-path stripped from the request.
+// The logger is adorned with a "handler" key of the endpoint name.
+func HandleAPIv1(prefix string, mux Mux, logger log.Logger, s storage.Storage, chk WorkflowNameChecker) {
+	mux.Handle(
+		prefix+"/schedule/:name",
+		api.RequirePermission(
+			GetHandler(s, logger.With("handler", "get-schedule")),
+			api.PermSchedulesManage,
+			logger,
+		),
+		"GET",
+	)
+
+	mux.Handle(
+		prefix+"/schedule/:name",
+		api.RequirePermission(
+			PutHandler(s, chk, logger.With("handler", "put-schedule")),
+			api.PermSchedulesManage,
+			logger,
+		),
+		"PUT",
+	)
+
+	mux.Handle(
+		prefix+"/schedule/:name",
+		api.RequirePermission(
+			DeleteHandler(s, logger.With("handler", "delete-schedule")),
+			api.PermSchedulesManage,
+			logger,
+		),
+		"DELETE",
+	)
+
+	mux.Handle(
+		prefix+"/schedule/:name/runs",
+		api.RequirePermission(
+			GetRunsHandler(s, logger.With("handler", "get-schedule-runs")),
+			api.PermSchedulesManage,
+			logger,
+		),
+		"GET",
+	)
+
+	mux.Handle(
+		prefix+"/schedules",
+		api.RequirePermission(
+			GetSchedulesHandler(s, logger.With("handler", "get-schedules")),
+			api.PermSchedulesManage,
+			logger,
+		),
+		"GET",
+	)
+}
@@ -0,0 +1,198 @@
+// Package scheduler starts workflows on the schedules in schedule storage.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/micromdm/nanocmd/logkeys"
+	"github.com/micromdm/nanocmd/subsystem/audit"
+	auditstorage "github.com/micromdm/nanocmd/subsystem/audit/storage"
+	groupstorage "github.com/micromdm/nanocmd/subsystem/group/storage"
+	"github.com/micromdm/nanocmd/subsystem/schedule/storage"
+	"github.com/micromdm/nanocmd/workflow"
+
+	"github.com/micromdm/nanolib/log"
+)
+
+var (
+	ErrNoGroupStorage = errors.New("no group storage")
+	ErrNoIDs          = errors.New("no enrollment IDs")
+	ErrDeadline       = errors.New("starting deadline exceeded")
+)
+
+// Auditor records audit events.
+type Auditor interface {
+	Audit(ctx context.Context, e *auditstorage.Event)
+}
+
+type WorkflowStarter interface {
+	StartWorkflow(ctx context.Context, name string, context []byte, ids []string, e *workflow.Event, mdmCtx *workflow.MDMContext) (string, error)
+}
+
+// Scheduler starts the workflows of due schedules.
+type Scheduler struct {
+	store   storage.Storage
+	starter WorkflowStarter
+	groups  groupstorage.ReadStorage
+	auditor Auditor
+	logger  log.Logger
+}
+
+type Option func(*Scheduler)
+
+func WithLogger(logger log.Logger) Option {
+	return func(s *Scheduler) {
+		s.logger = logger
+	}
+}
+
+// WithGroupStorage configures the group storage used to resolve the
+// members of schedules that target a group.
+func WithGroupStorage(groups groupstorage.ReadStorage) Option {
+	return func(s *Scheduler) {
+		s.groups = groups
+	}
+}
+
+// WithAuditor configures an auditor for recording schedule runs.
+func WithAuditor(auditor Auditor) Option {
+	return func(s *Scheduler) {
+		s.auditor = auditor
+	}
+}
+
+func New(store storage.Storage, starter WorkflowStarter, opts ...Option) *Scheduler {
+	s := &Scheduler{
+		store:   store,
+		starter: starter,
+		logger:  log.NopLogger,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// RunSchedules starts the workflows of schedules that are due at now.
+// Schedules without a next run time (e.g. newly stored) have their next
+// run time set without running. Errors for individual schedules are
+// logged and recorded in their run history.
+func (s *Scheduler) RunSchedules(ctx context.Context, now time.Time) error {
+	schedules, err := s.store.RetrieveSchedules(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("retrieving schedules: %w", err)
+	}
+	for name, sched := range schedules {
+		if sched.Disabled || sched.Next.After(now) {
+			continue
+		}
+		logger := s.logger.With("name", name, logkeys.WorkflowName, sched.Workflow)
+		if err = s.runSchedule(ctx, name, sched, now, logger); err != nil {
+			logger.Info(logkeys.Message, "running schedule", logkeys.Error, err)
+		}
+	}
+	return nil
+}
+
+func (s *Scheduler) runSchedule(ctx context.Context, name string, sched *storage.Schedule, now time.Time, logger log.Logger) error {
+	var run *storage.Run
+	if !sched.Next.IsZero() {
+		run = &storage.Run{ScheduledAt: sched.Next, RunAt: now}
+		// coalesce any missed runs into the latest due run
+		for {
+			next, err := sched.NextAfter(run.ScheduledAt)
+			if err != nil {
+				return err
+			}
+			if next.IsZero() || next.After(now) {
+				break
+			}
+			run.ScheduledAt = next
+			run.Missed++
+		}
+		ids := s.start(ctx, sched, run, now)
+		s.audit(ctx, name, sched, run, ids)
+		logger = logger.With(
+			"status", run.Status,
+			"scheduled_at", run.ScheduledAt,
+			"missed", run.Missed,
+		)
+		if run.Error != "" {
+			logger.Info(logkeys.Message, "schedule run", logkeys.InstanceID, run.InstanceID, logkeys.Error, run.Error)
+		} else {
+			logger.Debug(logkeys.Message, "schedule run", logkeys.InstanceID, run.InstanceID)
+		}
+	}
+	next, err := sched.NextAfter(now)
+	if err != nil {
+		return err
+	}
+	return s.store.StoreScheduleRun(ctx, name, run, next)
+}
+
+// start starts the workflow of sched and records the result in run.
+// The targeted enrollment IDs are returned.
+func (s *Scheduler) start(ctx context.Context, sched *storage.Schedule, run *storage.Run, now time.Time) []string {
+	if sched.StartingDeadline > 0 && now.Sub(run.ScheduledAt) > time.Duration(sched.StartingDeadline)*time.Second {
+		run.Status = storage.RunStatusSkipped
+		run.Error = ErrDeadline.Error()
+		return nil
+	}
+	ids, err := s.ids(ctx, sched)
+	if err != nil {
+		run.Status = storage.RunStatusFailed
+		run.Error = err.Error()
+		return nil
+	}
+	if len(ids) < 1 {
+		run.Status = storage.RunStatusSkipped
+		run.Error = ErrNoIDs.Error()
+		return nil
+	}
+	run.Count = len(ids)
+	run.InstanceID, err = s.starter.StartWorkflow(ctx, sched.Workflow, []byte(sched.Context), ids, nil, nil)
+	if err != nil {
+		run.Status = storage.RunStatusFailed
+		run.Error = err.Error()
+		return ids
+	}
+	run.Status = storage.RunStatusStarted
+	return ids
+}
+
+// audit records an audit event of run of the schedule name.
+func (s *Scheduler) audit(ctx context.Context, name string, sched *storage.Schedule, run *storage.Run, ids []string) {
+	if s.auditor == nil {
+		return
+	}
+	s.auditor.Audit(ctx, &auditstorage.Event{
+		Actor:        audit.ActorScheduler,
+		Action:       "schedule.start",
+		TargetIDs:    ids,
+		WorkflowName: sched.Workflow,
+		InstanceID:   run.InstanceID,
+		Params: map[string][]string{
+			"schedule": {name},
+			"status":   {run.Status},
+		},
+		Error: run.Error,
+	})
+}
+
+// ids returns the enrollment IDs targeted by sched.
+func (s *Scheduler) ids(ctx context.Context, sched *storage.Schedule) ([]string, error) {
+	if sched.Group == "" {
+		return sched.IDs, nil
+	}
+	if s.groups == nil {
+		return nil, ErrNoGroupStorage
+	}
+	ids, err := s.groups.RetrieveGroupMembers(ctx, sched.Group)
+	if err != nil {
+		return nil, fmt.Errorf("retrieving group members: %s: %w", sched.Group, err)
+	}
+	return ids, nil
+}
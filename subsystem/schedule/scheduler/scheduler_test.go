@@ -0,0 +1,117 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	groupinmem "github.com/micromdm/nanocmd/subsystem/group/storage/inmem"
+	"github.com/micromdm/nanocmd/subsystem/schedule/storage"
+	"github.com/micromdm/nanocmd/subsystem/schedule/storage/inmem"
+	"github.com/micromdm/nanocmd/workflow"
+)
+
+type starter struct {
+	ids []string
+}
+
+func (s *starter) StartWorkflow(_ context.Context, _ string, _ []byte, ids []string, _ *workflow.Event, _ *workflow.MDMContext) (string, error) {
+	s.ids = append(s.ids, ids...)
+	return "instance", nil
+}
+
+func TestRunSchedules(t *testing.T) {
+	ctx := context.Background()
+	store := inmem.New()
+	groups := groupinmem.New()
+	st := new(starter)
+	s := New(store, st, WithGroupStorage(groups))
+
+	err := groups.StoreGroupMembers(ctx, "staff", []string{"AAA111", "BBB222"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.StoreSchedule(ctx, "hourly", &storage.Schedule{
+		Cron:     "0 * * * *",
+		Workflow: "wf",
+		Group:    "staff",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.StoreSchedule(ctx, "deadline", &storage.Schedule{
+		Cron:             "0 * * * *",
+		Workflow:         "wf",
+		IDs:              []string{"CCC333"},
+		StartingDeadline: 60,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2024, time.May, 1, 12, 30, 0, 0, time.UTC)
+
+	// first run only sets the next run time
+	if err = s.RunSchedules(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+	schedules, err := store.RetrieveSchedules(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := schedules["hourly"].Next, now.Add(30*time.Minute); !have.Equal(want) {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+	if have, want := len(st.ids), 0; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	// simulate being down for three scheduled runs
+	now = now.Add(160 * time.Minute)
+	if err = s.RunSchedules(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+	if have, want := len(st.ids), 2; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	runs, err := store.RetrieveScheduleRuns(ctx, "hourly")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := len(runs), 1; have != want {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+	run := runs[0]
+	if have, want := run.Status, storage.RunStatusStarted; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+	if have, want := run.Missed, 2; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+	if have, want := run.ScheduledAt, now.Add(-10*time.Minute); !have.Equal(want) {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	// the coalesced run is past the starting deadline
+	runs, err = store.RetrieveScheduleRuns(ctx, "deadline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := len(runs), 1; have != want {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+	if have, want := runs[0].Status, storage.RunStatusSkipped; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	// not yet due
+	if err = s.RunSchedules(ctx, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if have, want := len(st.ids), 2; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+}
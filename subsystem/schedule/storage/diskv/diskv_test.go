@@ -0,0 +1,12 @@
+package diskv
+
+import (
+	"testing"
+
+	"github.com/micromdm/nanocmd/subsystem/schedule/storage"
+	"github.com/micromdm/nanocmd/subsystem/schedule/storage/test"
+)
+
+func TestDiskv(t *testing.T) {
+	test.TestScheduleStorage(t, func() storage.Storage { return New(t.TempDir()) })
+}
@@ -0,0 +1,12 @@
+package inmem
+
+import (
+	"testing"
+
+	"github.com/micromdm/nanocmd/subsystem/schedule/storage"
+	"github.com/micromdm/nanocmd/subsystem/schedule/storage/test"
+)
+
+func TestInMem(t *testing.T) {
+	test.TestScheduleStorage(t, func() storage.Storage { return New() })
+}
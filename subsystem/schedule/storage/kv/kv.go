@@ -0,0 +1,136 @@
+// Package kv implements a schedule storage backend using JSON with key-value storage.
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/micromdm/nanocmd/subsystem/schedule/storage"
+
+	"github.com/micromdm/nanolib/storage/kv"
+)
+
+// KV is a schedule storage backend using JSON with key-value storage.
+type KV struct {
+	mu        sync.Mutex
+	schedules kv.Bucket
+	runs      kv.Bucket
+}
+
+func New(schedules, runs kv.Bucket) *KV {
+	return &KV{schedules: schedules, runs: runs}
+}
+
+func (s *KV) retrieveSchedule(ctx context.Context, name string) (*storage.Schedule, error) {
+	raw, err := s.schedules.Get(ctx, name)
+	if errors.Is(err, kv.ErrKeyNotFound) {
+		return nil, fmt.Errorf("%w: %s: %v", storage.ErrScheduleNotFound, name, err)
+	} else if err != nil {
+		return nil, err
+	}
+	sched := new(storage.Schedule)
+	if err = json.Unmarshal(raw, sched); err != nil {
+		return nil, fmt.Errorf("unmarshal schedule: %s: %w", name, err)
+	}
+	return sched, nil
+}
+
+// RetrieveSchedules unmarshals the JSON stored using names and returns the schedules.
+// All schedules are returned if no names are provided.
+func (s *KV) RetrieveSchedules(ctx context.Context, names []string) (map[string]*storage.Schedule, error) {
+	if len(names) < 1 {
+		names = kv.AllKeys(ctx, s.schedules)
+	}
+	r := make(map[string]*storage.Schedule)
+	for _, name := range names {
+		sched, err := s.retrieveSchedule(ctx, name)
+		if err != nil {
+			return r, err
+		}
+		r[name] = sched
+	}
+	return r, nil
+}
+
+// RetrieveScheduleRuns unmarshals the JSON run history stored using name.
+func (s *KV) RetrieveScheduleRuns(ctx context.Context, name string) ([]*storage.Run, error) {
+	if ok, err := s.schedules.Has(ctx, name); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, fmt.Errorf("%w: %s", storage.ErrScheduleNotFound, name)
+	}
+	return s.retrieveRuns(ctx, name)
+}
+
+func (s *KV) retrieveRuns(ctx context.Context, name string) ([]*storage.Run, error) {
+	raw, err := s.runs.Get(ctx, name)
+	if errors.Is(err, kv.ErrKeyNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var runs []*storage.Run
+	if err = json.Unmarshal(raw, &runs); err != nil {
+		return nil, fmt.Errorf("unmarshal runs: %s: %w", name, err)
+	}
+	return runs, nil
+}
+
+// StoreSchedule marshals sched into JSON and stores it using name.
+func (s *KV) StoreSchedule(ctx context.Context, name string, sched *storage.Schedule) error {
+	raw, err := json.Marshal(sched)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.schedules.Set(ctx, name, raw)
+}
+
+// StoreScheduleRun appends run to the JSON run history stored using
+// name and updates the next run time of the schedule.
+func (s *KV) StoreScheduleRun(ctx context.Context, name string, run *storage.Run, next time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sched, err := s.retrieveSchedule(ctx, name)
+	if err != nil {
+		return err
+	}
+	if run != nil {
+		runs, err := s.retrieveRuns(ctx, name)
+		if err != nil {
+			return err
+		}
+		runs = append(runs, run)
+		if len(runs) > storage.MaxRuns {
+			runs = runs[len(runs)-storage.MaxRuns:]
+		}
+		raw, err := json.Marshal(runs)
+		if err != nil {
+			return err
+		}
+		if err = s.runs.Set(ctx, name, raw); err != nil {
+			return err
+		}
+	}
+	sched.Next = next
+	raw, err := json.Marshal(sched)
+	if err != nil {
+		return err
+	}
+	return s.schedules.Set(ctx, name, raw)
+}
+
+// DeleteSchedule deletes the JSON schedule and run history stored using name.
+func (s *KV) DeleteSchedule(ctx context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.schedules.Delete(ctx, name); err != nil {
+		return err
+	}
+	return s.runs.Delete(ctx, name)
+}
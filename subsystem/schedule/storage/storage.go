@@ -0,0 +1,137 @@
+// Package storage defines types and interfaces supporting scheduled workflow runs.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/micromdm/nanocmd/utils/cron"
+)
+
+var (
+	ErrScheduleNotFound = errors.New("schedule not found")
+	ErrNoWorkflow       = errors.New("no workflow name")
+	ErrNoTargets        = errors.New("exactly one of ids or group required")
+	ErrInvalidDeadline  = errors.New("invalid starting deadline")
+)
+
+// MaxRuns is the number of runs kept in a schedule's run history.
+const MaxRuns = 50
+
+// Schedule starts a workflow on a cron schedule.
+// If runs are missed (e.g. because the server was down) then the missed
+// runs are coalesced into a single run at the next opportunity. If
+// StartingDeadline is set and that single run is later than
+// StartingDeadline seconds after its scheduled time then the run is
+// skipped instead.
+type Schedule struct {
+	// Cron is a five-field cron expression (e.g. "0 2 * * *").
+	Cron string `json:"cron"`
+	// Location is the IANA time zone of Cron. Defaults to UTC.
+	Location string `json:"location,omitempty"`
+
+	Workflow string `json:"workflow"`
+	Context  string `json:"context,omitempty"`
+
+	// IDs are the enrollment IDs to start the workflow for.
+	IDs []string `json:"ids,omitempty"`
+	// Group is the name of an enrollment group to start the workflow for.
+	// The group members are resolved at run time.
+	Group string `json:"group,omitempty"`
+
+	// StartingDeadline is the number of seconds after the scheduled
+	// time that a run may still be started. Zero means no deadline.
+	StartingDeadline int  `json:"starting_deadline,omitempty"`
+	Disabled         bool `json:"disabled,omitempty"`
+
+	// Next is the time of the next run. It is maintained by the
+	// scheduler and is not user-settable.
+	Next time.Time `json:"next,omitempty"`
+}
+
+// Validate checks s for errors.
+func (s *Schedule) Validate() error {
+	if s == nil {
+		return errors.New("nil schedule")
+	}
+	if _, err := cron.Parse(s.Cron); err != nil {
+		return err
+	}
+	if _, err := time.LoadLocation(s.Location); err != nil {
+		return fmt.Errorf("loading location: %w", err)
+	}
+	if s.Workflow == "" {
+		return ErrNoWorkflow
+	}
+	if (len(s.IDs) < 1) == (s.Group == "") {
+		return ErrNoTargets
+	}
+	if s.StartingDeadline < 0 {
+		return ErrInvalidDeadline
+	}
+	return nil
+}
+
+// NextAfter returns the time of the first run of s after t.
+// The zero time is returned if s never runs.
+func (s *Schedule) NextAfter(t time.Time) (time.Time, error) {
+	c, err := cron.Parse(s.Cron)
+	if err != nil {
+		return time.Time{}, err
+	}
+	loc, err := time.LoadLocation(s.Location)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("loading location: %w", err)
+	}
+	return c.Next(t.In(loc)), nil
+}
+
+const (
+	RunStatusStarted = "started"
+	RunStatusSkipped = "skipped"
+	RunStatusFailed  = "failed"
+)
+
+// Run is an entry in a schedule's run history.
+type Run struct {
+	// ScheduledAt is the scheduled time of the run.
+	ScheduledAt time.Time `json:"scheduled_at"`
+	// RunAt is the time the run was processed.
+	RunAt time.Time `json:"run_at"`
+
+	Status     string `json:"status"`
+	InstanceID string `json:"instance_id,omitempty"`
+	// Count is the number of enrollment IDs the workflow was started for.
+	Count int    `json:"count,omitempty"`
+	Error string `json:"error,omitempty"`
+	// Missed is the number of earlier scheduled runs that were
+	// coalesced into this run.
+	Missed int `json:"missed,omitempty"`
+}
+
+type ReadStorage interface {
+	// RetrieveSchedules returns the schedules by name.
+	// All schedules are returned if no names are provided.
+	// ErrScheduleNotFound is returned for any name that hasn't been stored.
+	RetrieveSchedules(ctx context.Context, names []string) (map[string]*Schedule, error)
+
+	// RetrieveScheduleRuns returns the run history of the named schedule, oldest first.
+	RetrieveScheduleRuns(ctx context.Context, name string) ([]*Run, error)
+}
+
+type Storage interface {
+	ReadStorage
+
+	// StoreSchedule stores (replaces) the named schedule.
+	StoreSchedule(ctx context.Context, name string, s *Schedule) error
+
+	// StoreScheduleRun appends run (if not nil) to the run history of
+	// the named schedule and updates the time of its next run.
+	// Only the latest MaxRuns runs are kept.
+	StoreScheduleRun(ctx context.Context, name string, run *Run, next time.Time) error
+
+	// DeleteSchedule deletes the named schedule and its run history.
+	DeleteSchedule(ctx context.Context, name string) error
+}
@@ -0,0 +1,118 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/micromdm/nanocmd/subsystem/schedule/storage"
+)
+
+func TestScheduleStorage(t *testing.T, newStorage func() storage.Storage) {
+	s := newStorage()
+	ctx := context.Background()
+
+	sched := &storage.Schedule{
+		Cron:     "0 2 * * *",
+		Workflow: "io.micromdm.wf.devinfolog.v1",
+		Group:    "staff",
+	}
+
+	err := s.StoreSchedule(ctx, "nightly", sched)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.StoreSchedule(ctx, "hourly", &storage.Schedule{
+		Cron:     "@hourly",
+		Workflow: "io.micromdm.wf.devinfolog.v1",
+		IDs:      []string{"AAA111"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schedules, err := s.RetrieveSchedules(ctx, []string{"nightly"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := schedules["nightly"], sched; !reflect.DeepEqual(have, want) {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	schedules, err = s.RetrieveSchedules(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := len(schedules), 2; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	runs, err := s.RetrieveScheduleRuns(ctx, "nightly")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := len(runs), 0; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	next := time.Date(2024, time.May, 2, 2, 0, 0, 0, time.UTC)
+	for i := 0; i < storage.MaxRuns+2; i++ {
+		err = s.StoreScheduleRun(ctx, "nightly", &storage.Run{
+			ScheduledAt: next.AddDate(0, 0, i-1),
+			Status:      storage.RunStatusStarted,
+			Missed:      i,
+		}, next.AddDate(0, 0, i))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	runs, err = s.RetrieveScheduleRuns(ctx, "nightly")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := len(runs), storage.MaxRuns; have != want {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+	// the oldest runs should have been dropped
+	if have, want := runs[0].Missed, 2; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	// a nil run only updates the next run time
+	err = s.StoreScheduleRun(ctx, "nightly", nil, next)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schedules, err = s.RetrieveSchedules(ctx, []string{"nightly"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := schedules["nightly"].Next, next; !have.Equal(want) {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	err = s.DeleteSchedule(ctx, "nightly")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.RetrieveSchedules(ctx, []string{"nightly"})
+	if !errors.Is(err, storage.ErrScheduleNotFound) {
+		t.Errorf("expected ErrScheduleNotFound, have: %v", err)
+	}
+
+	_, err = s.RetrieveScheduleRuns(ctx, "nightly")
+	if !errors.Is(err, storage.ErrScheduleNotFound) {
+		t.Errorf("expected ErrScheduleNotFound, have: %v", err)
+	}
+
+	err = s.StoreScheduleRun(ctx, "nightly", nil, next)
+	if !errors.Is(err, storage.ErrScheduleNotFound) {
+		t.Errorf("expected ErrScheduleNotFound, have: %v", err)
+	}
+}
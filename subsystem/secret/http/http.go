@@ -0,0 +1,185 @@
+// Package http contains HTTP handlers for retrieving escrowed secrets.
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/micromdm/nanocmd/http/api"
+	"github.com/micromdm/nanocmd/logkeys"
+	"github.com/micromdm/nanocmd/subsystem/audit"
+	audithttp "github.com/micromdm/nanocmd/subsystem/audit/http"
+	auditstorage "github.com/micromdm/nanocmd/subsystem/audit/storage"
+	invstorage "github.com/micromdm/nanocmd/subsystem/inventory/storage"
+	"github.com/micromdm/nanocmd/subsystem/secret"
+	"github.com/micromdm/nanocmd/subsystem/secret/storage"
+	"github.com/micromdm/nanocmd/workflow"
+	"github.com/micromdm/nanocmd/workflow/fvrotate"
+
+	"github.com/alexedwards/flow"
+	"github.com/micromdm/nanolib/log"
+	"github.com/micromdm/nanolib/log/ctxlog"
+)
+
+var (
+	ErrNoID     = errors.New("no enrollment ID provided")
+	ErrNoReason = errors.New("no reason provided")
+)
+
+// PRKRetriever retrieves the escrowed FileVault PRK of an enrollment.
+type PRKRetriever interface {
+	RetrievePRK(ctx context.Context, id string) (string, error)
+}
+
+// SecretRetriever retrieves secrets encrypted at rest.
+type SecretRetriever interface {
+	RetrieveSecret(ctx context.Context, id, kind string) (string, error)
+}
+
+// WorkflowStarter starts workflows.
+type WorkflowStarter interface {
+	StartWorkflow(ctx context.Context, name string, context []byte, ids []string, e *workflow.Event, mdmCtx *workflow.MDMContext) (string, error)
+}
+
+// secretParams returns the enrollment ID path parameter and the reason
+// query parameter of r. The enrollment ID is added to the audit event.
+func secretParams(r *http.Request) (string, string, error) {
+	id := flow.Param(r.Context(), "id")
+	if id == "" {
+		return "", "", ErrNoID
+	}
+	audit.Annotate(r.Context(), func(e *auditstorage.Event) { e.TargetIDs = []string{id} })
+	reason := r.URL.Query().Get("reason")
+	if reason == "" {
+		return id, "", ErrNoReason
+	}
+	return id, reason, nil
+}
+
+// secretErrorStatus returns the HTTP status code of a secret retrieval error.
+func secretErrorStatus(err error) int {
+	if errors.Is(err, storage.ErrSecretNotFound) {
+		return http.StatusNotFound
+	}
+	return 0
+}
+
+// writeSecret encodes v as JSON to w and prevents caching.
+func writeSecret(w http.ResponseWriter, logger log.Logger, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Info(logkeys.Message, "encoding json to body", logkeys.Error, err)
+	}
+}
+
+// GetPRKHandler returns an HTTP handler that retrieves the escrowed
+// FileVault PRK of the enrollment ID in the path. The "reason" query
+// parameter is required and is intended to be audited. If rotator is
+// not nil then the FileVault rotate workflow is started for the
+// enrollment after the PRK is retrieved so that the viewed PRK is
+// replaced. A failure to start the rotation is reported in the response.
+// The start of the rotation is recorded with auditor, if not nil, as an
+// action of the engine.
+func GetPRKHandler(prks PRKRetriever, rotator WorkflowStarter, auditor audithttp.Auditor, logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := ctxlog.Logger(r.Context(), logger)
+		id, reason, err := secretParams(r)
+		if err != nil {
+			logger.Info(logkeys.Message, "parameters", logkeys.Error, err)
+			api.JSONError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With(logkeys.EnrollmentID, id, "reason", reason)
+		prk, err := prks.RetrievePRK(r.Context(), id)
+		if err != nil {
+			logger.Info(logkeys.Message, "retrieving PRK", logkeys.Error, err)
+			api.JSONError(w, err, secretErrorStatus(err))
+			return
+		}
+		logger.Info(logkeys.Message, "retrieved PRK")
+
+		resp := &struct {
+			PRK                string `json:"prk"`
+			RotationInstanceID string `json:"rotation_instance_id,omitempty"`
+			RotationError      string `json:"rotation_error,omitempty"`
+		}{PRK: prk}
+		if rotator != nil {
+			resp.RotationInstanceID, err = rotator.StartWorkflow(r.Context(), fvrotate.WorkflowName, nil, []string{id}, nil, nil)
+			if err != nil {
+				logger.Info(logkeys.Message, "starting PRK rotation", logkeys.Error, err)
+				resp.RotationError = err.Error()
+			} else {
+				logger.Debug(logkeys.Message, "started PRK rotation", logkeys.InstanceID, resp.RotationInstanceID)
+			}
+			audit.Annotate(r.Context(), func(e *auditstorage.Event) {
+				e.WorkflowName = fvrotate.WorkflowName
+				e.InstanceID = resp.RotationInstanceID
+				e.Error = resp.RotationError
+			})
+			if auditor != nil {
+				auditor.Audit(r.Context(), &auditstorage.Event{
+					Actor:        audit.ActorEngine,
+					Action:       "filevault_prk.rotate",
+					TargetIDs:    []string{id},
+					WorkflowName: fvrotate.WorkflowName,
+					InstanceID:   resp.RotationInstanceID,
+					Error:        resp.RotationError,
+					Params: map[string][]string{
+						"principal": {api.Principal(r.Context())},
+						"reason":    {reason},
+					},
+				})
+			}
+		}
+		writeSecret(w, logger, resp)
+	}
+}
+
+// GetLockPINHandler returns an HTTP handler that retrieves the escrowed
+// DeviceLock PIN of the lock workflow for the enrollment ID in the path.
+// The "reason" query parameter is required and is intended to be audited.
+// If inv is not nil then PINs stored in plaintext in inventory by
+// earlier versions are returned if no encrypted PIN exists.
+func GetLockPINHandler(secrets SecretRetriever, inv invstorage.ReadStorage, logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := ctxlog.Logger(r.Context(), logger)
+		id, reason, err := secretParams(r)
+		if err != nil {
+			logger.Info(logkeys.Message, "parameters", logkeys.Error, err)
+			api.JSONError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With(logkeys.EnrollmentID, id, "reason", reason)
+		pin, err := secrets.RetrieveSecret(r.Context(), id, secret.KindLockPIN)
+		if errors.Is(err, storage.ErrSecretNotFound) && inv != nil {
+			if legacyPIN, ok := retrieveLegacyPIN(r.Context(), inv, id); ok {
+				pin, err = legacyPIN, nil
+			}
+		}
+		if err != nil {
+			logger.Info(logkeys.Message, "retrieving lock PIN", logkeys.Error, err)
+			api.JSONError(w, err, secretErrorStatus(err))
+			return
+		}
+		logger.Info(logkeys.Message, "retrieved lock PIN")
+
+		writeSecret(w, logger, &struct {
+			PIN string `json:"pin"`
+		}{PIN: pin})
+	}
+}
+
+// retrieveLegacyPIN retrieves the plaintext lock PIN of id from inv.
+func retrieveLegacyPIN(ctx context.Context, inv invstorage.ReadStorage, id string) (string, bool) {
+	idVals, err := inv.RetrieveInventory(ctx, &invstorage.SearchOptions{IDs: []string{id}})
+	if err != nil || idVals == nil {
+		return "", false
+	}
+	pin, ok := idVals[id][invstorage.KeyLockPIN].(string)
+	return pin, ok && pin != ""
+}
@@ -0,0 +1,150 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/micromdm/nanocmd/http/api"
+	"github.com/micromdm/nanocmd/subsystem/audit"
+	auditstorage "github.com/micromdm/nanocmd/subsystem/audit/storage"
+	auditinmem "github.com/micromdm/nanocmd/subsystem/audit/storage/inmem"
+	"github.com/micromdm/nanocmd/subsystem/secret"
+	"github.com/micromdm/nanocmd/subsystem/secret/storage"
+	"github.com/micromdm/nanocmd/subsystem/secret/storage/inmem"
+	"github.com/micromdm/nanocmd/workflow"
+	"github.com/micromdm/nanocmd/workflow/fvrotate"
+
+	"github.com/alexedwards/flow"
+	"github.com/micromdm/nanolib/log"
+)
+
+type testPRKs map[string]string
+
+func (p testPRKs) RetrievePRK(_ context.Context, id string) (string, error) {
+	prk, ok := p[id]
+	if !ok {
+		return "", storage.ErrSecretNotFound
+	}
+	return prk, nil
+}
+
+type testStarter struct {
+	name string
+	ids  []string
+}
+
+func (s *testStarter) StartWorkflow(_ context.Context, name string, _ []byte, ids []string, _ *workflow.Event, _ *workflow.MDMContext) (string, error) {
+	s.name = name
+	s.ids = ids
+	return "inst1", nil
+}
+
+func TestSecretHandlers(t *testing.T) {
+	ctx := context.Background()
+
+	kek, err := secret.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyring, err := secret.NewKeyring(kek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secrets := secret.New(inmem.New(), keyring)
+	if err = secrets.StoreSecret(ctx, "AAA", secret.KindLockPIN, "123456"); err != nil {
+		t.Fatal(err)
+	}
+
+	auditStore := auditinmem.New()
+	starter := new(testStarter)
+	mux := flow.New()
+	HandleAPIv1("/v1", mux, log.NopLogger, testPRKs{"AAA": "PRK-123"}, secrets, nil, audit.New(auditStore), starter)
+
+	for _, test := range []struct {
+		target string
+		perms  []string
+		status int
+	}{
+		{"/v1/filevault/AAA/prk?reason=ticket+1", []string{api.PermFileVaultPRKRead}, http.StatusOK},
+		{"/v1/filevault/AAA/prk", []string{api.PermFileVaultPRKRead}, http.StatusBadRequest},
+		{"/v1/filevault/BBB/prk?reason=ticket+2", []string{api.PermFileVaultPRKRead}, http.StatusNotFound},
+		{"/v1/lock/AAA/pin?reason=ticket+3", []string{api.PermFileVaultPRKRead}, http.StatusForbidden},
+		{"/v1/lock/AAA/pin?reason=ticket+4", []string{api.PermLockPINRead}, http.StatusOK},
+	} {
+		h := api.NewPrincipalHandler(mux, "alice", test.perms...)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", test.target, nil))
+		if want, have := test.status, w.Code; have != want {
+			t.Errorf("%s: status: have: %d, want: %d", test.target, have, want)
+		}
+		if w.Code != http.StatusOK {
+			continue
+		}
+		if want, have := "no-store", w.Header().Get("Cache-Control"); have != want {
+			t.Errorf("%s: cache-control: have: %q, want: %q", test.target, have, want)
+		}
+		resp := make(map[string]string)
+		if err = json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp["prk"] != "PRK-123" && resp["pin"] != "123456" {
+			t.Errorf("%s: unexpected response: %v", test.target, resp)
+		}
+	}
+
+	if want, have := fvrotate.WorkflowName, starter.name; have != want {
+		t.Errorf("rotation workflow: have: %q, want: %q", have, want)
+	}
+	if len(starter.ids) != 1 || starter.ids[0] != "AAA" {
+		t.Errorf("rotation ids: have: %v, want: %v", starter.ids, []string{"AAA"})
+	}
+
+	events, err := auditStore.RetrieveEvents(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 6, len(events); have != want {
+		t.Fatalf("events: have: %d, want: %d", have, want)
+	}
+
+	// events are most recent first
+	e := events[4]
+	if want, have := "filevault_prk.rotate", e.Action; have != want {
+		t.Errorf("action: have: %q, want: %q", have, want)
+	}
+	if want, have := audit.ActorEngine, e.Actor; have != want {
+		t.Errorf("actor: have: %q, want: %q", have, want)
+	}
+	if want, have := "inst1", e.InstanceID; have != want {
+		t.Errorf("instance id: have: %q, want: %q", have, want)
+	}
+
+	e = events[5]
+	if want, have := "GET /v1/filevault/AAA/prk", e.Action; have != want {
+		t.Errorf("action: have: %q, want: %q", have, want)
+	}
+	if want, have := "alice", e.Actor; have != want {
+		t.Errorf("actor: have: %q, want: %q", have, want)
+	}
+	if len(e.TargetIDs) != 1 || e.TargetIDs[0] != "AAA" {
+		t.Errorf("target ids: have: %v, want: %v", e.TargetIDs, []string{"AAA"})
+	}
+	if want, have := "ticket 1", strings.Join(e.Params["reason"], ","); have != want {
+		t.Errorf("reason: have: %q, want: %q", have, want)
+	}
+	if want, have := "inst1", e.InstanceID; have != want {
+		t.Errorf("instance id: have: %q, want: %q", have, want)
+	}
+
+	e = events[1]
+	if want, have := auditstorage.OutcomeFailure, e.Outcome; have != want {
+		t.Errorf("outcome: have: %q, want: %q", have, want)
+	}
+	if want, have := http.StatusForbidden, e.Status; have != want {
+		t.Errorf("status: have: %d, want: %d", have, want)
+	}
+}
@@ -0,0 +1,56 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/micromdm/nanocmd/http/api"
+	audithttp "github.com/micromdm/nanocmd/subsystem/audit/http"
+	invstorage "github.com/micromdm/nanocmd/subsystem/inventory/storage"
+	"github.com/micromdm/nanolib/log"
+)
+
+// Mux can register HTTP handlers.
+// Ostensibly this supports flow router.
+type Mux interface {
+	// Handle registers the handler for the given pattern.
+	Handle(pattern string, handler http.Handler, methods ...string)
+}
+
+// HandleAPIv1 registers the various API handlers into mux.
+// API endpoint paths are prepended with prefix.
+// Authentication or any other layered handlers are not present.
+// They are assumed to be layered with mux, possibly at the Handle call.
+// The PRK handler requires the API principal to have the "filevault:prk:read"
+// permission and the lock PIN handler the "lock:pin:read" permission.
+// Every request, including denied requests, is recorded with auditor.
+// If rotator is not nil then viewed PRKs are rotated (see GetPRKHandler).
+// If prefix is empty and these handlers are used in sub-paths then
+// handlers should have that sub-path stripped from the request.
+// The logger is adorned with a "handler" key of the endpoint name.
+func HandleAPIv1(prefix string, mux Mux, logger log.Logger, prks PRKRetriever, secrets SecretRetriever, inv invstorage.ReadStorage, auditor audithttp.Auditor, rotator WorkflowStarter) {
+	mux.Handle(
+		prefix+"/filevault/:id/prk",
+		audithttp.NewRequestAuditHandler(
+			api.RequirePermission(
+				GetPRKHandler(prks, rotator, auditor, logger.With("handler", "get-filevault-prk")),
+				api.PermFileVaultPRKRead,
+				logger,
+			),
+			auditor,
+		),
+		"GET",
+	)
+
+	mux.Handle(
+		prefix+"/lock/:id/pin",
+		audithttp.NewRequestAuditHandler(
+			api.RequirePermission(
+				GetLockPINHandler(secrets, inv, logger.With("handler", "get-lock-pin")),
+				api.PermLockPINRead,
+				logger,
+			),
+			auditor,
+		),
+		"GET",
+	)
+}
@@ -0,0 +1,61 @@
+package secret
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	invstorage "github.com/micromdm/nanocmd/subsystem/inventory/storage"
+	"github.com/micromdm/nanocmd/subsystem/secret/storage"
+)
+
+// inventoryKinds maps the inventory keys of plaintext secrets stored
+// by earlier versions to their secret kinds.
+var inventoryKinds = map[string]string{
+	invstorage.KeyPRK:     KindPRK,
+	invstorage.KeyLockPIN: KindLockPIN,
+}
+
+// InventoryStorage finds and removes plaintext secrets in inventory.
+type InventoryStorage interface {
+	invstorage.KeysSearcher
+	StoreInventoryValues(ctx context.Context, id string, values invstorage.Values) error
+}
+
+// MigrateInventory encrypts the plaintext secrets stored in inventory
+// by earlier versions and removes them from inventory. Secrets that
+// were already encrypted are not replaced. It returns the number of
+// migrated secrets.
+func (s *Secrets) MigrateInventory(ctx context.Context, inv InventoryStorage) (int, error) {
+	keys := make([]string, 0, len(inventoryKinds))
+	for key := range inventoryKinds {
+		keys = append(keys, key)
+	}
+	idVals, err := inv.RetrieveInventoryKeys(ctx, keys)
+	if err != nil {
+		return 0, fmt.Errorf("retrieving inventory: %w", err)
+	}
+	var count int
+	for id, values := range idVals {
+		remove := make(invstorage.Values)
+		for key, v := range values {
+			kind := inventoryKinds[key]
+			if plaintext, ok := v.(string); ok && plaintext != "" {
+				_, err = s.RetrieveSecret(ctx, id, kind)
+				if errors.Is(err, storage.ErrSecretNotFound) {
+					if err = s.StoreSecret(ctx, id, kind, plaintext); err != nil {
+						return count, fmt.Errorf("storing secret: %s: %s: %w", kind, id, err)
+					}
+					count++
+				} else if err != nil {
+					return count, fmt.Errorf("retrieving secret: %s: %s: %w", kind, id, err)
+				}
+			}
+			remove[key] = nil
+		}
+		if err = inv.StoreInventoryValues(ctx, id, remove); err != nil {
+			return count, fmt.Errorf("removing plaintext secrets: %s: %w", id, err)
+		}
+	}
+	return count, nil
+}
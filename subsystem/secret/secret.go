@@ -0,0 +1,296 @@
+// Package secret encrypts secrets (such as FileVault PRKs) at rest.
+//
+// Each secret is encrypted with its own random data encryption key
+// (DEK) using AES-256-GCM. The DEK is then encrypted (wrapped) by a
+// key encryption key (KEK), also using AES-256-GCM. Rotating the KEK
+// only requires re-wrapping the DEKs of the stored secrets.
+package secret
+
+import (
+	"bufio"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/micromdm/nanocmd/subsystem/secret/storage"
+)
+
+// Kinds of secrets.
+const (
+	KindPRK     = "filevault_prk"
+	KindLockPIN = "lock_pin"
+)
+
+// KeySize is the size in bytes of KEKs and DEKs.
+const KeySize = 32
+
+var (
+	ErrNoKeys        = errors.New("no keys")
+	ErrInvalidKey    = errors.New("invalid key size")
+	ErrUnknownKEK    = errors.New("unknown kek")
+	ErrInvalidSealed = errors.New("invalid sealed secret")
+)
+
+// Keyring holds the KEKs for sealing and unsealing secrets.
+type Keyring struct {
+	currentID string
+	keks      map[string]cipher.AEAD
+}
+
+// KEKID returns the identifier of kek.
+// It is the hex-encoded first 8 bytes of the SHA-256 hash of kek.
+func KEKID(kek []byte) string {
+	h := sha256.Sum256(kek)
+	return hex.EncodeToString(h[:8])
+}
+
+// newAEAD creates an AES-256-GCM AEAD using key.
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidKey, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// NewKeyring creates a new keyring of keks.
+// The first KEK is the current KEK used to seal secrets. All KEKs can
+// unseal secrets: previous KEKs should be kept until all secrets have
+// been re-wrapped with the current KEK (see Rewrap).
+func NewKeyring(keks ...[]byte) (*Keyring, error) {
+	if len(keks) < 1 {
+		return nil, ErrNoKeys
+	}
+	k := &Keyring{
+		currentID: KEKID(keks[0]),
+		keks:      make(map[string]cipher.AEAD),
+	}
+	for _, kek := range keks {
+		aead, err := newAEAD(kek)
+		if err != nil {
+			return nil, err
+		}
+		k.keks[KEKID(kek)] = aead
+	}
+	return k, nil
+}
+
+// CurrentID returns the identifier of the current KEK.
+func (k *Keyring) CurrentID() string {
+	return k.currentID
+}
+
+// GenerateKey generates a new random KEK or DEK.
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	_, err := io.ReadFull(rand.Reader, key)
+	return key, err
+}
+
+// ParseKeys parses hex-encoded KEKs from r, one per line.
+// Empty lines and lines starting with "#" are ignored.
+func ParseKeys(r io.Reader) ([][]byte, error) {
+	var keks [][]byte
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		kek, err := hex.DecodeString(line)
+		if err != nil {
+			return nil, fmt.Errorf("decoding key %d: %w", len(keks)+1, err)
+		}
+		if len(kek) != KeySize {
+			return nil, fmt.Errorf("key %d: %w: %d", len(keks)+1, ErrInvalidKey, len(kek))
+		}
+		keks = append(keks, kek)
+	}
+	return keks, scanner.Err()
+}
+
+// ReadKeyFile parses hex-encoded KEKs from the file at path.
+// See ParseKeys.
+func ReadKeyFile(path string) ([][]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseKeys(f)
+}
+
+// seal encrypts plaintext with aead using a random nonce.
+// The nonce is prepended to the returned ciphertext.
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// open decrypts ciphertext sealed by seal.
+func open(aead cipher.AEAD, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, ErrInvalidSealed
+	}
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
+
+// additionalData binds sealed secrets to their reference.
+// This prevents e.g. swapping the sealed secrets of enrollments.
+func additionalData(ref storage.Ref) []byte {
+	return []byte(ref.Kind + "\x00" + ref.ID)
+}
+
+// Seal encrypts secret of ref with a new DEK wrapped by the current KEK.
+func (k *Keyring) Seal(ref storage.Ref, secret []byte) (*storage.Sealed, error) {
+	dek, err := GenerateKey()
+	if err != nil {
+		return nil, fmt.Errorf("generating dek: %w", err)
+	}
+	aead, err := newAEAD(dek)
+	if err != nil {
+		return nil, err
+	}
+	ad := additionalData(ref)
+	sealed := &storage.Sealed{KEKID: k.currentID}
+	if sealed.Ciphertext, err = seal(aead, secret, ad); err != nil {
+		return nil, fmt.Errorf("encrypting secret: %w", err)
+	}
+	if sealed.WrappedKey, err = seal(k.keks[k.currentID], dek, ad); err != nil {
+		return nil, fmt.Errorf("wrapping dek: %w", err)
+	}
+	return sealed, nil
+}
+
+// unwrap decrypts the DEK of sealed.
+func (k *Keyring) unwrap(ref storage.Ref, sealed *storage.Sealed) ([]byte, error) {
+	if sealed == nil {
+		return nil, ErrInvalidSealed
+	}
+	kek, ok := k.keks[sealed.KEKID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKEK, sealed.KEKID)
+	}
+	dek, err := open(kek, sealed.WrappedKey, additionalData(ref))
+	if err != nil {
+		return nil, fmt.Errorf("unwrapping dek: %w", err)
+	}
+	return dek, nil
+}
+
+// Open decrypts the sealed secret of ref.
+func (k *Keyring) Open(ref storage.Ref, sealed *storage.Sealed) ([]byte, error) {
+	dek, err := k.unwrap(ref, sealed)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(dek)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := open(aead, sealed.Ciphertext, additionalData(ref))
+	if err != nil {
+		return nil, fmt.Errorf("decrypting secret: %w", err)
+	}
+	return secret, nil
+}
+
+// Rewrap re-wraps the DEK of sealed with the current KEK.
+// The secret itself is not re-encrypted.
+func (k *Keyring) Rewrap(ref storage.Ref, sealed *storage.Sealed) (*storage.Sealed, error) {
+	dek, err := k.unwrap(ref, sealed)
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := seal(k.keks[k.currentID], dek, additionalData(ref))
+	if err != nil {
+		return nil, fmt.Errorf("wrapping dek: %w", err)
+	}
+	return &storage.Sealed{
+		KEKID:      k.currentID,
+		WrappedKey: wrapped,
+		Ciphertext: sealed.Ciphertext,
+	}, nil
+}
+
+// Secrets stores and retrieves secrets of enrollments encrypted at rest.
+type Secrets struct {
+	store   storage.Storage
+	keyring *Keyring
+}
+
+// New creates a new secret store that seals secrets in store using keyring.
+func New(store storage.Storage, keyring *Keyring) *Secrets {
+	return &Secrets{store: store, keyring: keyring}
+}
+
+// StoreSecret encrypts and stores the secret of kind for enrollment id.
+func (s *Secrets) StoreSecret(ctx context.Context, id, kind, secret string) error {
+	ref := storage.Ref{ID: id, Kind: kind}
+	if err := ref.Validate(); err != nil {
+		return err
+	}
+	sealed, err := s.keyring.Seal(ref, []byte(secret))
+	if err != nil {
+		return err
+	}
+	return s.store.StoreSealedSecret(ctx, ref, sealed)
+}
+
+// RetrieveSecret retrieves and decrypts the secret of kind for enrollment id.
+// An error wrapping storage.ErrSecretNotFound is returned if the secret does not exist.
+func (s *Secrets) RetrieveSecret(ctx context.Context, id, kind string) (string, error) {
+	ref := storage.Ref{ID: id, Kind: kind}
+	sealed, err := s.store.RetrieveSealedSecret(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	secret, err := s.keyring.Open(ref, sealed)
+	if err != nil {
+		return "", err
+	}
+	return string(secret), nil
+}
+
+// Rewrap re-wraps the DEKs of all stored secrets not wrapped by the
+// current KEK. It returns the number of re-wrapped secrets. Afterwards
+// previous KEKs are no longer needed and can be removed.
+func (s *Secrets) Rewrap(ctx context.Context) (int, error) {
+	refs, err := s.store.RetrieveSecretRefs(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("retrieving secret refs: %w", err)
+	}
+	var count int
+	for _, ref := range refs {
+		sealed, err := s.store.RetrieveSealedSecret(ctx, ref)
+		if err != nil {
+			return count, fmt.Errorf("retrieving secret: %s: %s: %w", ref.Kind, ref.ID, err)
+		}
+		if sealed.KEKID == s.keyring.CurrentID() {
+			continue
+		}
+		if sealed, err = s.keyring.Rewrap(ref, sealed); err != nil {
+			return count, fmt.Errorf("rewrapping secret: %s: %s: %w", ref.Kind, ref.ID, err)
+		}
+		if err = s.store.StoreSealedSecret(ctx, ref, sealed); err != nil {
+			return count, fmt.Errorf("storing secret: %s: %s: %w", ref.Kind, ref.ID, err)
+		}
+		count++
+	}
+	return count, nil
+}
@@ -0,0 +1,232 @@
+package secret
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	invstorage "github.com/micromdm/nanocmd/subsystem/inventory/storage"
+	invinmem "github.com/micromdm/nanocmd/subsystem/inventory/storage/inmem"
+	"github.com/micromdm/nanocmd/subsystem/secret/storage"
+	"github.com/micromdm/nanocmd/subsystem/secret/storage/inmem"
+)
+
+func TestSecrets(t *testing.T) {
+	ctx := context.Background()
+	store := inmem.New()
+
+	kek1, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyring, err := NewKeyring(kek1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New(store, keyring)
+
+	if err = s.StoreSecret(ctx, "AAA", KindPRK, "PRK-123"); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.StoreSecret(ctx, "BBB", KindLockPIN, "123456"); err != nil {
+		t.Fatal(err)
+	}
+
+	prk, err := s.RetrieveSecret(ctx, "AAA", KindPRK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := prk, "PRK-123"; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	_, err = s.RetrieveSecret(ctx, "BBB", KindPRK)
+	if !errors.Is(err, storage.ErrSecretNotFound) {
+		t.Errorf("expected error: %v, got: %v", storage.ErrSecretNotFound, err)
+	}
+
+	// secrets are not stored in plaintext
+	sealed, err := store.RetrieveSealedSecret(ctx, storage.Ref{ID: "AAA", Kind: KindPRK})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(sealed.Ciphertext), "PRK-123") {
+		t.Error("secret stored in plaintext")
+	}
+
+	// sealed secrets are bound to their enrollment
+	if err = store.StoreSealedSecret(ctx, storage.Ref{ID: "CCC", Kind: KindPRK}, sealed); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.RetrieveSecret(ctx, "CCC", KindPRK); err == nil {
+		t.Error("expected error opening swapped secret")
+	}
+
+	// rotate the kek
+	kek2, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keyring, err = NewKeyring(kek2, kek1); err != nil {
+		t.Fatal(err)
+	}
+	s = New(store, keyring)
+
+	pin, err := s.RetrieveSecret(ctx, "BBB", KindLockPIN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := pin, "123456"; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	// the swapped secret fails to re-wrap
+	if _, err = s.Rewrap(ctx); err == nil {
+		t.Error("expected error re-wrapping swapped secret")
+	}
+	if err = s.StoreSecret(ctx, "CCC", KindPRK, "PRK-456"); err != nil {
+		t.Fatal(err)
+	}
+
+	// secrets may have been re-wrapped before the failure above
+	if _, err = s.Rewrap(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, ref := range []storage.Ref{{ID: "AAA", Kind: KindPRK}, {ID: "BBB", Kind: KindLockPIN}} {
+		sealed, err = store.RetrieveSealedSecret(ctx, ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have, want := sealed.KEKID, KEKID(kek2); have != want {
+			t.Errorf("kek id: have: %v, want: %v", have, want)
+		}
+	}
+	if count, err := s.Rewrap(ctx); err != nil {
+		t.Fatal(err)
+	} else if count != 0 {
+		t.Errorf("expected no re-wrapped secrets, got: %d", count)
+	}
+
+	// the previous kek is no longer needed
+	if keyring, err = NewKeyring(kek2); err != nil {
+		t.Fatal(err)
+	}
+	s = New(store, keyring)
+	for id, want := range map[string]string{"AAA": "PRK-123", "CCC": "PRK-456"} {
+		prk, err = s.RetrieveSecret(ctx, id, KindPRK)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if prk != want {
+			t.Errorf("have: %v, want: %v", prk, want)
+		}
+	}
+
+	// an unknown kek
+	if keyring, err = NewKeyring(kek1); err != nil {
+		t.Fatal(err)
+	}
+	_, err = New(store, keyring).RetrieveSecret(ctx, "AAA", KindPRK)
+	if !errors.Is(err, ErrUnknownKEK) {
+		t.Errorf("expected error: %v, got: %v", ErrUnknownKEK, err)
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	keks, err := ParseKeys(strings.NewReader(`
+# current
+000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f
+
+1f1e1d1c1b1a191817161514131211100f0e0d0c0b0a09080706050403020100
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keks) != 2 || keks[0][1] != 1 || keks[1][0] != 0x1f {
+		t.Errorf("unexpected keys: %v", keks)
+	}
+
+	_, err = ParseKeys(strings.NewReader("0001"))
+	if !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("expected error: %v, got: %v", ErrInvalidKey, err)
+	}
+}
+
+func TestMigrateInventory(t *testing.T) {
+	ctx := context.Background()
+	inv := invinmem.New()
+
+	kek, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyring, err := NewKeyring(kek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New(inmem.New(), keyring)
+
+	for id, values := range map[string]invstorage.Values{
+		"AAA": {invstorage.KeyPRK: "PRK-123", invstorage.KeySerialNumber: "C02AAA"},
+		"BBB": {invstorage.KeyLockPIN: "123456"},
+		"CCC": {invstorage.KeyPRK: "PRK-OLD"},
+		"DDD": {invstorage.KeySerialNumber: "C02DDD"},
+	} {
+		if err = inv.StoreInventoryValues(ctx, id, values); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// an already encrypted secret is not replaced
+	if err = s.StoreSecret(ctx, "CCC", KindPRK, "PRK-NEW"); err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := s.MigrateInventory(ctx, inv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := count, 2; have != want {
+		t.Errorf("count: have: %v, want: %v", have, want)
+	}
+
+	for _, test := range []struct {
+		id     string
+		kind   string
+		secret string
+	}{
+		{"AAA", KindPRK, "PRK-123"},
+		{"BBB", KindLockPIN, "123456"},
+		{"CCC", KindPRK, "PRK-NEW"},
+	} {
+		secret, err := s.RetrieveSecret(ctx, test.id, test.kind)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have, want := secret, test.secret; have != want {
+			t.Errorf("%s: have: %v, want: %v", test.id, have, want)
+		}
+	}
+
+	// plaintext secrets are removed from inventory
+	idVals, err := inv.RetrieveInventoryKeys(ctx, invstorage.SecretKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(idVals) != 0 {
+		t.Errorf("plaintext secrets remain in inventory: %v", idVals)
+	}
+	idVals, err = inv.RetrieveInventory(ctx, &invstorage.SearchOptions{IDs: []string{"AAA"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := idVals["AAA"][invstorage.KeySerialNumber], "C02AAA"; have != want {
+		t.Errorf("serial number: have: %v, want: %v", have, want)
+	}
+
+	if count, err = s.MigrateInventory(ctx, inv); err != nil {
+		t.Fatal(err)
+	} else if count != 0 {
+		t.Errorf("expected no migrated secrets, got: %d", count)
+	}
+}
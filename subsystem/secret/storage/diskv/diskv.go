@@ -0,0 +1,28 @@
+// Package diskv implements a secret storage backend backed by an on-disk key-value store.
+package diskv
+
+import (
+	"github.com/micromdm/nanocmd/subsystem/secret/storage/kv"
+
+	"github.com/micromdm/nanolib/storage/kv/kvdiskv"
+	"github.com/peterbourgon/diskv/v3"
+)
+
+// Diskv is a secret storage backend backed by an on-disk key-value store.
+type Diskv struct {
+	*kv.KV
+}
+
+// New creates a new initialized secret data store.
+// Files and directories are only accessible by the owner.
+func New(path string) *Diskv {
+	return &Diskv{
+		KV: kv.New(kvdiskv.New(diskv.New(diskv.Options{
+			BasePath:     path,
+			Transform:    kvdiskv.FlatTransform,
+			CacheSizeMax: 1024 * 1024,
+			PathPerm:     0700,
+			FilePerm:     0600,
+		}))),
+	}
+}
@@ -0,0 +1,12 @@
+package diskv
+
+import (
+	"testing"
+
+	"github.com/micromdm/nanocmd/subsystem/secret/storage"
+	"github.com/micromdm/nanocmd/subsystem/secret/storage/test"
+)
+
+func TestDiskv(t *testing.T) {
+	test.TestSecretStorage(t, func() storage.Storage { return New(t.TempDir()) })
+}
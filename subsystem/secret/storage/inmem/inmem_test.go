@@ -0,0 +1,12 @@
+package inmem
+
+import (
+	"testing"
+
+	"github.com/micromdm/nanocmd/subsystem/secret/storage"
+	"github.com/micromdm/nanocmd/subsystem/secret/storage/test"
+)
+
+func TestInMem(t *testing.T) {
+	test.TestSecretStorage(t, func() storage.Storage { return New() })
+}
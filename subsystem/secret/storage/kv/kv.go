@@ -0,0 +1,75 @@
+// Package kv implements a secret storage backend using JSON with key-value storage.
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/micromdm/nanocmd/subsystem/secret/storage"
+
+	"github.com/micromdm/nanolib/storage/kv"
+)
+
+// KV is a secret storage backend using JSON with key-value storage.
+type KV struct {
+	b kv.Bucket
+}
+
+func New(b kv.Bucket) *KV {
+	return &KV{b: b}
+}
+
+// refKey returns the key of ref.
+// Secret kinds must not contain the separator.
+func refKey(ref storage.Ref) string {
+	return ref.Kind + "." + ref.ID
+}
+
+// StoreSealedSecret marshals s into JSON and stores it.
+func (s *KV) StoreSealedSecret(ctx context.Context, ref storage.Ref, sealed *storage.Sealed) error {
+	if err := ref.Validate(); err != nil {
+		return err
+	}
+	if strings.Contains(ref.Kind, ".") {
+		return fmt.Errorf("invalid secret kind: %s", ref.Kind)
+	}
+	raw, err := json.Marshal(sealed)
+	if err != nil {
+		return err
+	}
+	return s.b.Set(ctx, refKey(ref), raw)
+}
+
+// RetrieveSealedSecret unmarshals the stored JSON of the secret of ref.
+func (s *KV) RetrieveSealedSecret(ctx context.Context, ref storage.Ref) (*storage.Sealed, error) {
+	if err := ref.Validate(); err != nil {
+		return nil, err
+	}
+	raw, err := s.b.Get(ctx, refKey(ref))
+	if errors.Is(err, kv.ErrKeyNotFound) {
+		return nil, fmt.Errorf("%w: %s: %s", storage.ErrSecretNotFound, ref.Kind, ref.ID)
+	} else if err != nil {
+		return nil, err
+	}
+	sealed := new(storage.Sealed)
+	if err = json.Unmarshal(raw, sealed); err != nil {
+		return nil, fmt.Errorf("unmarshal secret: %w", err)
+	}
+	return sealed, nil
+}
+
+// RetrieveSecretRefs retrieves the references of all stored secrets.
+func (s *KV) RetrieveSecretRefs(ctx context.Context) ([]storage.Ref, error) {
+	var refs []storage.Ref
+	for _, key := range kv.AllKeys(ctx, s.b) {
+		kind, id, ok := strings.Cut(key, ".")
+		if !ok {
+			continue
+		}
+		refs = append(refs, storage.Ref{ID: id, Kind: kind})
+	}
+	return refs, nil
+}
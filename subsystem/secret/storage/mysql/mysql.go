@@ -0,0 +1,142 @@
+// Package mysql implements a secret storage backend using MySQL.
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"errors"
+	"fmt"
+
+	"github.com/micromdm/nanocmd/subsystem/secret/storage"
+)
+
+// Schema contains the MySQL schema for the secret storage.
+//
+//go:embed schema.sql
+var Schema string
+
+// MySQLStorage implements a secret storage.Storage using MySQL.
+type MySQLStorage struct {
+	db *sql.DB
+}
+
+type config struct {
+	driver string
+	dsn    string
+	db     *sql.DB
+}
+
+// Option allows configuring a MySQLStorage.
+type Option func(*config)
+
+// WithDSN sets the storage MySQL data source name.
+func WithDSN(dsn string) Option {
+	return func(c *config) {
+		c.dsn = dsn
+	}
+}
+
+// WithDriver sets a custom MySQL driver for the storage.
+//
+// Default driver is "mysql".
+// Value is ignored if WithDB is used.
+func WithDriver(driver string) Option {
+	return func(c *config) {
+		c.driver = driver
+	}
+}
+
+// WithDB sets a custom MySQL *sql.DB to the storage.
+//
+// If set, driver passed via WithDriver is ignored.
+func WithDB(db *sql.DB) Option {
+	return func(c *config) {
+		c.db = db
+	}
+}
+
+// New creates and returns a new MySQLStorage.
+func New(opts ...Option) (*MySQLStorage, error) {
+	cfg := &config{driver: "mysql"}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	var err error
+	if cfg.db == nil {
+		cfg.db, err = sql.Open(cfg.driver, cfg.dsn)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err = cfg.db.Ping(); err != nil {
+		return nil, err
+	}
+	return &MySQLStorage{db: cfg.db}, nil
+}
+
+// StoreSealedSecret stores the sealed secret of ref.
+// See the storage interface type for further docs.
+func (s *MySQLStorage) StoreSealedSecret(ctx context.Context, ref storage.Ref, sealed *storage.Sealed) error {
+	if err := ref.Validate(); err != nil {
+		return err
+	}
+	_, err := s.db.ExecContext(
+		ctx,
+		`
+INSERT INTO subsystem_secrets
+    (kind, id, kek_id, wrapped_key, ciphertext)
+VALUES
+    (?, ?, ?, ?, ?) AS new
+ON DUPLICATE KEY UPDATE
+    kek_id = new.kek_id,
+    wrapped_key = new.wrapped_key,
+    ciphertext = new.ciphertext;`,
+		ref.Kind,
+		ref.ID,
+		sealed.KEKID,
+		sealed.WrappedKey,
+		sealed.Ciphertext,
+	)
+	return err
+}
+
+// RetrieveSealedSecret retrieves the sealed secret of ref.
+// See the storage interface type for further docs.
+func (s *MySQLStorage) RetrieveSealedSecret(ctx context.Context, ref storage.Ref) (*storage.Sealed, error) {
+	if err := ref.Validate(); err != nil {
+		return nil, err
+	}
+	sealed := new(storage.Sealed)
+	err := s.db.QueryRowContext(
+		ctx,
+		`SELECT kek_id, wrapped_key, ciphertext FROM subsystem_secrets WHERE kind = ? AND id = ?;`,
+		ref.Kind,
+		ref.ID,
+	).Scan(&sealed.KEKID, &sealed.WrappedKey, &sealed.Ciphertext)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s: %s", storage.ErrSecretNotFound, ref.Kind, ref.ID)
+	} else if err != nil {
+		return nil, err
+	}
+	return sealed, nil
+}
+
+// RetrieveSecretRefs retrieves the references of all stored secrets.
+// See the storage interface type for further docs.
+func (s *MySQLStorage) RetrieveSecretRefs(ctx context.Context) ([]storage.Ref, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT kind, id FROM subsystem_secrets;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var refs []storage.Ref
+	for rows.Next() {
+		var ref storage.Ref
+		if err = rows.Scan(&ref.Kind, &ref.ID); err != nil {
+			return refs, err
+		}
+		refs = append(refs, ref)
+	}
+	return refs, rows.Err()
+}
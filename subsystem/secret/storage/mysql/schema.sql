@@ -0,0 +1,15 @@
+CREATE TABLE subsystem_secrets (
+    kind VARCHAR(63) NOT NULL,
+    id   VARCHAR(255) NOT NULL,
+
+    kek_id      VARCHAR(63) NOT NULL,
+    wrapped_key VARBINARY(255) NOT NULL,
+    ciphertext  BLOB NOT NULL,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (kind, id),
+
+    INDEX (kek_id)
+);
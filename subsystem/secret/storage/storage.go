@@ -0,0 +1,56 @@
+// Package storage defines types and interfaces supporting the secret subsystem.
+package storage
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrSecretNotFound = errors.New("secret not found")
+	ErrNoID           = errors.New("no secret id")
+	ErrNoKind         = errors.New("no secret kind")
+)
+
+// Sealed is an encrypted secret.
+// The secret is encrypted with its own data encryption key (DEK) and
+// the DEK is in turn encrypted (wrapped) by a key encryption key (KEK).
+type Sealed struct {
+	// KEKID identifies the KEK that wrapped the DEK.
+	KEKID string `json:"kek_id"`
+
+	// WrappedKey is the DEK encrypted by the KEK.
+	WrappedKey []byte `json:"wrapped_key"`
+
+	// Ciphertext is the secret encrypted by the DEK.
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// Ref references a stored secret of an enrollment ID.
+type Ref struct {
+	ID   string
+	Kind string
+}
+
+// Validate checks r for errors.
+func (r Ref) Validate() error {
+	if r.ID == "" {
+		return ErrNoID
+	} else if r.Kind == "" {
+		return ErrNoKind
+	}
+	return nil
+}
+
+type Storage interface {
+	// StoreSealedSecret stores the sealed secret s of ref.
+	// Any existing secret of ref is replaced.
+	StoreSealedSecret(ctx context.Context, ref Ref, s *Sealed) error
+
+	// RetrieveSealedSecret retrieves the sealed secret of ref.
+	// ErrSecretNotFound is returned if the secret does not exist.
+	RetrieveSealedSecret(ctx context.Context, ref Ref) (*Sealed, error)
+
+	// RetrieveSecretRefs retrieves the references of all stored secrets.
+	RetrieveSecretRefs(ctx context.Context) ([]Ref, error)
+}
@@ -0,0 +1,69 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/micromdm/nanocmd/subsystem/secret/storage"
+)
+
+func TestSecretStorage(t *testing.T, newStorage func() storage.Storage) {
+	s := newStorage()
+	ctx := context.Background()
+
+	ref := storage.Ref{ID: "AAA", Kind: "prk"}
+
+	_, err := s.RetrieveSealedSecret(ctx, ref)
+	if !errors.Is(err, storage.ErrSecretNotFound) {
+		t.Errorf("expected error: %v, got: %v", storage.ErrSecretNotFound, err)
+	}
+
+	if err = s.StoreSealedSecret(ctx, storage.Ref{Kind: "prk"}, &storage.Sealed{}); !errors.Is(err, storage.ErrNoID) {
+		t.Errorf("expected error: %v, got: %v", storage.ErrNoID, err)
+	}
+
+	sealed := &storage.Sealed{
+		KEKID:      "kek1",
+		WrappedKey: []byte{1, 2, 3},
+		Ciphertext: []byte{4, 5, 6},
+	}
+	if err = s.StoreSealedSecret(ctx, ref, sealed); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.StoreSealedSecret(ctx, storage.Ref{ID: "BBB:user", Kind: "pin"}, sealed); err != nil {
+		t.Fatal(err)
+	}
+
+	ret, err := s.RetrieveSealedSecret(ctx, ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret.KEKID != sealed.KEKID || !bytes.Equal(ret.WrappedKey, sealed.WrappedKey) || !bytes.Equal(ret.Ciphertext, sealed.Ciphertext) {
+		t.Errorf("have: %v, want: %v", ret, sealed)
+	}
+
+	// replace the secret
+	sealed.KEKID = "kek2"
+	if err = s.StoreSealedSecret(ctx, ref, sealed); err != nil {
+		t.Fatal(err)
+	}
+	ret, err = s.RetrieveSealedSecret(ctx, ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := ret.KEKID, sealed.KEKID; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	refs, err := s.RetrieveSecretRefs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].ID < refs[j].ID })
+	if len(refs) != 2 || refs[0] != ref || refs[1] != (storage.Ref{ID: "BBB:user", Kind: "pin"}) {
+		t.Errorf("unexpected refs: %v", refs)
+	}
+}
@@ -0,0 +1,57 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	from := time.Date(2024, time.May, 1, 12, 30, 15, 0, time.UTC) // a Wednesday
+	for _, tc := range []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, time.May, 1, 12, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, time.May, 1, 12, 45, 0, 0, time.UTC)},
+		{"0 2 * * *", time.Date(2024, time.May, 2, 2, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, time.May, 2, 0, 0, 0, 0, time.UTC)},
+		{"30 12 * * *", time.Date(2024, time.May, 2, 12, 30, 0, 0, time.UTC)},
+		{"0 9 * * mon-fri", time.Date(2024, time.May, 2, 9, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, time.May, 5, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 jan *", time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		// both day fields restricted: either matches
+		{"0 0 15 * sat", time.Date(2024, time.May, 4, 0, 0, 0, 0, time.UTC)},
+		{"5/20 13 * * *", time.Date(2024, time.May, 1, 13, 5, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	} {
+		t.Run(tc.expr, func(t *testing.T) {
+			s, err := Parse(tc.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if have := s.Next(from); !have.Equal(tc.want) {
+				t.Errorf("have: %v, want: %v", have, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("expected error: %q", expr)
+		}
+	}
+}
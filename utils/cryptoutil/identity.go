@@ -0,0 +1,72 @@
+package cryptoutil
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// ParsePEMIdentity parses a PEM-encoded certificate (chain) and private key.
+// The first certificate in certPEM is the identity certificate and any
+// following certificates are returned as intermediates. The private key
+// may be PKCS#1, PKCS#8, or SEC 1 (EC) encoded.
+func ParsePEMIdentity(certPEM, keyPEM []byte) (*x509.Certificate, crypto.PrivateKey, []*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, certPEM = pem.Decode(certPEM)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("parsing certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) < 1 {
+		return nil, nil, nil, errors.New("no PEM certificate found")
+	}
+
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, nil, nil, errors.New("no PEM private key found")
+	}
+	key, err := parsePrivateKey(block.Bytes)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return certs[0], key, certs[1:], nil
+}
+
+// parsePrivateKey tries to parse der as a PKCS#1, PKCS#8, and SEC 1 private key.
+func parsePrivateKey(der []byte) (crypto.PrivateKey, error) {
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return key, nil
+	}
+	return nil, errors.New("unknown private key type")
+}
+
+// ParsePKCS12Identity parses a PKCS#12 (.p12) encoded certificate and
+// private key protected by password. Any CA certificates in data are
+// returned as intermediates.
+func ParsePKCS12Identity(data []byte, password string) (*x509.Certificate, crypto.PrivateKey, []*x509.Certificate, error) {
+	key, cert, intermediates, err := pkcs12.DecodeChain(data, password)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("decoding pkcs12: %w", err)
+	}
+	return cert, key, intermediates, nil
+}
@@ -0,0 +1,146 @@
+// Package kvmysql implements a key-value store backed by a MySQL table.
+// This persists the storage backends of subsystems that are implemented
+// with key-value storage when using MySQL.
+package kvmysql
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/micromdm/nanolib/storage/kv"
+)
+
+// Schema contains the MySQL schema for the key-value table.
+//
+//go:embed schema.sql
+var Schema string
+
+// KVMySQL is a key-value store (bucket) backed by a MySQL table.
+// Multiple buckets share the same table and are separated by name.
+type KVMySQL struct {
+	db     *sql.DB
+	bucket string
+}
+
+// New creates a new key-value store named bucket in the table of db.
+func New(db *sql.DB, bucket string) *KVMySQL {
+	return &KVMySQL{db: db, bucket: bucket}
+}
+
+// Get retrieves the value at key in the MySQL table.
+// If key is not found then a wrapped ErrKeyNotFound will be returned.
+func (s *KVMySQL) Get(ctx context.Context, key string) ([]byte, error) {
+	var value []byte
+	err := s.db.QueryRowContext(
+		ctx,
+		`SELECT kv_value FROM kv_buckets WHERE bucket = ? AND kv_key = ?;`,
+		s.bucket,
+		key,
+	).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", kv.ErrKeyNotFound, key)
+	}
+	return value, err
+}
+
+// Set sets key to value in the MySQL table.
+func (s *KVMySQL) Set(ctx context.Context, key string, value []byte) error {
+	if value == nil {
+		value = []byte{}
+	}
+	_, err := s.db.ExecContext(
+		ctx, `
+INSERT INTO kv_buckets
+	(bucket, kv_key, kv_value)
+VALUES
+	(?, ?, ?) AS new
+ON DUPLICATE KEY UPDATE
+	kv_value = new.kv_value;`,
+		s.bucket,
+		key,
+		value,
+	)
+	return err
+}
+
+// Has checks that key is found in the MySQL table.
+func (s *KVMySQL) Has(ctx context.Context, key string) (bool, error) {
+	var found int
+	err := s.db.QueryRowContext(
+		ctx,
+		`SELECT COUNT(*) FROM kv_buckets WHERE bucket = ? AND kv_key = ?;`,
+		s.bucket,
+		key,
+	).Scan(&found)
+	return found > 0, err
+}
+
+// Delete deletes key in the MySQL table.
+func (s *KVMySQL) Delete(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		`DELETE FROM kv_buckets WHERE bucket = ? AND kv_key = ?;`,
+		s.bucket,
+		key,
+	)
+	return err
+}
+
+// Keys returns all keys in the MySQL table.
+// See KeysPrefix.
+func (s *KVMySQL) Keys(ctx context.Context, cancel <-chan struct{}) <-chan string {
+	return s.KeysPrefix(ctx, "", cancel)
+}
+
+// escapeLike escapes the LIKE pattern characters of s.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// KeysPrefix returns all keys starting with prefix in the MySQL table.
+// The keys are returned in ascending order.
+// The keys channel will be closed if cancel was provided and closed.
+// The keys are read before any are sent so callers may modify the
+// table while receiving keys. A query error closes the channel early.
+func (s *KVMySQL) KeysPrefix(ctx context.Context, prefix string, cancel <-chan struct{}) <-chan string {
+	r := make(chan string)
+	keys, _ := s.keysPrefix(ctx, prefix)
+	go func() {
+		defer close(r)
+		for _, k := range keys {
+			select {
+			case <-cancel:
+				return
+			case r <- k:
+			}
+		}
+	}()
+	return r
+}
+
+// keysPrefix reads all keys starting with prefix in the MySQL table.
+func (s *KVMySQL) keysPrefix(ctx context.Context, prefix string) ([]string, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT kv_key FROM kv_buckets WHERE bucket = ? AND kv_key LIKE ? ORDER BY kv_key;`,
+		s.bucket,
+		escapeLike(prefix)+"%",
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var keys []string
+	for rows.Next() {
+		var k string
+		if err = rows.Scan(&k); err != nil {
+			return keys, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
@@ -0,0 +1,38 @@
+package kvmysql
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/micromdm/nanolib/storage/kv/test"
+)
+
+func TestKVMySQL(t *testing.T) {
+	testDSN := os.Getenv("NANOCMD_MYSQL_STORAGE_TEST_DSN")
+	if testDSN == "" {
+		t.Skip("NANOCMD_MYSQL_STORAGE_TEST_DSN not set")
+	}
+
+	db, err := sql.Open("mysql", testDSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	// key traversal requires a pristine bucket
+	bucket := "test_" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	test.TestBucketSimple(t, ctx, New(db, bucket+"_simple"))
+	test.TestKeysTraversing(t, ctx, New(db, bucket+"_keys"))
+}
+
+func TestEscapeLike(t *testing.T) {
+	if have, want := escapeLike(`a_b%c\d`), `a\_b\%c\\d`; have != want {
+		t.Errorf("have: %s, want: %s", have, want)
+	}
+}
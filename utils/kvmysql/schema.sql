@@ -0,0 +1,10 @@
+CREATE TABLE kv_buckets (
+    bucket   VARCHAR(63)  NOT NULL,
+    kv_key   VARCHAR(255) NOT NULL,
+    kv_value MEDIUMBLOB   NOT NULL,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (bucket, kv_key)
+);
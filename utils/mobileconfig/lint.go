@@ -0,0 +1,123 @@
+package mobileconfig
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+
+	"github.com/micromdm/plist"
+	"github.com/smallstep/pkcs7"
+)
+
+//go:embed payloadtypes.json
+var payloadTypesJSON []byte
+
+// payloadType is an entry in the bundled payload type schema.
+type payloadType struct {
+	// Deprecated is a non-empty explanation if the payload type is deprecated.
+	Deprecated string `json:"deprecated,omitempty"`
+}
+
+// payloadTypes is the bundled payload type schema keyed by PayloadType.
+var payloadTypes map[string]payloadType
+
+func init() {
+	if err := json.Unmarshal(payloadTypesJSON, &payloadTypes); err != nil {
+		panic(fmt.Errorf("unmarshal payload types: %w", err))
+	}
+}
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Problem is a single lint finding in a profile.
+type Problem struct {
+	Severity string `json:"severity"`
+	// Payload locates the offending payload. Empty for the top-level
+	// payload or e.g. "PayloadContent[1]" for a sub-payload.
+	Payload string `json:"payload,omitempty"`
+	Message string `json:"message"`
+}
+
+// Problems is a list of lint findings.
+type Problems []Problem
+
+// HasErrors returns true if any problem is of error severity.
+func (p Problems) HasErrors() bool {
+	for _, problem := range p {
+		if problem.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// lintProfile is the top-level profile including its sub-payloads.
+type lintProfile struct {
+	Payload
+	PayloadContent []Payload
+}
+
+// Lint checks the profile and its sub-payloads for common problems.
+// Returned problems are errors for profiles that are likely to fail
+// installation and warnings otherwise. Signatures are not verified.
+func (mc Mobileconfig) Lint() (Problems, error) {
+	if mc.Signed() {
+		p7, err := pkcs7.Parse(mc)
+		if err != nil {
+			return nil, fmt.Errorf("parsing pkcs7: %w", err)
+		}
+		mc = Mobileconfig(p7.Content)
+	}
+	profile := new(lintProfile)
+	if err := plist.Unmarshal(mc, profile); err != nil {
+		return nil, fmt.Errorf("unmarshal plist: %w", err)
+	}
+
+	var problems Problems
+	add := func(severity, payload, format string, a ...interface{}) {
+		problems = append(problems, Problem{
+			Severity: severity,
+			Payload:  payload,
+			Message:  fmt.Sprintf(format, a...),
+		})
+	}
+
+	if profile.PayloadType != "Configuration" {
+		add(SeverityError, "", "top-level PayloadType is not Configuration: %q", profile.PayloadType)
+	}
+
+	uuids := map[string]string{profile.PayloadUUID: "top-level"}
+	identifiers := make(map[string]string)
+	for i, payload := range profile.PayloadContent {
+		loc := fmt.Sprintf("PayloadContent[%d]", i)
+		if payload.PayloadType == "" {
+			add(SeverityError, loc, "PayloadType is empty")
+		} else if pt, ok := payloadTypes[payload.PayloadType]; !ok {
+			add(SeverityWarning, loc, "unknown PayloadType: %q", payload.PayloadType)
+		} else if pt.Deprecated != "" {
+			add(SeverityWarning, loc, "deprecated PayloadType: %q: %s", payload.PayloadType, pt.Deprecated)
+		}
+		if payload.PayloadIdentifier == "" {
+			add(SeverityError, loc, "PayloadIdentifier is empty")
+		} else if prev, ok := identifiers[payload.PayloadIdentifier]; ok {
+			add(SeverityWarning, loc, "duplicate PayloadIdentifier %q (also in %s)", payload.PayloadIdentifier, prev)
+		} else {
+			identifiers[payload.PayloadIdentifier] = loc
+		}
+		if payload.PayloadUUID == "" {
+			add(SeverityError, loc, "PayloadUUID is empty")
+		} else if prev, ok := uuids[payload.PayloadUUID]; ok {
+			add(SeverityError, loc, "duplicate PayloadUUID %q (also in %s)", payload.PayloadUUID, prev)
+		} else {
+			uuids[payload.PayloadUUID] = loc
+		}
+		if payload.PayloadVersion != 1 {
+			add(SeverityWarning, loc, "PayloadVersion is not 1")
+		}
+	}
+
+	return problems, nil
+}
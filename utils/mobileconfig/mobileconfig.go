@@ -2,7 +2,6 @@
 package mobileconfig
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
 
@@ -50,8 +49,8 @@ type Mobileconfig []byte
 // Profile signed status is also returned.
 func (mc Mobileconfig) Parse() (*Payload, bool, error) {
 	signed := false
-	if !bytes.HasPrefix(mc, []byte("<?xml")) && !bytes.HasPrefix(mc, []byte("bplist0")) {
-		// we're not an XML plist nor a binary plist, so let's try PKCS7 (signed)
+	if mc.Signed() {
+		// we're a PKCS7 signed profile
 		p7, err := pkcs7.Parse(mc)
 		if err != nil {
 			return nil, signed, fmt.Errorf("parsing pkcs7: %w", err)
@@ -1,9 +1,12 @@
 package mobileconfig
 
 import (
+	"bytes"
 	"os"
 	"reflect"
 	"testing"
+
+	"github.com/micromdm/nanocmd/utils/cryptoutil"
 )
 
 func TestMobileconfig(t *testing.T) {
@@ -28,3 +31,124 @@ func TestMobileconfig(t *testing.T) {
 		t.Error("structures not equal")
 	}
 }
+
+func TestSign(t *testing.T) {
+	b, err := os.ReadFile("testdata/test.mobileconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, cert, err := cryptoutil.SelfSignedRSAKeypair("profile signer", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewSigner(cert, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mc, err := s.Sign(Mobileconfig(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !mc.Signed() {
+		t.Fatal("expected signed profile")
+	}
+	payload, signed, err := mc.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !signed {
+		t.Error("expected signed profile")
+	}
+	if have, want := payload.PayloadUUID, "D0CCE647-B1D6-49B0-82BC-C1BCC8A33218"; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+	crt, err := mc.SignerCertificate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := crt.Subject.CommonName, "profile signer"; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	// signing again should not change the profile
+	mc2, err := s.Sign(mc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(mc, mc2) {
+		t.Error("expected unmodified signed profile")
+	}
+}
+
+func TestSigned(t *testing.T) {
+	b, err := os.ReadFile("testdata/test.mobileconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, cert, err := cryptoutil.SelfSignedRSAKeypair("profile signer", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewSigner(cert, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := s.Sign(Mobileconfig(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		name string
+		mc   Mobileconfig
+		want bool
+	}{
+		{"xml", Mobileconfig(b), false},
+		{"plist", Mobileconfig(b[bytes.Index(b, []byte("<plist")):]), false},
+		{"whitespace", append(Mobileconfig("\n  "), b...), false},
+		{"bplist", Mobileconfig("bplist00"), false},
+		{"empty", nil, false},
+		{"sequence", Mobileconfig{0x30, 0x03, 0x02, 0x01, 0x01}, false},
+		{"signed", signed, true},
+	} {
+		if have := test.mc.Signed(); have != test.want {
+			t.Errorf("%s: have: %v, want: %v", test.name, have, test.want)
+		}
+	}
+}
+
+func TestLint(t *testing.T) {
+	b, err := os.ReadFile("testdata/test.mobileconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	problems, err := Mobileconfig(b).Lint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// custom preference domains are unknown to the bundled schema
+	if have, want := len(problems), 2; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+	if problems.HasErrors() {
+		t.Error("expected no errors")
+	}
+
+	b, err = os.ReadFile("testdata/lint.mobileconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	problems, err = Mobileconfig(b).Lint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := Problems{
+		{Severity: SeverityWarning, Payload: "PayloadContent[0]", Message: `deprecated PayloadType: "com.apple.apn.managed": use com.apple.cellular`},
+		{Severity: SeverityError, Payload: "PayloadContent[1]", Message: "PayloadType is empty"},
+		{Severity: SeverityError, Payload: "PayloadContent[1]", Message: `duplicate PayloadUUID "2E4D0C3A-9C0B-4D7E-8E7A-1B0F6C3D5A01" (also in PayloadContent[0])`},
+		{Severity: SeverityWarning, Payload: "PayloadContent[2]", Message: `unknown PayloadType: "com.example.app"`},
+		{Severity: SeverityError, Payload: "PayloadContent[2]", Message: "PayloadIdentifier is empty"},
+	}
+	if !reflect.DeepEqual(problems, expect) {
+		t.Errorf("have: %v, want: %v", problems, expect)
+	}
+}
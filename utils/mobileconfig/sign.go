@@ -0,0 +1,88 @@
+package mobileconfig
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"github.com/smallstep/pkcs7"
+)
+
+// oidSignedData is the DER encoded CMS/PKCS#7 signedData content type
+// object identifier (1.2.840.113549.1.7.2).
+var oidSignedData = []byte{0x06, 0x09, 0x2a, 0x86, 0x48, 0x86, 0xf7, 0x0d, 0x01, 0x07, 0x02}
+
+// Signed reports whether mc is a signed profile.
+// I.e. it is an ASN.1 SEQUENCE (a CMS/PKCS#7 ContentInfo) whose content
+// type is signedData. Both DER and BER (indefinite length) are detected.
+func (mc Mobileconfig) Signed() bool {
+	if len(mc) < 2 || mc[0] != 0x30 {
+		return false
+	}
+	// skip the length octets of the SEQUENCE
+	i := 2
+	if l := mc[1]; l > 0x80 {
+		i += int(l & 0x7f)
+	}
+	return i < len(mc) && bytes.HasPrefix(mc[i:], oidSignedData)
+}
+
+// SignerCertificate returns the certificate that signed mc.
+// A nil certificate is returned if mc is not signed.
+func (mc Mobileconfig) SignerCertificate() (*x509.Certificate, error) {
+	if !mc.Signed() {
+		return nil, nil
+	}
+	p7, err := pkcs7.Parse(mc)
+	if err != nil {
+		return nil, fmt.Errorf("parsing pkcs7: %w", err)
+	}
+	crt := p7.GetOnlySigner()
+	if crt == nil {
+		return nil, errors.New("no single signer certificate")
+	}
+	return crt, nil
+}
+
+// Signer signs profiles with a signing identity.
+type Signer struct {
+	cert          *x509.Certificate
+	key           crypto.PrivateKey
+	intermediates []*x509.Certificate
+}
+
+// NewSigner creates a new profile signer using cert and key.
+// Any intermediates are included in the signed profiles.
+func NewSigner(cert *x509.Certificate, key crypto.PrivateKey, intermediates ...*x509.Certificate) (*Signer, error) {
+	if cert == nil || key == nil {
+		return nil, errors.New("missing signing certificate or key")
+	}
+	return &Signer{cert: cert, key: key, intermediates: intermediates}, nil
+}
+
+// Certificate returns the signing certificate.
+func (s *Signer) Certificate() *x509.Certificate {
+	return s.cert
+}
+
+// Sign signs mc using SHA-256. Already signed profiles are returned unmodified.
+func (s *Signer) Sign(mc Mobileconfig) (Mobileconfig, error) {
+	if mc.Signed() {
+		return mc, nil
+	}
+	sd, err := pkcs7.NewSignedData(mc)
+	if err != nil {
+		return nil, fmt.Errorf("creating signed data: %w", err)
+	}
+	sd.SetDigestAlgorithm(pkcs7.OIDDigestAlgorithmSHA256)
+	if err = sd.AddSignerChain(s.cert, s.key, s.intermediates, pkcs7.SignerInfoConfig{}); err != nil {
+		return nil, fmt.Errorf("adding signer: %w", err)
+	}
+	signed, err := sd.Finish()
+	if err != nil {
+		return nil, fmt.Errorf("finishing signed data: %w", err)
+	}
+	return Mobileconfig(signed), nil
+}
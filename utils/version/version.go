@@ -0,0 +1,64 @@
+// Package version compares loosely-formatted software version strings.
+package version
+
+import (
+	"strconv"
+	"strings"
+)
+
+// split breaks v into its dot-separated components.
+// Leading and trailing whitespace is ignored.
+func split(v string) []string {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return nil
+	}
+	return strings.Split(v, ".")
+}
+
+// compareComponent compares a single version component.
+// Numeric components are compared numerically, otherwise lexically.
+// Numeric components sort after non-numeric ones.
+func compareComponent(a, b string) int {
+	ai, aErr := strconv.ParseUint(a, 10, 64)
+	bi, bErr := strconv.ParseUint(b, 10, 64)
+	switch {
+	case aErr == nil && bErr == nil:
+		if ai < bi {
+			return -1
+		} else if ai > bi {
+			return 1
+		}
+		return 0
+	case aErr == nil:
+		return 1
+	case bErr == nil:
+		return -1
+	}
+	return strings.Compare(a, b)
+}
+
+// Compare compares version strings a and b.
+// The result will be 0 if a == b, -1 if a < b, and +1 if a > b.
+// Versions are compared component by component (separated by dots).
+// Missing components are treated as zero. I.e. "1.2" == "1.2.0".
+func Compare(a, b string) int {
+	as, bs := split(a), split(b)
+	n := len(as)
+	if len(bs) > n {
+		n = len(bs)
+	}
+	for i := 0; i < n; i++ {
+		ac, bc := "0", "0"
+		if i < len(as) {
+			ac = as[i]
+		}
+		if i < len(bs) {
+			bc = bs[i]
+		}
+		if r := compareComponent(ac, bc); r != 0 {
+			return r
+		}
+	}
+	return 0
+}
@@ -0,0 +1,25 @@
+package version
+
+import "testing"
+
+func TestCompare(t *testing.T) {
+	for _, test := range []struct {
+		a    string
+		b    string
+		want int
+	}{
+		{"1.0", "1.0", 0},
+		{"1.2", "1.2.0", 0},
+		{"1.2.1", "1.2", 1},
+		{"1.10", "1.9", 1},
+		{"9.0", "10.0", -1},
+		{"", "0", 0},
+		{"1.0b1", "1.0", -1},
+		{"2.0a", "2.0b", -1},
+		{" 14.1 ", "14.1", 0},
+	} {
+		if have, want := Compare(test.a, test.b), test.want; have != want {
+			t.Errorf("Compare(%q, %q): have: %v, want: %v", test.a, test.b, have, want)
+		}
+	}
+}
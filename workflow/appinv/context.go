@@ -0,0 +1,24 @@
+package appinv
+
+import (
+	"encoding/json"
+)
+
+// Context configures the InstalledApplicationList query.
+type Context struct {
+	// Identifiers limits the query to these application bundle IDs.
+	Identifiers []string `json:"identifiers,omitempty"`
+
+	// ManagedAppsOnly limits the query to managed applications.
+	ManagedAppsOnly bool `json:"managed_apps_only,omitempty"`
+}
+
+// MarshalBinary marshals c into JSON data.
+func (c *Context) MarshalBinary() (data []byte, err error) {
+	return json.Marshal(c)
+}
+
+// UnmarshalBinary unmarshals JSON data into c.
+func (c *Context) UnmarshalBinary(data []byte) error {
+	return json.Unmarshal(data, c)
+}
@@ -0,0 +1,189 @@
+// Package appinv implements a NanoCMD Workflow that collects the installed applications of an enrollment.
+package appinv
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/micromdm/nanocmd/logkeys"
+	"github.com/micromdm/nanocmd/subsystem/inventory/storage"
+	"github.com/micromdm/nanocmd/utils/uuid"
+	"github.com/micromdm/nanocmd/workflow"
+
+	"github.com/jessepeterson/mdmcommands"
+	"github.com/micromdm/nanolib/log"
+	"github.com/micromdm/nanolib/log/ctxlog"
+)
+
+const WorkflowName = "io.micromdm.wf.appinv.v1"
+
+// Workflow collects the installed applications of enrollments and stores them in inventory storage.
+type Workflow struct {
+	enq    workflow.StepEnqueuer
+	ider   uuid.IDer
+	store  storage.AppStorage
+	logger log.Logger
+}
+
+type Option func(*Workflow)
+
+// WithLogger configures logger on the workflow.
+func WithLogger(logger log.Logger) Option {
+	return func(w *Workflow) {
+		w.logger = logger
+	}
+}
+
+func New(enq workflow.StepEnqueuer, store storage.AppStorage, opts ...Option) (*Workflow, error) {
+	w := &Workflow{
+		enq:    enq,
+		ider:   uuid.NewUUID(),
+		store:  store,
+		logger: log.NopLogger,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	w.logger = w.logger.With(logkeys.WorkflowName, w.Name())
+	return w, nil
+}
+
+func (w *Workflow) Name() string {
+	return WorkflowName
+}
+
+func (w *Workflow) Config() *workflow.Config {
+	return nil
+}
+
+// NewContextValue returns a new [Context] regardless of input.
+func (w *Workflow) NewContextValue(_ string) workflow.ContextMarshaler {
+	return new(Context)
+}
+
+func (w *Workflow) Start(ctx context.Context, step *workflow.StepStart) error {
+	wfCtx, ok := step.Context.(*Context)
+	if !ok {
+		return workflow.ErrIncorrectContextType
+	}
+
+	// build an InstalledApplicationList command
+	cmd := mdmcommands.NewInstalledApplicationListCommand(w.ider.ID())
+	if len(wfCtx.Identifiers) > 0 {
+		identifiers := wfCtx.Identifiers
+		cmd.Command.Identifiers = &identifiers
+	}
+	if wfCtx.ManagedAppsOnly {
+		managedAppsOnly := true
+		cmd.Command.ManagedAppsOnly = &managedAppsOnly
+	}
+
+	// assemble our StepEnqueuing
+	se := step.NewStepEnqueueing()
+	se.Commands = []interface{}{cmd}
+	// pass along our context so we know how the list was filtered
+	se.Context = wfCtx
+
+	// enqueue our step!
+	return w.enq.EnqueueStep(ctx, w, se)
+}
+
+// normalize converts InstalledApplicationList items into inventory apps.
+// Items without a bundle identifier are skipped.
+func normalize(items []mdmcommands.InstalledApplicationListItem, modified time.Time) []storage.App {
+	apps := make([]storage.App, 0, len(items))
+	for _, item := range items {
+		if item.Identifier == nil || *item.Identifier == "" {
+			continue
+		}
+		app := storage.App{Identifier: *item.Identifier, Modified: modified}
+		if item.Name != nil {
+			app.Name = *item.Name
+		}
+		if item.ShortVersion != nil {
+			app.ShortVersion = *item.ShortVersion
+		}
+		if item.Version != nil {
+			app.Version = *item.Version
+		}
+		if item.BundleSize != nil {
+			app.BundleSize = *item.BundleSize
+		}
+		if item.Source != nil {
+			app.Source = *item.Source
+		}
+		apps = append(apps, app)
+	}
+	return apps
+}
+
+// merge combines the existing app list with the newly queried apps.
+// An unfiltered query replaces the list entirely. A query filtered by
+// identifiers replaces just those identifiers (removing any that are no
+// longer installed). Otherwise (i.e. managed apps only) the queried
+// apps are updated in-place.
+func merge(wfCtx *Context, existing, apps []storage.App) []storage.App {
+	if len(wfCtx.Identifiers) < 1 && !wfCtx.ManagedAppsOnly {
+		return apps
+	}
+	replaced := make(map[string]struct{})
+	for _, id := range wfCtx.Identifiers {
+		replaced[id] = struct{}{}
+	}
+	for _, app := range apps {
+		replaced[app.Identifier] = struct{}{}
+	}
+	ret := make([]storage.App, 0, len(existing)+len(apps))
+	for _, app := range existing {
+		if _, ok := replaced[app.Identifier]; !ok {
+			ret = append(ret, app)
+		}
+	}
+	return append(ret, apps...)
+}
+
+func (w *Workflow) StepCompleted(ctx context.Context, stepResult *workflow.StepResult) error {
+	if len(stepResult.CommandResults) != 1 {
+		return workflow.ErrStepResultCommandLenMismatch
+	}
+	response, ok := stepResult.CommandResults[0].(*mdmcommands.InstalledApplicationListResponse)
+	if !ok {
+		return workflow.ErrIncorrectCommandType
+	}
+	if err := response.Validate(); err != nil {
+		return fmt.Errorf("installed application list response: %w", err)
+	}
+
+	wfCtx, ok := stepResult.Context.(*Context)
+	if !ok {
+		return workflow.ErrIncorrectContextType
+	}
+
+	apps := normalize(response.InstalledApplicationList, time.Now())
+
+	if len(wfCtx.Identifiers) > 0 || wfCtx.ManagedAppsOnly {
+		idApps, err := w.store.RetrieveApps(ctx, []string{stepResult.ID})
+		if err != nil {
+			return fmt.Errorf("retrieving apps: %w", err)
+		}
+		apps = merge(wfCtx, idApps[stepResult.ID], apps)
+	}
+
+	ctxlog.Logger(ctx, w.logger).Debug(
+		logkeys.Message, "storing installed applications",
+		logkeys.InstanceID, stepResult.InstanceID,
+		logkeys.EnrollmentID, stepResult.ID,
+		logkeys.GenericCount, len(apps),
+	)
+
+	return w.store.StoreApps(ctx, stepResult.ID, apps)
+}
+
+func (w *Workflow) StepTimeout(_ context.Context, _ *workflow.StepResult) error {
+	return workflow.ErrTimeoutNotUsed
+}
+
+func (w *Workflow) Event(_ context.Context, _ *workflow.Event, _ string, _ *workflow.MDMContext) error {
+	return workflow.ErrEventsNotSupported
+}
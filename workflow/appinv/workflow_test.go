@@ -0,0 +1,105 @@
+package appinv
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jessepeterson/mdmcommands"
+	"github.com/micromdm/nanocmd/engine"
+	enginestorage "github.com/micromdm/nanocmd/engine/storage/inmem"
+	"github.com/micromdm/nanocmd/subsystem/inventory/storage"
+	"github.com/micromdm/nanocmd/subsystem/inventory/storage/inmem"
+	"github.com/micromdm/nanocmd/utils/uuid"
+	"github.com/micromdm/nanocmd/workflow/test"
+)
+
+func TestWorkflow(t *testing.T) {
+	e := engine.New(enginestorage.New(), &test.NullEnqueuer{})
+
+	c := test.NewCollectingStepEnqueur(e)
+
+	s := inmem.New()
+
+	w, err := New(c, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.ider = uuid.NewStaticIDs(
+		// note: order is important and depends on values in plist testdata
+		"C1AB1D5E-7D53-4F0D-9A0B-3F1A2B9E7C01",
+		"C1AB1D5E-7D53-4F0D-9A0B-3F1A2B9E7C02",
+	)
+
+	ctx := context.Background()
+
+	// enrollment id
+	id := "AAABBBCCC111222333"
+
+	e.RegisterWorkflow(w)
+
+	_, err = e.StartWorkflow(ctx, w.Name(), nil, []string{id}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := c.Steps()
+	if want, have := 1, len(steps); want != have {
+		t.Fatalf("wanted: %d; have: %d", want, have)
+	}
+
+	err = test.SendCommandEvent(ctx, e, "testdata/applist.plist", id, "C1AB1D5E-7D53-4F0D-9A0B-3F1A2B9E7C01")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idApps, err := s.RetrieveApps(ctx, []string{id})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// note the item without an identifier is dropped
+	if want, have := 2, len(idApps[id]); want != have {
+		t.Fatalf("wanted: %d; have: %d", want, have)
+	}
+
+	// start a filtered query
+	_, err = e.StartWorkflow(ctx, w.Name(), []byte(`{"identifiers":["com.example.agent"]}`), []string{id}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps = c.Steps()
+	if want, have := 2, len(steps); want != have {
+		t.Fatalf("wanted: %d; have: %d", want, have)
+	}
+
+	cmd, ok := steps[1].StepEnqueueing.Commands[0].(*mdmcommands.InstalledApplicationListCommand)
+	if !ok {
+		t.Fatal("incorrect command type")
+	}
+	if cmd.Command.Identifiers == nil || len(*cmd.Command.Identifiers) != 1 {
+		t.Fatal("expected identifiers in command")
+	}
+
+	err = test.SendCommandEvent(ctx, e, "testdata/applist-filtered.plist", id, "C1AB1D5E-7D53-4F0D-9A0B-3F1A2B9E7C02")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the filtered query should only update the filtered app
+	idApp, err := s.SearchApps(ctx, &storage.AppSearchOptions{Identifier: "com.example.agent", MaxVersion: "2.4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "2.4", idApp[id].ShortVersion; want != have {
+		t.Errorf("wanted: %s; have: %s", want, have)
+	}
+
+	idApp, err = s.SearchApps(ctx, &storage.AppSearchOptions{Identifier: "com.apple.Safari"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "17.4.1", idApp[id].ShortVersion; want != have {
+		t.Errorf("wanted: %s; have: %s", want, have)
+	}
+}
@@ -0,0 +1,24 @@
+package baseline
+
+import (
+	"encoding/json"
+
+	"github.com/micromdm/nanocmd/workflow"
+)
+
+// outcomeContext carries the outcome of the reconcile step to the
+// refresh step which finishes the instance.
+type outcomeContext struct {
+	Outcome workflow.Outcome `json:"outcome"`
+	Message string           `json:"message,omitempty"`
+}
+
+// MarshalBinary marshals c into JSON data.
+func (c *outcomeContext) MarshalBinary() (data []byte, err error) {
+	return json.Marshal(c)
+}
+
+// UnmarshalBinary unmarshals JSON data into c.
+func (c *outcomeContext) UnmarshalBinary(data []byte) error {
+	return json.Unmarshal(data, c)
+}
@@ -0,0 +1,482 @@
+// Package baseline implements a NanoCMD Workflow that reconciles installed profiles with assigned baselines.
+package baseline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/micromdm/nanocmd/logkeys"
+	"github.com/micromdm/nanocmd/subsystem/baseline/storage"
+	groupstorage "github.com/micromdm/nanocmd/subsystem/group/storage"
+	invstorage "github.com/micromdm/nanocmd/subsystem/inventory/storage"
+	"github.com/micromdm/nanocmd/subsystem/profile/render"
+	profstorage "github.com/micromdm/nanocmd/subsystem/profile/storage"
+	"github.com/micromdm/nanocmd/utils/uuid"
+	"github.com/micromdm/nanocmd/workflow"
+	"github.com/micromdm/nanocmd/workflow/profile"
+
+	"github.com/jessepeterson/mdmcommands"
+	"github.com/micromdm/nanolib/log"
+	"github.com/micromdm/nanolib/log/ctxlog"
+)
+
+const WorkflowName = "io.micromdm.wf.baseline.v1"
+
+const (
+	stepNameList      = "list"
+	stepNameReconcile = "reconcile"
+	stepNameRefresh   = "refresh"
+)
+
+// Workflow reconciles the installed profiles of enrollments with the
+// profile baselines assigned to them. Baselines are assigned to
+// enrollments by group membership or by MDM URL parameters.
+type Workflow struct {
+	enq        workflow.StepEnqueuer
+	ider       uuid.IDer
+	store      storage.ReadStorage
+	profStore  profstorage.ReadStorage
+	groupStore groupstorage.ReadStorage
+	invStore   invstorage.InstalledProfileStorage
+	logger     log.Logger
+	renderer   *render.Renderer
+}
+
+type Option func(*Workflow)
+
+// WithLogger configures logger on the workflow.
+func WithLogger(logger log.Logger) Option {
+	return func(w *Workflow) {
+		w.logger = logger
+	}
+}
+
+// WithInstalledProfileStorage reconciles enrollments against their
+// installed profiles last stored in store (e.g. by the profile workflow)
+// instead of sending a ProfileList command. A ProfileList command is
+// only sent to enrollments without stored installed profiles. The
+// installed profiles are stored again after profiles are reconciled.
+func WithInstalledProfileStorage(store invstorage.InstalledProfileStorage) Option {
+	return func(w *Workflow) {
+		w.invStore = store
+	}
+}
+
+// WithRenderer renders profile templates with r when installing profiles.
+// By default only templates without inventory variables can be rendered.
+func WithRenderer(r *render.Renderer) Option {
+	return func(w *Workflow) {
+		w.renderer = r
+	}
+}
+
+func New(enq workflow.StepEnqueuer, store storage.ReadStorage, profStore profstorage.ReadStorage, groupStore groupstorage.ReadStorage, opts ...Option) (*Workflow, error) {
+	w := &Workflow{
+		enq:        enq,
+		ider:       uuid.NewUUID(),
+		store:      store,
+		profStore:  profStore,
+		groupStore: groupStore,
+		logger:     log.NopLogger,
+		renderer:   render.New(nil),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	w.logger = w.logger.With(logkeys.WorkflowName, w.Name())
+	return w, nil
+}
+
+func (w *Workflow) Name() string {
+	return WorkflowName
+}
+
+func (w *Workflow) Config() *workflow.Config {
+	return nil
+}
+
+// NewContextValue returns the outcome context for the refresh step and nil otherwise.
+func (w *Workflow) NewContextValue(name string) workflow.ContextMarshaler {
+	if name == stepNameRefresh {
+		return new(outcomeContext)
+	}
+	return nil
+}
+
+func (w *Workflow) Start(ctx context.Context, step *workflow.StepStart) error {
+	listIDs := step.IDs
+	if w.invStore != nil {
+		idProfiles, err := w.invStore.RetrieveInstalledProfiles(ctx, step.IDs)
+		if err != nil {
+			return fmt.Errorf("retrieving installed profiles: %w", err)
+		}
+		listIDs = nil
+		for _, id := range step.IDs {
+			profiles, ok := idProfiles[id]
+			if !ok {
+				listIDs = append(listIDs, id)
+				continue
+			}
+			se := &workflow.StepEnqueueing{
+				StepContext: *step.StepContext.NewForEnqueue(),
+				IDs:         []string{id},
+			}
+			logger := ctxlog.Logger(ctx, w.logger).With(
+				logkeys.InstanceID, step.InstanceID,
+				logkeys.EnrollmentID, id,
+			)
+			finish := func(o workflow.Outcome, message string) { step.Finish(id, o, message) }
+			if err = w.reconcile(ctx, logger, se, step.Params, storedInstalled(profiles), finish); err != nil {
+				return err
+			}
+		}
+		if len(listIDs) < 1 {
+			return nil
+		}
+	}
+
+	// build a ProfileList command
+	cmd := mdmcommands.NewProfileListCommand(w.ider.ID())
+	managedOnly := true
+	cmd.Command.ManagedOnly = &managedOnly
+
+	// assemble our StepEnqueuing
+	se := step.NewStepEnqueueing()
+	se.IDs = listIDs
+	se.Commands = []interface{}{cmd}
+	se.Name = stepNameList
+
+	// enqueue our step!
+	return w.enq.EnqueueStep(ctx, w, se)
+}
+
+// resolve returns the profile names to install and remove for the
+// enrollment id with MDM URL parameters params and the profile info of
+// those names. A profile that is both installed and removed by different
+// baselines is removed. A baseline that references a profile missing from
+// profile storage is skipped.
+func (w *Workflow) resolve(ctx context.Context, logger log.Logger, id string, params map[string]string) (install, remove []string, infos map[string]profstorage.ProfileInfo, err error) {
+	groups, err := w.groupStore.RetrieveGroups(ctx, id)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("retrieving groups: %w", err)
+	}
+	baselines, err := w.store.RetrieveBaselines(ctx, nil)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("retrieving baselines: %w", err)
+	}
+
+	names := make([]string, 0, len(baselines))
+	for name := range baselines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	infos = make(map[string]profstorage.ProfileInfo)
+	installs := make(map[string]struct{})
+	removes := make(map[string]struct{})
+	for _, name := range names {
+		b := baselines[name]
+		if !b.Applies(groups, params) {
+			continue
+		}
+		bInfos, err := w.profStore.RetrieveProfileInfos(ctx, append(append([]string{}, b.Install...), b.Remove...))
+		if errors.Is(err, profstorage.ErrProfileNotFound) {
+			logger.Info(
+				logkeys.Message, "baseline references missing profile; skipping",
+				"baseline", name,
+				logkeys.Error, err,
+			)
+			continue
+		} else if err != nil {
+			return nil, nil, nil, fmt.Errorf("retrieving profile info for baseline %s: %w", name, err)
+		}
+		for profName, info := range bInfos {
+			infos[profName] = info
+		}
+		for _, profName := range b.Install {
+			installs[profName] = struct{}{}
+		}
+		for _, profName := range b.Remove {
+			removes[profName] = struct{}{}
+		}
+	}
+
+	for name := range removes {
+		if _, ok := installs[name]; ok {
+			logger.Info(
+				logkeys.Message, "profile both installed and removed by baselines; removing",
+				"name", name,
+			)
+			delete(installs, name)
+		}
+		remove = append(remove, name)
+	}
+	for name := range installs {
+		install = append(install, name)
+	}
+	sort.Strings(install)
+	sort.Strings(remove)
+	return
+}
+
+// listInstalled maps the identifiers of the profiles in items to their UUIDs.
+func listInstalled(items []mdmcommands.ProfileListItem) map[string]string {
+	installed := make(map[string]string)
+	for _, item := range items {
+		installed[item.PayloadIdentifier] = item.PayloadUUID
+	}
+	return installed
+}
+
+// storedInstalled maps the identifiers of the stored installed profiles
+// to their UUIDs. Profiles not installed by MDM are skipped to match the
+// managed-only ProfileList command we would otherwise send.
+func storedInstalled(profiles []invstorage.InstalledProfile) map[string]string {
+	installed := make(map[string]string)
+	for _, p := range profiles {
+		if p.Managed != nil && !*p.Managed {
+			continue
+		}
+		installed[p.Identifier] = p.UUID
+	}
+	return installed
+}
+
+// diff returns the profile names of install that are not installed (or
+// are installed with a different UUID) and the profile names of remove
+// that are installed according to installed (a map of profile
+// identifiers to UUIDs).
+func diff(infos map[string]profstorage.ProfileInfo, install, remove []string, installed map[string]string) (toInstall, toRemove []string) {
+	for _, name := range install {
+		info := infos[name]
+		if uuid, ok := installed[info.Identifier]; !ok || uuid != info.UUID {
+			toInstall = append(toInstall, name)
+		}
+	}
+	for _, name := range remove {
+		if _, ok := installed[infos[name].Identifier]; ok {
+			toRemove = append(toRemove, name)
+		}
+	}
+	return
+}
+
+// profileList returns the validated ProfileList command response of stepResult.
+func profileList(stepResult *workflow.StepResult) (*mdmcommands.ProfileListResponse, error) {
+	if len(stepResult.CommandResults) != 1 {
+		return nil, workflow.ErrStepResultCommandLenMismatch
+	}
+	profListResp, ok := stepResult.CommandResults[0].(*mdmcommands.ProfileListResponse)
+	if !ok {
+		return nil, fmt.Errorf("%w: not a profile list", workflow.ErrIncorrectCommandType)
+	}
+	if err := profListResp.Validate(); err != nil {
+		return nil, fmt.Errorf("validating profile list: %w", err)
+	}
+	return profListResp, nil
+}
+
+func (w *Workflow) listStepCompleted(ctx context.Context, stepResult *workflow.StepResult) error {
+	profListResp, err := profileList(stepResult)
+	if err != nil {
+		return err
+	}
+
+	logger := ctxlog.Logger(ctx, w.logger).With(
+		logkeys.InstanceID, stepResult.InstanceID,
+		logkeys.StepName, stepResult.Name,
+		logkeys.EnrollmentID, stepResult.ID,
+	)
+
+	if err = w.storeInstalled(ctx, logger, stepResult.ID, profListResp); err != nil {
+		return err
+	}
+
+	return w.reconcile(ctx, logger, stepResult.NewStepEnqueueing(), stepResult.Params, listInstalled(profListResp.ProfileList), stepResult.Finish)
+}
+
+// storeInstalled stores the installed profiles of id in profListResp
+// if we have installed profile storage.
+func (w *Workflow) storeInstalled(ctx context.Context, logger log.Logger, id string, profListResp *mdmcommands.ProfileListResponse) error {
+	if w.invStore == nil {
+		return nil
+	}
+	profiles := profile.NormalizeProfileList(profListResp.ProfileList, time.Now())
+	logger.Debug(
+		logkeys.Message, "storing installed profiles",
+		logkeys.GenericCount, len(profiles),
+	)
+	if err := w.invStore.StoreInstalledProfiles(ctx, id, profiles); err != nil {
+		return fmt.Errorf("storing installed profiles: %w", err)
+	}
+	return nil
+}
+
+// enqueueRefresh enqueues a ProfileList command to refresh the stored
+// installed profiles after the reconcile step of stepResult. Otherwise
+// later starts would reconcile against stale installed profiles.
+// The outcome of the reconcile step oc is carried to the refresh step.
+func (w *Workflow) enqueueRefresh(ctx context.Context, stepResult *workflow.StepResult, oc *outcomeContext) error {
+	cmd := mdmcommands.NewProfileListCommand(w.ider.ID())
+	managedOnly := true
+	cmd.Command.ManagedOnly = &managedOnly
+
+	se := stepResult.NewStepEnqueueing()
+	se.Commands = []interface{}{cmd}
+	se.Context = oc
+	se.Name = stepNameRefresh
+
+	return w.enq.EnqueueStep(ctx, w, se)
+}
+
+// refreshStepCompleted stores the installed profiles after reconciling
+// and finishes the instance with the outcome of the reconcile step.
+func (w *Workflow) refreshStepCompleted(ctx context.Context, stepResult *workflow.StepResult) error {
+	profListResp, err := profileList(stepResult)
+	if err != nil {
+		return err
+	}
+
+	logger := ctxlog.Logger(ctx, w.logger).With(
+		logkeys.InstanceID, stepResult.InstanceID,
+		logkeys.StepName, stepResult.Name,
+		logkeys.EnrollmentID, stepResult.ID,
+	)
+
+	if err = w.storeInstalled(ctx, logger, stepResult.ID, profListResp); err != nil {
+		return err
+	}
+
+	oc, ok := stepResult.Context.(*outcomeContext)
+	if !ok {
+		return workflow.ErrIncorrectContextType
+	}
+	stepResult.Finish(oc.Outcome, oc.Message)
+	return nil
+}
+
+// reconcile enqueues a step of se (for a single enrollment) that installs
+// and removes the profiles of the baselines assigned to the enrollment
+// that differ from installed (a map of profile identifiers to UUIDs).
+// No step is enqueued if the installed profiles match the baselines and
+// the instance is finished with finish instead.
+func (w *Workflow) reconcile(ctx context.Context, logger log.Logger, se *workflow.StepEnqueueing, params map[string]string, installed map[string]string, finish func(workflow.Outcome, string)) error {
+	id := se.IDs[0]
+	install, remove, infos, err := w.resolve(ctx, logger, id, params)
+	if err != nil {
+		return err
+	}
+	if len(install) < 1 && len(remove) < 1 {
+		logger.Debug(logkeys.Message, "no baselines assigned")
+		finish(workflow.OutcomeSucceeded, "no baselines assigned")
+		return nil
+	}
+
+	toInstall, toRemove := diff(infos, install, remove, installed)
+	if len(toInstall) < 1 && len(toRemove) < 1 {
+		logger.Debug(logkeys.Message, "profiles match baselines")
+		finish(workflow.OutcomeSucceeded, "profiles match baselines")
+		return nil
+	}
+
+	// get our raw profiles (only if we need to)
+	var raws map[string][]byte
+	if len(toInstall) > 0 {
+		raws, err = w.profStore.RetrieveRawProfiles(ctx, toInstall)
+		if err != nil {
+			return fmt.Errorf("retrieving raw profiles: %w", err)
+		}
+	}
+
+	se.Name = stepNameReconcile
+
+	for _, name := range toInstall {
+		raw, err := w.renderer.Render(ctx, id, params, raws[name])
+		if err != nil {
+			return fmt.Errorf("rendering profile: %s: %w", name, err)
+		}
+		cmd := mdmcommands.NewInstallProfileCommand(w.ider.ID())
+		cmd.Command.Payload = raw
+		se.Commands = append(se.Commands, cmd)
+	}
+	for _, name := range toRemove {
+		cmd := mdmcommands.NewRemoveProfileCommand(w.ider.ID())
+		cmd.Command.Identifier = infos[name].Identifier
+		se.Commands = append(se.Commands, cmd)
+	}
+
+	logger.Debug(
+		logkeys.Message, "enqueuing reconcile step",
+		"install_count", len(toInstall),
+		"remove_count", len(toRemove),
+	)
+
+	// enqueue our step!
+	return w.enq.EnqueueStep(ctx, w, se)
+}
+
+func (w *Workflow) StepCompleted(ctx context.Context, stepResult *workflow.StepResult) error {
+	switch stepResult.Name {
+	case stepNameList:
+		return w.listStepCompleted(ctx, stepResult)
+	case stepNameReconcile:
+		logger := ctxlog.Logger(ctx, w.logger).With(
+			logkeys.InstanceID, stepResult.InstanceID,
+			logkeys.StepName, stepResult.Name,
+			logkeys.EnrollmentID, stepResult.ID,
+		)
+		statuses := make(map[string]int)
+		var failed int
+		for _, resp := range stepResult.CommandResults {
+			genResper, ok := resp.(mdmcommands.GenericResponser)
+			if !ok {
+				continue
+			}
+			genResp := genResper.GetGenericResponse()
+			statuses[genResp.Status] += 1
+			if err := genResp.Validate(); err != nil {
+				failed++
+				logger.Info(
+					logkeys.Message, "validate MDM response",
+					logkeys.CommandUUID, genResp.CommandUUID,
+					logkeys.Error, err,
+				)
+			}
+		}
+		logs := []interface{}{logkeys.Message, "reconcile complete"}
+		for k, v := range statuses {
+			logs = append(logs, "count_"+strings.ToLower(k), v)
+		}
+		logger.Debug(logs...)
+		oc := &outcomeContext{
+			Outcome: workflow.OutcomeSucceeded,
+			Message: fmt.Sprintf("%d profile commands succeeded", len(stepResult.CommandResults)),
+		}
+		if failed > 0 {
+			oc.Outcome = workflow.OutcomeFailed
+			oc.Message = fmt.Sprintf("%d of %d profile commands failed", failed, len(stepResult.CommandResults))
+		}
+		if w.invStore != nil {
+			// the refresh step finishes the instance
+			return w.enqueueRefresh(ctx, stepResult, oc)
+		}
+		stepResult.Finish(oc.Outcome, oc.Message)
+		return nil
+	case stepNameRefresh:
+		return w.refreshStepCompleted(ctx, stepResult)
+	default:
+		return fmt.Errorf("%w: %s", workflow.ErrUnknownStepName, stepResult.Name)
+	}
+}
+
+func (w *Workflow) StepTimeout(_ context.Context, _ *workflow.StepResult) error {
+	return workflow.ErrTimeoutNotUsed
+}
+
+func (w *Workflow) Event(_ context.Context, _ *workflow.Event, _ string, _ *workflow.MDMContext) error {
+	return workflow.ErrEventsNotSupported
+}
@@ -0,0 +1,308 @@
+package baseline
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/jessepeterson/mdmcommands"
+	"github.com/micromdm/nanocmd/engine"
+	enginestorage "github.com/micromdm/nanocmd/engine/storage/inmem"
+	"github.com/micromdm/nanocmd/subsystem/baseline/storage"
+	"github.com/micromdm/nanocmd/subsystem/baseline/storage/inmem"
+	groupinmem "github.com/micromdm/nanocmd/subsystem/group/storage/inmem"
+	invstorage "github.com/micromdm/nanocmd/subsystem/inventory/storage"
+	invinmem "github.com/micromdm/nanocmd/subsystem/inventory/storage/inmem"
+	profstorage "github.com/micromdm/nanocmd/subsystem/profile/storage"
+	profinmem "github.com/micromdm/nanocmd/subsystem/profile/storage/inmem"
+	"github.com/micromdm/nanocmd/utils/uuid"
+	"github.com/micromdm/nanocmd/workflow"
+	"github.com/micromdm/nanocmd/workflow/test"
+)
+
+// newTestWorkflow creates a baseline workflow with test baselines,
+// groups, and profiles registered with a new engine. The enrollment id
+// is a member of the staff group.
+func newTestWorkflow(t *testing.T, ctx context.Context, id string, opts ...Option) (*engine.Engine, *enginestorage.InMem, *test.CollectingStepEnqueur, *Workflow) {
+	t.Helper()
+
+	es := enginestorage.New()
+	e := engine.New(es, &test.NullEnqueuer{})
+
+	c := test.NewCollectingStepEnqueur(e)
+
+	s := inmem.New()
+	err := s.StoreBaseline(ctx, "staff", &storage.Baseline{
+		Install: []string{"wifi", "vpn", "legacy"},
+		Remove:  []string{"legacy", "unused"},
+		Groups:  []string{"staff"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.StoreBaseline(ctx, "kiosk", &storage.Baseline{
+		Install: []string{"kiosk"},
+		Groups:  []string{"kiosk"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// references a profile that doesn't exist and should be skipped
+	err = s.StoreBaseline(ctx, "broken", &storage.Baseline{
+		Install: []string{"missing"},
+		Remove:  []string{"wifi"},
+		Groups:  []string{"staff"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g := groupinmem.New()
+	if err = g.StoreGroupMembers(ctx, "staff", []string{id}); err != nil {
+		t.Fatal(err)
+	}
+
+	p := profinmem.New()
+	for name, info := range map[string]profstorage.ProfileInfo{
+		"wifi":   {Identifier: "com.example.wifi", UUID: "5A2D2B7C-8E2E-4A0B-9C44-7E1D5B0E6C11"},
+		"vpn":    {Identifier: "com.example.vpn", UUID: "9B1E3C5D-7F2A-4B6C-8D0E-2F4A6B8C0D1E"},
+		"legacy": {Identifier: "com.example.legacy", UUID: "0F3C7E1A-2B4D-4C6E-8A9B-1D2E3F4A5B6C"},
+		"unused": {Identifier: "com.example.unused", UUID: "3E5F7A9B-1C2D-4E6F-8A0B-4C6D8E0F2A3B"},
+		"kiosk":  {Identifier: "com.example.kiosk", UUID: "7D9E1F3A-5B6C-4D8E-9F0A-6B8C0D2E4F5A"},
+	} {
+		if err = p.StoreProfile(ctx, name, info, []byte(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w, err := New(c, s, p, g, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = e.RegisterWorkflow(w); err != nil {
+		t.Fatal(err)
+	}
+	return e, es, c, w
+}
+
+func TestWorkflow(t *testing.T) {
+	ctx := context.Background()
+
+	// enrollment id
+	id := "AAABBBCCC111222333"
+
+	e, _, c, w := newTestWorkflow(t, ctx, id)
+	w.ider = uuid.NewStaticIDs(
+		// note: order is important and depends on values in plist testdata
+		"BASELINE-LIST-01",
+		"BASELINE-INSTALL-01",
+		"BASELINE-REMOVE-01",
+	)
+
+	_, err := e.StartWorkflow(ctx, w.Name(), nil, []string{id}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = test.SendCommandEvent(ctx, e, "testdata/proflist.plist", id, "BASELINE-LIST-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := c.Steps()
+	if want, have := 2, len(steps); want != have {
+		t.Fatalf("wanted: %d; have: %d", want, have)
+	}
+
+	// wifi is already installed, kiosk is not assigned, legacy removal
+	// wins over its install, and unused is not installed.
+	cmds := steps[1].StepEnqueueing.Commands
+	if want, have := 2, len(cmds); want != have {
+		t.Fatalf("wanted: %d; have: %d", want, have)
+	}
+
+	installCmd, ok := cmds[0].(*mdmcommands.InstallProfileCommand)
+	if !ok {
+		t.Fatal("incorrect command type")
+	}
+	if want, have := "vpn", string(installCmd.Command.Payload); want != have {
+		t.Errorf("wanted: %s; have: %s", want, have)
+	}
+
+	removeCmd, ok := cmds[1].(*mdmcommands.RemoveProfileCommand)
+	if !ok {
+		t.Fatal("incorrect command type")
+	}
+	if want, have := "com.example.legacy", removeCmd.Command.Identifier; want != have {
+		t.Errorf("wanted: %s; have: %s", want, have)
+	}
+}
+
+func TestWorkflowInstalledProfiles(t *testing.T) {
+	ctx := context.Background()
+
+	// enrollment ids
+	id := "AAABBBCCC111222333"
+	noInvID := "DDDEEEFFF444555666"
+
+	inv := invinmem.New()
+	managed, unmanaged := true, false
+	err := inv.StoreInstalledProfiles(ctx, id, []invstorage.InstalledProfile{
+		{Identifier: "com.example.wifi", UUID: "5A2D2B7C-8E2E-4A0B-9C44-7E1D5B0E6C11", Managed: &managed},
+		{Identifier: "com.example.legacy", UUID: "0F3C7E1A-2B4D-4C6E-8A9B-1D2E3F4A5B6C"},
+		// not installed by MDM so not considered installed
+		{Identifier: "com.example.vpn", UUID: "9B1E3C5D-7F2A-4B6C-8D0E-2F4A6B8C0D1E", Managed: &unmanaged},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, _, c, w := newTestWorkflow(t, ctx, id, WithInstalledProfileStorage(inv))
+	w.ider = uuid.NewStaticIDs(
+		"BASELINE-INSTALL-01",
+		"BASELINE-REMOVE-01",
+		"BASELINE-LIST-01",
+	)
+
+	_, err = e.StartWorkflow(ctx, w.Name(), nil, []string{id, noInvID}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := c.Steps()
+	if want, have := 2, len(steps); want != have {
+		t.Fatalf("wanted: %d; have: %d", want, have)
+	}
+
+	// the enrollment with stored installed profiles is reconciled
+	// without sending a ProfileList command.
+	if want, have := []string{id}, steps[0].IDs; !reflect.DeepEqual(want, have) {
+		t.Errorf("wanted: %v; have: %v", want, have)
+	}
+	if want, have := stepNameReconcile, steps[0].Name; want != have {
+		t.Errorf("wanted: %s; have: %s", want, have)
+	}
+	cmds := steps[0].Commands
+	if want, have := 2, len(cmds); want != have {
+		t.Fatalf("wanted: %d; have: %d", want, have)
+	}
+	installCmd, ok := cmds[0].(*mdmcommands.InstallProfileCommand)
+	if !ok {
+		t.Fatal("incorrect command type")
+	}
+	if want, have := "vpn", string(installCmd.Command.Payload); want != have {
+		t.Errorf("wanted: %s; have: %s", want, have)
+	}
+	removeCmd, ok := cmds[1].(*mdmcommands.RemoveProfileCommand)
+	if !ok {
+		t.Fatal("incorrect command type")
+	}
+	if want, have := "com.example.legacy", removeCmd.Command.Identifier; want != have {
+		t.Errorf("wanted: %s; have: %s", want, have)
+	}
+
+	// the enrollment without stored installed profiles is sent a ProfileList command.
+	if want, have := []string{noInvID}, steps[1].IDs; !reflect.DeepEqual(want, have) {
+		t.Errorf("wanted: %v; have: %v", want, have)
+	}
+	if want, have := stepNameList, steps[1].Name; want != have {
+		t.Errorf("wanted: %s; have: %s", want, have)
+	}
+	if _, ok := steps[1].Commands[0].(*mdmcommands.ProfileListCommand); !ok {
+		t.Error("incorrect command type")
+	}
+}
+
+func TestWorkflowRefreshInstalledProfiles(t *testing.T) {
+	ctx := context.Background()
+
+	// enrollment id
+	id := "AAABBBCCC111222333"
+
+	inv := invinmem.New()
+	err := inv.StoreInstalledProfiles(ctx, id, []invstorage.InstalledProfile{
+		{Identifier: "com.example.wifi", UUID: "5A2D2B7C-8E2E-4A0B-9C44-7E1D5B0E6C11"},
+		{Identifier: "com.example.legacy", UUID: "0F3C7E1A-2B4D-4C6E-8A9B-1D2E3F4A5B6C"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, es, c, w := newTestWorkflow(t, ctx, id, WithInstalledProfileStorage(inv))
+	w.ider = uuid.NewStaticIDs(
+		// note: order is important and depends on values in plist testdata
+		"BASELINE-INSTALL-01",
+		"BASELINE-REMOVE-01",
+		"BASELINE-REFRESH-01",
+	)
+
+	instanceID, err := e.StartWorkflow(ctx, w.Name(), nil, []string{id}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, resp := range []struct{ path, uuid string }{
+		{"testdata/install.plist", "BASELINE-INSTALL-01"},
+		{"testdata/remove.plist", "BASELINE-REMOVE-01"},
+	} {
+		if err = test.SendCommandEvent(ctx, e, resp.path, id, resp.uuid); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the installed profiles are listed again after reconciling
+	steps := c.Steps()
+	if want, have := 2, len(steps); want != have {
+		t.Fatalf("wanted: %d; have: %d", want, have)
+	}
+	if want, have := stepNameRefresh, steps[1].Name; want != have {
+		t.Errorf("wanted: %s; have: %s", want, have)
+	}
+
+	err = test.SendCommandEvent(ctx, e, "testdata/refresh.plist", id, "BASELINE-REFRESH-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idProfiles, err := inv.RetrieveInstalledProfiles(ctx, []string{id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 2, len(idProfiles[id]); want != have {
+		t.Fatalf("wanted: %d; have: %d", want, have)
+	}
+	if want, have := "com.example.vpn", idProfiles[id][1].Identifier; want != have {
+		t.Errorf("wanted: %s; have: %s", want, have)
+	}
+
+	// the refresh step finishes the instance with the outcome of the reconcile step
+	testOutcome(t, ctx, es, instanceID, workflow.OutcomeSucceeded, "2 profile commands succeeded")
+
+	// the installed profiles now match so another start enqueues nothing
+	instanceID, err = e.StartWorkflow(ctx, w.Name(), nil, []string{id}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 2, len(c.Steps()); want != have {
+		t.Errorf("wanted: %d; have: %d", want, have)
+	}
+	testOutcome(t, ctx, es, instanceID, workflow.OutcomeSucceeded, "profiles match baselines")
+}
+
+// testOutcome checks the single outcome of instanceID in s.
+func testOutcome(t *testing.T, ctx context.Context, s *enginestorage.InMem, instanceID string, outcome workflow.Outcome, message string) {
+	t.Helper()
+	outcomes, err := s.RetrieveOutcomes(ctx, instanceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 1, len(outcomes); want != have {
+		t.Fatalf("wanted: %d; have: %d", want, have)
+	}
+	if want, have := outcome, outcomes[0].Outcome; want != have {
+		t.Errorf("wanted: %s; have: %s", want, have)
+	}
+	if want, have := message, outcomes[0].Message; want != have {
+		t.Errorf("wanted: %s; have: %s", want, have)
+	}
+}
@@ -0,0 +1,146 @@
+// Package certinv implements a NanoCMD Workflow that collects the installed certificates of an enrollment.
+package certinv
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/micromdm/nanocmd/logkeys"
+	"github.com/micromdm/nanocmd/subsystem/inventory/storage"
+	"github.com/micromdm/nanocmd/utils/uuid"
+	"github.com/micromdm/nanocmd/workflow"
+
+	"github.com/jessepeterson/mdmcommands"
+	"github.com/micromdm/nanolib/log"
+	"github.com/micromdm/nanolib/log/ctxlog"
+)
+
+const WorkflowName = "io.micromdm.wf.certinv.v1"
+
+var WorkflowConfig = &workflow.Config{
+	// we want all CertificateList commands, regardless of whether this workflow sent them.
+	AllCommandResponseRequestTypes: []string{"CertificateList"},
+}
+
+// Workflow collects the installed certificates of enrollments and stores them in inventory storage.
+type Workflow struct {
+	enq    workflow.StepEnqueuer
+	ider   uuid.IDer
+	store  storage.CertStorage
+	logger log.Logger
+}
+
+type Option func(*Workflow)
+
+// WithLogger configures logger on the workflow.
+func WithLogger(logger log.Logger) Option {
+	return func(w *Workflow) {
+		w.logger = logger
+	}
+}
+
+func New(enq workflow.StepEnqueuer, store storage.CertStorage, opts ...Option) (*Workflow, error) {
+	w := &Workflow{
+		enq:    enq,
+		ider:   uuid.NewUUID(),
+		store:  store,
+		logger: log.NopLogger,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	w.logger = w.logger.With(logkeys.WorkflowName, w.Name())
+	return w, nil
+}
+
+func (w *Workflow) Name() string {
+	return WorkflowName
+}
+
+func (w *Workflow) Config() *workflow.Config {
+	return WorkflowConfig
+}
+
+func (w *Workflow) NewContextValue(_ string) workflow.ContextMarshaler {
+	return nil
+}
+
+func (w *Workflow) Start(ctx context.Context, step *workflow.StepStart) error {
+	// build a CertificateList command for all (not just managed) certificates
+	cmd := mdmcommands.NewCertificateListCommand(w.ider.ID())
+
+	// assemble our StepEnqueuing
+	se := step.NewStepEnqueueing()
+	se.Commands = []interface{}{cmd}
+
+	// enqueue our step!
+	return w.enq.EnqueueStep(ctx, w, se)
+}
+
+func (w *Workflow) StepCompleted(_ context.Context, stepResult *workflow.StepResult) error {
+	if len(stepResult.CommandResults) != 1 {
+		return workflow.ErrStepResultCommandLenMismatch
+	}
+	// we process the CertificateList response (including our own) as
+	// an event. so there is nothing to do here.
+	return nil
+}
+
+func (w *Workflow) StepTimeout(_ context.Context, _ *workflow.StepResult) error {
+	return workflow.ErrTimeoutNotUsed
+}
+
+// normalize converts CertificateList items into inventory certificates.
+// Certificates that fail to parse keep their common name and hash.
+func normalize(items []mdmcommands.CertificateListItem, modified time.Time) ([]storage.Certificate, error) {
+	certs := make([]storage.Certificate, 0, len(items))
+	var parseErr error
+	for _, item := range items {
+		hash := sha256.Sum256(item.Data)
+		cert := storage.Certificate{
+			CommonName: item.CommonName,
+			IsIdentity: item.IsIdentity,
+			SHA256:     hex.EncodeToString(hash[:]),
+			Modified:   modified,
+		}
+		crt, err := x509.ParseCertificate(item.Data)
+		if err != nil {
+			parseErr = fmt.Errorf("parse certificate: %s: %w", item.CommonName, err)
+		} else {
+			cert.Subject = crt.Subject.String()
+			cert.Issuer = crt.Issuer.String()
+			cert.NotAfter = crt.NotAfter
+		}
+		certs = append(certs, cert)
+	}
+	return certs, parseErr
+}
+
+func (w *Workflow) Event(ctx context.Context, e *workflow.Event, id string, _ *workflow.MDMContext) error {
+	switch evData := e.EventData.(type) {
+	case *mdmcommands.CertificateListResponse:
+		if err := evData.Validate(); err != nil {
+			return fmt.Errorf("certificate list response: %w", err)
+		}
+
+		logger := ctxlog.Logger(ctx, w.logger).With(logkeys.EnrollmentID, id)
+
+		certs, err := normalize(evData.CertificateList, time.Now())
+		if err != nil {
+			// we still store the certificates we could parse
+			logger.Info(logkeys.Message, "normalizing certificates", logkeys.Error, err)
+		}
+
+		logger.Debug(
+			logkeys.Message, "storing certificates",
+			logkeys.GenericCount, len(certs),
+		)
+		return w.store.StoreCerts(ctx, id, certs)
+	default:
+		return fmt.Errorf("unknown event data type for event: %s", e.EventFlag)
+	}
+}
@@ -0,0 +1,79 @@
+package certinv
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/micromdm/nanocmd/engine"
+	enginestorage "github.com/micromdm/nanocmd/engine/storage/inmem"
+	"github.com/micromdm/nanocmd/subsystem/inventory/storage"
+	"github.com/micromdm/nanocmd/subsystem/inventory/storage/inmem"
+	"github.com/micromdm/nanocmd/utils/uuid"
+	"github.com/micromdm/nanocmd/workflow/test"
+)
+
+func TestWorkflow(t *testing.T) {
+	e := engine.New(enginestorage.New(), &test.NullEnqueuer{})
+
+	s := inmem.New()
+
+	w, err := New(e, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// note: order is important and depends on values in plist testdata
+	w.ider = uuid.NewStaticIDs("CERT-LIST-01")
+
+	ctx := context.Background()
+
+	// enrollment id
+	id := "6362F867-FFF2-4EA6-905C-3C796DF4EF68"
+
+	e.RegisterWorkflow(w)
+
+	_, err = e.StartWorkflow(ctx, w.Name(), nil, []string{id}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = test.SendCommandEvent(ctx, e, "testdata/certlist.plist", id, "CERT-LIST-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idCerts, err := s.RetrieveCerts(ctx, []string{id})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certs := idCerts[id]
+	if want, have := 1, len(certs); want != have {
+		t.Fatalf("wanted: %d; have: %d", want, have)
+	}
+
+	if want, have := true, certs[0].IsIdentity; want != have {
+		t.Errorf("wanted: %v; have: %v", want, have)
+	}
+
+	if want, have := "CN=ca,OU=SCEP CA,O=scep,C=US", certs[0].Issuer; want != have {
+		t.Errorf("wanted: %v; have: %v", want, have)
+	}
+
+	if certs[0].NotAfter.IsZero() {
+		t.Error("expected NotAfter")
+	}
+
+	if want, have := 64, len(certs[0].SHA256); want != have {
+		t.Errorf("wanted: %d; have: %d", want, have)
+	}
+
+	// test data certificate expires in 2034
+	expiring, err := s.SearchCerts(ctx, &storage.CertSearchOptions{ExpiresBefore: time.Date(2035, 1, 1, 0, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := expiring[id]; !ok {
+		t.Error("expected enrollment in expiring certificates")
+	}
+}
@@ -142,6 +142,7 @@ func (w *Workflow) certListStepCompleted(ctx context.Context, stepResult *workfl
 			// checkCriteria has determined this certificate need not be replaced.
 			logger.Debug(logkeys.Message, fmt.Sprintf("not installing profile: %s", reason))
 			// so simply exit gracefully.
+			stepResult.Finish(workflow.OutcomeSucceeded, "certificate not replaced: "+reason)
 			return nil
 		}
 		logger.Debug(logkeys.Message, fmt.Sprintf("installing profile: %s", reason))
@@ -181,9 +182,11 @@ func (w *Workflow) profileStepCompleted(_ context.Context, stepResult *workflow.
 		return workflow.ErrIncorrectCommandType
 	}
 	if err := response.Validate(); err != nil {
+		stepResult.Finish(workflow.OutcomeFailed, "install profile command failed")
 		return fmt.Errorf("validating install profile response: %w", err)
 	}
 
+	stepResult.Finish(workflow.OutcomeSucceeded, "certificate profile installed")
 	return nil
 }
 
@@ -38,7 +38,8 @@ func WithLogger(logger log.Logger) Option {
 }
 
 // WithName sets the workflow name. If not set a default will be used.
-// This can be useful to separate an "exclusivity domain" for the same workflow.
+// This can be useful to separate the exclusivity of the same workflow
+// (see also [workflow.Config] ExclusivityDomain).
 func WithName(name string) Option {
 	return func(w *Workflow) error {
 		w.name = name
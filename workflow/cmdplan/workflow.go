@@ -11,6 +11,7 @@ import (
 	"github.com/micromdm/nanocmd/logkeys"
 	"github.com/micromdm/nanocmd/mdm"
 	"github.com/micromdm/nanocmd/subsystem/cmdplan/storage"
+	"github.com/micromdm/nanocmd/subsystem/profile/render"
 	profstorage "github.com/micromdm/nanocmd/subsystem/profile/storage"
 	"github.com/micromdm/nanocmd/utils/uuid"
 	"github.com/micromdm/nanocmd/workflow"
@@ -28,6 +29,7 @@ type Workflow struct {
 	logger    log.Logger
 	store     storage.ReadStorage
 	profStore profstorage.ReadStorage
+	renderer  *render.Renderer
 }
 
 type Option func(*Workflow)
@@ -38,6 +40,14 @@ func WithLogger(logger log.Logger) Option {
 	}
 }
 
+// WithRenderer renders profile templates with r when installing profiles.
+// By default only templates without inventory variables can be rendered.
+func WithRenderer(r *render.Renderer) Option {
+	return func(w *Workflow) {
+		w.renderer = r
+	}
+}
+
 func New(enq workflow.StepEnqueuer, store storage.ReadStorage, profStorage profstorage.ReadStorage, opts ...Option) (*Workflow, error) {
 	w := &Workflow{
 		enq:       enq,
@@ -45,6 +55,7 @@ func New(enq workflow.StepEnqueuer, store storage.ReadStorage, profStorage profs
 		logger:    log.NopLogger,
 		store:     store,
 		profStore: profStorage,
+		renderer:  render.New(nil),
 	}
 	for _, opt := range opts {
 		opt(w)
@@ -57,8 +68,10 @@ func (w *Workflow) Name() string {
 	return WorkflowName
 }
 
+// Config queues the starts of command plans for enrollments that are
+// already running a command plan.
 func (w *Workflow) Config() *workflow.Config {
-	return nil
+	return &workflow.Config{Exclusivity: workflow.ExclusiveQueued}
 }
 
 func (w *Workflow) NewContextValue(name string) workflow.ContextMarshaler {
@@ -66,7 +79,7 @@ func (w *Workflow) NewContextValue(name string) workflow.ContextMarshaler {
 }
 
 // TODO: create a map of command UUID to more useful types for logging
-func (w *Workflow) commandsFromCMDPlan(ctx context.Context, cmdPlan *storage.CMDPlan, name string, e *workflow.Event) ([]interface{}, error) {
+func (w *Workflow) commandsFromCMDPlan(ctx context.Context, cmdPlan *storage.CMDPlan, rawProfiles map[string][]byte, name string, e *workflow.Event, id string, params map[string]string) ([]interface{}, error) {
 	// bail if invalid
 	if cmdPlan == nil {
 		return nil, errors.New("invalid cmdplan")
@@ -74,24 +87,21 @@ func (w *Workflow) commandsFromCMDPlan(ctx context.Context, cmdPlan *storage.CMD
 
 	var commands []interface{}
 
-	if len(cmdPlan.ProfileNames) > 0 {
-		// get our raw profiles
-		rawProfiles, err := w.profStore.RetrieveRawProfiles(ctx, cmdPlan.ProfileNames)
-		if err != nil {
-			return nil, fmt.Errorf("retrieving profiles: %w", err)
+	// build the profile MDM commands
+	for _, name := range cmdPlan.ProfileNames {
+		rawProfile, ok := rawProfiles[name]
+		if !ok {
+			return commands, fmt.Errorf("raw profile not found: %s", name)
 		}
 
-		// build the profile MDM commands
-		for _, name := range cmdPlan.ProfileNames {
-			rawProfile, ok := rawProfiles[name]
-			if !ok {
-				return commands, fmt.Errorf("raw profile not found: %s", name)
-			}
-
-			c := mdmcommands.NewInstallProfileCommand(w.ider.ID())
-			c.Command.Payload = rawProfile
-			commands = append(commands, c)
+		rawProfile, err := w.renderer.Render(ctx, id, params, rawProfile)
+		if err != nil {
+			return commands, fmt.Errorf("rendering profile: %s: %w", name, err)
 		}
+
+		c := mdmcommands.NewInstallProfileCommand(w.ider.ID())
+		c.Command.Payload = rawProfile
+		commands = append(commands, c)
 	}
 
 	// build the install application MDM commands
@@ -168,21 +178,54 @@ func (w *Workflow) Start(ctx context.Context, step *workflow.StepStart) error {
 		return fmt.Errorf("retrieving cmdplan: %w", err)
 	}
 
-	// gather commands from cmdplan
-	commands, err := w.commandsFromCMDPlan(ctx, cmdplan, name, step.Event)
-	if err != nil {
-		return fmt.Errorf("creating commands from cmdplan: %w", err)
+	// get our raw profiles
+	var rawProfiles map[string][]byte
+	if len(cmdplan.ProfileNames) > 0 {
+		rawProfiles, err = w.profStore.RetrieveRawProfiles(ctx, cmdplan.ProfileNames)
+		if err != nil {
+			return fmt.Errorf("retrieving profiles: %w", err)
+		}
 	}
-	if len(commands) < 1 {
-		return errors.New("no commands to queue")
+
+	// profile templates are rendered per-enrollment so we need to
+	// enqueue a step for each enrollment ID.
+	idsList := [][]string{step.IDs}
+	for _, raw := range rawProfiles {
+		if render.IsTemplate(raw) && len(step.IDs) > 1 {
+			idsList = nil
+			for _, id := range step.IDs {
+				idsList = append(idsList, []string{id})
+			}
+			break
+		}
 	}
 
-	// assemble our StepEnqueuing
-	se := step.NewStepEnqueueing()
-	se.Commands = commands // assign all the commands to the step
+	for _, ids := range idsList {
+		var id string
+		if len(ids) == 1 {
+			id = ids[0]
+		}
+
+		// gather commands from cmdplan
+		commands, err := w.commandsFromCMDPlan(ctx, cmdplan, rawProfiles, name, step.Event, id, step.Params)
+		if err != nil {
+			return fmt.Errorf("creating commands from cmdplan: %w", err)
+		}
+		if len(commands) < 1 {
+			return errors.New("no commands to queue")
+		}
+
+		// assemble our StepEnqueuing
+		se := step.NewStepEnqueueing()
+		se.IDs = ids
+		se.Commands = commands // assign all the commands to the step
 
-	// enqueue our step!
-	return w.enq.EnqueueStep(ctx, w, se)
+		// enqueue our step!
+		if err = w.enq.EnqueueStep(ctx, w, se); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (w *Workflow) StepCompleted(ctx context.Context, stepResult *workflow.StepResult) error {
@@ -190,6 +233,7 @@ func (w *Workflow) StepCompleted(ctx context.Context, stepResult *workflow.StepR
 	// TODO2: implement a map struct so we can log even better errors (i.e. which specific profile, etc.)
 	logger := ctxlog.Logger(ctx, w.logger).With(logkeys.InstanceID, stepResult.InstanceID)
 	statuses := make(map[string]int)
+	var failed int
 	for _, resp := range stepResult.CommandResults {
 		genResper, ok := resp.(mdmcommands.GenericResponser)
 		if !ok {
@@ -199,6 +243,7 @@ func (w *Workflow) StepCompleted(ctx context.Context, stepResult *workflow.StepR
 		statuses[genResp.Status] += 1
 		// TODO: log the association from command UUID to command details in context struct
 		if err := genResp.Validate(); err != nil {
+			failed++
 			logger.Info(
 				logkeys.Message, "validate MDM response",
 				logkeys.CommandUUID, genResp.CommandUUID,
@@ -211,6 +256,11 @@ func (w *Workflow) StepCompleted(ctx context.Context, stepResult *workflow.StepR
 		logs = append(logs, "count_"+strings.ToLower(k), v)
 	}
 	logger.Debug(logs...)
+	if failed > 0 {
+		stepResult.Finish(workflow.OutcomeFailed, fmt.Sprintf("%d of %d commands failed", failed, len(stepResult.CommandResults)))
+	} else {
+		stepResult.Finish(workflow.OutcomeSucceeded, fmt.Sprintf("%d commands succeeded", len(stepResult.CommandResults)))
+	}
 	return nil
 }
 
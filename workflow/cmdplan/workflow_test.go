@@ -1,6 +1,22 @@
 package cmdplan
 
-import "testing"
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/jessepeterson/mdmcommands"
+	"github.com/micromdm/nanocmd/engine"
+	enginestorage "github.com/micromdm/nanocmd/engine/storage/inmem"
+	"github.com/micromdm/nanocmd/subsystem/cmdplan/storage"
+	"github.com/micromdm/nanocmd/subsystem/cmdplan/storage/inmem"
+	invstorage "github.com/micromdm/nanocmd/subsystem/inventory/storage"
+	invinmem "github.com/micromdm/nanocmd/subsystem/inventory/storage/inmem"
+	"github.com/micromdm/nanocmd/subsystem/profile/render"
+	profstorage "github.com/micromdm/nanocmd/subsystem/profile/storage"
+	profinmem "github.com/micromdm/nanocmd/subsystem/profile/storage/inmem"
+	"github.com/micromdm/nanocmd/workflow/test"
+)
 
 func TestExpandParam(t *testing.T) {
 	for _, test := range []struct {
@@ -22,3 +38,61 @@ func TestExpandParam(t *testing.T) {
 		}
 	}
 }
+
+func TestTemplateStart(t *testing.T) {
+	ctx := context.Background()
+
+	e := engine.New(enginestorage.New(), &test.NullEnqueuer{})
+
+	c := test.NewCollectingStepEnqueur(e)
+
+	s := inmem.New()
+	if err := s.StoreCMDPlan(ctx, "plan", &storage.CMDPlan{ProfileNames: []string{"tmpl"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	p := profinmem.New()
+	err := p.StoreProfile(ctx, "tmpl", profstorage.ProfileInfo{}, []byte("<plist>{{serial_number}}</plist>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inv := invinmem.New()
+	ids := []string{"AAA111", "BBB222"}
+	for _, id := range ids {
+		if err = inv.StoreInventoryValues(ctx, id, invstorage.Values{invstorage.KeySerialNumber: "S" + id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w, err := New(c, s, p, WithRenderer(render.New(inv)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e.RegisterWorkflow(w)
+
+	_, err = e.StartWorkflow(ctx, w.Name(), []byte("plan"), ids, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// templates are rendered per-enrollment, so a step per ID
+	steps := c.Steps()
+	if want, have := 2, len(steps); want != have {
+		t.Fatalf("wanted: %d; have: %d", want, have)
+	}
+
+	for i, step := range steps {
+		if want, have := []string{ids[i]}, step.StepEnqueueing.IDs; !reflect.DeepEqual(want, have) {
+			t.Errorf("wanted: %v; have: %v", want, have)
+		}
+		cmd, ok := step.StepEnqueueing.Commands[0].(*mdmcommands.InstallProfileCommand)
+		if !ok {
+			t.Fatal("incorrect command type")
+		}
+		if want, have := "<plist>S"+ids[i]+"</plist>", string(cmd.Command.Payload); want != have {
+			t.Errorf("wanted: %s; have: %s", want, have)
+		}
+	}
+}
@@ -16,9 +16,18 @@ const (
 	// Workflow can run simultaneous instances for an enrollment ID.
 	MultipleSimultaneous
 
+	// Workflow can only run one instance for an enrollment ID. If an
+	// instance is already running then the start is queued and started
+	// once the running instance has no more pending steps.
+	ExclusiveQueued
+
 	maxExclusivity
 )
 
+// DefaultMaxQueued is the default maximum number of queued starts per
+// enrollment ID for workflows with ExclusiveQueued exclusivity.
+const DefaultMaxQueued = 5
+
 func (we Exclusivity) Valid() bool {
 	return we < maxExclusivity
 }
@@ -34,6 +43,20 @@ type Config struct {
 	// defines the workflow exclusivity style
 	Exclusivity
 
+	// workflows with the same (non-empty) exclusivity domain share
+	// exclusivity: a workflow is not started (or is queued) for an
+	// enrollment ID if any workflow in its domain is running for it.
+	// if empty then the workflow is only exclusive with itself.
+	// workflows with MultipleSimultaneous exclusivity can not have an
+	// exclusivity domain: the engine refuses to register them.
+	ExclusivityDomain string
+
+	// maximum number of queued starts per enrollment ID when using
+	// ExclusiveQueued. starts with the same context as an already
+	// queued start are coalesced into it. if not specified then the
+	// engine's maximum is used (DefaultMaxQueued by default).
+	MaxQueued int
+
 	// workflows have the option to receive command responses from
 	// any MDM command request type that the engine enqueues (i.e. from
 	// other workflows) — not just the commands that this workflow
@@ -44,4 +67,14 @@ type Config struct {
 	// event subscriptions. this workflow will get called every time
 	// these events happen. use bitwise OR to specify multiple events.
 	Events EventFlag
+
+	// only send the workflow's steps inside the maintenance windows of
+	// the enrollments. intended for disruptive commands. the engine
+	// delays (adjusts the NotUntil of) steps outside of the windows.
+	MaintenanceWindow bool
+
+	// starts of the workflow from the API require approval by a
+	// different API principal before the engine starts the workflow.
+	// intended for destructive workflows.
+	RequireApproval bool
 }
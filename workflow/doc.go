@@ -45,11 +45,36 @@ enqueued. Steps are intended to be sequential for an enrollment ID —
 that is a workflow's step completion handler should only enqueue one
 step at a time (or none, if the workflow is finished).
 
+A step can also be a timer step: a step without any MDM commands but
+with a NotUntil time. No MDM commands are sent. Instead the workflow is
+called back with the step's name and context after the NotUntil time
+as if the step had completed. Timer steps are fired by the engine
+worker so they are only as precise as the worker interval.
+
+Similarly a step can be an event step: a step without any MDM commands
+but with a WaitEvent check-in event (Authenticate, TokenUpdate,
+Enrollment, or CheckOut). The workflow is called back as if the step
+had completed when that check-in event is seen for the step's
+enrollment ID. The event is provided in the StepResult. If the step has
+a Timeout and the event is not seen before then the step times out
+instead.
+
 Steps are identified by name. There is no convention for these names as
 they are workflow specific but they should be human readable as they
 will likely be logged and keyed on. It is intended workflows will
 identify specific step completions by the name of the step.
 
+# Outcomes
+
+When a workflow is finished for an enrollment ID it should report the
+final outcome and a short summary message by calling Finish on the
+StepResult handed to its step completion or timeout handler. The engine
+persists the outcome per instance and enrollment ID and uses it to start
+any follow-up workflows. If a workflow does not call Finish then the
+engine infers the outcome once no more steps are outstanding for the
+enrollment ID: timed out, failed if the handler returned an error, or
+otherwise succeeded.
+
 # Context
 
 When you enqueue a step you can associate a context value with it. This
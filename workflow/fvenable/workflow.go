@@ -22,6 +22,11 @@ import (
 
 const WorkflowName = "io.micromdm.wf.fvenable.v1"
 
+// ExclusivityDomain is the exclusivity domain of the FileVault workflows.
+// It is shared with the fvrotate workflow so that FileVault is not
+// enabled and rotated at the same time for an enrollment.
+const ExclusivityDomain = "filevault"
+
 type Workflow struct {
 	enq       workflow.StepEnqueuer
 	ider      uuid.IDer
@@ -69,7 +74,7 @@ func (w *Workflow) Name() string {
 }
 
 func (w *Workflow) Config() *workflow.Config {
-	return nil
+	return &workflow.Config{ExclusivityDomain: ExclusivityDomain}
 }
 
 func (w *Workflow) NewContextValue(name string) workflow.ContextMarshaler {
@@ -190,6 +195,7 @@ func (w *Workflow) pollStepCompleted(ctx context.Context, logger log.Logger, ste
 		return errors.New("invalid context value type")
 	}
 	if *ctxVal < 0 {
+		stepResult.Finish(workflow.OutcomeFailed, "PRK not escrowed after maximum polling")
 		return errors.New("maximum poll counter reached, ending workflow")
 	}
 
@@ -217,6 +223,7 @@ func (w *Workflow) pollStepCompleted(ctx context.Context, logger log.Logger, ste
 		logger.Debug(
 			logkeys.Message, "escrowed PRK",
 		)
+		stepResult.Finish(workflow.OutcomeSucceeded, "FileVault enabled and PRK escrowed")
 		return nil
 	}
 
@@ -10,6 +10,7 @@ import (
 	"github.com/micromdm/nanocmd/subsystem/filevault/storage"
 	"github.com/micromdm/nanocmd/utils/uuid"
 	"github.com/micromdm/nanocmd/workflow"
+	"github.com/micromdm/nanocmd/workflow/fvenable"
 
 	"github.com/jessepeterson/mdmcommands"
 	"github.com/micromdm/nanolib/log"
@@ -52,7 +53,7 @@ func (w *Workflow) Name() string {
 }
 
 func (w *Workflow) Config() *workflow.Config {
-	return nil
+	return &workflow.Config{ExclusivityDomain: fvenable.ExclusivityDomain}
 }
 
 func (w *Workflow) NewContextValue(name string) workflow.ContextMarshaler {
@@ -99,6 +100,7 @@ func (w *Workflow) StepCompleted(ctx context.Context, stepResult *workflow.StepR
 		return workflow.ErrIncorrectCommandType
 	}
 	if err := response.Validate(); err != nil {
+		stepResult.Finish(workflow.OutcomeFailed, "rotate FileVault key command failed")
 		return fmt.Errorf("validating rotate response: %w", err)
 	}
 
@@ -115,6 +117,7 @@ func (w *Workflow) StepCompleted(ctx context.Context, stepResult *workflow.StepR
 		logkeys.EnrollmentID, stepResult.ID,
 		logkeys.Message, "escrowed PRK",
 	)
+	stepResult.Finish(workflow.OutcomeSucceeded, "FileVault key rotated and PRK escrowed")
 	return nil
 }
 
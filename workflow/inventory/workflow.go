@@ -101,6 +101,7 @@ func (w *Workflow) StepCompleted(ctx context.Context, stepResult *workflow.StepR
 			// command as an event.
 
 			if err := r.Validate(); err != nil {
+				stepResult.Finish(workflow.OutcomeFailed, "device information command failed")
 				return fmt.Errorf("device info response: %w", err)
 			}
 
@@ -121,10 +122,13 @@ func (w *Workflow) StepCompleted(ctx context.Context, stepResult *workflow.StepR
 			if len(v) > 0 {
 				v[storage.KeyLastSource] = mdmcommands.DeviceInformationRequestType
 				v[storage.KeyModified] = time.Now()
-				return w.store.StoreInventoryValues(ctx, stepResult.ID, v)
+				if err := w.store.StoreInventoryValues(ctx, stepResult.ID, v); err != nil {
+					return err
+				}
 			}
 		}
 	}
+	stepResult.Finish(workflow.OutcomeSucceeded, "inventory updated")
 	return nil
 }
 
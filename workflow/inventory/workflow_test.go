@@ -15,7 +15,8 @@ import (
 )
 
 func TestWorkflow(t *testing.T) {
-	e := engine.New(enginestorage.New(), &test.NullEnqueuer{})
+	es := enginestorage.New()
+	e := engine.New(es, &test.NullEnqueuer{})
 
 	c := test.NewCollectingStepEnqueur(e)
 
@@ -45,7 +46,7 @@ func TestWorkflow(t *testing.T) {
 		t.Fatal("workflow name not equal after registration")
 	}
 
-	_, err = e.StartWorkflow(ctx, w.Name(), nil, []string{id}, nil, nil)
+	instanceID, err := e.StartWorkflow(ctx, w.Name(), nil, []string{id}, nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -104,4 +105,16 @@ func TestWorkflow(t *testing.T) {
 	if want, have := true, values[storage.KeySIPEnabled].(bool); want != have {
 		t.Errorf("KeySIPEnabled: %v; have: %v", want, have)
 	}
+
+	// the engine persists the reported outcome of the instance
+	outcomes, err := es.RetrieveOutcomes(ctx, instanceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 1, len(outcomes); want != have {
+		t.Fatalf("wanted: %d; have: %d", want, have)
+	}
+	if want, have := workflow.OutcomeSucceeded, outcomes[0].Outcome; want != have {
+		t.Errorf("wanted: %s; have: %s", want, have)
+	}
 }
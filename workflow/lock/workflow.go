@@ -9,6 +9,7 @@ import (
 
 	"github.com/micromdm/nanocmd/logkeys"
 	"github.com/micromdm/nanocmd/subsystem/inventory/storage"
+	"github.com/micromdm/nanocmd/subsystem/secret"
 	"github.com/micromdm/nanocmd/utils/uuid"
 	"github.com/micromdm/nanocmd/workflow"
 
@@ -19,11 +20,17 @@ import (
 
 const WorkflowName = "io.micromdm.wf.lock.v1"
 
+// SecretStorer stores secrets encrypted at rest.
+type SecretStorer interface {
+	StoreSecret(ctx context.Context, id, kind, secret string) error
+}
+
 type Workflow struct {
-	enq    workflow.StepEnqueuer
-	ider   uuid.IDer
-	logger log.Logger
-	store  storage.Storage
+	enq     workflow.StepEnqueuer
+	ider    uuid.IDer
+	logger  log.Logger
+	store   storage.Storage
+	secrets SecretStorer
 }
 
 type Option func(*Workflow)
@@ -34,6 +41,14 @@ func WithLogger(logger log.Logger) Option {
 	}
 }
 
+// WithSecretStorage stores DeviceLock PINs encrypted in secrets.
+// Otherwise PINs are stored in plaintext in inventory.
+func WithSecretStorage(secrets SecretStorer) Option {
+	return func(w *Workflow) {
+		w.secrets = secrets
+	}
+}
+
 func New(q workflow.StepEnqueuer, store storage.Storage, opts ...Option) (*Workflow, error) {
 	w := &Workflow{
 		enq:    q,
@@ -69,11 +84,19 @@ func randomDigits(n int) string {
 }
 
 func (w *Workflow) storeLock(ctx context.Context, id, pin string) error {
-	return w.store.StoreInventoryValues(ctx, id, storage.Values{
-		WorkflowName + ".pin":  pin,
+	v := storage.Values{
+		storage.KeyLockPIN:     pin,
 		WorkflowName + ".sent": time.Now(),
 		storage.KeyLastSource:  WorkflowName,
-	})
+	}
+	if w.secrets != nil {
+		if err := w.secrets.StoreSecret(ctx, id, secret.KindLockPIN, pin); err != nil {
+			return fmt.Errorf("store secret: %w", err)
+		}
+		// remove any plaintext PIN of an earlier lock
+		v[storage.KeyLockPIN] = nil
+	}
+	return w.store.StoreInventoryValues(ctx, id, v)
 }
 
 func (w *Workflow) updateLock(ctx context.Context, id, msg string) error {
@@ -94,7 +117,7 @@ func (w *Workflow) Start(ctx context.Context, step *workflow.StepStart) error {
 
 		err := w.storeLock(ctx, id, pin)
 		if err != nil {
-			return fmt.Errorf("store inventory values for %s: %w", id, err)
+			return fmt.Errorf("store lock for %s: %w", id, err)
 		}
 
 		// create MDM command
@@ -123,6 +146,7 @@ func (w *Workflow) StepCompleted(ctx context.Context, stepResult *workflow.StepR
 		return workflow.ErrIncorrectCommandType
 	}
 	if err := response.Validate(); err != nil {
+		stepResult.Finish(workflow.OutcomeFailed, "device lock command failed")
 		return fmt.Errorf("validating lock response: %w", err)
 	}
 
@@ -142,6 +166,7 @@ func (w *Workflow) StepCompleted(ctx context.Context, stepResult *workflow.StepR
 		return fmt.Errorf("update inventory values for %s: %w", stepResult.ID, err)
 	}
 
+	stepResult.Finish(workflow.OutcomeSucceeded, "device locked")
 	return nil
 }
 
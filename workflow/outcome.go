@@ -0,0 +1,25 @@
+package workflow
+
+// Outcome is the final result of a workflow instance for an enrollment.
+type Outcome string
+
+const (
+	// OutcomeSucceeded is a workflow instance that finished successfully.
+	OutcomeSucceeded Outcome = "succeeded"
+
+	// OutcomeFailed is a workflow instance that finished unsuccessfully.
+	OutcomeFailed Outcome = "failed"
+
+	// OutcomeTimedOut is a workflow instance that finished because its
+	// last step timed out.
+	OutcomeTimedOut Outcome = "timed_out"
+)
+
+// Valid returns true if o is a known outcome.
+func (o Outcome) Valid() bool {
+	switch o {
+	case OutcomeSucceeded, OutcomeFailed, OutcomeTimedOut:
+		return true
+	}
+	return false
+}
@@ -3,11 +3,16 @@ package profile
 
 import (
 	"context"
+	"crypto/x509"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/micromdm/nanocmd/logkeys"
+	invstorage "github.com/micromdm/nanocmd/subsystem/inventory/storage"
+	"github.com/micromdm/nanocmd/subsystem/profile/render"
 	"github.com/micromdm/nanocmd/subsystem/profile/storage"
 	"github.com/micromdm/nanocmd/utils/uuid"
 	"github.com/micromdm/nanocmd/workflow"
@@ -21,10 +26,12 @@ const WorkflowName = "io.micromdm.wf.profile.v1"
 
 // Workflow "statefully" installs and removes profiles.
 type Workflow struct {
-	enq    workflow.StepEnqueuer
-	store  storage.ReadStorage
-	ider   uuid.IDer
-	logger log.Logger
+	enq      workflow.StepEnqueuer
+	store    storage.ReadStorage
+	ider     uuid.IDer
+	logger   log.Logger
+	invStore invstorage.InstalledProfileStorage
+	renderer *render.Renderer
 }
 
 type Option func(*Workflow)
@@ -35,12 +42,30 @@ func WithLogger(logger log.Logger) Option {
 	}
 }
 
+// WithInstalledProfileStorage stores the installed profiles from every
+// ProfileList command response in store. This includes ProfileList
+// commands sent by other workflows.
+func WithInstalledProfileStorage(store invstorage.InstalledProfileStorage) Option {
+	return func(w *Workflow) {
+		w.invStore = store
+	}
+}
+
+// WithRenderer renders profile templates with r when installing profiles.
+// By default only templates without inventory variables can be rendered.
+func WithRenderer(r *render.Renderer) Option {
+	return func(w *Workflow) {
+		w.renderer = r
+	}
+}
+
 func New(enq workflow.StepEnqueuer, store storage.ReadStorage, opts ...Option) (*Workflow, error) {
 	w := &Workflow{
-		enq:    enq,
-		store:  store,
-		ider:   uuid.NewUUID(),
-		logger: log.NopLogger,
+		enq:      enq,
+		store:    store,
+		ider:     uuid.NewUUID(),
+		logger:   log.NopLogger,
+		renderer: render.New(nil),
 	}
 	for _, opt := range opts {
 		opt(w)
@@ -54,7 +79,13 @@ func (w *Workflow) Name() string {
 }
 
 func (w *Workflow) Config() *workflow.Config {
-	return nil
+	if w.invStore == nil {
+		return nil
+	}
+	return &workflow.Config{
+		// we want all ProfileList commands, regardless of whether this workflow sent them.
+		AllCommandResponseRequestTypes: []string{"ProfileList"},
+	}
 }
 
 func (w *Workflow) NewContextValue(name string) workflow.ContextMarshaler {
@@ -85,7 +116,7 @@ func (w *Workflow) Start(ctx context.Context, step *workflow.StepStart) error {
 
 	// retrive the infos to make sure they exist. this avoids starting
 	// the workflow if we supplied an invalid set of profile names.
-	if _, err := w.store.RetrieveProfileInfos(ctx, all); err != nil {
+	if _, err := w.retrieveProfileInfos(ctx, all); err != nil {
 		return fmt.Errorf("retrieving profile info: %w", err)
 	}
 
@@ -138,6 +169,98 @@ func splitInstallRemove(s CommaStringSliceContext) (all []string, ret map[string
 	return
 }
 
+// splitRevision splits a profile name pinned to a revision in the form
+// "name@rev" into its name and revision. A zero revision is returned
+// if the name is not pinned.
+func splitRevision(name string) (string, int, error) {
+	base, revStr, ok := strings.Cut(name, storage.RevisionSeparator)
+	if !ok {
+		return name, 0, nil
+	}
+	rev, err := strconv.Atoi(revStr)
+	if err != nil || rev < 1 {
+		return name, 0, fmt.Errorf("invalid profile revision: %s", name)
+	}
+	return base, rev, nil
+}
+
+// retrieveProfileInfos retrieves the profile infos of names.
+// Names pinned to a revision use the info of that revision.
+func (w *Workflow) retrieveProfileInfos(ctx context.Context, names []string) (map[string]storage.ProfileInfo, error) {
+	var unpinned []string
+	ret := make(map[string]storage.ProfileInfo)
+	for _, name := range names {
+		base, rev, err := splitRevision(name)
+		if err != nil {
+			return nil, err
+		}
+		if rev < 1 {
+			unpinned = append(unpinned, name)
+			continue
+		}
+		revs, err := w.store.RetrieveRevisions(ctx, base)
+		if err != nil {
+			return nil, err
+		}
+		found := false
+		for _, r := range revs {
+			if r.Revision == rev {
+				ret[name] = storage.ProfileInfo{
+					Identifier: r.Identifier,
+					UUID:       r.UUID,
+					Signer:     r.Signer,
+					Revision:   r.Revision,
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: %s", storage.ErrRevisionNotFound, name)
+		}
+	}
+	if len(unpinned) > 0 {
+		infos, err := w.store.RetrieveProfileInfos(ctx, unpinned)
+		if err != nil {
+			return nil, err
+		}
+		for name, info := range infos {
+			ret[name] = info
+		}
+	}
+	return ret, nil
+}
+
+// retrieveRawProfiles retrieves the raw profiles of names.
+// Names pinned to a revision use the raw profile of that revision.
+func (w *Workflow) retrieveRawProfiles(ctx context.Context, names []string) (map[string][]byte, error) {
+	var unpinned []string
+	ret := make(map[string][]byte)
+	for _, name := range names {
+		base, rev, err := splitRevision(name)
+		if err != nil {
+			return nil, err
+		}
+		if rev < 1 {
+			unpinned = append(unpinned, name)
+			continue
+		}
+		if ret[name], err = w.store.RetrieveRawRevision(ctx, base, rev); err != nil {
+			return nil, err
+		}
+	}
+	if len(unpinned) > 0 {
+		raws, err := w.store.RetrieveRawProfiles(ctx, unpinned)
+		if err != nil {
+			return nil, err
+		}
+		for name, raw := range raws {
+			ret[name] = raw
+		}
+	}
+	return ret, nil
+}
+
 func (w *Workflow) listStepCompleted(ctx context.Context, stepResult *workflow.StepResult) error {
 	if len(stepResult.CommandResults) != 1 {
 		return workflow.ErrStepResultCommandLenMismatch
@@ -159,7 +282,7 @@ func (w *Workflow) listStepCompleted(ctx context.Context, stepResult *workflow.S
 	all, manageMap := splitInstallRemove(*manageList)
 
 	// retrieve the list of profiles provided to the workflow when started
-	allProfsToManage, err := w.store.RetrieveProfileInfos(ctx, all)
+	allProfsToManage, err := w.retrieveProfileInfos(ctx, all)
 	if err != nil {
 		return fmt.Errorf("retrieving profile info: %w", err)
 	}
@@ -196,7 +319,7 @@ loop:
 	var profToInstRaw map[string][]byte
 	if len(profToInstSlice) > 0 {
 		// retrieve the raw profiles from the store
-		profToInstRaw, err = w.store.RetrieveRawProfiles(ctx, profToInstSlice)
+		profToInstRaw, err = w.retrieveRawProfiles(ctx, profToInstSlice)
 		if err != nil {
 			return fmt.Errorf("retrieving raw profiles: %w", err)
 		}
@@ -211,9 +334,21 @@ loop:
 	for name, manageStyle := range manageMap {
 		switch manageStyle {
 		case manageToInstall:
+			raw, err := w.renderer.Render(ctx, stepResult.ID, stepResult.Params, profToInstRaw[name])
+			if err != nil {
+				return fmt.Errorf("rendering profile: %s: %w", name, err)
+			}
 			cmd := mdmcommands.NewInstallProfileCommand(w.ider.ID())
-			cmd.Command.Payload = profToInstRaw[name]
+			cmd.Command.Payload = raw
 			se.Commands = append(se.Commands, cmd)
+			ctxlog.Logger(ctx, w.logger).Debug(
+				logkeys.InstanceID, stepResult.InstanceID,
+				logkeys.EnrollmentID, stepResult.ID,
+				logkeys.CommandUUID, cmd.CommandUUID,
+				logkeys.Message, "installing profile",
+				"name", name,
+				"revision", allProfsToManage[name].Revision,
+			)
 		case manageToRemove:
 			cmd := mdmcommands.NewRemoveProfileCommand(w.ider.ID())
 			cmd.Command.Identifier = allProfsToManage[name].Identifier
@@ -231,6 +366,7 @@ loop:
 		logkeys.EnrollmentID, stepResult.ID,
 		logkeys.Message, "no profiles to install or remove after profile list",
 	)
+	stepResult.Finish(workflow.OutcomeSucceeded, "no profiles to install or remove")
 	return nil
 }
 
@@ -244,6 +380,7 @@ func (w *Workflow) StepCompleted(ctx context.Context, stepResult *workflow.StepR
 			logkeys.StepName, stepResult.Name,
 		)
 		statuses := make(map[string]int)
+		var failed int
 		for _, resp := range stepResult.CommandResults {
 			genResper, ok := resp.(mdmcommands.GenericResponser)
 			if !ok {
@@ -253,6 +390,7 @@ func (w *Workflow) StepCompleted(ctx context.Context, stepResult *workflow.StepR
 			statuses[genResp.Status] += 1
 			// TODO: log the association from command UUID to profile name
 			if err := genResp.Validate(); err != nil {
+				failed++
 				logger.Info(
 					logkeys.Message, "validate MDM response",
 					logkeys.CommandUUID, genResp.CommandUUID,
@@ -265,6 +403,11 @@ func (w *Workflow) StepCompleted(ctx context.Context, stepResult *workflow.StepR
 			logs = append(logs, "count_"+strings.ToLower(k), v)
 		}
 		logger.Debug(logs...)
+		if failed > 0 {
+			stepResult.Finish(workflow.OutcomeFailed, fmt.Sprintf("%d of %d profile commands failed", failed, len(stepResult.CommandResults)))
+		} else {
+			stepResult.Finish(workflow.OutcomeSucceeded, fmt.Sprintf("%d profile commands succeeded", len(stepResult.CommandResults)))
+		}
 		return nil
 	default:
 		return fmt.Errorf("%w: %s", workflow.ErrUnknownStepName, stepResult.Name)
@@ -275,6 +418,46 @@ func (w *Workflow) StepTimeout(_ context.Context, _ *workflow.StepResult) error
 	return workflow.ErrTimeoutNotUsed
 }
 
-func (w *Workflow) Event(_ context.Context, _ *workflow.Event, _ string, _ *workflow.MDMContext) error {
-	return workflow.ErrEventsNotSupported
+// NormalizeProfileList converts ProfileList items into inventory installed profiles.
+func NormalizeProfileList(items []mdmcommands.ProfileListItem, modified time.Time) []invstorage.InstalledProfile {
+	profiles := make([]invstorage.InstalledProfile, 0, len(items))
+	for _, item := range items {
+		profile := invstorage.InstalledProfile{
+			Identifier: item.PayloadIdentifier,
+			UUID:       item.PayloadUUID,
+			Managed:    item.IsManaged,
+			Modified:   modified,
+		}
+		if item.PayloadDisplayName != nil {
+			profile.DisplayName = *item.PayloadDisplayName
+		}
+		if item.SignerCertificates != nil && len(*item.SignerCertificates) > 0 {
+			if crt, err := x509.ParseCertificate((*item.SignerCertificates)[0]); err == nil {
+				profile.Signer = crt.Subject.String()
+			}
+		}
+		profiles = append(profiles, profile)
+	}
+	return profiles
+}
+
+func (w *Workflow) Event(ctx context.Context, e *workflow.Event, id string, _ *workflow.MDMContext) error {
+	if w.invStore == nil {
+		return workflow.ErrEventsNotSupported
+	}
+	switch evData := e.EventData.(type) {
+	case *mdmcommands.ProfileListResponse:
+		if err := evData.Validate(); err != nil {
+			return fmt.Errorf("profile list response: %w", err)
+		}
+		profiles := NormalizeProfileList(evData.ProfileList, time.Now())
+		ctxlog.Logger(ctx, w.logger).Debug(
+			logkeys.Message, "storing installed profiles",
+			logkeys.EnrollmentID, id,
+			logkeys.GenericCount, len(profiles),
+		)
+		return w.invStore.StoreInstalledProfiles(ctx, id, profiles)
+	default:
+		return fmt.Errorf("unknown event data type for event: %s", e.EventFlag)
+	}
 }
@@ -0,0 +1,34 @@
+package profile
+
+import "testing"
+
+func TestSplitRevision(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		name  string
+		rev   int
+		err   bool
+	}{
+		{"a", "a", 0, false},
+		{"a@2", "a", 2, false},
+		{"a@b@3", "", 0, true}, // names can not contain "@"
+		{"a@", "", 0, true},
+		{"a@0", "", 0, true},
+		{"a@x", "", 0, true},
+	} {
+		name, rev, err := splitRevision(test.input)
+		if have, want := err != nil, test.err; have != want {
+			t.Errorf("%s: have err: %v, want err: %v", test.input, err, want)
+			continue
+		}
+		if test.err {
+			continue
+		}
+		if have, want := name, test.name; have != want {
+			t.Errorf("have: %v, want: %v", have, want)
+		}
+		if have, want := rev, test.rev; have != want {
+			t.Errorf("have: %v, want: %v", have, want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Package restart implements a NanoCMD Workflow that restarts devices.
+// Its steps are only sent inside enrollment maintenance windows.
+package restart
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jessepeterson/mdmcommands"
+	"github.com/micromdm/nanocmd/logkeys"
+	"github.com/micromdm/nanocmd/utils/uuid"
+	"github.com/micromdm/nanocmd/workflow"
+	"github.com/micromdm/nanolib/log"
+	"github.com/micromdm/nanolib/log/ctxlog"
+)
+
+const WorkflowName = "io.micromdm.wf.restart.v1"
+
+// Workflow restarts devices.
+type Workflow struct {
+	enq    workflow.StepEnqueuer
+	ider   uuid.IDer
+	logger log.Logger
+}
+
+// Options configure [Workflow].
+type Option func(*Workflow)
+
+// WithLogger sets the logger.
+func WithLogger(logger log.Logger) Option {
+	return func(w *Workflow) {
+		w.logger = logger
+	}
+}
+
+// New creates a new [Workflow].
+func New(enq workflow.StepEnqueuer, opts ...Option) (*Workflow, error) {
+	w := &Workflow{
+		enq:    enq,
+		ider:   uuid.NewUUID(),
+		logger: log.NopLogger,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	w.logger = w.logger.With(logkeys.WorkflowName, w.Name())
+	return w, nil
+}
+
+// Name returns the name of w.
+func (w *Workflow) Name() string {
+	return WorkflowName
+}
+
+// Config opts the workflow into maintenance windows.
+func (w *Workflow) Config() *workflow.Config {
+	return &workflow.Config{MaintenanceWindow: true}
+}
+
+// NewContextValue returns nil.
+func (w *Workflow) NewContextValue(_ string) workflow.ContextMarshaler {
+	return nil
+}
+
+// Start starts the workflow.
+func (w *Workflow) Start(ctx context.Context, step *workflow.StepStart) error {
+	notify := true
+	cmd := mdmcommands.NewRestartDeviceCommand(w.ider.ID())
+	cmd.Command.NotifyUser = &notify
+
+	// assemble our StepEnqueuing
+	se := step.NewStepEnqueueing()
+	se.Commands = []interface{}{cmd}
+
+	// enqueue our step!
+	return w.enq.EnqueueStep(ctx, w, se)
+}
+
+// StepCompleted is called back whenever any step completes for this workflow.
+func (w *Workflow) StepCompleted(ctx context.Context, stepResult *workflow.StepResult) error {
+	if len(stepResult.CommandResults) != 1 {
+		return workflow.ErrStepResultCommandLenMismatch
+	}
+	response, ok := stepResult.CommandResults[0].(*mdmcommands.RestartDeviceResponse)
+	if !ok {
+		return workflow.ErrIncorrectCommandType
+	}
+	if err := response.Validate(); err != nil {
+		return fmt.Errorf("validating restart device response: %w", err)
+	}
+	ctxlog.Logger(ctx, w.logger).Debug(
+		logkeys.InstanceID, stepResult.InstanceID,
+		logkeys.EnrollmentID, stepResult.ID,
+		logkeys.Message, "restart acknowledged",
+	)
+	return nil
+}
+
+// StepTimeout returns an error; step timeouts are not used in this workflow.
+func (w *Workflow) StepTimeout(_ context.Context, _ *workflow.StepResult) error {
+	return workflow.ErrTimeoutNotUsed
+}
+
+// Event returns an error; events are not supported on this workflow.
+func (w *Workflow) Event(_ context.Context, _ *workflow.Event, _ string, _ *workflow.MDMContext) error {
+	return workflow.ErrEventsNotSupported
+}
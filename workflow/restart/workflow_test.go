@@ -0,0 +1,94 @@
+package restart
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/micromdm/nanocmd/engine"
+	"github.com/micromdm/nanocmd/engine/storage/inmem"
+)
+
+// recordingEnqueuer records the IDs and raw commands enqueued.
+type recordingEnqueuer struct {
+	ids [][]string
+	raw [][]byte
+}
+
+func (r *recordingEnqueuer) Enqueue(_ context.Context, ids []string, raw []byte) error {
+	r.ids = append(r.ids, ids)
+	r.raw = append(r.raw, raw)
+	return nil
+}
+
+func (r *recordingEnqueuer) SupportsMultiCommands() bool { return true }
+
+// laterWindows has maintenance windows starting at a later time for
+// the enrollments it contains.
+type laterWindows map[string]time.Time
+
+func (lw laterWindows) NextWindows(_ context.Context, ids []string, t time.Time) (map[string]time.Time, error) {
+	r := make(map[string]time.Time)
+	for _, id := range ids {
+		r[id] = t
+		if next, ok := lw[id]; ok && next.After(t) {
+			r[id] = next
+		}
+	}
+	return r, nil
+}
+
+func TestRestartMaintenanceWindow(t *testing.T) {
+	ctx := context.Background()
+	enq := new(recordingEnqueuer)
+	s := inmem.New()
+	windowStart := time.Now().Add(100 * time.Millisecond)
+	e := engine.New(s, enq, engine.WithMaintenanceWindows(laterWindows{"BBB": windowStart}))
+
+	w, err := New(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !w.Config().MaintenanceWindow {
+		t.Fatal("workflow not configured for maintenance windows")
+	}
+	if err = e.RegisterWorkflow(w); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = e.StartWorkflow(ctx, w.Name(), nil, []string{"AAA", "BBB"}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// only the enrollment inside its window is restarted now
+	if have, want := enq.ids, [][]string{{"AAA"}}; !reflect.DeepEqual(have, want) {
+		t.Fatalf("have: %v, want: %v", have, want)
+	}
+	for _, want := range []string{"RestartDevice", "NotifyUser"} {
+		if !bytes.Contains(enq.raw[0], []byte(want)) {
+			t.Errorf("command does not contain %s", want)
+		}
+	}
+
+	// the restart of the other enrollment waits for its window
+	steps, err := s.RetrieveStepsToEnqueue(ctx, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := len(steps), 0; have != want {
+		t.Errorf("have: %v, want: %v", have, want)
+	}
+
+	// wait for the window to start
+	time.Sleep(time.Until(windowStart))
+
+	steps, err = s.RetrieveStepsToEnqueue(ctx, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 1 || !reflect.DeepEqual(steps[0].IDs, []string{"BBB"}) {
+		t.Errorf("unexpected steps to enqueue: %v", steps)
+	}
+}
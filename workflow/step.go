@@ -43,7 +43,18 @@ type StepEnqueueing struct {
 
 	// A step should not be enqueued (that is, sent to enrollments)
 	// until after this time has passed. A delay of sorts.
+	// A step with no Commands but a NotUntil time is a timer step: the
+	// workflow's step completion handler is called back for each
+	// enrollment ID after this time with the step name and context
+	// but without any command results.
 	NotUntil time.Time
+
+	// WaitEvent makes a step with no Commands an event step: the step
+	// waits for this MDM check-in event (Authenticate, TokenUpdate,
+	// Enrollment, or CheckOut) from each enrollment ID. The workflow's
+	// step completion handler is called back with the event in the
+	// step result. Timeout is optional for event steps.
+	WaitEvent EventFlag
 }
 
 // StepStart is provided to a workflow when starting a new workflow instance.
@@ -52,6 +63,34 @@ type StepStart struct {
 	StepContext
 	Event *Event
 	IDs   []string // Enrollment IDs
+
+	// enrollments finished by the workflow using Finish
+	finished []*StepResult
+}
+
+// Finish reports that the workflow instance has finished for the
+// enrollment id of step with outcome o and a summary message. Workflows
+// should call Finish when they will not enqueue any steps for id when
+// starting. Otherwise no outcome is recorded for id.
+func (step *StepStart) Finish(id string, o Outcome, message string) {
+	if step == nil {
+		return
+	}
+	stepResult := &StepResult{
+		StepContext: StepContext{MDMContext: step.MDMContext, InstanceID: step.InstanceID},
+		ID:          id,
+	}
+	stepResult.Finish(o, message)
+	step.finished = append(step.finished, stepResult)
+}
+
+// Finished returns the enrollments reported by Finish as step results
+// with their outcomes.
+func (step *StepStart) Finished() []*StepResult {
+	if step == nil {
+		return nil
+	}
+	return step.finished
 }
 
 // NewStepEnqueueing preserves some context and IDs from step for enqueueing.
@@ -70,6 +109,36 @@ type StepResult struct {
 	StepContext
 	ID             string
 	CommandResults []interface{}
+
+	// Event is the MDM check-in event that completed an event step.
+	Event *Event
+
+	// outcome reported by the workflow using Finish
+	outcome        Outcome
+	outcomeMessage string
+}
+
+// Finish reports that the workflow instance has finished for the
+// enrollment of step with outcome o and a summary message. Workflows
+// should call Finish when they will not enqueue any further steps.
+// If a workflow does not call Finish then the engine considers the
+// instance finished once no more steps are outstanding for the
+// enrollment.
+func (step *StepResult) Finish(o Outcome, message string) {
+	if step == nil {
+		return
+	}
+	step.outcome = o
+	step.outcomeMessage = message
+}
+
+// Outcome returns the outcome and summary message reported by Finish.
+// The outcome is empty if Finish was not called.
+func (step *StepResult) Outcome() (Outcome, string) {
+	if step == nil {
+		return "", ""
+	}
+	return step.outcome, step.outcomeMessage
 }
 
 // NewStepEnqueueing preserves some context and IDs from step for enqueueing.